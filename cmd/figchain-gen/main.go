@@ -0,0 +1,92 @@
+// Command figchain-gen generates Go structs implementing client.AvroRecord from the Avro
+// schemas referenced by a namespace's fig definitions.
+//
+// Usage:
+//
+//	figchain-gen -config figchain.yaml -namespace web -package figs -out figs/figs_gen.go
+//
+// The client is configured from the config file and FIGCHAIN_* environment variables as
+// by config.LoadConfig. Add a go:generate directive running it to keep the structs in
+// step with the server's schemas.
+//
+// With -manifest, it also generates a struct of typed accessors for the keys listed in a
+// manifest (see codegen.LoadManifest), e.g.
+//
+//	figchain-gen -namespace payments -out figs/figs_gen.go -manifest figs.yaml -accessors-out figs/accessors_gen.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/figchain/go-client/pkg/client"
+	"github.com/figchain/go-client/pkg/codegen"
+	"github.com/figchain/go-client/pkg/config"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to the client config file (default ./figchain.yaml)")
+	namespaces := flag.String("namespace", "", "comma-separated namespaces to generate for (default all configured)")
+	pkg := flag.String("package", "figs", "package name of the generated code")
+	out := flag.String("out", "", "file to write (default stdout)")
+	manifestPath := flag.String("manifest", "", "accessor manifest to generate typed accessors from")
+	accessorsOut := flag.String("accessors-out", "", "file to write the accessors to (default stdout)")
+	timeout := flag.Duration("timeout", time.Minute, "how long to wait for the server")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	var nsList []string
+	if *namespaces != "" {
+		nsList = strings.Split(*namespaces, ",")
+		cfg.Namespaces = nsList
+	}
+	// Only the bootstrap is needed
+	c, err := client.New(config.WithConfig(cfg), config.WithFrozen())
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	var buf bytes.Buffer
+	if err := codegen.Generate(ctx, *pkg, c.Families(nsList...), c.FetchSchema, &buf); err != nil {
+		log.Fatalf("Failed to generate code: %v", err)
+	}
+	write(*out, buf.Bytes())
+
+	if *manifestPath == "" {
+		return
+	}
+	m, err := codegen.LoadManifest(*manifestPath)
+	if err != nil {
+		log.Fatalf("Failed to load manifest: %v", err)
+	}
+	if m.Package == "" {
+		m.Package = *pkg
+	}
+	buf.Reset()
+	if err := codegen.GenerateAccessors(ctx, m, c.Families(m.Namespace), c.FetchSchema, &buf); err != nil {
+		log.Fatalf("Failed to generate accessors: %v", err)
+	}
+	write(*accessorsOut, buf.Bytes())
+}
+
+// write writes generated code to path, or stdout if path is empty.
+func write(path string, src []byte) {
+	if path == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(path, src, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,83 @@
+// Command figchain-relay runs one FigChain client and serves its families to co-located
+// processes, which point their BaseURL at the relay instead of the FigChain server.
+//
+// Usage:
+//
+//	FIGCHAIN_RELAY_TOKEN=... figchain-relay -config figchain.yaml -listen unix:/run/figchain.sock
+//
+// The client is configured from the config file and FIGCHAIN_* environment variables as
+// by config.LoadConfig. Downstream clients authenticate with the relay token as their
+// client secret.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/figchain/go-client/pkg/client"
+	"github.com/figchain/go-client/pkg/config"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to the client config file (default ./figchain.yaml)")
+	listen := flag.String("listen", "127.0.0.1:7070", `address to listen on, "host:port" or "unix:/path"`)
+	pollTimeout := flag.Duration("poll-timeout", client.DefaultRelayPollTimeout, "how long update requests wait for changes")
+	flag.Parse()
+
+	token := os.Getenv("FIGCHAIN_RELAY_TOKEN")
+	if token == "" {
+		log.Fatal("FIGCHAIN_RELAY_TOKEN is required")
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	c, err := client.New(config.WithConfig(cfg))
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ln, err := listenOn(*listen)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", *listen, err)
+	}
+	server := &http.Server{
+		Handler:           client.NewRelayHandler(c, client.WithRelayToken(token), client.WithRelayPollTimeout(*pollTimeout)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Relaying on %s", *listen)
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Relay stopped: %v", err)
+	}
+}
+
+// listenOn listens on a TCP address or, with a "unix:" prefix, a Unix socket.
+func listenOn(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// Remove a socket left behind by a previous run
+		os.Remove(path)
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
@@ -0,0 +1,274 @@
+// Command figchain-repl is an interactive playground for rule authors: set evaluation
+// context attributes, evaluate keys and see the rule that matched and the decoded
+// payload, without deploying an application.
+//
+// Usage:
+//
+//	figchain-repl -config figchain.yaml
+//	figchain-repl -snapshot state.bin
+//
+// The client is configured from the config file and FIGCHAIN_* environment variables as
+// by config.LoadConfig. With -snapshot, it starts from state saved by Client.Handoff
+// instead and doesn't poll for updates. Keys are evaluated in the first configured
+// namespace. Type "help" at the prompt for the commands.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/figchain/go-client/pkg/client"
+	"github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+const help = `Commands:
+  set NAME=VALUE   set a context attribute
+  unset NAME       remove a context attribute
+  attrs            show the context attributes
+  clear            remove all context attributes
+  keys             list the keys
+  schema KEY FILE  decode KEY with the Avro schema in FILE, e.g. when offline
+  eval KEY         evaluate KEY (or just type KEY)
+  help             show this help
+  quit             exit`
+
+func main() {
+	configPath := flag.String("config", "", "path to the client config file (default ./figchain.yaml)")
+	snapshotPath := flag.String("snapshot", "", "start from state saved by Client.Handoff instead of the server")
+	timeout := flag.Duration("timeout", time.Minute, "how long to wait for the server")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	opts := []config.Option{config.WithConfig(cfg)}
+	if *snapshotPath != "" {
+		snapshotOpts, err := snapshotOptions(cfg, *snapshotPath)
+		if err != nil {
+			log.Fatalf("Failed to load snapshot: %v", err)
+		}
+		opts = append(opts, snapshotOpts...)
+	}
+	c, err := client.New(opts...)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	r := &repl{client: c, attributes: map[string]string{}, schemas: map[string]string{}, local: map[string]string{}, timeout: *timeout}
+	r.run(os.Stdin, os.Stdout)
+}
+
+// snapshotOptions returns the options starting a frozen client from the snapshot at path,
+// for the namespaces it holds unless the config names them.
+func snapshotOptions(cfg *config.Config, path string) ([]config.Option, error) {
+	state, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := store.UnmarshalSnapshot(state)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	opts := []config.Option{config.WithHandoffState(state), config.WithFrozen()}
+	if len(cfg.Namespaces) == 0 {
+		opts = append(opts, config.WithNamespaces(slices.Sorted(maps.Keys(snapshot.Cursors))...))
+	}
+	// The server isn't contacted, but the client still requires an environment and
+	// credentials
+	if cfg.EnvironmentID == "" {
+		opts = append(opts, config.WithEnvironmentID("snapshot"))
+	}
+	if cfg.ClientSecret == "" && cfg.AuthPrivateKeyPath == "" {
+		opts = append(opts, config.WithClientSecret("snapshot"))
+	}
+	return opts, nil
+}
+
+type repl struct {
+	client     *client.Client
+	attributes map[string]string
+	schemas    map[string]string // by schema URI
+	local      map[string]string // by key, loaded with the schema command
+	timeout    time.Duration
+}
+
+// result is the outcome of evaluating a key, printed as JSON.
+type result struct {
+	Key     string        `json:"key"`
+	Version string        `json:"version,omitempty"`
+	Rule    *matchedRule  `json:"rule,omitempty"`
+	Matches []matchedRule `json:"matches,omitempty"`
+	Value   any           `json:"value,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+type matchedRule struct {
+	Index       int    `json:"index"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+func (r *repl) run(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintln(out, `Type "help" for the commands.`)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "":
+		case "quit", "exit":
+			return
+		case "help":
+			fmt.Fprintln(out, help)
+		case "set":
+			name, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				name, value, ok = strings.Cut(arg, " ")
+			}
+			if !ok || strings.TrimSpace(name) == "" {
+				fmt.Fprintln(out, "usage: set NAME=VALUE")
+				continue
+			}
+			r.attributes[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		case "unset":
+			delete(r.attributes, arg)
+		case "clear":
+			clear(r.attributes)
+		case "attrs":
+			writeJSON(out, r.attributes)
+		case "keys":
+			var keys []string
+			for _, key := range r.client.Keys() {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				fmt.Fprintln(out, key)
+			}
+		case "schema":
+			key, path, ok := strings.Cut(arg, " ")
+			if !ok {
+				fmt.Fprintln(out, "usage: schema KEY FILE")
+				continue
+			}
+			schema, err := os.ReadFile(strings.TrimSpace(path))
+			if err != nil {
+				fmt.Fprintf(out, "failed to read schema: %v\n", err)
+				continue
+			}
+			r.local[key] = string(schema)
+		case "eval":
+			writeJSON(out, r.evaluate(arg))
+		default:
+			if arg != "" {
+				fmt.Fprintf(out, "unknown command %q, type \"help\" for the commands\n", cmd)
+				continue
+			}
+			writeJSON(out, r.evaluate(cmd))
+		}
+	}
+}
+
+// evaluate evaluates key with the current attributes.
+func (r *repl) evaluate(key string) result {
+	res := result{Key: key}
+	ctx := evaluation.NewEvaluationContext(maps.Clone(r.attributes))
+	var family *model.FigFamily
+	for ff := range r.client.All() {
+		if ff.Definition.Key == key {
+			family = &ff
+			break
+		}
+	}
+	if family == nil {
+		res.Error = "fig not found"
+		return res
+	}
+
+	matches, err := r.client.EvaluateAll(key, ctx)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	for _, m := range matches {
+		rule := matchedRule{Index: m.Index, Version: m.Fig.Version}
+		if m.Rule.Description != nil {
+			rule.Description = *m.Rule.Description
+		}
+		res.Matches = append(res.Matches, rule)
+	}
+	// The first matching rule is served, or the default version if none matches
+	switch {
+	case len(res.Matches) > 0:
+		res.Rule = &res.Matches[0]
+		res.Version = res.Rule.Version
+	case family.DefaultVersion != nil:
+		res.Version = *family.DefaultVersion
+	default:
+		res.Error = "no matching fig"
+		return res
+	}
+
+	schema, err := r.schema(key, family.Definition.SchemaURI)
+	if err != nil {
+		res.Error = fmt.Sprintf("failed to fetch schema, payload not decoded: %v", err)
+		return res
+	}
+	record := client.NewGenericRecord(schema)
+	if err := r.client.GetFigVersion(key, res.Version, record); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Value = record.Value
+	return res
+}
+
+// schema returns the schema loaded for key, or else the schema at uri, fetching it on
+// first use.
+func (r *repl) schema(key, uri string) (string, error) {
+	if schema, ok := r.local[key]; ok {
+		return schema, nil
+	}
+	if schema, ok := r.schemas[uri]; ok {
+		return schema, nil
+	}
+	if uri == "" {
+		return "", fmt.Errorf("fig definition has no schema URI")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	schema, err := r.client.FetchSchema(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+	r.schemas[uri] = schema
+	return schema, nil
+}
+
+func writeJSON(out io.Writer, v any) {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(out, "failed to encode result: %v\n", err)
+	}
+}
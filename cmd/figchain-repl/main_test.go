@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/figchain/go-client/pkg/client"
+	"github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+func TestREPL_Session(t *testing.T) {
+	dir := t.TempDir()
+	v1 := "v1"
+	state, err := store.MarshalSnapshot(&store.Snapshot{
+		Cursors: map[string]string{"default": "1"},
+		Families: []model.FigFamily{{
+			Definition: model.FigDefinition{Key: "greeting", Namespace: "default"},
+			Figs: []model.Fig{
+				{Version: "v1", Payload: []byte("\x06foo")},
+				{Version: "v2", Payload: []byte("\x06bar")},
+			},
+			Rules:          []model.Rule{model.NewRule("v2", model.NewCondition("plan", model.OperatorEquals, "pro"))},
+			DefaultVersion: &v1,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("MarshalSnapshot failed: %v", err)
+	}
+	snapshotPath := filepath.Join(dir, "state.bin")
+	schemaPath := filepath.Join(dir, "greeting.avsc")
+	os.WriteFile(snapshotPath, state, 0600)
+	os.WriteFile(schemaPath, []byte(`"string"`), 0600)
+
+	opts, err := snapshotOptions(&config.Config{}, snapshotPath)
+	if err != nil {
+		t.Fatalf("snapshotOptions failed: %v", err)
+	}
+	c, err := client.New(append(opts, config.WithBaseURL("http://127.0.0.1:0"))...)
+	if err != nil {
+		t.Fatalf("Failed to create client from snapshot: %v", err)
+	}
+	defer c.Close()
+
+	script := strings.Join([]string{
+		"schema greeting " + schemaPath,
+		"set plan=pro",
+		"attrs",
+		"eval greeting",
+		"unset plan",
+		"greeting",
+		"missing",
+		"set plan",
+		"bogus command",
+		"keys",
+		"quit",
+		"greeting",
+	}, "\n")
+	var out strings.Builder
+	r := &repl{client: c, attributes: map[string]string{}, schemas: map[string]string{}, local: map[string]string{}, timeout: time.Second}
+	r.run(strings.NewReader(script), &out)
+
+	// Results are printed as indented JSON objects between the prompts
+	var results []result
+	for _, chunk := range strings.Split(out.String(), "> ") {
+		if strings.HasPrefix(chunk, "{") && strings.Contains(chunk, `"key"`) {
+			var res result
+			if err := json.Unmarshal([]byte(chunk), &res); err != nil {
+				t.Fatalf("Failed to parse result %q: %v", chunk, err)
+			}
+			results = append(results, res)
+		}
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3:\n%s", len(results), out.String())
+	}
+	if got := results[0]; got.Version != "v2" || got.Rule == nil || got.Rule.Index != 0 || got.Value != "bar" {
+		t.Errorf("eval with plan=pro = %+v, want rule 0 serving v2 = bar", got)
+	}
+	if got := results[1]; got.Version != "v1" || got.Rule != nil || got.Value != "foo" {
+		t.Errorf("eval without attributes = %+v, want the default v1 = foo", got)
+	}
+	if got := results[2]; got.Error != "fig not found" {
+		t.Errorf("eval of a missing key = %+v, want fig not found", got)
+	}
+
+	for _, want := range []string{
+		`"plan": "pro"`,
+		"usage: set NAME=VALUE",
+		`unknown command "bogus"`,
+		"greeting\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -6,10 +6,22 @@ import (
 	"github.com/figchain/go-client/pkg/model"
 )
 
+// Bootstrap sources, as reported in Result.Source.
+const (
+	SourceServer  = "server"
+	SourceVault   = "vault"
+	SourceHybrid  = "hybrid"
+	SourceShared  = "shared"
+	SourceCache   = "cache"
+	SourceHandoff = "handoff"
+)
+
 // Result holds the result of a bootstrap operation.
 type Result struct {
 	FigFamilies []model.FigFamily
 	Cursors     map[string]string
+	// Source is where the families were loaded from.
+	Source string
 }
 
 // Strategy defines the interface for bootstrapping the client.
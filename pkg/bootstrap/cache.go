@@ -0,0 +1,65 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// CacheStrategy bootstraps with the next strategy and remembers the result in a local
+// FileStore, falling back to the families saved there when the next strategy fails.
+type CacheStrategy struct {
+	cache *store.FileStore
+	next  Strategy
+}
+
+// NewCacheStrategy creates a new CacheStrategy.
+func NewCacheStrategy(cache *store.FileStore, next Strategy) *CacheStrategy {
+	return &CacheStrategy{
+		cache: cache,
+		next:  next,
+	}
+}
+
+// Bootstrap loads namespaces with the next strategy, falling back to the local cache.
+func (s *CacheStrategy) Bootstrap(ctx context.Context, namespaces []string) (*Result, error) {
+	result, err := s.next.Bootstrap(ctx, namespaces)
+	if err == nil {
+		// Saved together with the families once the client stores them
+		for ns, cursor := range result.Cursors {
+			s.cache.SetCursor(ns, cursor)
+		}
+		return result, nil
+	}
+
+	logging.Printf("Bootstrap failed: %v. Falling back to the local cache.", err)
+	families, cacheErr := s.cache.Load()
+	if cacheErr != nil {
+		return nil, fmt.Errorf("%w; fallback to the local cache also failed: %w", err, cacheErr)
+	}
+
+	cursors := make(map[string]string)
+	for _, ns := range namespaces {
+		cursor, ok := s.cache.Cursor(ns)
+		if !ok {
+			return nil, fmt.Errorf("%w; the local cache has no state for namespace %s", err, ns)
+		}
+		cursors[ns] = cursor
+	}
+	var loaded []model.FigFamily
+	for _, ff := range families {
+		if _, ok := cursors[ff.Definition.Namespace]; ok {
+			loaded = append(loaded, ff)
+		}
+	}
+	logging.Printf("Cache bootstrap: Loaded %d families", len(loaded))
+
+	return &Result{
+		FigFamilies: loaded,
+		Cursors:     cursors,
+		Source:      SourceCache,
+	}, nil
+}
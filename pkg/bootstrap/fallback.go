@@ -3,7 +3,8 @@ package bootstrap
 import (
 	"context"
 	"fmt"
-	"log"
+
+	"github.com/figchain/go-client/pkg/logging"
 )
 
 // FallbackStrategy implements bootstrapping from Server then Vault if Server fails.
@@ -28,7 +29,7 @@ func (s *FallbackStrategy) Bootstrap(ctx context.Context, namespaces []string) (
 		return result, nil
 	}
 
-	log.Printf("Server bootstrap failed: %v. Falling back to Vault.", serverErr)
+	logging.Printf("Server bootstrap failed: %v. Falling back to Vault.", serverErr)
 
 	// 2. Try Vault
 	result, vaultErr := s.vaultStrategy.Bootstrap(ctx, namespaces)
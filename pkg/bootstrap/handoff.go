@@ -0,0 +1,71 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// HandoffStrategy bootstraps from the state handed off by a previous client. Namespaces
+// the previous client didn't have are bootstrapped with the next strategy.
+type HandoffStrategy struct {
+	snapshot *store.Snapshot
+	next     Strategy
+}
+
+// NewHandoffStrategy creates a new HandoffStrategy from state returned by Client.Handoff.
+func NewHandoffStrategy(state []byte, next Strategy) (*HandoffStrategy, error) {
+	snapshot, err := store.UnmarshalSnapshot(state)
+	if err != nil {
+		return nil, fmt.Errorf("invalid handoff state: %w", err)
+	}
+	return &HandoffStrategy{
+		snapshot: snapshot,
+		next:     next,
+	}, nil
+}
+
+// Bootstrap loads namespaces from the handed off state, falling back to the next
+// strategy for namespaces it doesn't cover.
+func (s *HandoffStrategy) Bootstrap(ctx context.Context, namespaces []string) (*Result, error) {
+	cursors := make(map[string]string)
+	var missing []string
+	for _, ns := range namespaces {
+		if cursor, ok := s.snapshot.Cursors[ns]; ok {
+			cursors[ns] = cursor
+		} else {
+			missing = append(missing, ns)
+		}
+	}
+	var allFamilies []model.FigFamily
+	for _, ff := range s.snapshot.Families {
+		if _, ok := cursors[ff.Definition.Namespace]; ok {
+			allFamilies = append(allFamilies, ff)
+		}
+	}
+	logging.Printf("Handoff bootstrap: Loaded %d families for %d namespaces", len(allFamilies), len(cursors))
+	source := SourceHandoff
+
+	if len(missing) > 0 {
+		result, err := s.next.Bootstrap(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for ns, cursor := range result.Cursors {
+			cursors[ns] = cursor
+		}
+		allFamilies = append(allFamilies, result.FigFamilies...)
+		if len(missing) == len(namespaces) {
+			source = result.Source
+		}
+	}
+
+	return &Result{
+		FigFamilies: allFamilies,
+		Cursors:     cursors,
+		Source:      source,
+	}, nil
+}
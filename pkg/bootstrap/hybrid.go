@@ -3,9 +3,9 @@ package bootstrap
 import (
 	"context"
 	"fmt"
-	"log"
 	"maps"
 
+	"github.com/figchain/go-client/pkg/logging"
 	"github.com/figchain/go-client/pkg/model"
 	"github.com/figchain/go-client/pkg/transport"
 )
@@ -33,7 +33,7 @@ func (s *HybridStrategy) Bootstrap(ctx context.Context, namespaces []string) (*R
 	// 1. Load from Vault
 	vaultResult, err := s.vaultStrategy.Bootstrap(ctx, namespaces)
 	if err != nil {
-		log.Printf("Vault bootstrap failed: %v. Falling back to full server fetch.", err)
+		logging.Printf("Vault bootstrap failed: %v. Falling back to full server fetch.", err)
 		// Fallback to full server fetch? Or fail?
 		// Java client seems to proceed or allow fallback logic.
 		// If explicit "Hybrid", maybe we should try server for everything?
@@ -63,7 +63,7 @@ func (s *HybridStrategy) Bootstrap(ctx context.Context, namespaces []string) (*R
 
 	// 3. Fetch missing from Server
 	if len(missingNamespaces) > 0 {
-		log.Printf("Fetching missing namespaces from server: %v", missingNamespaces)
+		logging.Printf("Fetching missing namespaces from server: %v", missingNamespaces)
 		serverResult, err := s.serverStrategy.Bootstrap(ctx, missingNamespaces)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch missing namespaces from server: %w", err)
@@ -120,5 +120,6 @@ func (s *HybridStrategy) Bootstrap(ctx context.Context, namespaces []string) (*R
 	return &Result{
 		FigFamilies: allFamilies,
 		Cursors:     finalCursors,
+		Source:      SourceHybrid,
 	}, nil
 }
@@ -0,0 +1,53 @@
+package bootstrap
+
+import (
+	"context"
+	"maps"
+)
+
+// NamespaceStrategy bootstraps namespaces with per-namespace strategies, e.g. from
+// namespace profiles. Namespaces without their own strategy use the default one.
+type NamespaceStrategy struct {
+	strategies map[string]Strategy
+	def        Strategy
+}
+
+// NewNamespaceStrategy creates a new NamespaceStrategy.
+func NewNamespaceStrategy(strategies map[string]Strategy, def Strategy) *NamespaceStrategy {
+	return &NamespaceStrategy{
+		strategies: strategies,
+		def:        def,
+	}
+}
+
+// Bootstrap bootstraps the namespaces of each strategy in one call to it, in the order
+// of the namespaces first using them. The result's source is that of the first
+// strategy.
+func (s *NamespaceStrategy) Bootstrap(ctx context.Context, namespaces []string) (*Result, error) {
+	var order []Strategy
+	groups := make(map[Strategy][]string)
+	for _, ns := range namespaces {
+		strategy, ok := s.strategies[ns]
+		if !ok {
+			strategy = s.def
+		}
+		if _, ok := groups[strategy]; !ok {
+			order = append(order, strategy)
+		}
+		groups[strategy] = append(groups[strategy], ns)
+	}
+
+	merged := &Result{Cursors: make(map[string]string)}
+	for i, strategy := range order {
+		result, err := strategy.Bootstrap(ctx, groups[strategy])
+		if err != nil {
+			return nil, err
+		}
+		merged.FigFamilies = append(merged.FigFamilies, result.FigFamilies...)
+		maps.Copy(merged.Cursors, result.Cursors)
+		if i == 0 {
+			merged.Source = result.Source
+		}
+	}
+	return merged, nil
+}
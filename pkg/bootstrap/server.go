@@ -3,9 +3,9 @@ package bootstrap
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
+	"github.com/figchain/go-client/pkg/logging"
 	"github.com/figchain/go-client/pkg/model"
 	"github.com/figchain/go-client/pkg/transport"
 )
@@ -25,7 +25,7 @@ func NewServerStrategy(tr transport.Transport, environmentID string, asOf string
 		if err == nil {
 			asOfTime = &t
 		} else {
-			log.Printf("Invalid AsOfTimestamp format ignored: %s", asOf)
+			logging.Printf("Invalid AsOfTimestamp format ignored: %s", asOf)
 		}
 	}
 	return &ServerStrategy{
@@ -47,20 +47,42 @@ func (s *ServerStrategy) Bootstrap(ctx context.Context, namespaces []string) (*R
 			AsOfTimestamp: s.asOf,
 		}
 
-		resp, err := s.transport.FetchInitial(ctx, req)
+		cursor, fetched, err := s.fetch(ctx, req, &allFamilies)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch initial data for %s: %w", ns, err)
 		}
 
-		allFamilies = append(allFamilies, resp.FigFamilies...)
-		if resp.Cursor != "" {
-			cursors[ns] = resp.Cursor
+		if cursor != "" {
+			cursors[ns] = cursor
 		}
-		log.Printf("Bootstrap: Fetched %d families for namespace %s, Cursor: %s", len(resp.FigFamilies), ns, resp.Cursor)
+		logging.Printf("Bootstrap: Fetched %d families for namespace %s, Cursor: %s", fetched, ns, cursor)
 	}
 
 	return &Result{
 		FigFamilies: allFamilies,
 		Cursors:     cursors,
+		Source:      SourceServer,
 	}, nil
 }
+
+// fetch appends the families of an initial fetch to families, returning their cursor
+// and number. Transports that can stream the fetch are used so that the encoded
+// response isn't buffered as well as the families, which are still all collected: they
+// are admitted and passed to wrapping strategies as a whole.
+func (s *ServerStrategy) fetch(ctx context.Context, req *model.InitialFetchRequest, families *[]model.FigFamily) (string, int, error) {
+	if streamer, ok := s.transport.(transport.InitialStreamer); ok {
+		fetched := 0
+		cursor, err := streamer.StreamInitial(ctx, req, func(ff *model.FigFamily) error {
+			*families = append(*families, *ff)
+			fetched++
+			return nil
+		})
+		return cursor, fetched, err
+	}
+	resp, err := s.transport.FetchInitial(ctx, req)
+	if err != nil {
+		return "", 0, err
+	}
+	*families = append(*families, resp.FigFamilies...)
+	return resp.Cursor, len(resp.FigFamilies), nil
+}
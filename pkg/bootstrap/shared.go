@@ -0,0 +1,80 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// SharedStrategy bootstraps from a RedisStore shared between processes, so that only the
+// first process bootstraps from the server. Namespaces missing from the shared store are
+// bootstrapped with the next strategy and saved for the processes that follow.
+type SharedStrategy struct {
+	store *store.RedisStore
+	next  Strategy
+}
+
+// NewSharedStrategy creates a new SharedStrategy.
+func NewSharedStrategy(s *store.RedisStore, next Strategy) *SharedStrategy {
+	return &SharedStrategy{
+		store: s,
+		next:  next,
+	}
+}
+
+// Bootstrap loads namespaces from the shared store, falling back to the next strategy for
+// namespaces that haven't been bootstrapped yet.
+func (s *SharedStrategy) Bootstrap(ctx context.Context, namespaces []string) (*Result, error) {
+	var allFamilies []model.FigFamily
+	cursors := make(map[string]string)
+	var missing []string
+	source := SourceShared
+
+	for _, ns := range namespaces {
+		cursor, ok, err := s.store.Cursor(ctx, ns)
+		if err != nil {
+			logging.Printf("Shared bootstrap: failed to read cursor for %s: %v", ns, err)
+		}
+		if !ok || err != nil {
+			missing = append(missing, ns)
+			continue
+		}
+		families, err := s.store.Load(ctx, ns)
+		if err != nil {
+			logging.Printf("Shared bootstrap: failed to load %s: %v", ns, err)
+			missing = append(missing, ns)
+			continue
+		}
+		allFamilies = append(allFamilies, families...)
+		cursors[ns] = cursor
+		logging.Printf("Shared bootstrap: Loaded %d families for namespace %s, Cursor: %s", len(families), ns, cursor)
+	}
+
+	if len(missing) > 0 {
+		result, err := s.next.Bootstrap(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		// Save the families before the cursors, so that a saved cursor always has its
+		// families available
+		s.store.PutAll(result.FigFamilies)
+		for ns, cursor := range result.Cursors {
+			if err := s.store.SaveCursor(ctx, ns, cursor); err != nil {
+				logging.Printf("Shared bootstrap: failed to save cursor for %s: %v", ns, err)
+			}
+			cursors[ns] = cursor
+		}
+		allFamilies = append(allFamilies, result.FigFamilies...)
+		if len(missing) == len(namespaces) {
+			source = result.Source
+		}
+	}
+
+	return &Result{
+		FigFamilies: allFamilies,
+		Cursors:     cursors,
+		Source:      source,
+	}, nil
+}
@@ -54,5 +54,6 @@ func (s *VaultStrategy) Bootstrap(ctx context.Context, namespaces []string) (*Re
 	return &Result{
 		FigFamilies: filteredFamilies,
 		Cursors:     cursors,
+		Source:      SourceVault,
 	}, nil
 }
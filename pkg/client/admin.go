@@ -0,0 +1,247 @@
+package client
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// AdminOption configures the handler returned by NewAdminHandler.
+type AdminOption func(*adminHandler)
+
+// WithAdminToken requires requests to carry "Authorization: Bearer <token>".
+func WithAdminToken(token string) AdminOption {
+	return func(h *adminHandler) {
+		h.authorize = func(r *http.Request) bool {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+		}
+	}
+}
+
+// WithAdminAuthorizer sets a custom authorization check, e.g. for mTLS or an existing
+// auth middleware. Requests it rejects get 401 Unauthorized.
+func WithAdminAuthorizer(authorize func(r *http.Request) bool) AdminOption {
+	return func(h *adminHandler) {
+		h.authorize = authorize
+	}
+}
+
+// WithAdminMetricsHandler serves handler at GET /metrics, e.g. promhttp.HandlerFor.
+func WithAdminMetricsHandler(handler http.Handler) AdminOption {
+	return func(h *adminHandler) {
+		h.metrics = handler
+	}
+}
+
+type adminHandler struct {
+	client    *Client
+	authorize func(r *http.Request) bool
+	metrics   http.Handler
+	mux       *http.ServeMux
+}
+
+// NewAdminHandler returns an HTTP handler to introspect a running client:
+//
+//	GET  /health          client health (see Client.Health)
+//	GET  /keys            stored fig families and their versions
+//	POST /evaluate        evaluates {"key", "attributes", "tenant"} and returns the result
+//	POST /refresh         fetches updates immediately and lists the changed keys
+//	POST /overrides/clear clears the tenant overrides of ?tenant=, or all of them
+//	GET  /quarantine      updates rejected as invalid or undecodable (see Client.Quarantined)
+//	GET  /metrics         metrics, if WithAdminMetricsHandler is set
+//
+// Every request must be authorized with WithAdminToken or WithAdminAuthorizer; without
+// either, all requests are rejected. Mount it under a prefix with http.StripPrefix.
+func NewAdminHandler(c *Client, opts ...AdminOption) http.Handler {
+	h := &adminHandler{
+		client:    c,
+		authorize: func(*http.Request) bool { return false },
+		mux:       http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("GET /health", h.handleHealth)
+	h.mux.HandleFunc("GET /keys", h.handleKeys)
+	h.mux.HandleFunc("POST /evaluate", h.handleEvaluate)
+	h.mux.HandleFunc("POST /refresh", h.handleRefresh)
+	h.mux.HandleFunc("POST /overrides/clear", h.handleClearOverrides)
+	h.mux.HandleFunc("GET /quarantine", h.handleQuarantine)
+	if h.metrics != nil {
+		h.mux.Handle("GET /metrics", h.metrics)
+	}
+	return h
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *adminHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := h.client.Health()
+	status := http.StatusOK
+	if health.Status != HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// adminKey describes a stored fig family.
+type adminKey struct {
+	Namespace      string    `json:"namespace"`
+	Key            string    `json:"key"`
+	FigID          string    `json:"figId"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	Versions       []string  `json:"versions"`
+	DefaultVersion string    `json:"defaultVersion,omitempty"`
+	Rules          int       `json:"rules"`
+}
+
+// handleKeys streams the keys as a JSON array, so large stores are listed in constant
+// memory.
+func (h *adminHandler) handleKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "[")
+	enc := json.NewEncoder(w)
+	first := true
+	for _, ns := range h.client.cfg.Namespaces {
+		for ff := range store.All(h.client.store, ns) {
+			k := adminKey{
+				Namespace: ff.Definition.Namespace,
+				Key:       ff.Definition.Key,
+				FigID:     ff.Definition.FigID,
+				UpdatedAt: ff.Definition.UpdatedAt,
+				Rules:     len(ff.Rules),
+			}
+			for _, fig := range ff.Figs {
+				k.Versions = append(k.Versions, fig.Version)
+			}
+			if ff.DefaultVersion != nil {
+				k.DefaultVersion = *ff.DefaultVersion
+			}
+			if !first {
+				io.WriteString(w, ",")
+			}
+			first = false
+			if err := enc.Encode(k); err != nil {
+				return
+			}
+		}
+	}
+	io.WriteString(w, "]\n")
+}
+
+type adminEvaluateRequest struct {
+	Key        string            `json:"key"`
+	Attributes map[string]string `json:"attributes"`
+	Tenant     string            `json:"tenant"`
+}
+
+type adminEvaluateResponse struct {
+	Namespace  string            `json:"namespace"`
+	Key        string            `json:"key"`
+	Version    string            `json:"version,omitempty"`
+	Encrypted  bool              `json:"encrypted"`
+	Attributes map[string]string `json:"attributes"`
+	Matches    []adminRuleMatch  `json:"matches,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type adminRuleMatch struct {
+	Rule        int    `json:"rule"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+func (h *adminHandler) handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	var req adminEvaluateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Key == "" {
+		http.Error(w, "request body must be JSON with a key", http.StatusBadRequest)
+		return
+	}
+	namespace, key, err := h.client.resolveKey(req.Key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ff, ok := h.client.getFamily(namespace, key)
+	if !ok {
+		http.Error(w, "fig not found", http.StatusNotFound)
+		return
+	}
+
+	var base context.Context = r.Context()
+	if req.Tenant != "" {
+		base = evaluation.ContextWithTenant(base, req.Tenant)
+	}
+	ctx := h.client.withDefaultAttributes(evaluation.NewEvaluationContextWithContext(base, req.Attributes))
+	resp := adminEvaluateResponse{Namespace: namespace, Key: key, Attributes: ctx.Attributes}
+	fig, err := h.client.evaluator.Evaluate(ff, ctx)
+	switch {
+	case err != nil:
+		resp.Error = err.Error()
+	case fig == nil:
+		resp.Error = "no matching fig"
+	default:
+		resp.Version = fig.Version
+		resp.Encrypted = fig.IsEncrypted
+	}
+	// List every matching rule, not just the one served, when the evaluator supports it
+	if evaluator, ok := h.client.evaluator.(evaluation.MultiEvaluator); ok && err == nil {
+		matches, _ := evaluator.EvaluateAll(ff, ctx)
+		for _, m := range matches {
+			match := adminRuleMatch{Rule: m.Index, Version: m.Fig.Version}
+			if m.Rule.Description != nil {
+				match.Description = *m.Rule.Description
+			}
+			resp.Matches = append(resp.Matches, match)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *adminHandler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	changed, err := h.client.Refresh(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	keys := []string{}
+	for _, ff := range changed {
+		keys = append(keys, ff.Definition.Namespace+"/"+ff.Definition.Key)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"changed": keys, "health": h.client.Health()})
+}
+
+func (h *adminHandler) handleClearOverrides(w http.ResponseWriter, r *http.Request) {
+	if tenant := r.URL.Query().Get("tenant"); tenant != "" {
+		h.client.overrides.Clear(tenant)
+	} else {
+		h.client.overrides.ClearAll()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (h *adminHandler) handleQuarantine(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.client.Quarantined())
+}
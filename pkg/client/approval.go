@@ -0,0 +1,62 @@
+package client
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/metrics"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// beforeApply asks the BeforeApply hook, if any, whether to apply u. Updates it rejects
+// or defers are held to be offered again on the next poll. c.mu must be held and released
+// with unlock.
+func (c *Client) beforeApply(u update) bool {
+	if c.cfg.BeforeApply == nil {
+		return true
+	}
+	ff := u.family
+	k := [2]string{ff.Definition.Namespace, ff.Definition.Key}
+	var old model.FigFamily
+	if current, ok := c.store.Get(ff.Definition.Namespace, ff.Definition.Key); ok {
+		old = *current
+	}
+
+	reason := "deferred"
+	switch c.cfg.BeforeApply(old, ff) {
+	case config.ApplyAccept:
+		delete(c.held, k)
+		return true
+	case config.ApplyReject:
+		reason = "rejected"
+		c.emitLocked(event.Event{
+			Type:      event.UpdateRejected,
+			Namespace: ff.Definition.Namespace,
+			Key:       ff.Definition.Key,
+			Message:   "update rejected by BeforeApply hook",
+		})
+	}
+	c.metrics.IncCounter(metrics.UpdatesIgnored, map[string]string{
+		"namespace": ff.Definition.Namespace,
+		"key":       ff.Definition.Key,
+		"reason":    reason,
+	})
+	c.held[k] = u
+	return false
+}
+
+// retryHeld offers the updates held back by the BeforeApply hook to it again.
+func (c *Client) retryHeld() {
+	c.mu.Lock()
+	defer c.unlock()
+	if len(c.held) == 0 || c.paused {
+		return
+	}
+	// Held updates that are stale by now are dropped; the others are held again if the
+	// hook still doesn't accept them
+	updates := slices.Collect(maps.Values(c.held))
+	clear(c.held)
+	c.applyLocked(updates)
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/figchain/go-client/pkg/clock"
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// Audit record sources, besides the bootstrap sources of package bootstrap.
+const (
+	AuditSourcePoll     = "poll"
+	AuditSourceRefresh  = "refresh"
+	AuditSourceBus      = "bus"
+	AuditSourceRollback = "rollback"
+	AuditSourceOverride = "override"
+	AuditSourcePriority = "priority"
+)
+
+// AuditRecord is a line of the audit log (see config.WithAuditLog): a change to the
+// configuration the client serves.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Source is where the change came from: a bootstrap source such as "server" or
+	// "vault", or one of the AuditSource constants.
+	Source    string `json:"source"`
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key,omitempty"`
+	// Tenant is set for tenant override changes.
+	Tenant string `json:"tenant,omitempty"`
+	// OldVersion and NewVersion are the default versions of the family before and after
+	// the change, or the overridden versions for override changes.
+	OldVersion string    `json:"oldVersion,omitempty"`
+	NewVersion string    `json:"newVersion,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt,omitzero"`
+	Cursor     string    `json:"cursor,omitempty"`
+}
+
+// auditLog appends AuditRecords as JSON lines.
+type auditLog struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+	clock  clock.Clock
+}
+
+// newAuditLog opens the configured audit log, or returns nil if auditing is disabled.
+func newAuditLog(path string, w io.Writer, clk clock.Clock) (*auditLog, error) {
+	if w != nil {
+		return &auditLog{enc: json.NewEncoder(w), clock: clk}, nil
+	}
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &auditLog{enc: json.NewEncoder(f), closer: f, clock: clk}, nil
+}
+
+func (a *auditLog) write(r AuditRecord) {
+	if a == nil {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = a.clock.Now()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.enc.Encode(r); err != nil {
+		logging.Printf("Failed to write audit record for %s/%s: %v", r.Namespace, r.Key, err)
+	}
+}
+
+func (a *auditLog) close() error {
+	if a == nil || a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// defaultVersion returns the default version of ff, or "" if ff is nil or has none.
+func defaultVersion(ff *model.FigFamily) string {
+	if ff == nil || ff.DefaultVersion == nil {
+		return ""
+	}
+	return *ff.DefaultVersion
+}
+
+// auditFamily records that ff replaced old, which may be nil.
+func (c *Client) auditFamily(source, cursor string, old *model.FigFamily, ff model.FigFamily) {
+	c.audit.write(AuditRecord{
+		Source:     source,
+		Namespace:  ff.Definition.Namespace,
+		Key:        ff.Definition.Key,
+		OldVersion: defaultVersion(old),
+		NewVersion: defaultVersion(&ff),
+		UpdatedAt:  ff.Definition.UpdatedAt,
+		Cursor:     cursor,
+	})
+}
+
+// auditOverride records a tenant override change.
+func (c *Client) auditOverride(change evaluation.OverrideChange) {
+	c.audit.write(AuditRecord{
+		Source:     AuditSourceOverride,
+		Key:        change.Key,
+		Tenant:     change.TenantID,
+		OldVersion: change.Previous,
+		NewVersion: change.Version,
+	})
+}
@@ -2,19 +2,28 @@ package client
 
 import (
 	"context"
+	"crypto/rsa"
+	"errors"
 	"fmt"
-	"log"
 	"maps"
 	"reflect"
+	"runtime/debug"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hamba/avro/v2"
 
 	"github.com/figchain/go-client/pkg/bootstrap"
+	"github.com/figchain/go-client/pkg/clock"
 	"github.com/figchain/go-client/pkg/config"
 	"github.com/figchain/go-client/pkg/encryption"
 	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/evaluation/cel"
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/metrics"
 	"github.com/figchain/go-client/pkg/model"
 	"github.com/figchain/go-client/pkg/store"
 	"github.com/figchain/go-client/pkg/transport"
@@ -27,23 +36,87 @@ type AvroRecord interface {
 	Schema() string
 }
 
+// GenericRecord is an AvroRecord for figs whose Go type isn't known, e.g. in tools. Figs
+// are deserialized with the schema it was created with into Value, as maps, slices and
+// scalars.
+type GenericRecord struct {
+	schema string
+	Value  any
+}
+
+// NewGenericRecord creates a GenericRecord deserializing with schema.
+func NewGenericRecord(schema string) *GenericRecord {
+	return &GenericRecord{schema: schema}
+}
+
+func (r *GenericRecord) Schema() string {
+	return r.schema
+}
+
 // Client is the main entry point for the FigChain client.
 type Client struct {
 	cfg               *config.Config
+	clock             clock.Clock
 	store             store.Store
 	evaluator         evaluation.Evaluator
+	cache             *store.FileStore  // nil unless a local cache is configured
+	shared            *store.RedisStore // nil unless the store is shared through Redis
 	transport         transport.Transport
+	tokens            *transport.SwappableTokenProvider
+	identity          Identity
+	usage             usageTracker
+	stats             *clientStats
+	diagnostics       *diagnostics // nil unless enabled
+	deprecations      deprecationWarnings
 	namespaceCursors  map[string]string
-	watchers          map[string][]chan model.FigFamily
+	synced            map[string]chan struct{} // closed once the namespace syncs, see WithRequireSync
+	watchers          map[string][]subscription
+	watchersClosed    bool           // set on close, see closeWatchers
+	unwatching        sync.WaitGroup // pending removals of watchers, see subscribe
 	listeners         map[string][]func(model.FigFamily)
+	schemas           map[string]avro.Schema // registered types, to check updates decode
+	validators        map[string][]validator // by key, see RegisterValidator
+	quarantine        quarantine
+	compat            schemaChecks
+	codec             avro.API // decodes fig payloads
+	history           *store.History
+	audit             *auditLog
+	dispatcher        *dispatcher
+	payloads          *payloadPool
+	overrides         *evaluation.TenantOverrides
+	health            pollHealth
+	paused            bool                 // updates are queued in pending instead of applied
+	pending           []update             // in the order they were received
+	staged            []*stagedBatch       // waiting out the rollout delay, in the order they were received
+	held              map[[2]string]update // rejected or deferred by BeforeApply, by namespace and key
+	unsavedCursors    map[string]string    // cursors kept from the local cache while updates are queued
+	nextPoll          map[string]time.Time // when namespaces with a polling interval are due
+	schedule          *pollSchedule        // nil polls continuously
+	rolloutDelay      time.Duration        // how long updates are staged before they apply
+	killSwitches      map[string]bool      // keys whose updates bypass staging and pauses
 	encryptionService *encryption.Service
+	nsEncryption      map[string]*encryption.Service // by namespace, see config.NamespaceProfile
+	metrics           metrics.Recorder
+	droppedUpdates    atomic.Uint64
+	updated           chan struct{} // closed and replaced whenever updates are applied
+	events            []event.Event // emitted under c.mu, delivered once it is released
 	mu                sync.RWMutex
 	wg                sync.WaitGroup
 	closeCh           chan struct{}
+	ctx               context.Context // cancelled to abort in-flight requests on close
+	cancel            context.CancelFunc
 }
 
-// New creates a new Client.
+// New creates a new Client. It is NewWithContext with context.Background(), so
+// bootstrapping is bounded only by the configured retries.
 func New(opts ...config.Option) (*Client, error) {
+	return NewWithContext(context.Background(), opts...)
+}
+
+// NewWithContext creates a new Client, bootstrapping it within ctx: cancelling ctx, or
+// its deadline passing, aborts construction. ctx only bounds startup; the client runs
+// until it is closed.
+func NewWithContext(ctx context.Context, opts ...config.Option) (*Client, error) {
 	cfg := config.DefaultConfig()
 	for _, opt := range opts {
 		opt(cfg)
@@ -55,10 +128,14 @@ func New(opts ...config.Option) (*Client, error) {
 	if cfg.EnvironmentID == "" {
 		return nil, fmt.Errorf("EnvironmentID is required")
 	}
-	if cfg.ClientSecret == "" && cfg.AuthPrivateKeyPath == "" {
+	if cfg.ClientSecret == "" && cfg.ClientSecretFile == "" && cfg.AuthPrivateKeyPath == "" {
 		return nil, fmt.Errorf("an authentication method must be configured. Please provide either a ClientSecret or an AuthPrivateKeyPath")
 	}
 
+	// Secrets are redacted from log messages, e.g. in errors echoing a request
+	logging.RegisterSecret(cfg.ClientSecret)
+	logging.RegisterSecret(cfg.SigningKey)
+
 	var tokenProvider transport.TokenProvider
 	if cfg.AuthPrivateKeyPath != "" {
 		if len(cfg.Namespaces) > 1 {
@@ -69,85 +146,298 @@ func New(opts ...config.Option) (*Client, error) {
 			return nil, fmt.Errorf("failed to load auth private key: %w", err)
 		}
 
-		// Use EnvironmentID as placeholder if AuthClientID not set, but prefer AuthClientID
-		serviceAccountID := cfg.EnvironmentID
-		if cfg.AuthClientID != "" {
-			serviceAccountID = cfg.AuthClientID
-		}
-
-		// Use first namespace if available for auth token scope
-		namespace := ""
-		if len(cfg.Namespaces) > 0 {
-			namespace = cfg.Namespaces[0]
+		tokenProvider = newPrivateKeyTokenProvider(cfg, pk)
+	} else if cfg.ClientSecretFile != "" {
+		secret, err := readClientSecret(cfg.ClientSecretFile)
+		if err != nil {
+			return nil, err
 		}
-		tokenProvider = transport.NewPrivateKeyTokenProvider(pk, serviceAccountID, cfg.TenantID, namespace, "")
+		tokenProvider = transport.NewSharedSecretTokenProvider(secret)
 	} else {
 		tokenProvider = transport.NewSharedSecretTokenProvider(cfg.ClientSecret)
 	}
+	tokens := transport.NewSwappableTokenProvider(tokenProvider)
 
-	tr := transport.NewHTTPTransport(cfg.HTTPClient, cfg.BaseURL, tokenProvider, cfg.EnvironmentID)
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = newHTTPClient(cfg)
+	}
+	identity := newIdentity(cfg)
+	requestInterceptors := append([]transport.RequestInterceptor{identity.setHeaders}, cfg.RequestInterceptors...)
+	if cfg.SigningKey != "" {
+		// Sign last, so that the signature covers the request as sent
+		signer, err := transport.NewHMACSigner(cfg.SigningAlgorithm, []byte(cfg.SigningKey), transport.WithSignerClock(cfg.Clock))
+		if err != nil {
+			return nil, fmt.Errorf("invalid request signing configuration: %w", err)
+		}
+		requestInterceptors = append(requestInterceptors, signer)
+	}
+	stats := &clientStats{}
+	diag := newDiagnostics(cfg.DiagnosticsBufferSize, clock.OrReal(cfg.Clock))
+	tr := transport.NewHTTPTransport(countingClient(recordingClient(cfg.HTTPClient, diag), stats), cfg.BaseURL, tokens, cfg.EnvironmentID,
+		transport.WithRequestInterceptors(requestInterceptors...),
+		transport.WithResponseInterceptors(cfg.ResponseInterceptors...),
+		transport.WithFallbackURLs(cfg.FallbackURLs...),
+		transport.WithFailoverCooldown(cfg.FailoverCooldown),
+		transport.WithClock(cfg.Clock),
+		transport.WithSingleObjectEncoding(cfg.SingleObjectEncoding),
+	)
 
+	encOpts := []encryption.ServiceOption{encryption.WithPayloadCacheSize(cfg.DecryptedPayloadCacheSize)}
+	if cfg.LockKeyMemory {
+		encOpts = append(encOpts, encryption.WithLockedKeys())
+	}
 	var encService *encryption.Service
 	if cfg.EncryptionPrivateKeyPath != "" {
-		svc, err := encryption.NewService(tr, cfg.EncryptionPrivateKeyPath)
+		svc, err := encryption.NewService(tr, cfg.EncryptionPrivateKeyPath, encOpts...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create encryption service: %w", err)
 		}
 		encService = svc
 	}
+	// Namespaces whose profile has its own encryption key decrypt with it
+	nsEncServices := make(map[string]*encryption.Service)
+	for _, ns := range cfg.Namespaces {
+		path := cfg.Profile(ns).EncryptionPrivateKeyPath
+		if path == cfg.EncryptionPrivateKeyPath {
+			continue
+		}
+		svc, err := encryption.NewService(tr, path, encOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encryption service for namespace %s: %w", ns, err)
+		}
+		nsEncServices[ns] = svc
+	}
+	if cfg.AutoRegisterPublicKey != "" {
+		if encService == nil && len(nsEncServices) == 0 {
+			return nil, fmt.Errorf("auto-registering a public key requires an encryption private key")
+		}
+		km := encryption.NewKeyManager(tr, cfg.AutoRegisterPublicKey)
+		for _, svc := range append(slices.Collect(maps.Values(nsEncServices)), encService) {
+			if svc == nil {
+				continue
+			}
+			if err := km.EnsureRegistered(ctx, svc.PublicKey()); err != nil {
+				return nil, fmt.Errorf("failed to register public key: %w", err)
+			}
+		}
+	}
+
+	schedule, err := newPollSchedule(cfg)
+	if err != nil {
+		return nil, err
+	}
+	delay, err := rolloutDelay(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var st store.Store = store.NewMemoryStore()
+	if cfg.MaxFamilies > 0 {
+		st = store.NewLRUStore(cfg.MaxFamilies)
+	}
+	if cfg.Store != nil {
+		st = cfg.Store
+	}
+	var cache *store.FileStore
+	if cfg.CachePath != "" {
+		if cfg.Store != nil {
+			return nil, fmt.Errorf("a local cache can't be used with a custom store")
+		}
+		key := cfg.CacheKey
+		if key == nil {
+			if encService == nil {
+				return nil, fmt.Errorf("a local cache requires a cache key or an encryption private key")
+			}
+			derived, err := encService.DeriveKey("figchain local cache", store.CacheKeySize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to derive cache key: %w", err)
+			}
+			key = derived
+		}
+		fs, err := store.NewFileStore(cfg.CachePath, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create local cache: %w", err)
+		}
+		cache = fs
+		st = fs
+	}
+
+	shared, _ := cfg.Store.(*store.RedisStore)
 
 	c := &Client{
 		cfg:               cfg,
-		store:             store.NewMemoryStore(),
-		evaluator:         evaluation.NewRuleBasedEvaluator(),
+		clock:             clock.OrReal(cfg.Clock),
+		store:             st,
+		cache:             cache,
+		shared:            shared,
 		transport:         tr,
+		tokens:            tokens,
+		identity:          identity,
+		stats:             stats,
+		diagnostics:       diag,
 		encryptionService: encService,
+		nsEncryption:      nsEncServices,
+		schedule:          schedule,
+		rolloutDelay:      delay,
+		killSwitches:      make(map[string]bool),
+		held:              make(map[[2]string]update),
+		unsavedCursors:    make(map[string]string),
+		nextPoll:          make(map[string]time.Time),
 		namespaceCursors:  make(map[string]string),
-		watchers:          make(map[string][]chan model.FigFamily),
+		synced:            make(map[string]chan struct{}),
+		watchers:          make(map[string][]subscription),
 		listeners:         make(map[string][]func(model.FigFamily)),
+		schemas:           make(map[string]avro.Schema),
+		validators:        make(map[string][]validator),
+		quarantine:        quarantine{families: make(map[string]QuarantinedFamily)},
+		compat:            schemaChecks{checks: make(map[string]*schemaCheck)},
+		codec:             newPayloadAPI(cfg),
+		history:           store.NewHistory(cfg.HistoryDepth),
+		payloads:          newPayloadPool(),
+		overrides:         evaluation.NewTenantOverrides(),
+		updated:           make(chan struct{}),
 		closeCh:           make(chan struct{}),
 	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	for _, key := range cfg.KillSwitchKeys {
+		c.killSwitches[key] = true
+	}
+
+	for key, s := range cfg.Types {
+		schema, err := avro.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema for type of %s: %w", key, err)
+		}
+		c.schemas[key] = schema
+	}
+
+	var evaluator evaluation.Evaluator
+	if cfg.Evaluator != nil {
+		evaluator = cfg.Evaluator
+	} else {
+		celEngine, err := cel.NewEngine()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create expression engine: %w", err)
+		}
+		evalOpts := []evaluation.Option{
+			evaluation.WithExpressionEngine(celEngine),
+			evaluation.WithFamilyLookup(evaluation.FamilyLookupFunc(c.getFamily)),
+		}
+		if cfg.BucketingStrategy != nil {
+			evalOpts = append(evalOpts, evaluation.WithBucketingStrategy(cfg.BucketingStrategy))
+		}
+		evaluator = evaluation.NewRuleBasedEvaluator(evalOpts...)
+	}
+
+	var recorder metrics.Recorder = metrics.NopRecorder{}
+	if cfg.MetricsRecorder != nil {
+		recorder = cfg.MetricsRecorder
+	}
+
+	if cfg.TenantAttributeKey != "" {
+		evaluator = evaluation.NewTenantOverrideEvaluator(evaluator, c.overrides, cfg.TenantAttributeKey)
+	}
+	c.evaluator = evaluator
+	c.metrics = recorder
+	c.metrics.SetGauge(metrics.ClientInfo, 1, identity.labels())
 
 	// Select Bootstrap Strategy
-	var strategy bootstrap.Strategy
 	serverStrategy := bootstrap.NewServerStrategy(tr, cfg.EnvironmentID, cfg.AsOfTimestamp)
-
+	var vaultStrategy bootstrap.Strategy
 	if cfg.VaultEnabled {
-		vs, err := vault.NewDefaultVaultService(context.Background(), cfg)
+		var vs *vault.VaultService
+		if cfg.VaultFetcher != nil {
+			vs = vault.NewVaultService(cfg, cfg.VaultFetcher)
+		} else {
+			dvs, err := vault.NewDefaultVaultService(ctx, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create vault service: %w", err)
+			}
+			vs = dvs
+		}
+		vaultStrategy = bootstrap.NewVaultStrategy(vs)
+	}
+	strategies := make(map[config.BootstrapStrategy]bootstrap.Strategy)
+	strategyFor := func(kind config.BootstrapStrategy) bootstrap.Strategy {
+		if s, ok := strategies[kind]; ok {
+			return s
+		}
+		var s bootstrap.Strategy
+		if vaultStrategy == nil {
+			s = serverStrategy
+		} else {
+			switch kind {
+			case config.BootstrapStrategyVault:
+				s = vaultStrategy
+			case config.BootstrapStrategyHybrid:
+				s = bootstrap.NewHybridStrategy(vaultStrategy, serverStrategy, tr, cfg.EnvironmentID)
+			case config.BootstrapStrategyServerFirst, "":
+				s = bootstrap.NewFallbackStrategy(serverStrategy, vaultStrategy)
+			case config.BootstrapStrategyServer:
+				s = serverStrategy
+			default:
+				logging.Printf("Unknown bootstrap strategy %q, using Default (ServerFirst with Fallback)", kind)
+				s = bootstrap.NewFallbackStrategy(serverStrategy, vaultStrategy)
+			}
+		}
+		strategies[kind] = s
+		return s
+	}
+	strategy := strategyFor(cfg.BootstrapStrategy)
+	// Namespaces whose profile picks another strategy bootstrap with it
+	perNamespace := make(map[string]bootstrap.Strategy)
+	for _, ns := range cfg.Namespaces {
+		if s := strategyFor(cfg.Profile(ns).BootstrapStrategy); s != strategy {
+			perNamespace[ns] = s
+		}
+	}
+	if len(perNamespace) > 0 {
+		strategy = bootstrap.NewNamespaceStrategy(perNamespace, strategy)
+	}
+
+	if c.shared != nil {
+		strategy = bootstrap.NewSharedStrategy(c.shared, strategy)
+	}
+	if cfg.HandoffState != nil {
+		hs, err := bootstrap.NewHandoffStrategy(cfg.HandoffState, strategy)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create vault service: %w", err)
-		}
-		vaultStrategy := bootstrap.NewVaultStrategy(vs)
-
-		switch cfg.BootstrapStrategy {
-		case config.BootstrapStrategyVault:
-			strategy = vaultStrategy
-		case config.BootstrapStrategyHybrid:
-			strategy = bootstrap.NewHybridStrategy(vaultStrategy, serverStrategy, tr, cfg.EnvironmentID)
-		case config.BootstrapStrategyServerFirst, "":
-			strategy = bootstrap.NewFallbackStrategy(serverStrategy, vaultStrategy)
-		case config.BootstrapStrategyServer:
-			strategy = serverStrategy
-		default:
-			log.Printf("Unknown bootstrap strategy %q, using Default (ServerFirst with Fallback)", cfg.BootstrapStrategy)
-			strategy = bootstrap.NewFallbackStrategy(serverStrategy, vaultStrategy)
+			return nil, err
 		}
-	} else {
-		strategy = serverStrategy
+		strategy = hs
 	}
+	if cache != nil {
+		strategy = bootstrap.NewCacheStrategy(cache, strategy)
+	}
+
+	logging.Printf("Bootstrapping with strategy: %T", strategy)
 
-	log.Printf("Bootstrapping with strategy: %T", strategy)
+	audit, err := newAuditLog(cfg.AuditLogPath, cfg.AuditWriter, c.clock)
+	if err != nil {
+		return nil, err
+	}
+	c.audit = audit
+	if audit != nil {
+		c.overrides.OnChange(c.auditOverride)
+	}
 
 	// Execute Bootstrap
-	result, err := strategy.Bootstrap(context.Background(), cfg.Namespaces)
+	start := c.clock.Now()
+	result, err := strategy.Bootstrap(ctx, c.namespacesByPriority())
 	if err != nil {
+		audit.close()
 		return nil, fmt.Errorf("bootstrap failed: %w", err)
 	}
+	c.metrics.ObserveDuration(metrics.BootstrapDuration, c.clock.Now().Sub(start), nil)
 
 	// Populate Store
-	for _, ff := range result.FigFamilies {
-		c.store.Put(ff)
+	families := c.admit(result.FigFamilies)
+	for i := range families {
+		c.payloads.intern(&families[i])
+		c.recordHistory(families[i])
+		c.auditFamily(result.Source, result.Cursors[families[i].Definition.Namespace], nil, families[i])
 	}
+	c.store.PutAll(families)
+	c.metrics.SetGauge(metrics.StoreFamilies, float64(c.storeLen()), nil)
 
 	// Set Cursors
 	c.mu.Lock()
@@ -155,55 +445,341 @@ func New(opts ...config.Option) (*Client, error) {
 		c.namespaceCursors[ns] = cursor
 	}
 	c.mu.Unlock()
+	c.initSync(result)
 
 	// Start polling
+	c.dispatcher = newDispatcher(cfg.ListenerWorkers)
+	if cfg.CredentialReloadInterval > 0 {
+		c.wg.Add(1)
+		go c.reloadLoop(c.credentialFiles())
+	}
+	if cfg.UsageReportInterval > 0 {
+		c.wg.Add(1)
+		go c.usageLoop()
+	}
+	if cfg.Frozen {
+		logging.Printf("Client is frozen at its bootstrap state; updates will not be applied")
+		return c, nil
+	}
 	c.wg.Add(1)
 	go c.pollLoop()
+	if d, ok := c.store.(store.Deleter); ok && cfg.ExpiryGCInterval > 0 {
+		c.wg.Add(1)
+		go c.gcLoop(d)
+	}
+	if cfg.PriorityPollingInterval > 0 && (len(cfg.KillSwitchKeys) > 0 || len(cfg.KillSwitchPrefixes) > 0) {
+		c.wg.Add(1)
+		go c.priorityLoop()
+	}
+	for _, src := range cfg.UpdateSources {
+		c.wg.Add(1)
+		go c.consumeSource(src)
+	}
 
 	return c, nil
 }
 
-// Close closes the client and releases resources.
+// newPrivateKeyTokenProvider creates the token provider for private key authentication.
+func newPrivateKeyTokenProvider(cfg *config.Config, pk *rsa.PrivateKey) transport.TokenProvider {
+	// Use EnvironmentID as placeholder if AuthClientID not set, but prefer AuthClientID
+	serviceAccountID := cfg.EnvironmentID
+	if cfg.AuthClientID != "" {
+		serviceAccountID = cfg.AuthClientID
+	}
+
+	// Use first namespace if available for auth token scope
+	namespace := ""
+	if len(cfg.Namespaces) > 0 {
+		namespace = cfg.Namespaces[0]
+	}
+	return transport.NewPrivateKeyTokenProvider(pk, serviceAccountID, cfg.TenantID, namespace, "", transport.WithTokenClock(cfg.Clock))
+}
+
+// UpdateCredentials switches the client to shared secret authentication with secret.
+// It takes effect for the next request; polling and watchers are not interrupted.
+func (c *Client) UpdateCredentials(secret string) error {
+	if secret == "" {
+		return fmt.Errorf("client secret must not be empty")
+	}
+	logging.RegisterSecret(secret)
+	c.tokens.Swap(transport.NewSharedSecretTokenProvider(secret))
+	return nil
+}
+
+// SwapAuthKey switches the client to private key authentication signed with key, using
+// the configured client, tenant and namespace. It takes effect for the next request;
+// polling and watchers are not interrupted.
+func (c *Client) SwapAuthKey(key *rsa.PrivateKey) error {
+	if key == nil {
+		return fmt.Errorf("auth private key must not be nil")
+	}
+	c.tokens.Swap(newPrivateKeyTokenProvider(c.cfg, key))
+	return nil
+}
+
+// TenantOverrides returns the per-tenant override layer. Overrides only apply when
+// tenant support is enabled with config.WithTenantAttributeKey.
+func (c *Client) TenantOverrides() *evaluation.TenantOverrides {
+	return c.overrides
+}
+
+// Close closes the client, cancelling in-flight requests, and releases resources. Use
+// Shutdown to let in-flight requests complete first.
 func (c *Client) Close() error {
+	c.cancel()
 	close(c.closeCh)
 	c.wg.Wait()
+	return c.release()
+}
+
+// Shutdown stops the client gracefully: no new requests are started, and in-flight ones
+// are given until ctx is done to complete before they are cancelled. Long polls are held
+// open by the server, so ctx should carry a deadline. It then releases the client's
+// resources like Close, and returns ctx's error if requests had to be cancelled.
+func (c *Client) Shutdown(ctx context.Context) error {
+	close(c.closeCh)
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	var drainErr error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		drainErr = ctx.Err()
+	}
+	c.cancel()
+	<-done
+	return errors.Join(drainErr, c.release())
+}
+
+// release releases the resources of a stopped client.
+func (c *Client) release() error {
+	for _, src := range c.cfg.UpdateSources {
+		if err := src.Close(); err != nil {
+			logging.Printf("Failed to close update source: %v", err)
+		}
+	}
+	c.closeWatchers()
+	c.dispatcher.close()
+	if c.encryptionService != nil {
+		c.encryptionService.Close()
+	}
+	for _, svc := range c.nsEncryption {
+		svc.Close()
+	}
+	if err := c.audit.close(); err != nil {
+		logging.Printf("Failed to close audit log: %v", err)
+	}
 	return c.transport.Close()
 }
 
+// memoKey identifies a decoded value in an EvaluationContext memo.
+type memoKey struct {
+	namespace string
+	key       string
+	typ       reflect.Type
+}
+
 // GetFig retrieves a configuration and deserializes it into target.
+//
+// If the context has a memo enabled (see evaluation.EvaluationContext.WithMemo), the
+// decoded value is cached for the lifetime of the context and repeated calls for the
+// same key and target type are served from it. Values served from the memo are shallow
+// copies: slices and maps in them must be treated as read-only.
 func (c *Client) GetFig(key string, target any, ctx *evaluation.EvaluationContext) error {
-	// Assume single namespace for now or pick first
-	if len(c.cfg.Namespaces) == 0 {
-		return fmt.Errorf("no namespaces configured")
+	if ctx == nil {
+		ctx = evaluation.NewEvaluationContext(nil)
 	}
-	namespace := c.cfg.Namespaces[0]
+	namespace, key, err := c.resolveKey(key)
+	if err != nil {
+		return err
+	}
+	c.usage.record(namespace, key, c.clock.Now())
 
-	figFamily, ok := c.store.Get(namespace, key)
+	memo := ctx.Memo()
+	targetVal := reflect.ValueOf(target)
+	if memo == nil || targetVal.Kind() != reflect.Pointer || targetVal.IsNil() {
+		return c.getFig(namespace, key, target, ctx)
+	}
+
+	mk := memoKey{namespace: namespace, key: key, typ: targetVal.Type()}
+	labels := map[string]string{"namespace": namespace}
+	if cached, ok := memo.Load(mk); ok {
+		c.metrics.IncCounter(metrics.EvaluationMemoHits, labels)
+		targetVal.Elem().Set(cached.(reflect.Value))
+		return nil
+	}
+	c.metrics.IncCounter(metrics.EvaluationMemoMisses, labels)
+
+	if err := c.getFig(namespace, key, target, ctx); err != nil {
+		return err
+	}
+
+	// Memoize a copy so that reassigning target's fields doesn't leak into the memo. The
+	// copy is shallow: slices, maps and pointers in the decoded value are shared by every
+	// target served from the memo, so they must not be modified in place
+	decoded := reflect.New(targetVal.Elem().Type()).Elem()
+	decoded.Set(targetVal.Elem())
+	memo.Store(mk, decoded)
+	return nil
+}
+
+// getFamily returns the family for namespace and key. With a bounded store, families
+// that aren't held are fetched from the server and cached.
+func (c *Client) getFamily(namespace, key string) (*model.FigFamily, bool) {
+	if ff, ok := c.store.Get(namespace, key); ok || c.cfg.MaxFamilies <= 0 {
+		return ff, ok
+	}
+	ff, err := c.transport.FetchFamily(c.ctx, namespace, key)
+	if err != nil {
+		if !errors.Is(err, transport.ErrNotFound) {
+			logging.Printf("Failed to fetch fig family %s/%s: %v", namespace, key, err)
+		}
+		return nil, false
+	}
+	// Fetched families are checked like updates, and don't overwrite a newer version the
+	// poll loop applied while this one was fetched
+	admitted := c.admit([]model.FigFamily{*ff})
+	if len(admitted) == 0 {
+		return nil, false
+	}
+	fetched := admitted[0]
+	c.payloads.intern(&fetched)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.staleReason(fetched) == "" {
+		c.store.Put(fetched)
+	}
+	return c.store.Get(namespace, key)
+}
+
+func (c *Client) getFig(namespace, key string, target any, ctx *evaluation.EvaluationContext) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.awaitSync(ctx, namespace); err != nil {
+		return err
+	}
+	figFamily, ok := c.getFamily(namespace, key)
 	if !ok {
 		return fmt.Errorf("fig not found: %s", key)
 	}
+	c.warnDeprecated(figFamily)
+	start := c.clock.Now()
+	if figFamily.Expired(start) {
+		return fmt.Errorf("%w: %s", ErrExpired, key)
+	}
 
-	fig, err := c.evaluator.Evaluate(figFamily, ctx)
+	ectx := c.withDefaultAttributes(ctx)
+	fig, err := c.evaluator.Evaluate(figFamily, ectx)
+	c.stats.evaluated(namespace, key)
+	c.metrics.ObserveDuration(metrics.EvaluationDuration, c.clock.Now().Sub(start), map[string]string{"namespace": namespace})
 	if err != nil {
 		return fmt.Errorf("evaluation failed: %w", err)
 	}
-	if fig == nil {
+	if fig == nil || fig.Expired(c.clock.Now()) {
 		return fmt.Errorf("no matching fig found for key: %s", key)
 	}
 
-	log.Printf("DEBUG GetFig: key=%s, IsEncrypted=%v, PayloadLen=%d", key, fig.IsEncrypted, len(fig.Payload))
+	// Don't decode for requests that ended during evaluation
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if err := c.decodeFig(ctx, namespace, key, fig, target); err != nil {
+		return err
+	}
+	return c.interpolate(ectx, key, target)
+}
+
+// interpolate expands the placeholders in a decoded payload with the configured
+// interpolator, if any.
+func (c *Client) interpolate(ctx *evaluation.EvaluationContext, key string, target any) error {
+	if c.cfg.Interpolator == nil {
+		return nil
+	}
+	if generic, ok := target.(*GenericRecord); ok {
+		target = &generic.Value
+	}
+	if err := evaluation.InterpolateValue(c.cfg.Interpolator, ctx, target); err != nil {
+		return fmt.Errorf("failed to interpolate %s: %w", key, err)
+	}
+	return nil
+}
 
+// GetFigVersion retrieves a specific version of a configuration, bypassing rule
+// evaluation, and deserializes it into target. The version must still be present in
+// the key's fig family.
+func (c *Client) GetFigVersion(key, version string, target any) error {
+	namespace, key, err := c.resolveKey(key)
+	if err != nil {
+		return err
+	}
+	c.usage.record(namespace, key, c.clock.Now())
+	if err := c.awaitSync(c.ctx, namespace); err != nil {
+		return err
+	}
+
+	figFamily, ok := c.getFamily(namespace, key)
+	if !ok {
+		return fmt.Errorf("fig not found: %s", key)
+	}
+	c.warnDeprecated(figFamily)
+
+	for i := range figFamily.Figs {
+		if figFamily.Figs[i].Version == version {
+			return c.decodeFig(c.ctx, namespace, key, &figFamily.Figs[i], target)
+		}
+	}
+	return fmt.Errorf("fig version %s not found for key: %s", version, key)
+}
+
+// EvaluateAll returns every rule of key's fig family that matches ctx, in order, with
+// the figs they target, rather than just the first as GetFig does. Use GetFigVersion to
+// deserialize the matched figs. It returns evaluation.ErrMultiEvaluationUnsupported if
+// the configured evaluator doesn't implement evaluation.MultiEvaluator.
+func (c *Client) EvaluateAll(key string, ctx *evaluation.EvaluationContext) ([]evaluation.RuleMatch, error) {
+	namespace, key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator, ok := c.evaluator.(evaluation.MultiEvaluator)
+	if !ok {
+		return nil, evaluation.ErrMultiEvaluationUnsupported
+	}
+	figFamily, ok := c.getFamily(namespace, key)
+	if !ok {
+		return nil, fmt.Errorf("fig not found: %s", key)
+	}
+	matches, err := evaluator.EvaluateAll(figFamily, c.withDefaultAttributes(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("evaluation failed: %w", err)
+	}
+	return matches, nil
+}
+
+// decodeFig decrypts the fig payload if needed and deserializes it into target.
+func (c *Client) decodeFig(ctx context.Context, namespace, key string, fig *model.Fig, target any) error {
 	// Decrypt
 	payload := fig.Payload
 	if fig.IsEncrypted {
-		if c.encryptionService == nil {
+		svc := c.encryptionFor(namespace)
+		if svc == nil {
 			return fmt.Errorf("received encrypted fig for key '%s' but client is not configured for decryption", key)
 		}
-		p, err := c.encryptionService.Decrypt(ctx, fig, namespace)
+		p, err := svc.Decrypt(ctx, fig, namespace)
 		if err != nil {
-			log.Printf("Failed to decrypt fig with key '%s' in namespace '%s': %v", key, namespace, err)
+			c.stats.decryptFailures.Add(1)
+			logging.Printf("Failed to decrypt fig with key '%s' in namespace '%s': %v", key, namespace, err)
 			return fmt.Errorf("failed to decrypt fig with key '%s' in namespace '%s': %w", key, namespace, err)
 		}
+		// Unmarshal copies what it needs, so the plaintext can be scrubbed right after
+		defer encryption.Zero(p)
 		payload = p
 	}
 
@@ -218,38 +794,35 @@ func (c *Client) GetFig(key string, target any, ctx *evaluation.EvaluationContex
 		return fmt.Errorf("failed to parse schema from target: %w", err)
 	}
 
-	if err := avro.Unmarshal(schema, payload, target); err != nil {
+	if generic, ok := record.(*GenericRecord); ok {
+		target = &generic.Value
+	}
+	if err := c.codec.Unmarshal(schema, payload, target); err != nil {
 		return fmt.Errorf("failed to unmarshal avro: %w", err)
 	}
 
 	return nil
 }
 
-// Watch returns a channel that receives updates for a specific key.
-func (c *Client) Watch(ctx context.Context, key string) <-chan model.FigFamily {
-	ch := make(chan model.FigFamily, 1)
-	c.mu.Lock()
-	c.watchers[key] = append(c.watchers[key], ch)
-	c.mu.Unlock()
-
-	go func() {
-		<-ctx.Done()
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		// Remove channel from watchers
-		if chans, ok := c.watchers[key]; ok {
-			for i, listener := range chans {
-				if listener == ch {
-					c.watchers[key] = append(chans[:i], chans[i+1:]...)
-					break
-				}
-			}
-		}
-		close(ch)
-	}()
-
-	return ch
+// withDefaultAttributes merges the configured global attributes, the tenant attribute
+// and context provider attributes under the request-scoped attributes of ctx.
+func (c *Client) withDefaultAttributes(ctx *evaluation.EvaluationContext) *evaluation.EvaluationContext {
+	if len(c.cfg.GlobalAttributes) == 0 && len(c.cfg.ContextProviders) == 0 && c.cfg.TenantAttributeKey == "" {
+		return ctx
+	}
+	defaults := maps.Clone(c.cfg.GlobalAttributes)
+	if defaults == nil {
+		defaults = make(map[string]string)
+	}
+	if tenantID, ok := evaluation.TenantFromContext(ctx); ok && c.cfg.TenantAttributeKey != "" {
+		defaults[c.cfg.TenantAttributeKey] = tenantID
+	}
+	for _, provider := range c.cfg.ContextProviders {
+		maps.Copy(defaults, provider.Attributes(ctx))
+	}
+	return ctx.WithDefaultAttributes(defaults)
 }
+
 func (c *Client) pollLoop() {
 	defer c.wg.Done()
 
@@ -258,133 +831,325 @@ func (c *Client) pollLoop() {
 		case <-c.closeCh:
 			return
 		default:
-			// Perform long poll
-			c.pollUpdates()
+			if c.schedule != nil && !c.waitForSchedule() {
+				return
+			}
+			// Perform long poll. A panic aborts only the current iteration; polling
+			// restarts after a backoff so one bad payload can't stop all future updates.
+			if err := c.safePollUpdates(); err != nil {
+				logging.Printf("Recovered from panic in poll loop: %v", err)
+				c.emit(event.Event{Type: event.PollPanicked, Message: "poll loop panicked, restarting", Err: err})
+				if c.cfg.FatalHandler != nil {
+					c.cfg.FatalHandler(err)
+				}
+				if !c.sleep(c.cfg.PollingInterval) {
+					return
+				}
+			}
 		}
 	}
 }
 
+// sleep waits for d on the client's clock. It returns false if the client was closed
+// first.
+func (c *Client) sleep(d time.Duration) bool {
+	timer := c.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-c.closeCh:
+		return false
+	case <-timer.C():
+		return true
+	}
+}
+
+// safePollUpdates runs pollUpdates, converting a panic into an error.
+func (c *Client) safePollUpdates() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("poll panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	c.pollUpdates()
+	return nil
+}
+
+// storeLen returns the number of families stored across the configured namespaces.
+func (c *Client) storeLen() int {
+	n := 0
+	for _, ns := range c.cfg.Namespaces {
+		n += c.store.Len(ns)
+	}
+	return n
+}
+
+// staleReason reports why ff must not be applied over the stored family: "stale" when it
+// is older than the stored one, "duplicate" when it is identical to it. It returns "" if
+// ff should be applied. Families without an updatedAt are always applied.
+func (c *Client) staleReason(ff model.FigFamily) string {
+	current, ok := c.store.Get(ff.Definition.Namespace, ff.Definition.Key)
+	if !ok || ff.Definition.UpdatedAt.IsZero() {
+		return ""
+	}
+	switch {
+	case ff.Definition.UpdatedAt.Before(current.Definition.UpdatedAt):
+		return "stale"
+	case ff.Definition.UpdatedAt.Equal(current.Definition.UpdatedAt) && reflect.DeepEqual(ff, *current):
+		return "duplicate"
+	}
+	return ""
+}
+
 func (c *Client) pollUpdates() {
+	c.retryHeld()
+
 	c.mu.RLock()
 	cursors := make(map[string]string)
 	maps.Copy(cursors, c.namespaceCursors)
 	c.mu.RUnlock()
 
-	for ns, cursor := range cursors {
-		req := &model.UpdateFetchRequest{
-			Namespace:     ns,
-			Cursor:        cursor,
-			EnvironmentID: c.cfg.EnvironmentID,
+	polled := false
+	for _, ns := range c.namespacesByPriority() {
+		cursor, ok := cursors[ns]
+		now := c.clock.Now()
+		if !ok || !c.pollDue(ns, now) {
+			continue
 		}
-		resp, err := c.transport.FetchUpdate(context.Background(), req)
-		if err != nil {
-			log.Printf("Failed to fetch updates for %s: %v", ns, err)
-			// Prevent tight loop on error (backoff)
-			select {
-			case <-c.closeCh:
+		polled = true
+		c.polled(ns, now)
+		if _, err := c.fetchUpdates(c.ctx, ns, cursor, AuditSourcePoll); err != nil {
+			if c.ctx.Err() != nil {
+				return
+			}
+			logging.Printf("Failed to fetch updates for %s: %v", ns, err)
+			// Prevent tight loop on error (backoff), longer if the server asked for it
+			backoff := c.cfg.PollingInterval
+			var te *transport.TransportError
+			if errors.As(err, &te) && te.RetryAfter > backoff {
+				backoff = te.RetryAfter
+			}
+			if !c.sleep(backoff) {
 				return
-			case <-time.After(c.cfg.PollingInterval):
-				continue
 			}
+			continue
 		}
+	}
+	// Every namespace is waiting out its profile's polling interval
+	if !polled && len(cursors) > 0 {
+		c.waitForNextPoll()
+	}
+}
 
-		if len(resp.FigFamilies) > 0 {
-			c.mu.Lock()
-			for _, ff := range resp.FigFamilies {
-				c.store.Put(ff)
+// fetchUpdates fetches and applies the updates of namespace after cursor. It may run
+// concurrently with the poll loop: the cursor only advances if nobody else moved it in
+// the meantime, and families fetched twice are ignored as duplicates.
+func (c *Client) fetchUpdates(ctx context.Context, namespace, cursor, source string) ([]model.FigFamily, error) {
+	req := &model.UpdateFetchRequest{
+		Namespace:     namespace,
+		Cursor:        cursor,
+		EnvironmentID: c.cfg.EnvironmentID,
+	}
+	resp, err := c.transport.FetchUpdate(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		// Cancelled, e.g. by Close, rather than failed
+		return nil, err
+	}
+	c.recordPoll(err)
+	c.stats.polls.Add(1)
+	if err != nil {
+		c.metrics.IncCounter(metrics.PollErrors, map[string]string{"namespace": namespace})
+		return nil, err
+	}
+	defer c.markSynced(namespace)
 
-				// Notify type-specific listeners
-				if callbacks, ok := c.listeners[ff.Definition.Key]; ok {
-					for _, cb := range callbacks {
-						cb(ff)
-					}
-				}
+	var applied []model.FigFamily
+	if len(resp.FigFamilies) > 0 {
+		applied = c.applyUpdates(resp.FigFamilies, source, resp.Cursor)
+	}
 
-				// Notify watchers
-				if chans, ok := c.watchers[ff.Definition.Key]; ok {
-					for _, ch := range chans {
-						select {
-						case ch <- ff:
-						default:
-							// Drop update if channel is full
-						}
-					}
-				}
-			}
-			c.mu.Unlock()
+	if resp.Cursor != "" {
+		c.mu.Lock()
+		if c.namespaceCursors[namespace] == cursor {
+			c.namespaceCursors[namespace] = resp.Cursor
+			c.saveCursor(namespace, resp.Cursor)
 		}
+		c.mu.Unlock()
+	}
+	return applied, nil
+}
 
-		if resp.Cursor != "" {
-			c.mu.Lock()
-			c.namespaceCursors[ns] = resp.Cursor
-			c.mu.Unlock()
+// saveCursor records the cursor of namespace in the local cache, if any. It is saved with
+// the next update, so the saved cursor never runs ahead of the saved families. The cursor
+// of a store shared through Redis is advanced too, after the families were put, so that
+// processes bootstrapping from it don't fetch updates already shared. While the
+// namespace has updates queued, by PauseUpdates or a staged rollout, the cursor is kept
+// back until they are applied, since a client restarted from the cache would otherwise
+// skip them. c.mu must be held.
+func (c *Client) saveCursor(namespace, cursor string) {
+	if c.cache == nil && c.shared == nil {
+		return
+	}
+	if c.hasQueued(namespace) {
+		c.unsavedCursors[namespace] = cursor
+		return
+	}
+	delete(c.unsavedCursors, namespace)
+	if c.cache != nil {
+		c.cache.SetCursor(namespace, cursor)
+	}
+	if c.shared != nil {
+		if err := c.shared.SaveCursor(c.ctx, namespace, cursor); err != nil {
+			logging.Printf("Failed to save the shared cursor of %s: %v", namespace, err)
 		}
 	}
 }
 
-// RegisterListener registers a callback for updates to a specific key.
-// The callback is invoked with the deserialized object when an update occurs.
+// saveKeptCursors records the cursors kept back by saveCursor of the namespaces that no
+// longer have updates queued. c.mu must be held.
+func (c *Client) saveKeptCursors() {
+	for ns, cursor := range c.unsavedCursors {
+		c.saveCursor(ns, cursor)
+	}
+}
+
+// hasQueued reports whether updates of namespace are queued or staged rather than
+// applied. c.mu must be held.
+func (c *Client) hasQueued(namespace string) bool {
+	inNamespace := func(u update) bool {
+		return u.family.Definition.Namespace == namespace
+	}
+	return slices.ContainsFunc(c.pending, inNamespace) || slices.ContainsFunc(c.staged, func(b *stagedBatch) bool {
+		return slices.ContainsFunc(b.updates, inNamespace)
+	})
+}
+
+// applyUpdates stores updated families and notifies their listeners and watchers. It
+// returns the families that were applied, i.e. not ignored as stale or duplicate. source
+// and cursor describe where the updates came from, for the audit log.
 //
-// IMPORTANT: This feature should be used for SERVER-SCOPED configuration only (e.g. global flags).
-// The update is evaluated with an empty context. If your rules depend on user-specific attributes
-// (like request-scoped context), this listener may receive default values or fail to match rules.
-// For request-scoped configuration, use GetFig() with the appropriate context when needed.
-func (c *Client) RegisterListener(key string, prototype AvroRecord, callback func(AvroRecord)) {
+// While updates are paused, families are queued instead, and with a staged rollout they
+// are applied once the rollout delay has passed; neither are returned. Kill-switch
+// families bypass both and are always applied immediately.
+func (c *Client) applyUpdates(families []model.FigFamily, source, cursor string) []model.FigFamily {
+	families = c.admit(families)
+	updates := make([]update, len(families))
+	for i, ff := range families {
+		updates[i] = update{family: ff, source: source, cursor: cursor}
+	}
+	priority, rest := c.splitPriority(updates)
+	if len(rest) > 0 && c.rolloutDelay > 0 {
+		c.stageUpdates(rest)
+		rest = nil
+	}
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
+	if c.paused {
+		c.pending = append(c.pending, rest...)
+		rest = nil
+	}
+	return c.applyLocked(append(priority, rest...))
+}
 
-	// We create a wrapper func that handles the logic
-	wrapper := func(ff model.FigFamily) {
-		// Empty evaluation context (embeds context.Background())
-		ctx := evaluation.NewEvaluationContext(nil)
-		fig, err := c.evaluator.Evaluate(&ff, ctx)
-		if err != nil || fig == nil {
-			log.Printf("Listener evaluation failed for %s: %v", key, err)
-			return
-		}
+// update is a family to apply and where it came from, for the audit log.
+type update struct {
+	family         model.FigFamily
+	source, cursor string
+}
 
-		// Create new instance of prototype type using reflection
-		// prototype should be a pointer to a struct
-		t := reflect.TypeOf(prototype)
-		if t.Kind() == reflect.Pointer {
-			t = t.Elem()
+// applyLocked stores admitted updates at once and notifies their listeners and watchers.
+// Of several updates of a family, only the last is applied. c.mu must be held and released
+// with unlock, which emits the resulting events.
+func (c *Client) applyLocked(updates []update) []model.FigFamily {
+	var applied []model.FigFamily
+	defer func() {
+		c.payloads.prune(c.store, c.cfg.Namespaces)
+		c.metrics.SetGauge(metrics.StoreFamilies, float64(c.storeLen()), nil)
+		if len(applied) > 0 {
+			close(c.updated)
+			c.updated = make(chan struct{})
 		}
-		targetVal := reflect.New(t)
-		target := targetVal.Interface()
+	}()
 
-		schema, err := avro.Parse(prototype.Schema())
-		if err != nil {
-			log.Printf("Listener schema parse failed for %s: %v", key, err)
-			return
+	last := make(map[[2]string]int, len(updates))
+	for i, u := range updates {
+		last[[2]string{u.family.Definition.Namespace, u.family.Definition.Key}] = i
+	}
+	var accepted []update
+	for i, u := range updates {
+		ff := u.family
+		if last[[2]string{ff.Definition.Namespace, ff.Definition.Key}] != i {
+			continue
 		}
-
-		payload := fig.Payload
-		if fig.IsEncrypted {
-			if c.encryptionService == nil {
-				log.Printf("Listener received encrypted fig for key '%s' but client is not configured for decryption", key)
-				return
-			}
-			// Use the evaluation context (which implements context.Context)
-			p, err := c.encryptionService.Decrypt(ctx, fig, ff.Definition.Namespace)
-			if err != nil {
-				log.Printf("Listener decryption failed for %s: %v", key, err)
-				return
-			}
-			payload = p
+		if reason := c.staleReason(ff); reason != "" {
+			c.metrics.IncCounter(metrics.UpdatesIgnored, map[string]string{
+				"namespace": ff.Definition.Namespace,
+				"key":       ff.Definition.Key,
+				"reason":    reason,
+			})
+			continue
 		}
-
-		if err := avro.Unmarshal(schema, payload, target); err != nil {
-			log.Printf("Listener unmarshal failed for %s: %v", key, err)
-			return
+		if !c.beforeApply(u) {
+			continue
 		}
+		c.payloads.intern(&u.family)
+		accepted = append(accepted, u)
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
 
-		// Callback with the new object (cast back to interface)
-		if record, ok := target.(AvroRecord); ok {
-			callback(record)
-		} else {
-			log.Printf("Listener callback failed for key %s: created object of type %T does not implement AvroRecord", key, target)
+	olds := make([]*model.FigFamily, len(accepted))
+	families := make([]model.FigFamily, len(accepted))
+	for i, u := range accepted {
+		olds[i], _ = c.store.Get(u.family.Definition.Namespace, u.family.Definition.Key)
+		families[i] = u.family
+	}
+	c.store.PutAll(families)
+	for i, u := range accepted {
+		ff := u.family
+		c.recordHistory(ff)
+		c.auditFamily(u.source, u.cursor, olds[i], ff)
+		applied = append(applied, ff)
+		c.metrics.IncCounter(metrics.UpdatesApplied, map[string]string{"namespace": ff.Definition.Namespace})
+		c.stats.updatesApplied.Add(1)
+		change, _ := model.DiffFamilies(olds[i], &ff)
+		c.emitLocked(event.Event{
+			Type:      event.UpdateApplied,
+			Namespace: ff.Definition.Namespace,
+			Key:       ff.Definition.Key,
+			Message:   "update applied",
+			Change:    &change,
+		})
+		c.notifyLocked(ff, &change)
+	}
+	return applied
+}
+
+// notifyLocked notifies the listeners and watchers of ff's key of a change. c.mu must be
+// held and released with unlock.
+func (c *Client) notifyLocked(ff model.FigFamily, change *FamilyChange) {
+	// Notify type-specific listeners. Callbacks run on the dispatcher, outside
+	// c.mu, but are queued under it so they are ordered with initial values. Bare keys
+	// are only notified of the namespace they resolve to, as GetFig reads from it.
+	var keys []string
+	if ns, _, err := c.resolveKey(ff.Definition.Key); err == nil && ns == ff.Definition.Namespace {
+		keys = append(keys, ff.Definition.Key)
+	}
+	if c.cfg.KeyRouting {
+		keys = append(keys, ff.Definition.Namespace+"/"+ff.Definition.Key)
+	}
+	for _, key := range keys {
+		for _, cb := range c.listeners[key] {
+			c.notifyListener(key, cb, ff)
 		}
 	}
 
-	c.listeners[key] = append(c.listeners[key], wrapper)
+	// Notify watchers
+	for _, key := range keys {
+		for _, w := range c.watchers[key] {
+			if w.matches(ff) {
+				c.notifyWatcher(w, ff, change)
+			}
+		}
+	}
 }
@@ -3,19 +3,43 @@ package client_test
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"filippo.io/age"
 	"github.com/hamba/avro/v2"
 	"github.com/hamba/avro/v2/ocf"
 
 	"github.com/figchain/go-client/pkg/client"
 	"github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/encryption"
 	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/figtest"
+	"github.com/figchain/go-client/pkg/metrics"
 	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/source"
+	"github.com/figchain/go-client/pkg/store"
+	"github.com/figchain/go-client/pkg/transport"
+	"github.com/figchain/go-client/pkg/vault"
 )
 
 // MockAvroRecord implements AvroRecord for testing
@@ -207,3 +231,4929 @@ func TestClient_Watch(t *testing.T) {
 func ptr(s string) *string {
 	return &s
 }
+
+// newTestServer serves the given initial response and empty updates. Families of the
+// initial response are also served individually.
+func newTestServer(t *testing.T, initial *model.InitialFetchResponse) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/family":
+			schemaStr = getRespSchema("FigFamily").String()
+			for i := range initial.FigFamilies {
+				if initial.FigFamilies[i].Definition.Key == r.URL.Query().Get("key") {
+					resp = &initial.FigFamilies[i]
+				}
+			}
+			if resp == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = initial
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: initial.Cursor}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type fixedVersionEvaluator struct {
+	version string
+}
+
+func (e *fixedVersionEvaluator) Evaluate(ff *model.FigFamily, _ *evaluation.EvaluationContext) (*model.Fig, error) {
+	for i := range ff.Figs {
+		if ff.Figs[i].Version == e.version {
+			return &ff.Figs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func TestClient_WithEvaluator(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition: model.FigDefinition{Key: "test-key", Namespace: "default"},
+				Figs: []model.Fig{
+					{Version: "v1", Payload: []byte("\x06foo")},
+					{Version: "v2", Payload: []byte("\x06bar")},
+				},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithEvaluator(&fixedVersionEvaluator{version: "v2"}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var record MockAvroRecord
+	if err := c.GetFig("test-key", &record, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Fatalf("GetFig failed: %v", err)
+	}
+	if record.Value != "bar" {
+		t.Errorf("Expected value 'bar' from custom evaluator, got '%s'", record.Value)
+	}
+}
+
+func TestClient_GetFigMemo(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "test-key", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ctx := evaluation.NewEvaluationContext(nil).WithMemo()
+	for i := 0; i < 3; i++ {
+		var record MockAvroRecord
+		if err := c.GetFig("test-key", &record, ctx); err != nil {
+			t.Fatalf("GetFig failed: %v", err)
+		}
+		if record.Value != "foo" {
+			t.Errorf("Expected value 'foo', got '%s'", record.Value)
+		}
+		// Mutating the result must not affect later memo hits
+		record.Value = "mutated"
+	}
+
+	hits, misses := ctx.Memo().Stats()
+	if hits != 2 || misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d hits and %d misses", hits, misses)
+	}
+
+	// Without a context there is no memo to consult
+	var record MockAvroRecord
+	if err := c.GetFig("test-key", &record, nil); err != nil {
+		t.Fatalf("GetFig with nil context failed: %v", err)
+	}
+	if record.Value != "foo" {
+		t.Errorf("Expected value 'foo', got '%s'", record.Value)
+	}
+}
+
+func TestClient_GlobalAttributes(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition: model.FigDefinition{Key: "test-key", Namespace: "default"},
+				Figs: []model.Fig{
+					{Version: "v1", Payload: []byte("\x06foo")},
+					{Version: "v2", Payload: []byte("\x06bar")},
+				},
+				Rules: []model.Rule{
+					{
+						TargetVersion: "v2",
+						Conditions: []model.Condition{
+							{Variable: "region", Operator: "EQUALS", Values: []string{"eu-1"}},
+							{Variable: "service", Operator: "EQUALS", Values: []string{"checkout"}},
+						},
+					},
+				},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithGlobalAttributes(map[string]string{"region": "eu-1"}),
+		config.WithContextProvider(evaluation.ContextProviderFunc(func(context.Context) map[string]string {
+			return map[string]string{"service": "checkout"}
+		})),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		want       string
+	}{
+		{"global and provider attributes", nil, "bar"},
+		{"request attributes take precedence", map[string]string{"region": "us-1"}, "foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var record MockAvroRecord
+			if err := c.GetFig("test-key", &record, evaluation.NewEvaluationContext(tt.attributes)); err != nil {
+				t.Fatalf("GetFig failed: %v", err)
+			}
+			if record.Value != tt.want {
+				t.Errorf("Expected value '%s', got '%s'", tt.want, record.Value)
+			}
+		})
+	}
+
+	// Rules are evaluated against the default attributes alone
+	t.Run("nil context", func(t *testing.T) {
+		var record MockAvroRecord
+		if err := c.GetFig("test-key", &record, nil); err != nil {
+			t.Fatalf("GetFig failed: %v", err)
+		}
+		if record.Value != "bar" {
+			t.Errorf("Expected value 'bar', got '%s'", record.Value)
+		}
+	})
+}
+
+func TestClient_WatchInitialValue(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "watch-key", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ch := c.Watch(context.Background(), "watch-key", client.WithInitialValue())
+	select {
+	case ff := <-ch:
+		if *ff.DefaultVersion != "v1" {
+			t.Errorf("Expected initial version v1, got %s", *ff.DefaultVersion)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for initial value")
+	}
+
+	got := make(chan string, 1)
+	c.RegisterListener("watch-key", &MockAvroRecord{}, func(r client.AvroRecord) {
+		got <- r.(*MockAvroRecord).Value
+	}, client.WithInitialValue())
+	select {
+	case v := <-got:
+		if v != "foo" {
+			t.Errorf("Expected listener to receive initial value 'foo', got %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for listener initial value")
+	}
+
+	// Keys that don't exist yet deliver nothing
+	select {
+	case ff := <-c.Watch(context.Background(), "missing-key", client.WithInitialValue()):
+		t.Errorf("Expected no initial value for missing key, got %v", ff)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_GetFigVersionAndFigIDFilter(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition: model.FigDefinition{Key: "test-key", Namespace: "default", FigID: "fig-1"},
+				Figs: []model.Fig{
+					{Version: "v1", Payload: []byte("\x06foo")},
+					{Version: "v2", Payload: []byte("\x06bar")},
+				},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var record MockAvroRecord
+	if err := c.GetFigVersion("test-key", "v2", &record); err != nil {
+		t.Fatalf("GetFigVersion failed: %v", err)
+	}
+	if record.Value != "bar" {
+		t.Errorf("Expected value 'bar', got '%s'", record.Value)
+	}
+	if err := c.GetFigVersion("test-key", "v3", &record); err == nil {
+		t.Error("Expected error for missing version")
+	}
+
+	select {
+	case ff := <-c.Watch(context.Background(), "test-key", client.WithInitialValue(), client.WithFigID("fig-1")):
+		if ff.Definition.FigID != "fig-1" {
+			t.Errorf("Expected fig-1, got %s", ff.Definition.FigID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for matching fig ID")
+	}
+
+	select {
+	case ff := <-c.Watch(context.Background(), "test-key", client.WithInitialValue(), client.WithFigID("fig-2")):
+		t.Errorf("Expected no delivery for other fig ID, got %v", ff)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// newUpdatingTestServer serves the given initial response, then each update in turn on
+// subsequent polls, then empty updates.
+func newUpdatingTestServer(t *testing.T, initial *model.InitialFetchResponse, updates ...*model.UpdateFetchResponse) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	cursor := initial.Cursor
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = initial
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			mu.Lock()
+			if len(updates) > 0 {
+				resp = updates[0]
+				cursor = updates[0].Cursor
+				updates = updates[1:]
+			} else {
+				resp = &model.UpdateFetchResponse{Cursor: cursor}
+			}
+			mu.Unlock()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_WatchBackpressure(t *testing.T) {
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "watch-key", Namespace: "default"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2")}},
+		&model.UpdateFetchResponse{Cursor: "3", FigFamilies: []model.FigFamily{family("v3")}},
+	)
+
+	var mu sync.Mutex
+	var dropped []event.Event
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+		config.WithEventHandler(func(e event.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			if e.Type == event.UpdateDropped {
+				dropped = append(dropped, e)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	// Both channels are filled by the initial value and not read until updates are done
+	dropping := c.Watch(context.Background(), "watch-key", client.WithInitialValue())
+	coalescing := c.Watch(context.Background(), "watch-key", client.WithInitialValue(), client.WithCoalesce())
+
+	deadline := time.Now().Add(time.Second)
+	for c.DroppedUpdates() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := c.DroppedUpdates(); got != 2 {
+		t.Fatalf("Expected 2 dropped updates, got %d", got)
+	}
+	mu.Lock()
+	if len(dropped) != 2 || dropped[0].Key != "watch-key" {
+		t.Errorf("Expected 2 drop events for watch-key, got %+v", dropped)
+	}
+	mu.Unlock()
+
+	if ff := <-dropping; *ff.DefaultVersion != "v1" {
+		t.Errorf("Expected dropping watcher to keep v1, got %s", *ff.DefaultVersion)
+	}
+	if ff := <-coalescing; *ff.DefaultVersion != "v3" {
+		t.Errorf("Expected coalescing watcher to hold latest v3, got %s", *ff.DefaultVersion)
+	}
+}
+
+func TestClient_IgnoresStaleAndDuplicateUpdates(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	family := func(version string, updatedAt time.Time) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "watch-key", Namespace: "default", UpdatedAt: updatedAt},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v2", base.Add(time.Minute))}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v1", base)}},
+		&model.UpdateFetchResponse{Cursor: "3", FigFamilies: []model.FigFamily{family("v2", base.Add(time.Minute))}},
+		&model.UpdateFetchResponse{Cursor: "4", FigFamilies: []model.FigFamily{family("v3", base.Add(2*time.Minute))}},
+	)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ch := c.Watch(context.Background(), "watch-key", client.WithBufferSize(4))
+	select {
+	case ff := <-ch:
+		if *ff.DefaultVersion != "v3" {
+			t.Errorf("Expected first delivered update to be v3, got %s", *ff.DefaultVersion)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for update")
+	}
+}
+
+func TestClient_IgnoresInvalidUpdates(t *testing.T) {
+	family := func(version, defaultVersion string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "watch-key", Namespace: "default", UpdatedAt: time.Now()},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(defaultVersion),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1", "v1")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2", "missing")}},
+		&model.UpdateFetchResponse{Cursor: "3", FigFamilies: []model.FigFamily{family("v3", "v3")}},
+	)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ch := c.Watch(context.Background(), "watch-key", client.WithBufferSize(4))
+	select {
+	case ff := <-ch:
+		if *ff.DefaultVersion != "v3" {
+			t.Errorf("Expected first delivered update to be v3, got %s", *ff.DefaultVersion)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for update")
+	}
+}
+
+func TestClient_QuarantinesUndecodableUpdates(t *testing.T) {
+	family := func(version string, payload []byte) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "q-key", Namespace: "default", UpdatedAt: time.Now()},
+			Figs:           []model.Fig{{Version: version, Payload: payload}},
+			DefaultVersion: ptr(version),
+		}
+	}
+
+	// Updates are served once a listener has registered the key's schema
+	var ready atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1", []byte("\x06foo"))}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			if ready.Load() {
+				// A negative string length doesn't decode
+				resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2", []byte("\x07"))}}
+			} else {
+				time.Sleep(10 * time.Millisecond)
+				resp = &model.UpdateFetchResponse{Cursor: "1"}
+			}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	quarantined := make(chan event.Event, 1)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithEventHandler(func(e event.Event) {
+			if e.Type == event.FamilyQuarantined {
+				select {
+				case quarantined <- e:
+				default:
+				}
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	c.RegisterListener("q-key", &MockAvroRecord{}, func(client.AvroRecord) {})
+	ready.Store(true)
+
+	select {
+	case e := <-quarantined:
+		if e.Key != "q-key" || e.Err == nil {
+			t.Errorf("Unexpected quarantine event %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for quarantine event")
+	}
+
+	q := c.Quarantined()
+	if len(q) != 1 || q[0].Key != "q-key" || q[0].Reason == "" {
+		t.Errorf("Quarantined() = %+v, want q-key", q)
+	}
+	if got := c.Health().Quarantined; got != 1 {
+		t.Errorf("Health().Quarantined = %d, want 1", got)
+	}
+
+	// The last good version keeps being served
+	var record MockAvroRecord
+	if err := c.GetFigVersion("q-key", "v1", &record); err != nil || record.Value != "foo" {
+		t.Errorf("GetFigVersion(v1) = %+v, %v, want foo", record, err)
+	}
+}
+
+func TestClient_WatchLifecycle(t *testing.T) {
+	updates := make([]*model.UpdateFetchResponse, 50)
+	for i := range updates {
+		version := "v" + strconv.Itoa(i+2)
+		updates[i] = &model.UpdateFetchResponse{Cursor: strconv.Itoa(i + 2), FigFamilies: []model.FigFamily{{
+			Definition:     model.FigDefinition{Key: "watch-key", Namespace: "default", UpdatedAt: time.Now().Add(time.Duration(i+1) * time.Second)},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}}}
+	}
+	server := newUpdatingTestServer(t, &model.InitialFetchResponse{Cursor: "1"}, updates...)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// Watchers come and go while updates are delivered
+	closed := func(ch <-chan model.FigFamily) bool {
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case _, ok := <-ch:
+				if !ok {
+					return true
+				}
+			case <-timeout:
+				return false
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				ctx, cancel := context.WithCancel(context.Background())
+				ch := c.Watch(ctx, "watch-key", client.WithBufferSize(1))
+				cancel()
+				if !closed(ch) {
+					t.Error("Expected the channel to close once the context is done")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	pending := c.Watch(context.Background(), "watch-key")
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := c.Watch(done, "watch-key")
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	for _, ch := range []<-chan model.FigFamily{pending, cancelled, c.Watch(context.Background(), "watch-key")} {
+		if !closed(ch) {
+			t.Fatal("Expected every Watch channel to be closed once Close returns")
+		}
+	}
+}
+
+func TestClient_HistoryAndRollback(t *testing.T) {
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "history-key", Namespace: "default", UpdatedAt: time.Now()},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2")}},
+	)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ch := c.Watch(context.Background(), "history-key", client.WithInitialValue(), client.WithBufferSize(4))
+	next := func() string {
+		t.Helper()
+		select {
+		case ff := <-ch:
+			return *ff.DefaultVersion
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for update")
+			return ""
+		}
+	}
+	// The initial value may already be v2
+	if v := next(); v == "v1" {
+		next()
+	}
+
+	history := c.GetFigHistory("history-key")
+	if len(history) != 2 || *history[0].Family.DefaultVersion != "v2" || *history[1].Family.DefaultVersion != "v1" {
+		t.Fatalf("GetFigHistory() = %+v, want v2, v1", history)
+	}
+
+	if err := c.Rollback("history-key"); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if v := next(); v != "v1" {
+		t.Errorf("Expected watchers to see the rollback to v1, got %s", v)
+	}
+	if err := c.Rollback("history-key"); err == nil {
+		t.Error("Expected Rollback() past the oldest version to fail")
+	}
+}
+
+func TestClient_AuditLog(t *testing.T) {
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "audit-key", Namespace: "default", UpdatedAt: time.Now()},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2")}},
+	)
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+		config.WithAuditLog(path),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(c.GetFigHistory("audit-key")) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	c.TenantOverrides().Set("acme", "audit-key", "v1")
+	c.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	var records []client.AuditRecord
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var r client.AuditRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			t.Fatalf("Invalid audit line %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+
+	want := []client.AuditRecord{
+		{Source: "server", Namespace: "default", Key: "audit-key", NewVersion: "v1", Cursor: "1"},
+		{Source: client.AuditSourcePoll, Namespace: "default", Key: "audit-key", OldVersion: "v1", NewVersion: "v2", Cursor: "2"},
+		{Source: client.AuditSourceOverride, Key: "audit-key", Tenant: "acme", NewVersion: "v1"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Audit log has %d records, want %d:\n%s", len(records), len(want), data)
+	}
+	for i, r := range records {
+		if r.Time.IsZero() {
+			t.Errorf("record %d has no time", i)
+		}
+		r.Time, r.UpdatedAt = time.Time{}, time.Time{}
+		if r != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+}
+
+func TestClient_Frozen(t *testing.T) {
+	var updates atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1"}
+		case "/data/updates":
+			updates.Add(1)
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "2"}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithTimeTravel("2024-01-01T00:00:00Z"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Refresh(context.Background()); !errors.Is(err, client.ErrFrozen) {
+		t.Errorf("Refresh() error = %v, want ErrFrozen", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := updates.Load(); got != 0 {
+		t.Errorf("Frozen client fetched updates %d times", got)
+	}
+	if h := c.Health(); !h.Frozen || h.Cursors["default"] != "1" {
+		t.Errorf("Health() = %+v, want frozen at cursor 1", h)
+	}
+}
+
+func TestClient_ListenerReentrancyAndPanics(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "watch-key", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	panicked := make(chan event.Event, 1)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithEventHandler(func(e event.Event) {
+			if e.Type == event.ListenerPanicked {
+				panicked <- e
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	c.RegisterListener("watch-key", &MockAvroRecord{}, func(client.AvroRecord) {
+		panic("boom")
+	}, client.WithInitialValue())
+
+	// A listener that calls back into the client must not deadlock
+	got := make(chan string, 1)
+	c.RegisterListener("watch-key", &MockAvroRecord{}, func(client.AvroRecord) {
+		var record MockAvroRecord
+		if err := c.GetFig("watch-key", &record, evaluation.NewEvaluationContext(nil)); err != nil {
+			t.Errorf("GetFig() in listener error = %v", err)
+		}
+		c.Watch(context.Background(), "other-key")
+		got <- record.Value
+	}, client.WithInitialValue())
+
+	select {
+	case e := <-panicked:
+		if e.Key != "watch-key" || e.Err == nil {
+			t.Errorf("Unexpected panic event: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for panic event")
+	}
+	select {
+	case v := <-got:
+		if v != "foo" {
+			t.Errorf("Expected 'foo', got %q", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for reentrant listener")
+	}
+}
+
+func TestClient_ListenerQueueReentrancy(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "watch-key", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithListenerWorkers(1),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	// Hold up the only worker so that callbacks queue up behind it
+	release := make(chan struct{})
+	c.RegisterListener("watch-key", &MockAvroRecord{}, func(client.AvroRecord) {
+		<-release
+	}, client.WithInitialValue())
+
+	const listeners = 1000
+	var called sync.WaitGroup
+	called.Add(listeners)
+	registered := make(chan struct{})
+	go func() {
+		defer close(registered)
+		for range listeners {
+			c.RegisterListener("watch-key", &MockAvroRecord{}, func(client.AvroRecord) {
+				defer called.Done()
+				c.UpdatesPaused()
+				c.Watch(context.Background(), "other-key")
+			}, client.WithInitialValue())
+		}
+	}()
+	select {
+	case <-registered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RegisterListener blocked on a full listener queue")
+	}
+	close(release)
+
+	done := make(chan struct{})
+	go func() {
+		called.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for reentrant listeners")
+	}
+}
+
+func TestClient_EventHandlerReentrancy(t *testing.T) {
+	family := func(key, version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "default"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	var ready atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("a", "v1"), family("rejected", "v1")}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			if ready.Load() {
+				resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("a", "v2"), family("rejected", "v2")}}
+			} else {
+				time.Sleep(10 * time.Millisecond)
+				resp = &model.UpdateFetchResponse{Cursor: "1"}
+			}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	// Handlers call back into the client for every event they receive
+	var c atomic.Pointer[client.Client]
+	handled := make(chan event.Type, 10)
+	cl, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithBeforeApply(func(_, new model.FigFamily) config.ApplyDecision {
+			if new.Definition.Key == "rejected" {
+				return config.ApplyReject
+			}
+			return config.ApplyAccept
+		}),
+		config.WithEventHandler(func(e event.Event) {
+			switch e.Type {
+			case event.UpdateApplied, event.UpdateDropped, event.UpdateRejected:
+				c.Load().UpdatesPaused()
+				c.Load().Watch(context.Background(), "other-key")
+				select {
+				case handled <- e.Type:
+				default:
+				}
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer cl.Close()
+	c.Store(cl)
+
+	// The initial value fills the channel, so the update is dropped
+	cl.Watch(context.Background(), "a", client.WithInitialValue(), client.WithBufferSize(1))
+	ready.Store(true)
+
+	want := map[event.Type]bool{event.UpdateApplied: true, event.UpdateDropped: true, event.UpdateRejected: true}
+	timeout := time.After(2 * time.Second)
+	for len(want) > 0 {
+		select {
+		case typ := <-handled:
+			delete(want, typ)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for reentrant event handlers, missing %v", want)
+		}
+	}
+}
+
+func TestClient_PollLoopRecoversFromPanic(t *testing.T) {
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "watch-key", Namespace: "default"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2")}},
+		&model.UpdateFetchResponse{Cursor: "3", FigFamilies: []model.FigFamily{family("v3")}},
+	)
+
+	var once sync.Once
+	fatal := make(chan error, 1)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+		config.WithEventHandler(func(e event.Event) {
+			if e.Type == event.UpdateDropped {
+				once.Do(func() { panic("bad handler") })
+			}
+		}),
+		config.WithFatalHandler(func(err error) {
+			fatal <- err
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	// The initial value fills the channel, so every update is dropped
+	c.Watch(context.Background(), "watch-key", client.WithInitialValue())
+
+	select {
+	case err := <-fatal:
+		if err == nil {
+			t.Error("Expected FatalHandler to receive an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for FatalHandler")
+	}
+
+	// Polling continues after the panic
+	deadline := time.Now().Add(time.Second)
+	for c.DroppedUpdates() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := c.DroppedUpdates(); got != 2 {
+		t.Errorf("Expected polling to continue after panic with 2 dropped updates, got %d", got)
+	}
+}
+
+func TestClient_MaxFamilies(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "key-a", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+			{
+				Definition:     model.FigDefinition{Key: "key-b", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06bar")}},
+				DefaultVersion: ptr("v1"),
+			},
+			{
+				Definition: model.FigDefinition{Key: "invalid", Namespace: "default"},
+				Figs: []model.Fig{
+					{Version: "v1", Payload: []byte("\x06foo")},
+					{Version: "v1", Payload: []byte("\x06bar")},
+				},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithMaxFamilies(1),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	// Only one family is held; the other is fetched on demand
+	for key, want := range map[string]string{"key-a": "foo", "key-b": "bar"} {
+		var record MockAvroRecord
+		if err := c.GetFig(key, &record, evaluation.NewEvaluationContext(nil)); err != nil {
+			t.Fatalf("GetFig(%s) error = %v", key, err)
+		}
+		if record.Value != want {
+			t.Errorf("GetFig(%s) = %q, want %q", key, record.Value, want)
+		}
+	}
+
+	var record MockAvroRecord
+	if err := c.GetFig("missing", &record, evaluation.NewEvaluationContext(nil)); err == nil {
+		t.Error("Expected error for missing key")
+	}
+	// Families fetched on demand are validated like updates
+	if err := c.GetFig("invalid", &record, evaluation.NewEvaluationContext(nil)); err == nil {
+		t.Error("Expected error for invalid family fetched on demand")
+	}
+}
+
+// authRecorder is a RoundTripper recording the Authorization header of every request.
+type authRecorder struct {
+	mu      sync.Mutex
+	headers []string
+}
+
+func (a *authRecorder) RoundTrip(r *http.Request) (*http.Response, error) {
+	a.mu.Lock()
+	a.headers = append(a.headers, r.Header.Get("Authorization"))
+	a.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func (a *authRecorder) last() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.headers[len(a.headers)-1]
+}
+
+func TestNewWithContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // the server doesn't answer until the test is over
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.NewWithContext(ctx,
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("NewWithContext() error = %v, want the context's deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("NewWithContext() took %s, want it aborted at the deadline", elapsed)
+	}
+}
+
+type gaugeRecorder struct {
+	metrics.NopRecorder
+	mu     sync.Mutex
+	labels map[string]map[string]string
+}
+
+func (r *gaugeRecorder) SetGauge(name string, _ float64, labels map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.labels[name] = labels
+}
+
+func TestClient_Identity(t *testing.T) {
+	var mu sync.Mutex
+	var headers http.Header
+	initial := newTestServer(t, &model.InitialFetchResponse{Cursor: "1"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = r.Header.Clone()
+		mu.Unlock()
+		initial.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	recorder := &gaugeRecorder{labels: make(map[string]map[string]string)}
+	events := make(chan event.Event, 1)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithAppName("checkout"),
+		config.WithInstanceID("checkout-7f9c"),
+		config.WithMetricsRecorder(recorder),
+		config.WithEventHandler(func(e event.Event) { events <- e }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	id := c.Identity()
+	if id.AppName != "checkout" || id.InstanceID != "checkout-7f9c" || id.SDKVersion == "" {
+		t.Errorf("Identity() = %+v, want the configured app and instance", id)
+	}
+	mu.Lock()
+	got := headers
+	mu.Unlock()
+	if got.Get("X-FigChain-App") != "checkout" || got.Get("X-FigChain-Instance-ID") != "checkout-7f9c" ||
+		got.Get("X-FigChain-SDK-Version") != id.SDKVersion || !strings.HasPrefix(got.Get("User-Agent"), "figchain-go-client/") {
+		t.Errorf("Request headers = %v, want the client identity", got)
+	}
+	recorder.mu.Lock()
+	info := recorder.labels[metrics.ClientInfo]
+	recorder.mu.Unlock()
+	if info["app"] != "checkout" || info["instance_id"] != "checkout-7f9c" {
+		t.Errorf("%s labels = %v, want the client identity", metrics.ClientInfo, info)
+	}
+
+	c.PauseUpdates()
+	if e := <-events; e.App != "checkout" || e.Instance != "checkout-7f9c" {
+		t.Errorf("Event = %+v, want it attributed to the client", e)
+	}
+}
+
+func TestClient_UsageReporting(t *testing.T) {
+	initial := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{{
+			Definition:     model.FigDefinition{Key: "test-key", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		}},
+	})
+	reports := make(chan model.UsageReport, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/usage" {
+			initial.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		var report model.UsageReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reports <- report
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithInstanceID("instance-a"),
+		config.WithUsageReporting(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var record MockAvroRecord
+	for range 3 {
+		if err := c.GetFig("test-key", &record, nil); err != nil {
+			t.Fatalf("GetFig() error = %v", err)
+		}
+	}
+	usage := c.Usage()
+	if len(usage) != 1 || usage[0].Key != "test-key" || usage[0].Reads != 3 || usage[0].LastRead.IsZero() {
+		t.Fatalf("Usage() = %+v, want 3 reads of test-key", usage)
+	}
+
+	select {
+	case report := <-reports:
+		if report.EnvironmentID != "env-1" || report.InstanceID != "instance-a" || len(report.Keys) != 1 || report.Keys[0].Reads != 3 {
+			t.Errorf("Usage report = %+v, want 3 reads of test-key", report)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a usage report")
+	}
+
+	// Only the reads since the last report are reported
+	c.GetFig("test-key", &record, nil)
+	select {
+	case report := <-reports:
+		if len(report.Keys) != 1 || report.Keys[0].Reads != 1 {
+			t.Errorf("Usage report = %+v, want the read since the last report", report)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a usage report")
+	}
+	if usage := c.Usage(); usage[0].Reads != 4 {
+		t.Errorf("Usage() = %+v, want all 4 reads", usage)
+	}
+}
+
+func TestClient_FakeClock(t *testing.T) {
+	initial := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{{
+			Definition:     model.FigDefinition{Key: "test-key", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		}},
+	})
+	reports := make(chan model.UsageReport, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/usage" {
+			initial.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		var report model.UsageReport
+		json.NewDecoder(r.Body).Decode(&report)
+		reports <- report
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := figtest.NewFakeClock(start)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithClock(clk),
+		config.WithExpiryGCInterval(0),
+		config.WithUsageReporting(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var record MockAvroRecord
+	if err := c.GetFig("test-key", &record, nil); err != nil {
+		t.Fatalf("GetFig() error = %v", err)
+	}
+	if usage := c.Usage(); len(usage) != 1 || !usage[0].LastRead.Equal(start) {
+		t.Fatalf("Usage() = %+v, want a read at the fake clock's time", usage)
+	}
+
+	// The report is only due once the fake clock has advanced by the interval
+	clk.BlockUntil(1)
+	select {
+	case report := <-reports:
+		t.Fatalf("Unexpected usage report %+v before the clock advanced", report)
+	case <-time.After(50 * time.Millisecond):
+	}
+	clk.Advance(time.Hour)
+	select {
+	case report := <-reports:
+		if len(report.Keys) != 1 || report.Keys[0].Reads != 1 {
+			t.Errorf("Usage report = %+v, want the read of test-key", report)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a usage report once the clock advanced")
+	}
+}
+
+func TestClient_Stats(t *testing.T) {
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "stats-key", Namespace: "default", UpdatedAt: time.Now()},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2")}},
+	)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ch := c.Watch(context.Background(), "stats-key", client.WithInitialValue())
+	deadline := time.After(time.Second)
+	for version := ""; version != "v2"; {
+		select {
+		case ff := <-ch:
+			version = *ff.DefaultVersion
+		case <-deadline:
+			t.Fatal("Timeout waiting for update")
+		}
+	}
+	var record MockAvroRecord
+	for range 2 {
+		if err := c.GetFig("stats-key", &record, nil); err != nil {
+			t.Fatalf("GetFig() error = %v", err)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Polls == 0 || stats.UpdatesApplied != 1 || stats.DecryptFailures != 0 {
+		t.Errorf("Stats() = %+v, want polls and the update applied", stats)
+	}
+	if stats.Evaluations["default/stats-key"] != 2 {
+		t.Errorf("Stats().Evaluations = %v, want 2 of default/stats-key", stats.Evaluations)
+	}
+	if stats.BytesSent == 0 || stats.BytesReceived == 0 {
+		t.Errorf("Stats() = %+v, want the bytes exchanged with the server", stats)
+	}
+	if stats.Cursors["default"] != "2" {
+		t.Errorf("Stats().Cursors = %v, want cursor 2", stats.Cursors)
+	}
+}
+
+func TestClient_Publish(t *testing.T) {
+	initial := newTestServer(t, &model.InitialFetchResponse{Cursor: "1"})
+	published := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/figs/default/publish-key/versions" {
+			initial.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		published <- body
+		json.NewEncoder(w).Encode(map[string]string{"version": body["version"].(string)})
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Publish(context.Background(), "publish-key", &MockAvroRecord{Value: "foo"},
+		client.WithRules(model.Rule{Conditions: []model.Condition{{Variable: "region", Operator: "IN", Values: []string{"eu"}}}}),
+		client.AsDefault(),
+	)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	version := result.Version
+	if len(version) != 36 || strings.Count(version, "-") != 4 {
+		t.Errorf("Publish() version = %q, want a UUID", version)
+	}
+	body := <-published
+	if body["payload"] != base64.StdEncoding.EncodeToString([]byte("\x06foo")) || body["defaultVersion"] != version {
+		t.Errorf("Published %v, want the encoded value as the default version", body)
+	}
+	if rules := body["rules"].([]any); len(rules) != 1 || rules[0].(map[string]any)["targetVersion"] != version {
+		t.Errorf("Published rules %v, want a rule targeting the new version", body["rules"])
+	}
+
+	// The family's rules and default version are kept unless asked otherwise
+	if _, err := c.Publish(context.Background(), "publish-key", &MockAvroRecord{Value: "bar"}, client.WithVersion("v2")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if body := <-published; body["version"] != "v2" || body["rules"] != nil || body["defaultVersion"] != nil {
+		t.Errorf("Published %v, want version v2 keeping the rules and default version", body)
+	}
+}
+
+func TestClient_PublishDryRun(t *testing.T) {
+	initial := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{{
+			Definition:     model.FigDefinition{Key: "publish-key", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		}},
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/figs/default/publish-key/versions" {
+			initial.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.URL.Query().Get("dryRun") == "true":
+			json.NewEncoder(w).Encode(model.PublishResponse{
+				Version:  body["version"].(string),
+				Problems: []model.PublishProblem{{Field: "payload", Message: "value must not be bar"}},
+			})
+		case r.Header.Get("Idempotency-Key") == "":
+			json.NewEncoder(w).Encode(model.PublishResponse{
+				Problems: []model.PublishProblem{{Field: "payload", Message: "value must not be bar"}},
+			})
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(model.PublishResponse{ApprovalRequired: true, ApprovalID: r.Header.Get("Idempotency-Key")})
+		}
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Publish(context.Background(), "publish-key", &MockAvroRecord{Value: "bar"}, client.WithVersion("v2"), client.AsDefault(), client.DryRun())
+	if err != nil {
+		t.Fatalf("Publish(dry run) error = %v", err)
+	}
+	if !result.DryRun || len(result.Problems) != 1 || result.Problems[0].Field != "payload" {
+		t.Errorf("Publish(dry run) = %+v, want the server's problems", result)
+	}
+	preview := result.Preview
+	if preview.Kind != model.ChangeModified || !slices.Equal(preview.AddedVersions, []string{"v2"}) ||
+		preview.OldDefaultVersion != "v1" || preview.NewDefaultVersion != "v2" {
+		t.Errorf("Publish(dry run) preview = %+v, want v2 added as the default", preview)
+	}
+
+	if _, err := c.Publish(context.Background(), "publish-key", &MockAvroRecord{Value: "bar"}); err == nil {
+		t.Error("Expected Publish() to fail with validation problems")
+	}
+
+	result, err = c.Publish(context.Background(), "publish-key", &MockAvroRecord{Value: "baz"}, client.WithIdempotencyKey("deploy-42"))
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if result.DryRun || !result.PendingApproval || result.ApprovalID != "deploy-42" {
+		t.Errorf("Publish() = %+v, want a version pending approval", result)
+	}
+}
+
+func TestClient_CreateNamespace(t *testing.T) {
+	initial := newTestServer(t, &model.InitialFetchResponse{Cursor: "1"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/namespaces" {
+			initial.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		var req model.CreateNamespaceRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.EnvironmentID != "env-1" {
+			t.Errorf("Expected the namespace to be created in env-1, got %q", req.EnvironmentID)
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(model.NamespaceInfo{Name: req.Name})
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ns, err := c.CreateNamespace(context.Background(), model.CreateNamespaceRequest{Name: "billing"})
+	if err != nil || ns.Name != "billing" {
+		t.Errorf("CreateNamespace() = %+v, %v", ns, err)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	key, err := encryption.NewKeyManager(nil, "", encryption.WithRSABits(2048)).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := encryption.SaveKey(keyPath, key); err != nil {
+		t.Fatalf("SaveKey failed: %v", err)
+	}
+	nsk := bytes.Repeat([]byte{7}, 32)
+	wrappedNsk, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key.Public().(*rsa.PublicKey), nsk, nil)
+	if err != nil {
+		t.Fatalf("EncryptOAEP failed: %v", err)
+	}
+	dek := bytes.Repeat([]byte{9}, 32)
+	wrappedDek, _ := encryption.WrapAESKey(dek, nsk)
+	encrypted, _ := encryption.EncryptAESGCM([]byte("\x06bar"), dek)
+
+	initial := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{{
+			Definition: model.FigDefinition{Key: "promote-key", Namespace: "default"},
+			Figs: []model.Fig{
+				{Version: "v1", Payload: []byte("\x06foo")},
+				{Version: "v2", Payload: encrypted, IsEncrypted: true, WrappedDek: wrappedDek, KeyID: ptr("k1")},
+			},
+			Rules:          []model.Rule{{Conditions: []model.Condition{{Variable: "region", Operator: "IN", Values: []string{"eu"}}}, TargetVersion: "v1"}},
+			DefaultVersion: ptr("v2"),
+		}},
+	})
+	type publish struct {
+		body           map[string]any
+		idempotencyKey string
+	}
+	var published []publish
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/keys/namespace/default":
+			json.NewEncoder(w).Encode([]model.NamespaceKey{{WrappedKey: base64.StdEncoding.EncodeToString(wrappedNsk), KeyID: "k1"}})
+		case "/admin/figs/default/promote-key/versions":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			published = append(published, publish{body, r.Header.Get("Idempotency-Key")})
+			json.NewEncoder(w).Encode(map[string]any{"version": body["version"]})
+		default:
+			initial.Config.Handler.ServeHTTP(w, r)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(env string) *client.Client {
+		c, err := client.New(
+			config.WithBaseURL(server.URL),
+			config.WithEnvironmentID(env),
+			config.WithNamespaces("default"),
+			config.WithClientSecret("test-secret"),
+			config.WithEncryptionPrivateKeyPath(keyPath),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	staging, prod := newClient("staging"), newClient("prod")
+
+	results, err := client.Promote(context.Background(), staging, prod, "promote-key")
+	if err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	if len(results) != 2 || len(published) != 2 {
+		t.Fatalf("Promote() = %d results, %d publishes, want one per version", len(results), len(published))
+	}
+
+	first, last := published[0], published[1]
+	if first.body["environmentId"] != "prod" || first.body["version"] != "v1" || first.body["payload"] != base64.StdEncoding.EncodeToString([]byte("\x06foo")) {
+		t.Errorf("First publish %v, want v1 unchanged in prod", first.body)
+	}
+	if first.body["rules"] != nil || first.body["defaultVersion"] != nil {
+		t.Errorf("First publish %v, want the rules and default version left to the last", first.body)
+	}
+	if first.idempotencyKey != "promote/staging/default/promote-key/v1" {
+		t.Errorf("Idempotency key = %q", first.idempotencyKey)
+	}
+
+	if last.body["version"] != "v2" || last.body["isEncrypted"] != true || last.body["keyId"] != "k1" || last.body["defaultVersion"] != "v2" {
+		t.Errorf("Last publish %v, want v2 encrypted as the default", last.body)
+	}
+	if rules := last.body["rules"].([]any); len(rules) != 1 || rules[0].(map[string]any)["targetVersion"] != "v1" {
+		t.Errorf("Last publish rules %v, want the family's", last.body["rules"])
+	}
+	// The payload was encrypted again, with a fresh data key
+	payload, _ := base64.StdEncoding.DecodeString(last.body["payload"].(string))
+	lastDek, _ := base64.StdEncoding.DecodeString(last.body["wrappedDek"].(string))
+	if bytes.Equal(payload, encrypted) || bytes.Equal(lastDek, wrappedDek) {
+		t.Error("Expected the promoted payload to be re-encrypted")
+	}
+	dek, err = encryption.UnwrapAESKey(lastDek, nsk)
+	if err != nil {
+		t.Fatalf("UnwrapAESKey failed: %v", err)
+	}
+	if plaintext, err := encryption.DecryptAESGCM(payload, dek); err != nil || string(plaintext) != "\x06bar" {
+		t.Errorf("Promoted payload decrypts to %q, %v", plaintext, err)
+	}
+}
+
+func TestClient_PublishEncrypted(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	key, err := encryption.NewKeyManager(nil, "", encryption.WithRSABits(2048)).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := encryption.SaveKey(keyPath, key); err != nil {
+		t.Fatalf("SaveKey failed: %v", err)
+	}
+	nsk := bytes.Repeat([]byte{7}, 32)
+	wrappedNsk, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key.Public().(*rsa.PublicKey), nsk, nil)
+	if err != nil {
+		t.Fatalf("EncryptOAEP failed: %v", err)
+	}
+
+	initial := newTestServer(t, &model.InitialFetchResponse{Cursor: "1"})
+	var published map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/keys/namespace/default":
+			json.NewEncoder(w).Encode([]model.NamespaceKey{{WrappedKey: base64.StdEncoding.EncodeToString(wrappedNsk), KeyID: "k1"}})
+		case "/admin/figs/default/secret-key/versions":
+			json.NewDecoder(r.Body).Decode(&published)
+			json.NewEncoder(w).Encode(map[string]any{"version": published["version"]})
+		default:
+			initial.Config.Handler.ServeHTTP(w, r)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(opts ...config.Option) *client.Client {
+		c, err := client.New(append([]config.Option{
+			config.WithBaseURL(server.URL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces("default"),
+			config.WithClientSecret("test-secret"),
+		}, opts...)...)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+
+	plain := newClient()
+	if _, err := plain.Publish(context.Background(), "secret-key", &MockAvroRecord{Value: "foo"}, client.Encrypted()); err == nil || published != nil {
+		t.Error("Expected an encrypted publish without an encryption key to fail before publishing")
+	}
+
+	c := newClient(config.WithEncryptionPrivateKeyPath(keyPath))
+	if _, err := c.Publish(context.Background(), "secret-key", &MockAvroRecord{Value: "foo"}, client.Encrypted()); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if published["isEncrypted"] != true || published["keyId"] != "k1" {
+		t.Fatalf("Published %v, want an encrypted payload with key k1", published)
+	}
+	payload, _ := base64.StdEncoding.DecodeString(published["payload"].(string))
+	wrappedDek, _ := base64.StdEncoding.DecodeString(published["wrappedDek"].(string))
+	dek, err := encryption.UnwrapAESKey(wrappedDek, nsk)
+	if err != nil {
+		t.Fatalf("UnwrapAESKey failed: %v", err)
+	}
+	if plaintext, err := encryption.DecryptAESGCM(payload, dek); err != nil || string(plaintext) != "\x06foo" {
+		t.Errorf("Published payload decrypts to %q, %v", plaintext, err)
+	}
+}
+
+func TestClient_HealthProtocol(t *testing.T) {
+	initial := newTestServer(t, &model.InitialFetchResponse{Cursor: "1"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(transport.ProtocolVersionHeader, "1")
+		w.Header().Set(transport.CapabilitiesHeader, transport.CapabilityStreamedInitialFetch)
+		initial.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	want := transport.Protocol{Version: 1, Capabilities: []string{transport.CapabilityStreamedInitialFetch}}
+	if p := c.Health().Protocol; !reflect.DeepEqual(p, want) {
+		t.Errorf("Health().Protocol = %+v, want %+v", p, want)
+	}
+}
+
+func TestClient_DumpDiagnostics(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{Cursor: "1"})
+
+	newClient := func(opts ...config.Option) *client.Client {
+		c, err := client.New(append([]config.Option{
+			config.WithBaseURL(server.URL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces("default"),
+			config.WithClientSecret("diagnostics-secret"),
+		}, opts...)...)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+
+	if err := newClient().DumpDiagnostics(io.Discard); err == nil {
+		t.Error("Expected DumpDiagnostics to fail without diagnostics enabled")
+	}
+
+	// Frozen, so that no poll is recorded after the events
+	c := newClient(config.WithDiagnostics(2), config.WithFrozen())
+	c.PauseUpdates()
+	c.ResumeUpdates()
+
+	var buf bytes.Buffer
+	if err := c.DumpDiagnostics(&buf); err != nil {
+		t.Fatalf("DumpDiagnostics() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "diagnostics-secret") {
+		t.Errorf("Diagnostics leak the client secret: %s", buf.String())
+	}
+	var report client.Diagnostics
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode diagnostics: %v", err)
+	}
+	if report.EnvironmentID != "env-1" || report.Health.Cursors["default"] != "1" {
+		t.Errorf("Diagnostics = %+v, want the client's environment and health", report)
+	}
+	// The ring buffer keeps the last two records: the bootstrap exchange was evicted
+	if len(report.Records) != 2 || report.Records[0].Event != event.UpdatesPaused || report.Records[1].Event != event.UpdatesResumed {
+		t.Fatalf("Records = %+v, want the pause and resume events", report.Records)
+	}
+
+	c = newClient(config.WithDiagnostics(10))
+	buf.Reset()
+	c.DumpDiagnostics(&buf)
+	json.Unmarshal(buf.Bytes(), &report)
+	if len(report.Records) == 0 {
+		t.Fatal("Expected the bootstrap exchange to be recorded")
+	}
+	exchange := report.Records[0]
+	if exchange.Kind != client.DiagnosticExchange || exchange.Method != "POST" || !strings.HasSuffix(exchange.URL, "/data/initial") || exchange.Status != http.StatusOK || exchange.ResponseSize == 0 {
+		t.Errorf("Exchange = %+v, want the initial fetch", exchange)
+	}
+	if got := exchange.RequestHeaders.Get("Authorization"); got != "[REDACTED]" {
+		t.Errorf("Authorization = %q, want it redacted", got)
+	}
+}
+
+// cancellingEvaluator serves the default version, cancelling the context of the
+// evaluation as it does.
+type cancellingEvaluator struct {
+	cancel context.CancelFunc
+}
+
+func (e *cancellingEvaluator) Evaluate(ff *model.FigFamily, _ *evaluation.EvaluationContext) (*model.Fig, error) {
+	e.cancel()
+	return &ff.Figs[0], nil
+}
+
+func TestClient_GetFigContextEnded(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "test-key", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var record MockAvroRecord
+	err = c.GetFig("test-key", &record, evaluation.NewEvaluationContextWithContext(ctx, nil))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("GetFig() error = %v with a cancelled context, want context.Canceled", err)
+	}
+
+	// A request cancelled during evaluation isn't decoded
+	ctx, cancel = context.WithCancel(context.Background())
+	c2, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithEvaluator(&cancellingEvaluator{cancel: cancel}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c2.Close()
+	err = c2.GetFig("test-key", &record, evaluation.NewEvaluationContextWithContext(ctx, nil))
+	if !errors.Is(err, context.Canceled) || record.Value != "" {
+		t.Errorf("GetFig() = %q, %v when cancelled during evaluation, want context.Canceled without decoding", record.Value, err)
+	}
+}
+
+func TestClient_DeprecatedKeys(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition: model.FigDefinition{Key: "old-key", Namespace: "default", Deprecation: &model.Deprecation{
+					ReplacementKey: ptr("new-key"),
+					Message:        ptr("removed in Q3"),
+				}},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+			{
+				Definition:     model.FigDefinition{Key: "new-key", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+	events := make(chan event.Event, 10)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithEventHandler(func(e event.Event) { events <- e }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var record MockAvroRecord
+	for _, key := range []string{"old-key", "old-key", "new-key"} {
+		if err := c.GetFig(key, &record, nil); err != nil {
+			t.Fatalf("GetFig(%s) error = %v", key, err)
+		}
+	}
+
+	// The deprecated key is reported once, however often it is read
+	var warnings []event.Event
+	for len(events) > 0 {
+		if e := <-events; e.Type == event.KeyDeprecated {
+			warnings = append(warnings, e)
+		}
+	}
+	if len(warnings) != 1 || warnings[0].Key != "old-key" || warnings[0].ReplacementKey != "new-key" ||
+		!strings.Contains(warnings[0].Message, "removed in Q3") {
+		t.Errorf("KeyDeprecated events = %+v, want one for old-key", warnings)
+	}
+
+	keys := c.DeprecatedKeys()
+	if len(keys) != 1 || keys[0].Key != "old-key" || keys[0].Reads != 2 || *keys[0].Deprecation.ReplacementKey != "new-key" {
+		t.Errorf("DeprecatedKeys() = %+v, want old-key read twice", keys)
+	}
+}
+
+func TestClient_UpdateCredentials(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{Cursor: "1"})
+	recorder := &authRecorder{}
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("old-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+		config.WithHTTPClient(&http.Client{Transport: recorder}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if got := recorder.last(); got != "Bearer old-secret" {
+		t.Errorf("Expected initial requests to use old secret, got %q", got)
+	}
+
+	if err := c.UpdateCredentials(""); err == nil {
+		t.Error("Expected error for empty secret")
+	}
+	if err := c.UpdateCredentials("new-secret"); err != nil {
+		t.Fatalf("UpdateCredentials() error = %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for recorder.last() != "Bearer new-secret" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := recorder.last(); got != "Bearer new-secret" {
+		t.Errorf("Expected polling to use new secret, got %q", got)
+	}
+}
+
+func TestClient_CredentialReload(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{Cursor: "1"})
+	recorder := &authRecorder{}
+	secretFile := filepath.Join(t.TempDir(), "client-secret")
+	if err := os.WriteFile(secretFile, []byte("old-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	events := make(chan event.Event, 10)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecretFile(secretFile),
+		config.WithCredentialReload(10*time.Millisecond),
+		config.WithPollingInterval(20*time.Millisecond),
+		config.WithHTTPClient(&http.Client{Transport: recorder}),
+		config.WithEventHandler(func(e event.Event) { events <- e }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if got := recorder.last(); got != "Bearer old-secret" {
+		t.Errorf("Expected initial requests to use the secret file, got %q", got)
+	}
+
+	// An invalid file is reported and the previous secret kept
+	if err := os.WriteFile(secretFile, nil, 0600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	select {
+	case e := <-events:
+		if e.Type != event.CredentialReloadFailed || e.Err == nil {
+			t.Errorf("Expected a CredentialReloadFailed event, got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the empty secret file to be reported")
+	}
+
+	if err := os.WriteFile(secretFile, []byte("new-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	select {
+	case e := <-events:
+		if e.Type != event.CredentialsReloaded {
+			t.Errorf("Expected a CredentialsReloaded event, got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the rotated secret file to be reloaded")
+	}
+	deadline := time.Now().Add(time.Second)
+	for recorder.last() != "Bearer new-secret" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := recorder.last(); got != "Bearer new-secret" {
+		t.Errorf("Expected polling to use the rotated secret, got %q", got)
+	}
+}
+
+func TestClient_TenantOverrides(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition: model.FigDefinition{Key: "tenant-key", Namespace: "default"},
+				Figs: []model.Fig{
+					{Version: "v1", Payload: []byte("\x06foo")},
+					{Version: "v2", Payload: []byte("\x06bar")},
+					{Version: "v3", Payload: []byte("\x06baz")},
+				},
+				Rules: []model.Rule{
+					{TargetVersion: "v2", Conditions: []model.Condition{{Variable: "tenant", Operator: "EQUALS", Values: []string{"acme"}}}},
+				},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithTenantAttributeKey("tenant"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	get := func(tenantID string) string {
+		t.Helper()
+		ctx := evaluation.NewEvaluationContextWithContext(evaluation.ContextWithTenant(context.Background(), tenantID), nil)
+		var record MockAvroRecord
+		if err := c.GetFig("tenant-key", &record, ctx); err != nil {
+			t.Fatalf("GetFig() error = %v", err)
+		}
+		return record.Value
+	}
+
+	// The tenant from the context flows into rules
+	if got := get("acme"); got != "bar" {
+		t.Errorf("Expected acme to match rule and get 'bar', got %q", got)
+	}
+	if got := get("globex"); got != "foo" {
+		t.Errorf("Expected globex to get default 'foo', got %q", got)
+	}
+
+	c.TenantOverrides().Set("globex", "tenant-key", "v3")
+	if got := get("globex"); got != "baz" {
+		t.Errorf("Expected globex override 'baz', got %q", got)
+	}
+	if got := get("acme"); got != "bar" {
+		t.Errorf("Expected acme unaffected by globex override, got %q", got)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition: model.FigDefinition{Key: "admin-key", Namespace: "default"},
+				Figs:       []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}, {Version: "v2", Payload: []byte("\x06bar")}},
+				Rules: []model.Rule{
+					{TargetVersion: "v2", Conditions: []model.Condition{{Variable: "plan", Operator: "EQUALS", Values: []string{"pro"}}}},
+				},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithTenantAttributeKey("tenant"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	admin := httptest.NewServer(client.NewAdminHandler(c, client.WithAdminToken("admin-token")))
+	defer admin.Close()
+
+	do := func(method, path, token, body string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(method, admin.URL+path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		buf.ReadFrom(resp.Body)
+		return resp, buf.Bytes()
+	}
+
+	if resp, _ := do("GET", "/keys", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", resp.StatusCode)
+	}
+	if resp, _ := do("GET", "/keys", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", resp.StatusCode)
+	}
+
+	resp, body := do("GET", "/keys", "admin-token", "")
+	if resp.StatusCode != http.StatusOK || !json.Valid(body) || !bytes.Contains(body, []byte(`"key":"admin-key"`)) {
+		t.Errorf("GET /keys = %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = do("POST", "/evaluate", "admin-token", `{"key":"admin-key","attributes":{"plan":"pro"},"tenant":"acme"}`)
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte(`"version":"v2"`)) || !bytes.Contains(body, []byte(`"tenant":"acme"`)) {
+		t.Errorf("POST /evaluate = %d %s", resp.StatusCode, body)
+	}
+
+	c.TenantOverrides().Set("acme", "admin-key", "v1")
+	if resp, _ := do("POST", "/overrides/clear?tenant=acme", "admin-token", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("POST /overrides/clear = %d", resp.StatusCode)
+	}
+	if _, ok := c.TenantOverrides().Version("acme", "admin-key"); ok {
+		t.Error("Expected acme overrides to be cleared")
+	}
+
+	resp, body = do("POST", "/refresh", "admin-token", "")
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte(`"status":"ok"`)) {
+		t.Errorf("POST /refresh = %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = do("GET", "/health", "admin-token", "")
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte(`"families":1`)) {
+		t.Errorf("GET /health = %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestClient_Refresh(t *testing.T) {
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "refresh-key", Namespace: "default", UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+
+	// Updates fail until the test is ready, so the poll loop backs off and only Refresh
+	// fetches the update
+	var ready atomic.Bool
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1")}}
+		case "/data/updates":
+			polls.Add(1)
+			if !ready.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2")}}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	// Wait for the poll loop's first (failed) poll
+	deadline := time.Now().Add(time.Second)
+	for polls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := c.Refresh(context.Background()); err == nil {
+		t.Error("Expected error while updates fail")
+	}
+	if _, err := c.Refresh(context.Background(), "unknown"); err == nil {
+		t.Error("Expected error for unknown namespace")
+	}
+
+	ready.Store(true)
+	changed, err := c.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(changed) != 1 || *changed[0].DefaultVersion != "v2" {
+		t.Errorf("Refresh() = %v, want the v2 update", changed)
+	}
+
+	// Fetching the same update again changes nothing
+	changed, err = c.Refresh(context.Background(), "default")
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("Refresh() = %v, want no changes", changed)
+	}
+}
+
+func TestClient_WebhookHandler(t *testing.T) {
+	var updates atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1"}
+		case "/data/updates":
+			// Fail polls so that the poll loop backs off; count webhook refreshes
+			if updates.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "1"}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	// Wait for the poll loop's first (failed) poll
+	deadline := time.Now().Add(time.Second)
+	for updates.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	webhook := httptest.NewServer(c.WebhookHandler("hook-secret"))
+	defer webhook.Close()
+
+	send := func(secret string, timestamp time.Time, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", webhook.URL, bytes.NewBufferString(body))
+		req.Header.Set(client.WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+		req.Header.Set(client.WebhookSignatureHeader, client.SignWebhook(secret, timestamp.Unix(), []byte(body)))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("webhook request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := send("wrong-secret", time.Now(), `{"namespace":"default"}`); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for wrong secret, got %d", status)
+	}
+	if status := send("hook-secret", time.Now().Add(-time.Hour), `{"namespace":"default"}`); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for stale timestamp, got %d", status)
+	}
+	if status := send("hook-secret", time.Now(), `{"namespace":"other"}`); status != http.StatusNoContent {
+		t.Errorf("Expected 204 for unknown namespace, got %d", status)
+	}
+	if got := updates.Load(); got != 1 {
+		t.Errorf("Expected no refresh for rejected webhooks, got %d update fetches", got)
+	}
+
+	if status := send("hook-secret", time.Now(), `{"namespace":"default"}`); status != http.StatusNoContent {
+		t.Errorf("Expected 204 for valid webhook, got %d", status)
+	}
+	if got := updates.Load(); got != 2 {
+		t.Errorf("Expected valid webhook to trigger a refresh, got %d update fetches", got)
+	}
+}
+
+// chanSource is an UpdateSource fed from a channel.
+type chanSource struct {
+	updates chan *source.Update
+	acks    atomic.Int32
+	closed  atomic.Bool
+}
+
+func (s *chanSource) Next(ctx context.Context) (*source.Update, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case u := <-s.updates:
+		u.Ack = func(context.Context) error {
+			s.acks.Add(1)
+			return nil
+		}
+		return u, nil
+	}
+}
+
+func (s *chanSource) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestClient_UpdateSource(t *testing.T) {
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "bus-key", Namespace: "default", UpdatedAt: time.Now()},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+
+	// The first poll fails so that the poll loop backs off; later fetches come from
+	// gap reconciliation
+	var updates atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1")}}
+		case "/data/updates":
+			if updates.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "4", FigFamilies: []model.FigFamily{family("v4")}}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	src := &chanSource{updates: make(chan *source.Update)}
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(time.Hour),
+		config.WithUpdateSource(src),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	waitForCursor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for c.Health().Cursors["default"] != want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := c.Health().Cursors["default"]; got != want {
+			t.Fatalf("cursor = %q, want %q", got, want)
+		}
+	}
+
+	// Wait for the poll loop's first (failed) poll
+	deadline := time.Now().Add(time.Second)
+	for updates.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// An update following the current cursor is applied directly
+	src.updates <- &source.Update{Namespace: "default", PrevCursor: "1", Cursor: "2", FigFamilies: []model.FigFamily{family("v2")}}
+	waitForCursor("2")
+	var record MockAvroRecord
+	if err := c.GetFigVersion("bus-key", "v2", &record); err != nil {
+		t.Errorf("GetFigVersion(v2) error = %v", err)
+	}
+	fetches := updates.Load()
+
+	// An update after a gap is reconciled against the server
+	src.updates <- &source.Update{Namespace: "default", PrevCursor: "3", Cursor: "4", FigFamilies: []model.FigFamily{family("v4")}}
+	waitForCursor("4")
+	if updates.Load() != fetches+1 {
+		t.Errorf("Expected a gap to trigger one server fetch, got %d", updates.Load()-fetches)
+	}
+
+	// Updates for other namespaces are acknowledged but ignored
+	src.updates <- &source.Update{Namespace: "other", PrevCursor: "4", Cursor: "5"}
+
+	c.Close()
+	if got := src.acks.Load(); got != 3 {
+		t.Errorf("Expected 3 acks, got %d", got)
+	}
+	if !src.closed.Load() {
+		t.Error("Expected Close to close the update source")
+	}
+}
+
+func TestRelayHandler(t *testing.T) {
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "relay-key", Namespace: "default", UpdatedAt: time.Now()},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+
+	// The upstream serves v2 once the test is ready
+	var ready atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1")}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			if ready.Load() {
+				resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2")}}
+			} else {
+				time.Sleep(10 * time.Millisecond)
+				resp = &model.UpdateFetchResponse{Cursor: "1"}
+			}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer upstream.Close()
+
+	relayed, err := client.New(
+		config.WithBaseURL(upstream.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer relayed.Close()
+
+	relay := httptest.NewServer(client.NewRelayHandler(relayed, client.WithRelayToken("relay-token")))
+	defer relay.Close()
+
+	if _, err := client.New(
+		config.WithBaseURL(relay.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("wrong-token"),
+	); err == nil {
+		t.Error("Expected bootstrap with the wrong relay token to fail")
+	}
+
+	downstream, err := client.New(
+		config.WithBaseURL(relay.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("relay-token"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create downstream client: %v", err)
+	}
+	defer downstream.Close()
+
+	var record MockAvroRecord
+	if err := downstream.GetFigVersion("relay-key", "v1", &record); err != nil {
+		t.Fatalf("GetFigVersion(v1) error = %v", err)
+	}
+
+	ch := downstream.Watch(context.Background(), "relay-key")
+	ready.Store(true)
+	select {
+	case ff := <-ch:
+		if *ff.DefaultVersion != "v2" {
+			t.Errorf("Expected relayed update v2, got %s", *ff.DefaultVersion)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for relayed update")
+	}
+}
+
+func TestRelayHandler_Restart(t *testing.T) {
+	family := func(key, version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "default"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	relayOf := func(families ...model.FigFamily) http.Handler {
+		t.Helper()
+		upstream := newTestServer(t, &model.InitialFetchResponse{Cursor: "1", FigFamilies: families})
+		relayed, err := client.New(
+			config.WithBaseURL(upstream.URL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces("default"),
+			config.WithClientSecret("test-secret"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		t.Cleanup(func() { relayed.Close() })
+		return client.NewRelayHandler(relayed, client.WithRelayToken("relay-token"), client.WithRelayPollTimeout(20*time.Millisecond))
+	}
+
+	// The relay restarts with a store whose revisions overlap the old one's
+	var handler atomic.Pointer[http.Handler]
+	before := relayOf(family("relay-key", "v1"), family("other-key", "v1"))
+	handler.Store(&before)
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*handler.Load()).ServeHTTP(w, r)
+	}))
+	defer relay.Close()
+
+	downstream, err := client.New(
+		config.WithBaseURL(relay.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("relay-token"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create downstream client: %v", err)
+	}
+	defer downstream.Close()
+
+	ch := downstream.Watch(context.Background(), "relay-key")
+	after := relayOf(family("relay-key", "v2"), family("other-key", "v1"))
+	handler.Store(&after)
+	select {
+	case ff := <-ch:
+		if *ff.DefaultVersion != "v2" {
+			t.Errorf("Expected relayed update v2, got %s", *ff.DefaultVersion)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the update after the relay restarted")
+	}
+}
+
+func TestClient_DiffAsOf(t *testing.T) {
+	current := []model.FigFamily{
+		{
+			Definition:     model.FigDefinition{Key: "same", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		},
+		{
+			Definition:     model.FigDefinition{Key: "changed", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		},
+		{
+			Definition:     model.FigDefinition{Key: "removed", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		},
+	}
+	future := []model.FigFamily{
+		current[0],
+		{
+			Definition: model.FigDefinition{Key: "changed", Namespace: "default"},
+			Figs: []model.Fig{
+				{Version: "v1", Payload: []byte("\x06foo")},
+				{Version: "v2", Payload: []byte("\x06bar")},
+			},
+			Rules:          []model.Rule{model.NewRule("v1", model.NewCondition("plan", model.OperatorEquals, "free"))},
+			DefaultVersion: ptr("v2"),
+		},
+		{
+			Definition:     model.FigDefinition{Key: "added", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		},
+	}
+
+	// The first initial fetch bootstraps the client, later ones return the future state
+	var initials atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			families := current
+			if initials.Add(1) > 1 {
+				families = future
+			}
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: families}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "1"}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithFrozen(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	diff, err := c.DiffAsOf(context.Background(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("DiffAsOf failed: %v", err)
+	}
+	if len(diff.Changes) != 3 {
+		t.Fatalf("DiffAsOf() changes = %+v, want 3", diff.Changes)
+	}
+	added, changed, removed := diff.Changes[0], diff.Changes[1], diff.Changes[2]
+	if added.Key != "added" || added.Kind != client.ChangeAdded || added.NewDefaultVersion != "v1" {
+		t.Errorf("added = %+v", added)
+	}
+	if changed.Key != "changed" || changed.Kind != client.ChangeModified ||
+		changed.OldDefaultVersion != "v1" || changed.NewDefaultVersion != "v2" ||
+		!reflect.DeepEqual(changed.AddedVersions, []string{"v2"}) || len(changed.NewRules) != 1 {
+		t.Errorf("changed = %+v", changed)
+	}
+	if removed.Key != "removed" || removed.Kind != client.ChangeRemoved || removed.OldDefaultVersion != "v1" {
+		t.Errorf("removed = %+v", removed)
+	}
+
+	// The client still serves the current state
+	var record MockAvroRecord
+	if err := c.GetFig("removed", &record, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Errorf("GetFig(removed) after DiffAsOf failed: %v", err)
+	}
+}
+
+func TestClient_Families(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{Definition: model.FigDefinition{Key: "a", Namespace: "default", SchemaURI: "schemas/a"}},
+			{Definition: model.FigDefinition{Key: "b", Namespace: "default"}},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithFrozen(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if got := c.Families(); len(got) != 2 {
+		t.Errorf("Families() returned %d families, want 2", len(got))
+	}
+	if got := c.Families("other"); len(got) != 0 {
+		t.Errorf("Families(other) returned %d families, want 0", len(got))
+	}
+}
+
+func TestClient_SchemaCompatibility(t *testing.T) {
+	schemas := map[string]string{
+		"/schemas/compatible":   `{"type":"record","name":"Cfg","fields":[{"name":"value","type":"string"},{"name":"extra","type":"int"}]}`,
+		"/schemas/incompatible": `{"type":"record","name":"Cfg","fields":[{"name":"value","type":"boolean"}]}`,
+	}
+	initial := &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "good", Namespace: "default", SchemaURI: "schemas/compatible", SchemaVersion: "1"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+			{
+				Definition:     model.FigDefinition{Key: "bad", Namespace: "default", SchemaURI: "schemas/incompatible", SchemaVersion: "2"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+			{
+				Definition:     model.FigDefinition{Key: "untyped", Namespace: "default", SchemaURI: "schemas/incompatible", SchemaVersion: "2"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s, ok := schemas[r.URL.Path]; ok {
+			w.Write([]byte(s))
+			return
+		}
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = initial
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var incompatible []string
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithFrozen(),
+		config.WithType("good", &MockAvroRecord{}),
+		config.WithType("bad", &MockAvroRecord{}),
+		config.WithEventHandler(func(e event.Event) {
+			if e.Type == event.SchemaIncompatible {
+				mu.Lock()
+				incompatible = append(incompatible, e.Key)
+				mu.Unlock()
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	// Schemas are checked in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(incompatible)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(incompatible, []string{"bad"}) {
+		t.Errorf("incompatible keys = %v, want [bad]", incompatible)
+	}
+}
+
+func TestClient_SchemaCompatibilityUnavailable(t *testing.T) {
+	family := model.FigFamily{
+		Definition:     model.FigDefinition{Key: "good", Namespace: "default", SchemaURI: "schemas/cfg", SchemaVersion: "1"},
+		Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+		DefaultVersion: ptr("v1"),
+	}
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/schemas/cfg":
+			// The first fetch hangs until released; all fail
+			if fetches.Add(1) == 1 {
+				<-release
+			}
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family}}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+	defer close(release)
+
+	clk := figtest.NewFakeClock(time.Now())
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithLongPolling(false),
+		config.WithClock(clk),
+		config.WithType("good", &MockAvroRecord{}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// Updates are applied while the schema fetch hangs, without starting another
+	if _, err := c.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	var record MockAvroRecord
+	if err := c.GetFig("good", &record, nil); err != nil {
+		t.Fatalf("GetFig failed: %v", err)
+	}
+	release <- struct{}{}
+
+	// The failed fetch is retried once the backoff has passed, and not before
+	deadline := time.Now().Add(5 * time.Second)
+	for fetches.Load() < 2 && time.Now().Before(deadline) {
+		clk.Advance(time.Minute)
+		if _, err := c.Refresh(context.Background()); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for range 3 {
+		if _, err := c.Refresh(context.Background()); err != nil {
+			t.Fatalf("Refresh failed: %v", err)
+		}
+	}
+	c.Close()
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("schema fetched %d times, want 2", got)
+	}
+}
+
+// richRecord uses logical types and a union with a record member.
+type richRecord struct {
+	Price     *big.Rat  `avro:"price"`
+	ID        string    `avro:"id"`
+	Trace     any       `avro:"trace"`
+	StartsAt  time.Time `avro:"startsAt"`
+	Threshold any       `avro:"threshold"`
+}
+
+type richLimits struct {
+	Max int `avro:"max"`
+}
+
+func (r *richRecord) Schema() string {
+	return `{
+		"type": "record",
+		"name": "RichRecord",
+		"fields": [
+			{"name": "price", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+			{"name": "id", "type": {"type": "string", "logicalType": "uuid"}},
+			{"name": "trace", "type": {"type": "string", "logicalType": "uuid"}},
+			{"name": "startsAt", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+			{"name": "threshold", "type": ["null", "string", {"type": "record", "name": "Limits", "fields": [{"name": "max", "type": "int"}]}]}
+		]
+	}`
+}
+
+// upperUUIDConverter upper-cases UUIDs decoded into fields of type any.
+type upperUUIDConverter struct{}
+
+func (upperUUIDConverter) Type() avro.Type               { return avro.String }
+func (upperUUIDConverter) LogicalType() avro.LogicalType { return avro.UUID }
+func (upperUUIDConverter) EncodeTypeConvert(in any, _ avro.Schema) (any, error) {
+	return in, nil
+}
+func (upperUUIDConverter) DecodeTypeConvert(in any, _ avro.Schema) (any, error) {
+	return strings.ToUpper(in.(string)), nil
+}
+
+func TestClient_LogicalTypesAndUnions(t *testing.T) {
+	const id = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	startsAt := time.UnixMilli(1700000000000).UTC()
+	payload, err := avro.Marshal(avro.MustParse((&richRecord{}).Schema()), map[string]any{
+		"price":     big.NewRat(1999, 100),
+		"id":        id,
+		"trace":     id,
+		"startsAt":  startsAt,
+		"threshold": map[string]any{"Limits": map[string]any{"max": 5}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode payload: %v", err)
+	}
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{{
+			Definition:     model.FigDefinition{Key: "rich", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: payload}},
+			DefaultVersion: ptr("v1"),
+		}},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithFrozen(),
+		config.WithType("rich", &richRecord{}),
+		config.WithAvroType("Limits", richLimits{}),
+		config.WithAvroTypeConverters(upperUUIDConverter{}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var got richRecord
+	if err := c.GetFig("rich", &got, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Fatalf("GetFig failed: %v", err)
+	}
+	if got.Price == nil || got.Price.Cmp(big.NewRat(1999, 100)) != 0 {
+		t.Errorf("Price = %v, want 19.99", got.Price)
+	}
+	if got.ID != id {
+		t.Errorf("ID = %q, want %q", got.ID, id)
+	}
+	if got.Trace != strings.ToUpper(id) {
+		t.Errorf("Trace = %v, want the converted UUID", got.Trace)
+	}
+	if !got.StartsAt.Equal(startsAt) {
+		t.Errorf("StartsAt = %v, want %v", got.StartsAt, startsAt)
+	}
+	if limits, ok := got.Threshold.(richLimits); !ok || limits.Max != 5 {
+		t.Errorf("Threshold = %#v, want richLimits{Max: 5}", got.Threshold)
+	}
+}
+
+func TestClient_AutoRegisterPublicKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	key, err := encryption.NewKeyManager(nil, "", encryption.WithRSABits(2048)).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := encryption.SaveKey(keyPath, key); err != nil {
+		t.Fatalf("SaveKey failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	var registered []model.UserPublicKey
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/keys/public" && r.Method == "GET":
+			json.NewEncoder(w).Encode(registered)
+			return
+		case r.URL.Path == "/keys/public" && r.Method == "PUT":
+			var k model.UserPublicKey
+			json.NewDecoder(r.Body).Decode(&k)
+			registered = append(registered, k)
+			return
+		case r.URL.Path != "/data/initial":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(getRespSchema("InitialFetchResponse").String(), &buf)
+		enc.Encode(&model.InitialFetchResponse{Cursor: "1"})
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	// The key is registered by the first client only
+	for range 2 {
+		c, err := client.New(
+			config.WithBaseURL(server.URL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces("default"),
+			config.WithClientSecret("test-secret"),
+			config.WithFrozen(),
+			config.WithEncryptionPrivateKeyPath(keyPath),
+			config.WithAutoRegisterPublicKey("svc@example.com"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		c.Close()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(registered) != 1 || registered[0].Email != "svc@example.com" || registered[0].Algorithm != encryption.AlgorithmRSA {
+		t.Errorf("registered keys = %+v, want one RSA key for svc@example.com", registered)
+	}
+}
+
+func TestClient_RequestSigning(t *testing.T) {
+	key := []byte("edge-key")
+	var unsigned atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(transport.SignatureTimestampHeader), 10, 64)
+		want, _ := transport.HMACSignature(transport.HMACSHA256, key, ts, body)
+		if r.Header.Get(transport.SignatureHeader) != want {
+			unsigned.Add(1)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(getRespSchema("InitialFetchResponse").String(), &buf)
+		enc.Encode(&model.InitialFetchResponse{Cursor: "1"})
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithFrozen(),
+		config.WithRequestSigning(transport.HMACSHA256, key),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	c.Close()
+	if n := unsigned.Load(); n != 0 {
+		t.Errorf("server rejected %d requests with invalid signatures", n)
+	}
+
+	if _, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithClientSecret("test-secret"),
+		config.WithRequestSigning("md5", key),
+	); err == nil {
+		t.Error("New with an unsupported signing algorithm succeeded")
+	}
+}
+
+func TestClient_LocalCache(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "a", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "cache")
+	key := make([]byte, store.CacheKeySize)
+	newClient := func(baseURL string, cacheKey []byte) (*client.Client, error) {
+		return client.New(
+			config.WithBaseURL(baseURL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces("default"),
+			config.WithClientSecret("test-secret"),
+			config.WithLocalCache(cachePath),
+			config.WithCacheKey(cacheKey),
+			config.WithFrozen(),
+		)
+	}
+
+	if _, err := newClient(down.URL, key); err == nil {
+		t.Fatal("New succeeded without the server or a local cache")
+	}
+	c, err := newClient(server.URL, key)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	c.Close()
+
+	// The server is down, so the client starts from the cache
+	c, err = newClient(down.URL, key)
+	if err != nil {
+		t.Fatalf("Failed to create client from the local cache: %v", err)
+	}
+	defer c.Close()
+	var rec MockAvroRecord
+	if err := c.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); err != nil || rec.Value != "foo" {
+		t.Errorf("GetFig = %q, %v; want foo", rec.Value, err)
+	}
+
+	if _, err := newClient(down.URL, bytes.Repeat([]byte{1}, store.CacheKeySize)); err == nil {
+		t.Error("New succeeded with a cache encrypted with another key")
+	}
+	if _, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithClientSecret("test-secret"),
+		config.WithLocalCache(cachePath),
+	); err == nil {
+		t.Error("New succeeded with a local cache but no key")
+	}
+}
+
+// cursorRedis is a RedisClient keeping strings only: shared families are accepted but
+// not kept, which is enough to follow the shared cursor.
+type cursorRedis struct {
+	mu      sync.Mutex
+	strings map[string][]byte
+}
+
+func (r *cursorRedis) Get(_ context.Context, key string) ([]byte, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.strings[key]
+	return v, ok, nil
+}
+
+func (r *cursorRedis) Set(_ context.Context, key string, value []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strings[key] = value
+	return nil
+}
+
+func (r *cursorRedis) HGet(context.Context, string, string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (r *cursorRedis) HGetAll(context.Context, string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// Eval reports every family as set, as the put-if-newer script does for new families.
+func (r *cursorRedis) Eval(_ context.Context, _ string, _ []string, args ...any) (any, error) {
+	var set []any
+	for i := 0; i < len(args); i += 3 {
+		set = append(set, args[i])
+	}
+	return set, nil
+}
+
+func (r *cursorRedis) Publish(context.Context, string, []byte) error { return nil }
+
+func (r *cursorRedis) Subscribe(ctx context.Context, _ string) (<-chan []byte, error) {
+	ch := make(chan []byte)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func TestClient_SharedCursor(t *testing.T) {
+	family := model.FigFamily{
+		Definition:     model.FigDefinition{Key: "a", Namespace: "default", UpdatedAt: time.Now()},
+		Figs:           []model.Fig{{Version: "v2", Payload: []byte("\x06bar")}},
+		DefaultVersion: ptr("v2"),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1"}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family}}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	redis := &cursorRedis{strings: make(map[string][]byte)}
+	shared, err := store.NewRedisStore(redis, "figchain")
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithStore(shared),
+		config.WithLongPolling(false),
+		config.WithPollingInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	cursor := func() string {
+		v, _, _ := redis.Get(context.Background(), "figchain:cursor:default")
+		return string(v)
+	}
+	if got := cursor(); got != "1" {
+		t.Errorf("shared cursor after bootstrap = %q, want 1", got)
+	}
+	// A poll advances the shared cursor, so that processes bootstrapping from Redis
+	// don't fetch the update again
+	if _, err := c.Refresh(context.Background(), "default"); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got := cursor(); got != "2" {
+		t.Errorf("shared cursor after a poll = %q, want 2", got)
+	}
+}
+
+type fakeVaultFetcher struct {
+	backup      []byte
+	fingerprint string
+}
+
+func (f *fakeVaultFetcher) FetchBackup(_ context.Context, keyFingerprint string) (io.ReadCloser, error) {
+	f.fingerprint = keyFingerprint
+	return io.NopCloser(bytes.NewReader(f.backup)), nil
+}
+
+func TestClient_VaultFetcher(t *testing.T) {
+	identity, _ := age.GenerateX25519Identity()
+	keyPath := filepath.Join(t.TempDir(), "vault.key")
+	os.WriteFile(keyPath, []byte(identity.String()+"\n"), 0600)
+
+	plaintext, _ := json.Marshal(&vault.VaultPayload{
+		SyncToken: "sync-1",
+		Items: []model.FigFamily{{
+			Definition:     model.FigDefinition{Key: "a", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		}},
+	})
+	var encrypted bytes.Buffer
+	w, _ := age.Encrypt(&encrypted, identity.Recipient())
+	w.Write(plaintext)
+	w.Close()
+	backup, _ := json.Marshal(&vault.VaultBackup{
+		Version:       vault.BackupVersionAge,
+		EncryptedData: base64.StdEncoding.EncodeToString(encrypted.Bytes()),
+	})
+	fetcher := &fakeVaultFetcher{backup: backup}
+
+	c, err := client.New(
+		config.WithBaseURL("http://127.0.0.1:1"),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithVaultFetcher(fetcher),
+		config.WithVaultPrivateKeyPath(keyPath),
+		config.WithBootstrapStrategy(config.BootstrapStrategyVault),
+		config.WithFrozen(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if fetcher.fingerprint == "" {
+		t.Error("backup fetched without a key fingerprint")
+	}
+	var rec MockAvroRecord
+	if err := c.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); err != nil || rec.Value != "foo" {
+		t.Errorf("GetFig = %q, %v; want foo", rec.Value, err)
+	}
+}
+
+func TestClient_Handoff(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "a", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	newClient := func(baseURL string, state []byte, namespaces ...string) (*client.Client, error) {
+		return client.New(
+			config.WithBaseURL(baseURL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces(namespaces...),
+			config.WithClientSecret("test-secret"),
+			config.WithHandoffState(state),
+			config.WithFrozen(),
+		)
+	}
+
+	old, err := newClient(server.URL, nil, "default")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer old.Close()
+	state, err := old.Handoff()
+	if err != nil {
+		t.Fatalf("Handoff failed: %v", err)
+	}
+
+	// The replacement doesn't need the server for namespaces it was handed
+	c, err := newClient(down.URL, state, "default")
+	if err != nil {
+		t.Fatalf("Failed to create client from handoff state: %v", err)
+	}
+	defer c.Close()
+	var rec MockAvroRecord
+	if err := c.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); err != nil || rec.Value != "foo" {
+		t.Errorf("GetFig = %q, %v; want foo", rec.Value, err)
+	}
+
+	if _, err := newClient(down.URL, state, "default", "extra"); err == nil {
+		t.Error("New succeeded without bootstrapping a namespace missing from the handoff state")
+	}
+	if _, err := newClient(server.URL, []byte("garbage"), "default"); err == nil {
+		t.Error("New succeeded with invalid handoff state")
+	}
+}
+
+func TestClient_Shutdown(t *testing.T) {
+	// newClient returns a client whose update polls block until release is closed or the
+	// request is cancelled, and a channel receiving each poll once it is in flight.
+	newClient := func(t *testing.T, release chan struct{}) (*client.Client, chan struct{}) {
+		polling := make(chan struct{}, 10)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var schemaStr string
+			var resp any
+			switch r.URL.Path {
+			case "/data/initial":
+				schemaStr = getRespSchema("InitialFetchResponse").String()
+				resp = &model.InitialFetchResponse{Cursor: "1"}
+			case "/data/updates":
+				// The server only notices a cancelled request once the body is read
+				io.Copy(io.Discard, r.Body)
+				polling <- struct{}{}
+				select {
+				case <-release:
+				case <-r.Context().Done():
+					return
+				}
+				schemaStr = getRespSchema("UpdateFetchResponse").String()
+				resp = &model.UpdateFetchResponse{Cursor: "1"}
+			}
+			var buf bytes.Buffer
+			enc, _ := ocf.NewEncoder(schemaStr, &buf)
+			enc.Encode(resp)
+			enc.Flush()
+			w.Write(buf.Bytes())
+		}))
+		t.Cleanup(server.Close)
+		c, err := client.New(
+			config.WithBaseURL(server.URL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces("default"),
+			config.WithClientSecret("test-secret"),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		<-polling
+		return c, polling
+	}
+
+	t.Run("close cancels in-flight polls", func(t *testing.T) {
+		c, _ := newClient(t, make(chan struct{}))
+		start := time.Now()
+		if err := c.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("Close took %v waiting for the in-flight poll", d)
+		}
+	})
+
+	t.Run("shutdown drains in-flight polls", func(t *testing.T) {
+		release := make(chan struct{})
+		c, _ := newClient(t, release)
+		time.AfterFunc(50*time.Millisecond, func() { close(release) })
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown failed: %v", err)
+		}
+		if c.Health().ConsecutiveFailures != 0 {
+			t.Error("the drained poll was recorded as failed")
+		}
+	})
+
+	t.Run("shutdown cancels polls at the deadline", func(t *testing.T) {
+		c, _ := newClient(t, make(chan struct{}))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := c.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Shutdown error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestClient_PauseKeepsCachedCursor(t *testing.T) {
+	family := func(key, version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "default"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	var ready atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("a", "v1"), family("ks", "v1")}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			if ready.Load() {
+				resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("a", "v2"), family("ks", "v2")}}
+			} else {
+				time.Sleep(10 * time.Millisecond)
+				resp = &model.UpdateFetchResponse{Cursor: "1"}
+			}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "cache")
+	key := make([]byte, store.CacheKeySize)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithLocalCache(cachePath),
+		config.WithCacheKey(key),
+		config.WithKillSwitchKeys("ks"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	savedCursor := func() string {
+		t.Helper()
+		fs, _ := store.NewFileStore(cachePath, key)
+		if _, err := fs.Load(); err != nil {
+			t.Fatalf("Failed to load the local cache: %v", err)
+		}
+		cursor, _ := fs.Cursor("default")
+		return cursor
+	}
+
+	// The kill switch applies while paused, saving the cache, but the queued update of a
+	// doesn't, so the saved cursor stays before it
+	c.PauseUpdates()
+	ready.Store(true)
+	deadline := time.Now().Add(2 * time.Second)
+	for c.Health().Cursors["default"] != "2" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := c.Health().Cursors["default"]; got != "2" {
+		t.Fatalf("cursor = %q, want 2", got)
+	}
+	if got := savedCursor(); got != "1" {
+		t.Errorf("saved cursor while paused = %q, want 1", got)
+	}
+
+	c.ResumeUpdates()
+	if got := savedCursor(); got != "2" {
+		t.Errorf("saved cursor after resume = %q, want 2", got)
+	}
+}
+
+func TestClient_PauseUpdates(t *testing.T) {
+	family := func(version, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "a", Namespace: "default", UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Figs:           []model.Fig{{Version: version, Payload: []byte(payload)}},
+			DefaultVersion: ptr(version),
+		}
+	}
+
+	// The update is served once the client is paused; polls after it count as caught up
+	var ready, served atomic.Bool
+	var caughtUp atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1", "\x06foo")}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			switch {
+			case !ready.Load():
+				resp = &model.UpdateFetchResponse{Cursor: "1"}
+			case served.CompareAndSwap(false, true):
+				resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2", "\x06bar")}}
+			default:
+				caughtUp.Add(1)
+				resp = &model.UpdateFetchResponse{Cursor: "2"}
+			}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var events []event.Type
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithEventHandler(func(e event.Event) {
+			if e.Type != event.UpdatesPaused && e.Type != event.UpdatesResumed {
+				return
+			}
+			mu.Lock()
+			events = append(events, e.Type)
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	get := func() string {
+		var rec MockAvroRecord
+		if err := c.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); err != nil {
+			t.Fatalf("GetFig failed: %v", err)
+		}
+		return rec.Value
+	}
+
+	c.PauseUpdates()
+	c.PauseUpdates()
+	if !c.UpdatesPaused() {
+		t.Fatal("UpdatesPaused() = false after PauseUpdates")
+	}
+	ready.Store(true)
+	deadline := time.Now().Add(2 * time.Second)
+	for caughtUp.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if caughtUp.Load() == 0 {
+		t.Fatal("the update was never polled")
+	}
+	if got := get(); got != "foo" {
+		t.Errorf("GetFig() while paused = %q, want the paused value foo", got)
+	}
+
+	applied := c.ResumeUpdates()
+	if len(applied) != 1 || *applied[0].DefaultVersion != "v2" {
+		t.Errorf("ResumeUpdates() = %v, want the queued v2 update", applied)
+	}
+	if got := get(); got != "bar" {
+		t.Errorf("GetFig() after resume = %q, want bar", got)
+	}
+	if applied := c.ResumeUpdates(); applied != nil {
+		t.Errorf("ResumeUpdates() when not paused = %v, want nil", applied)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []event.Type{event.UpdatesPaused, event.UpdatesResumed}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestClient_StagedRollout(t *testing.T) {
+	family := func(key, version, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "default", UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Figs:           []model.Fig{{Version: version, Payload: []byte(payload)}},
+			DefaultVersion: ptr(version),
+		}
+	}
+
+	var served atomic.Bool
+	var caughtUp atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{
+				family("a", "v1", "\x06foo"),
+				family("kill", "v1", "\x06foo"),
+			}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			if served.CompareAndSwap(false, true) {
+				resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{
+					family("a", "v2", "\x06bar"),
+					family("kill", "v2", "\x06bar"),
+				}}
+			} else {
+				caughtUp.Add(1)
+				resp = &model.UpdateFetchResponse{Cursor: "2"}
+			}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	// instance-b is delayed by about 960ms within the 2s window
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithStagedRollout(2*time.Second),
+		config.WithInstanceID("instance-b"),
+		config.WithKillSwitchKeys("kill"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	get := func(key string) string {
+		var rec MockAvroRecord
+		if err := c.GetFig(key, &rec, evaluation.NewEvaluationContext(nil)); err != nil {
+			t.Fatalf("GetFig(%s) failed: %v", key, err)
+		}
+		return rec.Value
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for caughtUp.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := get("kill"); got != "bar" {
+		t.Errorf("kill switch = %q, want bar applied immediately", got)
+	}
+	if got := get("a"); got != "foo" {
+		t.Errorf("a = %q, want foo until the rollout delay has passed", got)
+	}
+
+	deadline = time.Now().Add(3 * time.Second)
+	for get("a") != "bar" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := get("a"); got != "bar" {
+		t.Errorf("a = %q, want bar after the rollout delay", got)
+	}
+}
+
+func TestClient_StagedRolloutHandoff(t *testing.T) {
+	family := func(key, version, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "default"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte(payload)}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{
+			family("a", "v1", "\x06foo"),
+			family("kill", "v1", "\x06foo"),
+		}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{
+			family("a", "v2", "\x06bar"),
+			family("kill", "v2", "\x06bar"),
+		}},
+	)
+
+	cachePath := filepath.Join(t.TempDir(), "cache")
+	key := make([]byte, store.CacheKeySize)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithStagedRollout(time.Hour),
+		config.WithInstanceID("instance-b"),
+		config.WithKillSwitchKeys("kill"),
+		config.WithLocalCache(cachePath),
+		config.WithCacheKey(key),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for c.Health().Cursors["default"] != "2" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := c.Health().Cursors["default"]; got != "2" {
+		t.Fatalf("cursor = %q, want 2", got)
+	}
+
+	// The kill switch saved the cache, but before the staged update of a
+	fs, _ := store.NewFileStore(cachePath, key)
+	if _, err := fs.Load(); err != nil {
+		t.Fatalf("Failed to load the local cache: %v", err)
+	}
+	if cursor, _ := fs.Cursor("default"); cursor != "1" {
+		t.Errorf("saved cursor = %q, want 1 while a is staged", cursor)
+	}
+
+	// The staged update is handed off with the cursor past it
+	state, err := c.Handoff()
+	if err != nil {
+		t.Fatalf("Handoff failed: %v", err)
+	}
+	replacement, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithHandoffState(state),
+		config.WithFrozen(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client from handoff state: %v", err)
+	}
+	defer replacement.Close()
+	var rec MockAvroRecord
+	if err := replacement.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); err != nil || rec.Value != "bar" {
+		t.Errorf("GetFig(a) after handoff = %q, %v; want the staged value bar", rec.Value, err)
+	}
+}
+
+func TestClient_PriorityPolling(t *testing.T) {
+	family := func(version, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "kill-checkout", Namespace: "default", UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Figs:           []model.Fig{{Version: version, Payload: []byte(payload)}},
+			DefaultVersion: ptr(version),
+		}
+	}
+
+	var flipped atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1", "\x06foo")}}
+		case "/data/family":
+			schemaStr = getRespSchema("FigFamily").String()
+			ff := family("v1", "\x06foo")
+			if flipped.Load() {
+				ff = family("v2", "\x06bar")
+			}
+			resp = &ff
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	// Regular updates are only polled once a year, and paused
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollSchedule("0 0 1 1 *"),
+		config.WithKillSwitchPrefixes("kill-"),
+		config.WithPriorityPollingInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+	c.PauseUpdates()
+
+	get := func() string {
+		var rec MockAvroRecord
+		if err := c.GetFig("kill-checkout", &rec, evaluation.NewEvaluationContext(nil)); err != nil {
+			t.Fatalf("GetFig failed: %v", err)
+		}
+		return rec.Value
+	}
+
+	flipped.Store(true)
+	deadline := time.Now().Add(2 * time.Second)
+	for get() != "bar" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := get(); got != "bar" {
+		t.Errorf("kill switch = %q, want bar from the priority channel", got)
+	}
+}
+
+func TestClient_BeforeApply(t *testing.T) {
+	family := func(key, version, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "default", UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Figs:           []model.Fig{{Version: version, Payload: []byte(payload)}},
+			DefaultVersion: ptr(version),
+		}
+	}
+
+	var served atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{
+				family("a", "v1", "\x06foo"),
+				family("b", "v1", "\x06foo"),
+			}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "2"}
+			if served.CompareAndSwap(false, true) {
+				resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{
+					family("a", "v2", "\x06bar"),
+					family("b", "v2", "\x06bar"),
+				}}
+			}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	// Updates of a are rejected until approved
+	var approved atomic.Bool
+	var rejected atomic.Int32
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithBeforeApply(func(old, new model.FigFamily) config.ApplyDecision {
+			if new.Definition.Key != "a" || approved.Load() {
+				return config.ApplyAccept
+			}
+			if *old.DefaultVersion != "v1" {
+				t.Errorf("old version = %s, want v1", *old.DefaultVersion)
+			}
+			return config.ApplyReject
+		}),
+		config.WithEventHandler(func(e event.Event) {
+			if e.Type == event.UpdateRejected && e.Key == "a" {
+				rejected.Add(1)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	get := func(key string) string {
+		var rec MockAvroRecord
+		if err := c.GetFig(key, &rec, evaluation.NewEvaluationContext(nil)); err != nil {
+			t.Fatalf("GetFig(%s) failed: %v", key, err)
+		}
+		return rec.Value
+	}
+	waitFor := func(cond func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// The rejection is retried on later polls
+	waitFor(func() bool { return rejected.Load() >= 2 })
+	if rejected.Load() < 2 {
+		t.Fatalf("rejected %d times, want the rejection reported and retried", rejected.Load())
+	}
+	if got := get("a"); got != "foo" {
+		t.Errorf("a = %q, want the rejected update not applied", got)
+	}
+	if got := get("b"); got != "bar" {
+		t.Errorf("b = %q, want bar", got)
+	}
+
+	approved.Store(true)
+	waitFor(func() bool { return get("a") == "bar" })
+	if got := get("a"); got != "bar" {
+		t.Errorf("a = %q, want bar once approved", got)
+	}
+}
+
+func TestClient_WatchChanges(t *testing.T) {
+	v1 := model.FigFamily{
+		Definition:     model.FigDefinition{Key: "a", Namespace: "default", UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+		DefaultVersion: ptr("v1"),
+	}
+	v2 := model.FigFamily{
+		Definition: model.FigDefinition{Key: "a", Namespace: "default", UpdatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		Figs: []model.Fig{
+			{Version: "v1", Payload: []byte("\x06baz")},
+			{Version: "v2", Payload: []byte("\x06bar")},
+		},
+		DefaultVersion: ptr("v2"),
+	}
+
+	var served atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{v1}}
+		case "/data/updates":
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "2"}
+			if served.Load() {
+				resp = &model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{v2}}
+			}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	events := make(chan event.Event, 10)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithEventHandler(func(e event.Event) {
+			if e.Type == event.UpdateApplied {
+				events <- e
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := c.WatchChanges(ctx, "a", client.WithInitialValue())
+	initial := <-ch
+	if initial.Change != nil || *initial.Family.DefaultVersion != "v1" {
+		t.Errorf("initial value = %+v, want v1 without a change", initial)
+	}
+
+	served.Store(true)
+	var update client.FamilyUpdate
+	select {
+	case update = <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the update")
+	}
+	want := &client.FamilyChange{
+		Namespace:         "default",
+		Key:               "a",
+		Kind:              client.ChangeModified,
+		OldDefaultVersion: "v1",
+		NewDefaultVersion: "v2",
+		AddedVersions:     []string{"v2"},
+		ChangedVersions:   []string{"v1"},
+	}
+	if !reflect.DeepEqual(update.Change, want) {
+		t.Errorf("change = %+v, want %+v", update.Change, want)
+	}
+
+	select {
+	case e := <-events:
+		if !reflect.DeepEqual(e.Change, want) {
+			t.Errorf("UpdateApplied change = %+v, want %+v", e.Change, want)
+		}
+	case <-time.After(time.Second):
+		t.Error("no UpdateApplied event")
+	}
+}
+
+func TestClient_Expiry(t *testing.T) {
+	expiresAt := time.Now().Add(300 * time.Millisecond)
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "a", Namespace: "default", ExpiresAt: &expiresAt},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+			{
+				Definition:     model.FigDefinition{Key: "b", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithExpiryGCInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var rec MockAvroRecord
+	if err := c.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Fatalf("GetFig(a) before expiry error = %v", err)
+	}
+
+	time.Sleep(time.Until(expiresAt))
+	if err := c.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); !errors.Is(err, client.ErrExpired) {
+		t.Errorf("GetFig(a) after expiry error = %v, want ErrExpired", err)
+	}
+	if err := c.GetFig("b", &rec, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Errorf("GetFig(b) error = %v", err)
+	}
+}
+
+func TestClient_ExpiryGC(t *testing.T) {
+	expiresAt := time.Now().Add(100 * time.Millisecond)
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "a", Namespace: "default", ExpiresAt: &expiresAt},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+			{
+				Definition:     model.FigDefinition{Key: "b", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithExpiryGCInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(c.Families()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Families() = %d families, want the expired family removed", len(c.Families()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := c.Families()[0].Definition.Key; got != "b" {
+		t.Errorf("Families() kept %q, want b", got)
+	}
+}
+
+func TestClient_EvaluateAll(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{{
+			Definition: model.FigDefinition{Key: "plugins", Namespace: "default"},
+			Figs: []model.Fig{
+				{Version: "none", Payload: []byte("\x00")},
+				{Version: "audit", Payload: []byte("\x0aaudit")},
+				{Version: "sso", Payload: []byte("\x06sso")},
+			},
+			DefaultVersion: ptr("none"),
+			Rules: []model.Rule{
+				{TargetVersion: "audit", Conditions: []model.Condition{{Variable: "plan", Operator: "EQUALS", Values: []string{"enterprise"}}}},
+				{TargetVersion: "sso", Conditions: []model.Condition{{Variable: "plan", Operator: "IN", Values: []string{"business", "enterprise"}}}},
+			},
+		}},
+	})
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	matches, err := c.EvaluateAll("plugins", evaluation.NewEvaluationContext(map[string]string{"plan": "enterprise"}))
+	if err != nil {
+		t.Fatalf("EvaluateAll() error = %v", err)
+	}
+	var plugins []string
+	for _, m := range matches {
+		var rec MockAvroRecord
+		if err := c.GetFigVersion("plugins", m.Fig.Version, &rec); err != nil {
+			t.Fatalf("GetFigVersion(%s) error = %v", m.Fig.Version, err)
+		}
+		plugins = append(plugins, rec.Value)
+	}
+	if !reflect.DeepEqual(plugins, []string{"audit", "sso"}) {
+		t.Errorf("EvaluateAll() plugins = %v, want [audit sso]", plugins)
+	}
+
+	if _, err := c.EvaluateAll("missing", evaluation.NewEvaluationContext(nil)); err == nil {
+		t.Error("EvaluateAll(missing) error = nil, want error")
+	}
+}
+
+func TestClient_Iterators(t *testing.T) {
+	initial := &model.InitialFetchResponse{Cursor: "1"}
+	for _, key := range []string{"a", "b", "c"} {
+		initial.FigFamilies = append(initial.FigFamilies, model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		})
+	}
+	server := newTestServer(t, initial)
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var keys []string
+	for ns, key := range c.Keys() {
+		if ns != "default" {
+			t.Errorf("Keys() namespace = %q, want default", ns)
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "b", "c"}) {
+		t.Errorf("Keys() = %v, want [a b c]", keys)
+	}
+
+	visited := 0
+	for range c.All() {
+		visited++
+		break
+	}
+	if visited != 1 {
+		t.Errorf("All() visited %d families after break, want 1", visited)
+	}
+	if n := len(c.Families("missing")); n != 0 {
+		t.Errorf("Families(missing) = %d families, want 0", n)
+	}
+}
+
+func TestClient_GenericRecord(t *testing.T) {
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{{
+			Definition:     model.FigDefinition{Key: "a", Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+			DefaultVersion: ptr("v1"),
+		}},
+	})
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	record := client.NewGenericRecord((&MockAvroRecord{}).Schema())
+	if err := c.GetFig("a", record, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Fatalf("GetFig() error = %v", err)
+	}
+	if want := map[string]any{"value": "foo"}; !reflect.DeepEqual(record.Value, want) {
+		t.Errorf("GetFig() value = %#v, want %#v", record.Value, want)
+	}
+}
+
+func TestClient_NamespaceProfiles(t *testing.T) {
+	var mu sync.Mutex
+	polls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schemaStr string
+		var resp any
+		switch r.URL.Path {
+		case "/data/initial":
+			schemaStr = getRespSchema("InitialFetchResponse").String()
+			resp = &model.InitialFetchResponse{Cursor: "1"}
+		case "/data/updates":
+			dec, err := ocf.NewDecoder(r.Body)
+			if err != nil {
+				t.Errorf("Failed to decode update request: %v", err)
+				return
+			}
+			var req model.UpdateFetchRequest
+			if dec.HasNext() {
+				dec.Decode(&req)
+			}
+			mu.Lock()
+			polls[req.Namespace]++
+			mu.Unlock()
+			// Stand in for a long poll
+			time.Sleep(10 * time.Millisecond)
+			schemaStr = getRespSchema("UpdateFetchResponse").String()
+			resp = &model.UpdateFetchResponse{Cursor: "1"}
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(schemaStr, &buf)
+		enc.Encode(resp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("fast"),
+		config.WithNamespaceProfiles(config.NamespaceProfile{Name: "slow", PollingInterval: time.Hour}),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	c.Close()
+
+	mu.Lock()
+	if polls["slow"] != 1 {
+		t.Errorf("slow namespace polled %d times, want 1", polls["slow"])
+	}
+	if polls["fast"] < 3 {
+		t.Errorf("fast namespace polled %d times, want it polled continuously", polls["fast"])
+	}
+	clear(polls)
+	mu.Unlock()
+
+	// With every namespace waiting out its interval, Close doesn't wait for it
+	c, err = client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaceProfiles(config.NamespaceProfile{Name: "slow", PollingInterval: time.Hour}),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	c.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close() took %s", elapsed)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if polls["slow"] != 1 {
+		t.Errorf("slow namespace polled %d times while waiting out its interval, want 1", polls["slow"])
+	}
+}
+
+func TestValue(t *testing.T) {
+	family := func(version, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "value-key", Namespace: "default"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte(payload)}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1", "\x06foo")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2", "\x06bar")}},
+	)
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := client.NewValue[MockAvroRecord](c, "missing-key"); err == nil {
+		t.Error("Expected NewValue to fail for a missing key")
+	}
+
+	v, err := client.NewValue[MockAvroRecord](c, "value-key")
+	if err != nil {
+		t.Fatalf("NewValue() error = %v", err)
+	}
+	if got := v.Get().Value; got != "foo" && got != "bar" {
+		t.Errorf("Expected initial value, got %q", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for v.Get().Value != "bar" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := v.Get().Value; got != "bar" {
+		t.Errorf("Expected value to refresh to 'bar', got %q", got)
+	}
+}
+
+func TestClient_Flags(t *testing.T) {
+	family := func(key, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "default"},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte(payload)}},
+			DefaultVersion: ptr("v1"),
+		}
+	}
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			family("new-checkout", "\x01"),
+			family("max-items", "\x54"),   // 42
+			family("timeout", "\xe8\x07"), // 500ms
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if !c.NewBoolFlag("new-checkout", false).Enabled() {
+		t.Error("Expected new-checkout to be enabled")
+	}
+	if got := c.NewIntFlag("max-items", 10).Value(); got != 42 {
+		t.Errorf("Expected max-items 42, got %d", got)
+	}
+	if got := c.NewDurationFlag("timeout", time.Second).Value(); got != 500*time.Millisecond {
+		t.Errorf("Expected timeout 500ms, got %s", got)
+	}
+	if !c.NewBoolFlag("missing-flag", true).Enabled() {
+		t.Error("Expected a missing flag to keep its default")
+	}
+}
+
+func TestClient_Interpolation(t *testing.T) {
+	payload := "api.${attr:region}"
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "endpoint", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: append([]byte{byte(2 * len(payload))}, payload...)}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithGlobalAttributes(map[string]string{"region": "eu"}),
+		config.WithInterpolator(evaluation.NewInterpolator(nil)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	var record MockAvroRecord
+	if err := c.GetFig("endpoint", &record, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Fatalf("GetFig() error = %v", err)
+	}
+	if record.Value != "api.eu" {
+		t.Errorf("Expected 'api.eu', got %q", record.Value)
+	}
+	// Request attributes take precedence over global ones
+	if err := c.GetFig("endpoint", &record, evaluation.NewEvaluationContext(map[string]string{"region": "us"})); err != nil {
+		t.Fatalf("GetFig() error = %v", err)
+	}
+	if record.Value != "api.us" {
+		t.Errorf("Expected 'api.us', got %q", record.Value)
+	}
+}
+
+type layeredSettings struct {
+	Endpoint string           `avro:"endpoint"`
+	Limits   map[string]int64 `avro:"limits"`
+	Tags     []string         `avro:"tags"`
+	Timeout  *int64           `avro:"timeout"`
+}
+
+func (s *layeredSettings) Schema() string {
+	return `{"type":"record","name":"LayeredSettings","fields":[
+		{"name":"endpoint","type":"string"},
+		{"name":"limits","type":{"type":"map","values":"long"}},
+		{"name":"tags","type":{"type":"array","items":"string"}},
+		{"name":"timeout","type":["null","long"]}
+	]}`
+}
+
+func TestClient_GetMergedFig(t *testing.T) {
+	timeout := int64(500)
+	family := func(namespace, key string, s layeredSettings) model.FigFamily {
+		payload, err := avro.Marshal(avro.MustParse(s.Schema()), s)
+		if err != nil {
+			t.Fatalf("Failed to encode %s: %v", key, err)
+		}
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: namespace},
+			Figs:           []model.Fig{{Version: "v1", Payload: payload}},
+			DefaultVersion: ptr("v1"),
+		}
+	}
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			family("base", "settings", layeredSettings{
+				Endpoint: "https://api.example.com",
+				Limits:   map[string]int64{"items": 10, "orders": 5},
+				Tags:     []string{"base"},
+				Timeout:  &timeout,
+			}),
+			family("prod", "settings", layeredSettings{
+				Limits: map[string]int64{"items": 100},
+				Tags:   []string{"prod"},
+			}),
+		},
+	})
+
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("base", "prod"),
+		config.WithClientSecret("test-secret"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	layers := []client.Layer{
+		{Namespace: "base", Key: "settings"},
+		{Namespace: "prod", Key: "settings"},
+		{Namespace: "prod", Key: "missing-overlay"},
+	}
+	ctx := evaluation.NewEvaluationContext(nil)
+
+	var deep layeredSettings
+	if err := c.GetMergedFig(layers, &deep, ctx, client.MergeDeep); err != nil {
+		t.Fatalf("GetMergedFig() error = %v", err)
+	}
+	if deep.Endpoint != "https://api.example.com" || deep.Limits["items"] != 100 || deep.Limits["orders"] != 5 ||
+		!slices.Equal(deep.Tags, []string{"prod"}) || deep.Timeout == nil || *deep.Timeout != 500 {
+		t.Errorf("Unexpected deep merge: %+v", deep)
+	}
+
+	var appended layeredSettings
+	if err := c.GetMergedFig(layers, &appended, ctx, client.MergeAppendLists); err != nil {
+		t.Fatalf("GetMergedFig() error = %v", err)
+	}
+	if !slices.Equal(appended.Tags, []string{"base", "prod"}) {
+		t.Errorf("Expected appended tags, got %v", appended.Tags)
+	}
+
+	var override layeredSettings
+	if err := c.GetMergedFig(layers, &override, ctx, client.MergeOverride); err != nil {
+		t.Fatalf("GetMergedFig() error = %v", err)
+	}
+	if override.Endpoint != "" || override.Limits["orders"] != 0 {
+		t.Errorf("Expected the prod layer only, got %+v", override)
+	}
+
+	generic := client.NewGenericRecord((&layeredSettings{}).Schema())
+	if err := c.GetMergedFig(layers, generic, ctx, client.MergeDeep); err != nil {
+		t.Fatalf("GetMergedFig() error = %v", err)
+	}
+	limits := generic.Value.(map[string]any)["limits"].(map[string]any)
+	if limits["items"] != int64(100) || limits["orders"] != int64(5) {
+		t.Errorf("Unexpected generic merge: %v", generic.Value)
+	}
+
+	if err := c.GetMergedFig([]client.Layer{{Key: "missing-overlay"}}, &deep, ctx, client.MergeDeep); err == nil {
+		t.Error("Expected an error when no layer exists")
+	}
+}
+
+func TestClient_KeyRouting(t *testing.T) {
+	family := func(namespace, key, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: namespace},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte(payload)}},
+			DefaultVersion: ptr("v1"),
+		}
+	}
+	server := newTestServer(t, &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			family("web", "shared", "\x06foo"),
+			family("payments", "shared", "\x06bar"),
+			family("payments", "moved", "\x06baz"),
+		},
+	})
+
+	newClient := func(opts ...config.Option) *client.Client {
+		c, err := client.New(append([]config.Option{
+			config.WithBaseURL(server.URL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces("web", "payments"),
+			config.WithClientSecret("test-secret"),
+		}, opts...)...)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	ctx := evaluation.NewEvaluationContext(nil)
+
+	var record MockAvroRecord
+	if err := newClient().GetFig("moved", &record, ctx); err == nil {
+		t.Error("Expected keys outside the first namespace not to be found without routing")
+	}
+
+	c := newClient(config.WithKeyRouting())
+	for key, want := range map[string]string{
+		"shared":          "foo",
+		"web/shared":      "foo",
+		"payments/shared": "bar",
+		"moved":           "baz",
+	} {
+		if err := c.GetFig(key, &record, ctx); err != nil {
+			t.Errorf("GetFig(%q) error = %v", key, err)
+		} else if record.Value != want {
+			t.Errorf("GetFig(%q) = %q, want %q", key, record.Value, want)
+		}
+	}
+
+	select {
+	case ff := <-c.Watch(context.Background(), "payments/shared", client.WithInitialValue()):
+		if ff.Definition.Namespace != "payments" {
+			t.Errorf("Expected the payments family, got %s", ff.Definition.Namespace)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for initial value")
+	}
+
+	var merged MockAvroRecord
+	if err := c.GetMergedFig([]client.Layer{{Key: "payments/shared"}}, &merged, ctx, client.MergeOverride); err != nil {
+		t.Errorf("GetMergedFig() error = %v", err)
+	} else if merged.Value != "bar" {
+		t.Errorf("GetMergedFig() = %q, want bar", merged.Value)
+	}
+
+	admin := httptest.NewServer(client.NewAdminHandler(c, client.WithAdminToken("admin-token")))
+	defer admin.Close()
+	req, _ := http.NewRequest("POST", admin.URL+"/evaluate", strings.NewReader(`{"key":"payments/shared"}`))
+	req.Header.Set("Authorization", "Bearer admin-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /evaluate failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte(`"namespace":"payments","key":"shared"`)) {
+		t.Errorf("POST /evaluate = %d %s, want the payments family", resp.StatusCode, body)
+	}
+}
+
+func TestClient_BareKeyNotifications(t *testing.T) {
+	family := func(namespace, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "shared", Namespace: namespace},
+			Figs:           []model.Fig{{Version: "v1", Payload: []byte(payload)}},
+			DefaultVersion: ptr("v1"),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("web", "\x06foo")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("payments", "\x06bar")}},
+		&model.UpdateFetchResponse{Cursor: "3", FigFamilies: []model.FigFamily{family("web", "\x06baz")}},
+	)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("web", "payments"),
+		config.WithClientSecret("test-secret"),
+		config.WithLongPolling(false),
+		config.WithPollingInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	// GetFig reads the bare key from web, so watchers of it aren't told of payments
+	updates := c.Watch(context.Background(), "shared")
+	for _, ns := range []string{"payments", "web"} {
+		if _, err := c.Refresh(context.Background(), ns); err != nil {
+			t.Fatalf("Refresh(%s) error = %v", ns, err)
+		}
+	}
+	select {
+	case ff := <-updates:
+		if ff.Definition.Namespace != "web" {
+			t.Errorf("Expected the web update, got one of %s", ff.Definition.Namespace)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the web update")
+	}
+}
+
+func TestRegisterValidator(t *testing.T) {
+	family := func(version, payload string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "validated-key", Namespace: "default"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte(payload)}},
+			DefaultVersion: ptr(version),
+		}
+	}
+	server := newUpdatingTestServer(t,
+		&model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family("v1", "\x06foo")}},
+		&model.UpdateFetchResponse{Cursor: "2", FigFamilies: []model.FigFamily{family("v2", "\x00")}},
+		&model.UpdateFetchResponse{Cursor: "3", FigFamilies: []model.FigFamily{family("v3", "\x06bad")}},
+	)
+
+	quarantined := make(chan event.Event, 2)
+	c, err := client.New(
+		config.WithBaseURL(server.URL),
+		config.WithEnvironmentID("env-1"),
+		config.WithNamespaces("default"),
+		config.WithClientSecret("test-secret"),
+		config.WithPollingInterval(20*time.Millisecond),
+		config.WithEventHandler(func(e event.Event) {
+			if e.Type == event.FamilyQuarantined {
+				quarantined <- e
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	err = client.RegisterValidator(c, "validated-key", func(r MockAvroRecord) error {
+		if r.Value == "bad" {
+			panic("bad value")
+		}
+		if r.Value == "" {
+			return errors.New("value is required")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterValidator() error = %v", err)
+	}
+
+	for _, want := range []string{"value is required", "validator panicked"} {
+		select {
+		case e := <-quarantined:
+			if e.Key != "validated-key" || !strings.Contains(e.Err.Error(), want) {
+				t.Errorf("Expected a quarantine event for %q, got %+v", want, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for the update to be quarantined")
+		}
+	}
+	var record MockAvroRecord
+	if err := c.GetFig("validated-key", &record, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Fatalf("GetFig() error = %v", err)
+	}
+	if record.Value != "foo" {
+		t.Errorf("Expected the last good value 'foo', got %q", record.Value)
+	}
+}
+
+func TestClient_RequireSync(t *testing.T) {
+	initial := &model.InitialFetchResponse{
+		Cursor: "1",
+		FigFamilies: []model.FigFamily{
+			{
+				Definition:     model.FigDefinition{Key: "a", Namespace: "default"},
+				Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+				DefaultVersion: ptr("v1"),
+			},
+		},
+	}
+	server := newTestServer(t, initial)
+	// The server can't serve the initial fetch, and answers polls once released
+	release := make(chan struct{})
+	degraded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/data/initial" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer degraded.Close()
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	defer unblock()
+
+	cachePath := filepath.Join(t.TempDir(), "cache")
+	key := make([]byte, store.CacheKeySize)
+	newClient := func(baseURL string, opts ...config.Option) *client.Client {
+		c, err := client.New(append([]config.Option{
+			config.WithBaseURL(baseURL),
+			config.WithEnvironmentID("env-1"),
+			config.WithNamespaces("default"),
+			config.WithClientSecret("test-secret"),
+			config.WithLocalCache(cachePath),
+			config.WithCacheKey(key),
+			config.WithPollingInterval(10 * time.Millisecond),
+		}, opts...)...)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		return c
+	}
+
+	// Bootstrapped from the server, the namespace is synced
+	c := newClient(server.URL, config.WithRequireSync(0))
+	var rec MockAvroRecord
+	if err := c.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); err != nil {
+		t.Errorf("GetFig() error = %v", err)
+	}
+	c.Close()
+
+	// Bootstrapped from the local cache, it isn't until a poll succeeds
+	c = newClient(degraded.URL, config.WithRequireSync(0))
+	defer c.Close()
+	if err := c.GetFig("a", &rec, evaluation.NewEvaluationContext(nil)); !errors.Is(err, client.ErrNotBootstrapped) {
+		t.Errorf("GetFig() error = %v, want ErrNotBootstrapped", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.WaitForSync(ctx); !errors.Is(err, client.ErrNotBootstrapped) {
+		t.Errorf("WaitForSync() error = %v, want ErrNotBootstrapped", err)
+	}
+
+	waiting := newClient(degraded.URL, config.WithRequireSync(5*time.Second))
+	defer waiting.Close()
+	done := make(chan error, 1)
+	go func() {
+		var rec MockAvroRecord
+		done <- waiting.GetFig("a", &rec, evaluation.NewEvaluationContext(nil))
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("GetFig() returned %v before the namespace synced", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	unblock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("GetFig() error = %v after sync", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetFig() still waiting after sync")
+	}
+	if err := c.WaitForSync(context.Background()); err != nil {
+		t.Errorf("WaitForSync() error = %v", err)
+	}
+}
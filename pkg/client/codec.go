@@ -0,0 +1,27 @@
+package client
+
+import (
+	"github.com/hamba/avro/v2"
+
+	"github.com/figchain/go-client/pkg/config"
+)
+
+// newPayloadAPI returns the Avro API fig payloads are decoded with. Unlike the default
+// API, union members of registered types are resolved even when the other members of
+// their union aren't registered, so nested unions decode into typed values.
+func newPayloadAPI(cfg *config.Config) avro.API {
+	api := cfg.AvroAPI
+	if api == nil {
+		api = avro.Config{
+			TagKey:                     "avro",
+			BlockLength:                100,
+			MaxByteSliceSize:           1024 * 1024,
+			PartialUnionTypeResolution: true,
+		}.Freeze()
+	}
+	for name, obj := range cfg.AvroTypes {
+		api.Register(name, obj)
+	}
+	api.RegisterTypeConverters(cfg.AvroTypeConverters...)
+	return api
+}
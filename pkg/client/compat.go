@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/metrics"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// schemaFetchTimeout bounds fetching a fig's schema for a compatibility check.
+const schemaFetchTimeout = 10 * time.Second
+
+// schemaRetryDelay is how long after a failed fetch a schema is fetched again. It doubles
+// with each consecutive failure, up to maxSchemaRetryDelay.
+const (
+	schemaRetryDelay    = 30 * time.Second
+	maxSchemaRetryDelay = 10 * time.Minute
+)
+
+// schemaChecks records the compatibility checks of the schema versions of each key, so
+// that each is fetched and reported once.
+type schemaChecks struct {
+	mu     sync.Mutex
+	checks map[string]*schemaCheck
+}
+
+// schemaCheck is the state of the compatibility check of a schema version.
+type schemaCheck struct {
+	checked  bool
+	running  bool
+	failures int       // consecutive failed fetches
+	retryAt  time.Time // when a failed fetch is retried
+}
+
+// checkCompatibility checks that the schema ff's figs are written with, identified by
+// its SchemaURI and SchemaVersion, can be read into the Go type registered for its key.
+// The schema is fetched in the background, so that updates aren't delayed by the check.
+// Incompatibilities are reported with a SchemaIncompatible event; schemas that can't be
+// fetched are logged and checked again with a later update, backing off while fetches
+// keep failing.
+func (c *Client) checkCompatibility(ff *model.FigFamily) {
+	def := ff.Definition
+	if def.SchemaURI == "" {
+		return
+	}
+	c.mu.RLock()
+	reader := c.schemas[def.Key]
+	c.mu.RUnlock()
+	if reader == nil {
+		return
+	}
+
+	id := def.Namespace + "/" + def.Key + "@" + def.SchemaURI + "#" + def.SchemaVersion
+	now := c.clock.Now()
+	c.compat.mu.Lock()
+	check := c.compat.checks[id]
+	if check == nil {
+		check = &schemaCheck{}
+		c.compat.checks[id] = check
+	}
+	start := !check.checked && !check.running && !now.Before(check.retryAt)
+	check.running = check.running || start
+	c.compat.mu.Unlock()
+	if !start {
+		return
+	}
+
+	select {
+	case <-c.closeCh:
+		return
+	default:
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.runCompatibilityCheck(def, reader, check)
+	}()
+}
+
+// runCompatibilityCheck fetches the schema of def and checks it against reader, recording
+// the outcome of the fetch in check.
+func (c *Client) runCompatibilityCheck(def model.FigDefinition, reader avro.Schema, check *schemaCheck) {
+	ctx, cancel := context.WithTimeout(c.ctx, schemaFetchTimeout)
+	data, err := c.transport.FetchSchema(ctx, def.SchemaURI)
+	cancel()
+
+	c.compat.mu.Lock()
+	check.running = false
+	if err != nil {
+		check.failures++
+		check.retryAt = c.clock.Now().Add(min(schemaRetryDelay<<min(check.failures-1, 8), maxSchemaRetryDelay))
+	} else {
+		check.checked = true
+	}
+	c.compat.mu.Unlock()
+	if err != nil {
+		logging.Printf("Warning: cannot check schema compatibility of %s/%s: %v", def.Namespace, def.Key, err)
+		return
+	}
+
+	writer, err := writerSchema(data, reader)
+	if err == nil {
+		err = avro.NewSchemaCompatibility().Compatible(reader, writer)
+	}
+	if err == nil {
+		return
+	}
+
+	logging.Printf("Error: schema version %s of %s/%s is incompatible with the registered type: %v", def.SchemaVersion, def.Namespace, def.Key, err)
+	c.metrics.IncCounter(metrics.SchemaIncompatible, map[string]string{
+		"namespace": def.Namespace,
+		"key":       def.Key,
+	})
+	c.emit(event.Event{
+		Type:      event.SchemaIncompatible,
+		Namespace: def.Namespace,
+		Key:       def.Key,
+		Message:   fmt.Sprintf("schema version %s can't be read into the registered type", def.SchemaVersion),
+		Err:       err,
+	})
+}
+
+// writerSchema parses a fig's schema for comparison with reader. Go types name their
+// records freely, so the writer's top-level record takes the reader's name; it is parsed
+// on its own so that its other names don't clash with registered types.
+func writerSchema(data string, reader avro.Schema) (avro.Schema, error) {
+	var def map[string]any
+	if err := json.Unmarshal([]byte(data), &def); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if named, ok := reader.(avro.NamedSchema); ok {
+		def["name"] = named.FullName()
+		delete(def, "namespace")
+	}
+	renamed, err := json.Marshal(def)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	schema, err := avro.ParseWithCache(string(renamed), "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return schema, nil
+}
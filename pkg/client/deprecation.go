@@ -0,0 +1,72 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// deprecationWarnings remembers the deprecated keys already warned about, so that a key
+// read in a hot path is reported once rather than on every read.
+type deprecationWarnings struct {
+	warned sync.Map // [2]string -> struct{}
+}
+
+// DeprecatedKey is a deprecated or archived key the client has read.
+type DeprecatedKey struct {
+	model.KeyUsage
+	Deprecation model.Deprecation
+}
+
+// warnDeprecated logs and emits a KeyDeprecated event the first time ff is read while it
+// is deprecated.
+func (c *Client) warnDeprecated(ff *model.FigFamily) {
+	d := ff.Definition.Deprecation
+	if d == nil {
+		return
+	}
+	id := [2]string{ff.Definition.Namespace, ff.Definition.Key}
+	if _, warned := c.deprecations.warned.LoadOrStore(id, struct{}{}); warned {
+		return
+	}
+
+	state := "deprecated"
+	if d.Archived {
+		state = "archived"
+	}
+	msg := fmt.Sprintf("fig %s/%s is %s", id[0], id[1], state)
+	replacement := ""
+	if d.ReplacementKey != nil && *d.ReplacementKey != "" {
+		replacement = *d.ReplacementKey
+		msg += "; use " + replacement + " instead"
+	}
+	if d.Message != nil && *d.Message != "" {
+		msg += ": " + *d.Message
+	}
+	logging.Printf("Warning: %s", msg)
+	c.emit(event.Event{
+		Type:           event.KeyDeprecated,
+		Namespace:      id[0],
+		Key:            id[1],
+		Message:        msg,
+		ReplacementKey: replacement,
+	})
+}
+
+// DeprecatedKeys returns the keys read since the client started that are currently
+// deprecated or archived, with their usage, ordered by namespace and key. It helps to
+// find the code still reading flags that are being retired.
+func (c *Client) DeprecatedKeys() []DeprecatedKey {
+	var keys []DeprecatedKey
+	for _, usage := range c.Usage() {
+		ff, ok := c.store.Get(usage.Namespace, usage.Key)
+		if !ok || ff.Definition.Deprecation == nil {
+			continue
+		}
+		keys = append(keys, DeprecatedKey{KeyUsage: usage, Deprecation: *ff.Definition.Deprecation})
+	}
+	return keys
+}
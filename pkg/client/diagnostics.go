@@ -0,0 +1,226 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/figchain/go-client/pkg/clock"
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// Diagnostic record kinds.
+const (
+	DiagnosticExchange = "exchange"
+	DiagnosticEvent    = "event"
+)
+
+// sensitiveHeaders are recorded as logging.Redacted.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", transport.SignatureHeader}
+
+// DiagnosticRecord is an entry of the diagnostics (see config.WithDiagnostics): an HTTP
+// exchange with the server, or a client event. Credentials are redacted and bodies are
+// only recorded by size, since they hold configuration values.
+type DiagnosticRecord struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	// Method to Duration describe an exchange. Duration includes reading the response.
+	Method          string        `json:"method,omitempty"`
+	URL             string        `json:"url,omitempty"`
+	RequestHeaders  http.Header   `json:"requestHeaders,omitempty"`
+	RequestSize     int64         `json:"requestSize,omitempty"`
+	Status          int           `json:"status,omitempty"`
+	ResponseHeaders http.Header   `json:"responseHeaders,omitempty"`
+	ResponseSize    int64         `json:"responseSize,omitempty"`
+	Duration        time.Duration `json:"duration,omitempty"`
+	// Event to Message describe an event.
+	Event     event.Type `json:"event,omitempty"`
+	Namespace string     `json:"namespace,omitempty"`
+	Key       string     `json:"key,omitempty"`
+	Message   string     `json:"message,omitempty"`
+	// Error is the error of a failed exchange, or that caused an event.
+	Error string `json:"error,omitempty"`
+}
+
+// Diagnostics is the report written by DumpDiagnostics.
+type Diagnostics struct {
+	Time          time.Time `json:"time"`
+	Identity      Identity  `json:"identity"`
+	EnvironmentID string    `json:"environmentId"`
+	Namespaces    []string  `json:"namespaces"`
+	Health        Health    `json:"health"`
+	Stats         Stats     `json:"stats"`
+	// Records are the recorded exchanges and events, oldest first.
+	Records []DiagnosticRecord `json:"records"`
+}
+
+// diagnostics keeps the most recent DiagnosticRecords in a ring buffer.
+type diagnostics struct {
+	clock   clock.Clock
+	mu      sync.Mutex
+	records []DiagnosticRecord
+	next    int // index of the oldest record once the buffer is full
+}
+
+// newDiagnostics returns a buffer of size records, or nil if diagnostics are disabled.
+func newDiagnostics(size int, clk clock.Clock) *diagnostics {
+	if size <= 0 {
+		return nil
+	}
+	return &diagnostics{clock: clk, records: make([]DiagnosticRecord, 0, size)}
+}
+
+func (d *diagnostics) record(r DiagnosticRecord) {
+	if d == nil {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = d.clock.Now()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.records) < cap(d.records) {
+		d.records = append(d.records, r)
+		return
+	}
+	d.records[d.next] = r
+	d.next = (d.next + 1) % len(d.records)
+}
+
+// snapshot returns the records, oldest first.
+func (d *diagnostics) snapshot() []DiagnosticRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append(slices.Clone(d.records[d.next:]), d.records[:d.next]...)
+}
+
+// recordEvent records e.
+func (d *diagnostics) recordEvent(e event.Event) {
+	if d == nil {
+		return
+	}
+	r := DiagnosticRecord{
+		Time:      e.Time,
+		Kind:      DiagnosticEvent,
+		Event:     e.Type,
+		Namespace: e.Namespace,
+		Key:       e.Key,
+		Message:   logging.Redact(e.Message),
+	}
+	if e.Err != nil {
+		r.Error = logging.Redact(e.Err.Error())
+	}
+	d.record(r)
+}
+
+// DumpDiagnostics writes the recorded exchanges and events, with the client's identity,
+// health and stats, to w as JSON, e.g. to attach to a bug report. It fails if
+// diagnostics aren't enabled with config.WithDiagnostics.
+func (c *Client) DumpDiagnostics(w io.Writer) error {
+	if c.diagnostics == nil {
+		return fmt.Errorf("diagnostics are not enabled, see config.WithDiagnostics")
+	}
+	report := Diagnostics{
+		Time:          c.clock.Now(),
+		Identity:      c.identity,
+		EnvironmentID: c.cfg.EnvironmentID,
+		Namespaces:    c.cfg.Namespaces,
+		Health:        c.Health(),
+		Stats:         c.Stats(),
+		Records:       c.diagnostics.snapshot(),
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// recordingClient returns a copy of hc recording its exchanges in d, or hc if d is nil.
+func recordingClient(hc *http.Client, d *diagnostics) *http.Client {
+	if d == nil {
+		return hc
+	}
+	recorded := *hc
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	recorded.Transport = &recordingRoundTripper{base: base, d: d}
+	return &recorded
+}
+
+// recordingRoundTripper records the exchanges it makes once their response is read.
+type recordingRoundTripper struct {
+	base http.RoundTripper
+	d    *diagnostics
+}
+
+func (t *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := t.d.clock.Now()
+	r := DiagnosticRecord{
+		Time:           start,
+		Kind:           DiagnosticExchange,
+		Method:         req.Method,
+		URL:            logging.Redact(req.URL.String()),
+		RequestHeaders: redactHeaders(req.Header),
+		RequestSize:    req.ContentLength,
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		r.Duration = t.d.clock.Now().Sub(start)
+		r.Error = logging.Redact(err.Error())
+		t.d.record(r)
+		return nil, err
+	}
+	r.Status = resp.StatusCode
+	r.ResponseHeaders = redactHeaders(resp.Header)
+	resp.Body = &recordingBody{ReadCloser: resp.Body, d: t.d, r: r, start: start}
+	return resp, nil
+}
+
+// recordingBody records its exchange when it is closed.
+type recordingBody struct {
+	io.ReadCloser
+	d      *diagnostics
+	r      DiagnosticRecord
+	start  time.Time
+	n      atomic.Int64
+	closed sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.closed.Do(func() {
+		b.r.ResponseSize = b.n.Load()
+		b.r.Duration = b.d.clock.Now().Sub(b.start)
+		b.d.record(b.r)
+	})
+	return err
+}
+
+// redactHeaders returns a copy of h without credentials.
+func redactHeaders(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		if slices.Contains(sensitiveHeaders, http.CanonicalHeaderKey(name)) {
+			redacted[name] = []string{logging.Redacted}
+			continue
+		}
+		for _, v := range values {
+			redacted[name] = append(redacted[name], logging.Redact(v))
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// Kinds of FamilyChange.
+const (
+	ChangeAdded    = model.ChangeAdded
+	ChangeRemoved  = model.ChangeRemoved
+	ChangeModified = model.ChangeModified
+)
+
+// FamilyChange describes how a fig family differs between the client's current state and
+// another point in time, or between the versions an update replaced.
+type FamilyChange = model.FamilyChange
+
+// Diff is the result of DiffAsOf.
+type Diff struct {
+	AsOf time.Time `json:"asOf,omitzero"`
+	// Changes are ordered by namespace and key.
+	Changes []FamilyChange `json:"changes"`
+}
+
+// DiffAsOf fetches the state of every configured namespace as of asOf, or the latest
+// state if asOf is zero, and returns how it differs from what the client currently
+// serves. The client's state is not modified, so a change can be reviewed before it is
+// deployed by passing its future activation time.
+func (c *Client) DiffAsOf(ctx context.Context, asOf time.Time) (*Diff, error) {
+	diff := &Diff{AsOf: asOf, Changes: []FamilyChange{}}
+	for _, ns := range c.cfg.Namespaces {
+		req := &model.InitialFetchRequest{Namespace: ns, EnvironmentID: c.cfg.EnvironmentID}
+		if !asOf.IsZero() {
+			req.AsOfTimestamp = &asOf
+		}
+		resp, err := c.transport.FetchInitial(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", ns, err)
+		}
+
+		target := make(map[string]*model.FigFamily, len(resp.FigFamilies))
+		for i := range resp.FigFamilies {
+			target[resp.FigFamilies[i].Definition.Key] = &resp.FigFamilies[i]
+		}
+		c.store.Range(ns, func(current *model.FigFamily) bool {
+			next := target[current.Definition.Key]
+			delete(target, current.Definition.Key)
+			if change, changed := model.DiffFamilies(current, next); changed {
+				diff.Changes = append(diff.Changes, change)
+			}
+			return true
+		})
+		for _, next := range target {
+			change, _ := model.DiffFamilies(nil, next)
+			diff.Changes = append(diff.Changes, change)
+		}
+	}
+
+	slices.SortFunc(diff.Changes, func(a, b FamilyChange) int {
+		if n := strings.Compare(a.Namespace, b.Namespace); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return diff, nil
+}
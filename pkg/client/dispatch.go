@@ -0,0 +1,90 @@
+package client
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// dispatcher runs tasks on a fixed pool of workers. Tasks for the same key always run on
+// the same worker, so they run one at a time and in the order they were dispatched.
+type dispatcher struct {
+	workers []*worker
+	wg      sync.WaitGroup
+}
+
+// worker runs its queued tasks in order. The queue is unbounded, so that dispatching
+// under c.mu never waits for a callback that may itself be waiting for c.mu.
+type worker struct {
+	mu     sync.Mutex
+	tasks  []func()
+	closed bool
+	ready  chan struct{} // signalled when tasks are queued or the worker is closed
+}
+
+func newDispatcher(workers int) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	d := &dispatcher{workers: make([]*worker, workers)}
+	for i := range d.workers {
+		w := &worker{ready: make(chan struct{}, 1)}
+		d.workers[i] = w
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			w.run()
+		}()
+	}
+	return d
+}
+
+// run runs tasks as they are queued until the worker is closed and its queue is empty.
+func (w *worker) run() {
+	for {
+		w.mu.Lock()
+		if len(w.tasks) == 0 {
+			closed := w.closed
+			w.mu.Unlock()
+			if closed {
+				return
+			}
+			<-w.ready
+			continue
+		}
+		task := w.tasks[0]
+		w.tasks[0] = nil
+		w.tasks = w.tasks[1:]
+		w.mu.Unlock()
+		task()
+	}
+}
+
+func (w *worker) signal() {
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch queues task on the worker for key. It never blocks.
+func (d *dispatcher) dispatch(key string, task func()) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	w := d.workers[h.Sum32()%uint32(len(d.workers))]
+	w.mu.Lock()
+	w.tasks = append(w.tasks, task)
+	w.mu.Unlock()
+	w.signal()
+}
+
+// close runs the remaining queued tasks and stops the workers. dispatch must not be
+// called after close.
+func (d *dispatcher) close() {
+	for _, w := range d.workers {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		w.signal()
+	}
+	d.wg.Wait()
+}
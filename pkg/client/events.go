@@ -0,0 +1,41 @@
+package client
+
+import (
+	"github.com/figchain/go-client/pkg/event"
+)
+
+// emit delivers e to the configured event handlers. c.mu must not be held, since
+// handlers may call back into the client; use emitLocked instead.
+func (c *Client) emit(e event.Event) {
+	if len(c.cfg.EventHandlers) == 0 && c.diagnostics == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = c.clock.Now()
+	}
+	e.App, e.Instance = c.identity.AppName, c.identity.InstanceID
+	c.diagnostics.recordEvent(e)
+	for _, h := range c.cfg.EventHandlers {
+		h(e)
+	}
+}
+
+// emitLocked buffers e to be delivered by unlock once c.mu is released. c.mu must be
+// held.
+func (c *Client) emitLocked(e event.Event) {
+	if len(c.cfg.EventHandlers) == 0 && c.diagnostics == nil {
+		return
+	}
+	e.Time = c.clock.Now()
+	c.events = append(c.events, e)
+}
+
+// unlock releases c.mu and delivers the events buffered by emitLocked while it was held.
+func (c *Client) unlock() {
+	events := c.events
+	c.events = nil
+	c.mu.Unlock()
+	for _, e := range events {
+		c.emit(e)
+	}
+}
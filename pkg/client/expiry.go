@@ -0,0 +1,58 @@
+package client
+
+import (
+	"errors"
+	"time"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/metrics"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// ErrExpired is returned by GetFig when the fig family's expiry time has passed.
+var ErrExpired = errors.New("fig expired")
+
+// gcLoop removes expired families from the store every ExpiryGCInterval until the client
+// is closed.
+func (c *Client) gcLoop(d store.Deleter) {
+	defer c.wg.Done()
+	ticker := c.clock.NewTicker(c.cfg.ExpiryGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case <-ticker.C():
+			if n := c.collectExpired(d, c.clock.Now()); n > 0 {
+				logging.Printf("Removed %d expired fig families", n)
+			}
+		}
+	}
+}
+
+// collectExpired removes the families that expired at now from the store and returns how
+// many were removed.
+func (c *Client) collectExpired(d store.Deleter, now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for _, ns := range c.cfg.Namespaces {
+		var expired []string
+		c.store.Range(ns, func(ff *model.FigFamily) bool {
+			if ff.Expired(now) {
+				expired = append(expired, ff.Definition.Key)
+			}
+			return true
+		})
+		if len(expired) > 0 {
+			d.Delete(ns, expired...)
+			removed += len(expired)
+		}
+	}
+	if removed > 0 {
+		c.payloads.prune(c.store, c.cfg.Namespaces)
+		c.metrics.SetGauge(metrics.StoreFamilies, float64(c.storeLen()), nil)
+	}
+	return removed
+}
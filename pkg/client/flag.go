@@ -0,0 +1,99 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/figchain/go-client/pkg/evaluation"
+)
+
+// boolRecord is the record of a BoolFlag key.
+type boolRecord struct {
+	Value bool `avro:"value"`
+}
+
+func (r *boolRecord) Schema() string {
+	return `{"type":"record","name":"BoolFlag","fields":[{"name":"value","type":"boolean"}]}`
+}
+
+// longRecord is the record of an IntFlag or DurationFlag key.
+type longRecord struct {
+	Value int64 `avro:"value"`
+}
+
+func (r *longRecord) Schema() string {
+	return `{"type":"record","name":"LongFlag","fields":[{"name":"value","type":"long"}]}`
+}
+
+// BoolFlag is a server-scoped boolean flag kept up to date as its key is updated, for
+// hot paths that can't afford GetFig. The key's figs hold a record with a single boolean
+// field.
+type BoolFlag struct {
+	v atomic.Bool
+}
+
+// NewBoolFlag binds a BoolFlag to key. It is def until the key can be read.
+func (c *Client) NewBoolFlag(key string, def bool) *BoolFlag {
+	f := &BoolFlag{}
+	f.v.Store(def)
+	bindFlag(c, key, &boolRecord{}, func(r AvroRecord) {
+		f.v.Store(r.(*boolRecord).Value)
+	})
+	return f
+}
+
+// Enabled returns the flag's latest value.
+func (f *BoolFlag) Enabled() bool {
+	return f.v.Load()
+}
+
+// IntFlag is a server-scoped integer flag kept up to date as its key is updated. The
+// key's figs hold a record with a single long field.
+type IntFlag struct {
+	v atomic.Int64
+}
+
+// NewIntFlag binds an IntFlag to key. It is def until the key can be read.
+func (c *Client) NewIntFlag(key string, def int64) *IntFlag {
+	f := &IntFlag{}
+	f.v.Store(def)
+	bindFlag(c, key, &longRecord{}, func(r AvroRecord) {
+		f.v.Store(r.(*longRecord).Value)
+	})
+	return f
+}
+
+// Value returns the flag's latest value.
+func (f *IntFlag) Value() int64 {
+	return f.v.Load()
+}
+
+// DurationFlag is a server-scoped duration flag kept up to date as its key is updated.
+// The key's figs hold a record with a single long field, in milliseconds.
+type DurationFlag struct {
+	v atomic.Int64
+}
+
+// NewDurationFlag binds a DurationFlag to key. It is def until the key can be read.
+func (c *Client) NewDurationFlag(key string, def time.Duration) *DurationFlag {
+	f := &DurationFlag{}
+	f.v.Store(int64(def))
+	bindFlag(c, key, &longRecord{}, func(r AvroRecord) {
+		f.v.Store(int64(time.Duration(r.(*longRecord).Value) * time.Millisecond))
+	})
+	return f
+}
+
+// Value returns the flag's latest value.
+func (f *DurationFlag) Value() time.Duration {
+	return time.Duration(f.v.Load())
+}
+
+// bindFlag sets a flag from the current value of key, if it can be read, and registers
+// set as a listener for its updates. The flag evaluates key like RegisterListener does.
+func bindFlag(c *Client, key string, prototype AvroRecord, set func(AvroRecord)) {
+	if err := c.GetFig(key, prototype, evaluation.NewEvaluationContext(nil)); err == nil {
+		set(prototype)
+	}
+	c.RegisterListener(key, prototype, set, WithInitialValue())
+}
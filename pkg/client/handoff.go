@@ -0,0 +1,46 @@
+package client
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// Handoff returns the client's families and cursors, serialized, for a replacement client
+// to start from with config.WithHandoffState instead of bootstrapping, e.g. when the
+// client is rebuilt after a credential or namespace change. The old client keeps serving
+// until it is closed, and the new one resumes polling where it left off, so no update is
+// missed in between.
+//
+// Payloads are serialized as held: figs encrypted by the server stay encrypted.
+func (c *Client) Handoff() ([]byte, error) {
+	// Updates are stored and their cursors advanced under the lock, so the families and
+	// cursors are consistent
+	c.mu.RLock()
+	snapshot := &store.Snapshot{Cursors: maps.Clone(c.namespaceCursors)}
+	for ns := range snapshot.Cursors {
+		c.store.Range(ns, func(ff *model.FigFamily) bool {
+			snapshot.Families = append(snapshot.Families, *ff)
+			return true
+		})
+	}
+	// Cursors have advanced past updates queued while paused or staged for a rollout, so
+	// hand them off too, oldest first
+	for _, u := range c.pending {
+		snapshot.Families = append(snapshot.Families, u.family)
+	}
+	for _, b := range c.staged {
+		for _, u := range b.updates {
+			snapshot.Families = append(snapshot.Families, u.family)
+		}
+	}
+	c.mu.RUnlock()
+
+	data, err := store.MarshalSnapshot(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize handoff state: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// Health statuses reported by Client.Health.
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusDegraded = "degraded"
+)
+
+// Health describes the state of a running client.
+type Health struct {
+	// Status is HealthStatusDegraded while update polls are failing.
+	Status              string    `json:"status"`
+	LastPoll            time.Time `json:"lastPoll"`
+	LastSuccessfulPoll  time.Time `json:"lastSuccessfulPoll"`
+	LastError           string    `json:"lastError,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	Families            int       `json:"families"`
+	DroppedUpdates      uint64    `json:"droppedUpdates"`
+	Quarantined         int       `json:"quarantined"`
+	// Frozen is set when the client doesn't apply updates (see config.WithFrozen).
+	Frozen  bool              `json:"frozen"`
+	Cursors map[string]string `json:"cursors"`
+	// Protocol is the wire protocol negotiated with the server.
+	Protocol transport.Protocol `json:"protocol"`
+}
+
+// pollHealth tracks the outcome of update polls.
+type pollHealth struct {
+	mu                  sync.Mutex
+	lastPoll            time.Time
+	lastSuccessfulPoll  time.Time
+	lastErr             error
+	consecutiveFailures int
+}
+
+// recordPoll records the outcome of an update poll.
+func (c *Client) recordPoll(err error) {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	now := c.clock.Now()
+	c.health.lastPoll = now
+	if err != nil {
+		c.health.lastErr = err
+		c.health.consecutiveFailures++
+		return
+	}
+	c.health.lastSuccessfulPoll = now
+	c.health.consecutiveFailures = 0
+}
+
+// Health reports the state of the client, e.g. for readiness probes.
+func (c *Client) Health() Health {
+	c.health.mu.Lock()
+	h := Health{
+		Status:              HealthStatusOK,
+		LastPoll:            c.health.lastPoll,
+		LastSuccessfulPoll:  c.health.lastSuccessfulPoll,
+		ConsecutiveFailures: c.health.consecutiveFailures,
+	}
+	if c.health.lastErr != nil {
+		h.LastError = c.health.lastErr.Error()
+	}
+	c.health.mu.Unlock()
+
+	if h.ConsecutiveFailures > 0 {
+		h.Status = HealthStatusDegraded
+	}
+	h.Frozen = c.cfg.Frozen
+	h.Families = c.storeLen()
+	h.DroppedUpdates = c.DroppedUpdates()
+	c.quarantine.mu.Lock()
+	h.Quarantined = len(c.quarantine.families)
+	c.quarantine.mu.Unlock()
+	c.mu.RLock()
+	h.Cursors = maps.Clone(c.namespaceCursors)
+	c.mu.RUnlock()
+	if n, ok := c.transport.(transport.Negotiator); ok {
+		h.Protocol = n.Protocol()
+	}
+	return h
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// recordHistory records ff as the current version of its family.
+func (c *Client) recordHistory(ff model.FigFamily) {
+	if c.cfg.HistoryDepth > 0 {
+		c.history.Record(ff, c.clock.Now())
+	}
+}
+
+// GetFigHistory returns the versions of a fig family the client has applied, newest
+// first, up to config.WithHistoryDepth. The families must not be modified.
+func (c *Client) GetFigHistory(key string) []store.HistoryEntry {
+	namespace, key, err := c.resolveKey(key)
+	if err != nil {
+		return nil
+	}
+	return c.history.Get(namespace, key)
+}
+
+// Rollback locally reverts a fig family to the version applied before the current one,
+// e.g. to mitigate a bad update during an incident. Listeners and watchers are notified.
+// The rollback lasts until the server sends the next update for the family.
+func (c *Client) Rollback(key string) error {
+	namespace, key, err := c.resolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	ff, ok := c.history.Rollback(namespace, key)
+	if !ok {
+		return fmt.Errorf("no previous version of %s to roll back to", key)
+	}
+	c.payloads.intern(&ff)
+	old, _ := c.store.Get(namespace, key)
+	c.store.Put(ff)
+	c.auditFamily(AuditSourceRollback, "", old, ff)
+	change, _ := model.DiffFamilies(old, &ff)
+	c.notifyLocked(ff, &change)
+	close(c.updated)
+	c.updated = make(chan struct{})
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build !(js && wasm)
+
+package client
+
+import (
+	"net/http"
+
+	"github.com/figchain/go-client/pkg/config"
+)
+
+// newHTTPClient builds the HTTP client used when none is configured, applying the
+// connection settings of cfg on top of the net/http defaults.
+func newHTTPClient(cfg *config.Config) *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.MaxIdleConns > 0 {
+		t.MaxIdleConns = cfg.MaxIdleConns
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConns
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.ForceHTTP2 {
+		t.ForceAttemptHTTP2 = true
+		t.Protocols = new(http.Protocols)
+		t.Protocols.SetHTTP2(true)
+		t.Protocols.SetUnencryptedHTTP2(true)
+	}
+	if cfg.HTTP2PingInterval > 0 {
+		t.HTTP2 = &http.HTTP2Config{SendPingTimeout: cfg.HTTP2PingInterval}
+	}
+	return &http.Client{Transport: t}
+}
@@ -0,0 +1,17 @@
+//go:build js && wasm
+
+package client
+
+import (
+	"net/http"
+
+	"github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// newHTTPClient builds the HTTP client used when none is configured, sending requests
+// with fetch. The JavaScript runtime manages connections, so the connection settings of
+// cfg don't apply.
+func newHTTPClient(cfg *config.Config) *http.Client {
+	return &http.Client{Transport: transport.FetchRoundTripper{}}
+}
@@ -0,0 +1,55 @@
+//go:build !(js && wasm)
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/figchain/go-client/pkg/config"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	cfg := config.DefaultConfig()
+	config.WithConnectionPool(20, 5)(cfg)
+	config.WithIdleConnTimeout(time.Minute)(cfg)
+	config.WithHTTP2PingInterval(30 * time.Second)(cfg)
+
+	tr := newHTTPClient(cfg).Transport.(*http.Transport)
+	if tr.MaxIdleConns != 20 || tr.MaxIdleConnsPerHost != 20 || tr.MaxConnsPerHost != 5 {
+		t.Errorf("pool = %d idle, %d idle per host, %d per host; want 20, 20, 5", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.MaxConnsPerHost)
+	}
+	if tr.IdleConnTimeout != time.Minute {
+		t.Errorf("IdleConnTimeout = %v, want 1m", tr.IdleConnTimeout)
+	}
+	if tr.HTTP2 == nil || tr.HTTP2.SendPingTimeout != 30*time.Second {
+		t.Errorf("HTTP2 = %+v, want pings after 30s", tr.HTTP2)
+	}
+	if tr == http.DefaultTransport {
+		t.Error("newHTTPClient modified the default transport")
+	}
+}
+
+func TestNewHTTPClient_ForceHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	config.WithForceHTTP2()(cfg)
+	resp, err := newHTTPClient(cfg).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("X-Proto"); got != "HTTP/2.0" {
+		t.Errorf("request used %s, want HTTP/2.0", got)
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"net/http"
+	"os"
+	"runtime/debug"
+
+	"github.com/figchain/go-client/pkg/config"
+)
+
+// modulePath is the module the SDK version is read from.
+const modulePath = "github.com/figchain/go-client"
+
+// Identity identifies a client instance to the FigChain server, so that polling traffic
+// and staleness can be attributed to the consumers they come from.
+type Identity struct {
+	// SDKVersion is the version of this module the client was built with.
+	SDKVersion string
+	// AppName is the configured name of the application.
+	AppName string
+	// InstanceID is the configured instance ID, or the hostname.
+	InstanceID string
+	Hostname   string
+}
+
+// newIdentity returns the identity of a client with cfg.
+func newIdentity(cfg *config.Config) Identity {
+	hostname, _ := os.Hostname()
+	id := Identity{
+		SDKVersion: sdkVersion(),
+		AppName:    cfg.AppName,
+		InstanceID: cfg.InstanceID,
+		Hostname:   hostname,
+	}
+	if id.InstanceID == "" {
+		id.InstanceID = hostname
+	}
+	return id
+}
+
+// sdkVersion returns the version of this module in the running binary, or "(devel)"
+// when it isn't known, e.g. in its own tests.
+func sdkVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath && info.Main.Version != "" {
+			return info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				return dep.Version
+			}
+		}
+	}
+	return "(devel)"
+}
+
+// setHeaders is a request interceptor sending the identity with every request. Empty
+// fields are left out.
+func (id Identity) setHeaders(req *http.Request) error {
+	req.Header.Set("User-Agent", "figchain-go-client/"+id.SDKVersion)
+	headers := map[string]string{
+		"X-FigChain-SDK-Version": id.SDKVersion,
+		"X-FigChain-App":         id.AppName,
+		"X-FigChain-Instance-ID": id.InstanceID,
+		"X-FigChain-Hostname":    id.Hostname,
+	}
+	for name, value := range headers {
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return nil
+}
+
+// labels returns the identity as metric labels, for the metrics.ClientInfo gauge.
+func (id Identity) labels() map[string]string {
+	return map[string]string{
+		"sdk_version": id.SDKVersion,
+		"app":         id.AppName,
+		"instance_id": id.InstanceID,
+		"hostname":    id.Hostname,
+	}
+}
+
+// Identity returns the identity the client sends to the FigChain server.
+func (c *Client) Identity() Identity {
+	return c.identity
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/figchain/go-client/pkg/evaluation"
+)
+
+// MergePolicy controls how GetMergedFig combines the values of its layers.
+type MergePolicy int
+
+const (
+	// MergeDeep merges records and maps recursively. A layer's non-zero values, i.e. set
+	// nullable fields, non-empty strings, lists and maps, and non-zero numbers and
+	// booleans, override those of the layers below it. Lists are replaced.
+	MergeDeep MergePolicy = iota
+	// MergeAppendLists is MergeDeep, except that a layer's lists are appended to those of
+	// the layers below it.
+	MergeAppendLists
+	// MergeOverride returns the value of the highest layer present, unmerged.
+	MergeOverride
+)
+
+// Layer is a key in a composite fig. Without a Namespace, Key is resolved as by GetFig,
+// so it may be qualified with config.WithKeyRouting.
+type Layer struct {
+	Namespace string
+	Key       string
+}
+
+// GetMergedFig evaluates each layer of a composite fig with ctx, e.g. a base key and an
+// environment-specific overlay, and merges the values into target according to policy.
+// Layers are given in increasing precedence: later layers override earlier ones. Layers
+// whose key doesn't exist are skipped, but at least one must exist; a layer that exists
+// but has no matching fig fails the read.
+func (c *Client) GetMergedFig(layers []Layer, target any, ctx *evaluation.EvaluationContext, policy MergePolicy) error {
+	targetVal := reflect.ValueOf(target)
+	if targetVal.Kind() != reflect.Pointer || targetVal.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer")
+	}
+	if ctx == nil {
+		ctx = evaluation.NewEvaluationContext(nil)
+	}
+	generic, isGeneric := target.(*GenericRecord)
+
+	var merged reflect.Value
+	found := false
+	for i := range layers {
+		layer := layers[i]
+		if policy == MergeOverride {
+			layer = layers[len(layers)-1-i]
+		}
+		if layer.Namespace == "" {
+			ns, key, err := c.resolveKey(layer.Key)
+			if err != nil {
+				return err
+			}
+			layer.Namespace, layer.Key = ns, key
+		}
+		c.usage.record(layer.Namespace, layer.Key, c.clock.Now())
+		if _, ok := c.getFamily(layer.Namespace, layer.Key); !ok {
+			continue
+		}
+
+		// Each layer is decoded into a value of its own
+		var value any
+		if isGeneric {
+			value = NewGenericRecord(generic.Schema())
+		} else {
+			value = reflect.New(targetVal.Elem().Type()).Interface()
+		}
+		if err := c.getFig(layer.Namespace, layer.Key, value, ctx); err != nil {
+			return fmt.Errorf("layer %s/%s: %w", layer.Namespace, layer.Key, err)
+		}
+		if isGeneric {
+			value = &value.(*GenericRecord).Value
+		}
+
+		v := reflect.ValueOf(value).Elem()
+		if !found {
+			merged, found = v, true
+		} else {
+			mergeValue(merged, v, policy)
+		}
+		if policy == MergeOverride {
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("fig not found: none of %d layers exist", len(layers))
+	}
+
+	if isGeneric {
+		generic.Value = merged.Interface()
+	} else {
+		targetVal.Elem().Set(merged)
+	}
+	return nil
+}
+
+// mergeValue merges src into dst, which must be settable, according to policy.
+func mergeValue(dst, src reflect.Value, policy MergePolicy) {
+	switch src.Kind() {
+	case reflect.Struct:
+		for i := range src.NumField() {
+			if dst.Field(i).CanSet() {
+				mergeValue(dst.Field(i), src.Field(i), policy)
+			}
+		}
+	case reflect.Pointer:
+		switch {
+		case src.IsNil():
+		case dst.IsNil():
+			dst.Set(src)
+		default:
+			mergeValue(dst.Elem(), src.Elem(), policy)
+		}
+	case reflect.Interface:
+		switch {
+		case src.IsNil():
+		case dst.IsNil() || dst.Elem().Type() != src.Elem().Type():
+			dst.Set(src)
+		default:
+			// The dynamic value isn't settable, so it is merged in a copy
+			elem := reflect.New(dst.Elem().Type()).Elem()
+			elem.Set(dst.Elem())
+			mergeValue(elem, src.Elem(), policy)
+			dst.Set(elem)
+		}
+	case reflect.Map:
+		if src.Len() == 0 {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			elem := reflect.New(src.Type().Elem()).Elem()
+			if existing := dst.MapIndex(iter.Key()); existing.IsValid() {
+				elem.Set(existing)
+				mergeValue(elem, iter.Value(), policy)
+			} else {
+				elem.Set(iter.Value())
+			}
+			dst.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.Slice:
+		switch {
+		case src.Len() == 0:
+		case policy == MergeAppendLists:
+			dst.Set(reflect.AppendSlice(dst, src))
+		default:
+			dst.Set(src)
+		}
+	default:
+		if !src.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// ListNamespaces lists the namespaces of the client's environment, with their key counts
+// and when they were last updated, including namespaces the client isn't configured for.
+func (c *Client) ListNamespaces(ctx context.Context) ([]*model.NamespaceInfo, error) {
+	return c.transport.ListNamespaces(ctx)
+}
+
+// GetNamespace fetches the metadata of a namespace of the client's environment. It
+// returns an error matching transport.ErrNotFound if the namespace doesn't exist.
+func (c *Client) GetNamespace(ctx context.Context, name string) (*model.NamespaceInfo, error) {
+	return c.transport.GetNamespace(ctx, name)
+}
+
+// CreateNamespace creates a namespace, in the client's environment unless
+// req.EnvironmentID is set. It returns an error matching transport.ErrConflict if the
+// namespace already exists. Creating namespaces requires admin-scoped credentials. The
+// client doesn't start polling the new namespace.
+func (c *Client) CreateNamespace(ctx context.Context, req model.CreateNamespaceRequest) (*model.NamespaceInfo, error) {
+	if req.EnvironmentID == "" {
+		req.EnvironmentID = c.cfg.EnvironmentID
+	}
+	return c.transport.CreateNamespace(ctx, &req)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// PauseUpdates stops applying updates, e.g. to keep configuration stable during a
+// deployment or while an incident is mitigated. Updates are still fetched, by polling,
+// update sources and Refresh, but queued until ResumeUpdates. Updates of kill-switch
+// keys (see config.WithKillSwitchKeys) and Rollback still apply.
+func (c *Client) PauseUpdates() {
+	c.mu.Lock()
+	wasPaused := c.paused
+	c.paused = true
+	c.mu.Unlock()
+	if !wasPaused {
+		c.emit(event.Event{Type: event.UpdatesPaused, Message: "updates paused"})
+	}
+}
+
+// ResumeUpdates applies the updates queued since PauseUpdates in one step, so that a
+// namespace's families change together, and applies updates as they arrive again. It
+// returns the families that changed.
+func (c *Client) ResumeUpdates() []model.FigFamily {
+	c.mu.Lock()
+	if !c.paused {
+		c.mu.Unlock()
+		return nil
+	}
+	c.paused = false
+	pending := c.pending
+	c.pending = nil
+	// Saved together with the queued families
+	c.saveKeptCursors()
+	applied := c.applyLocked(pending)
+	c.unlock()
+
+	c.emit(event.Event{
+		Type:    event.UpdatesResumed,
+		Message: fmt.Sprintf("updates resumed, %d queued families applied", len(applied)),
+	})
+	return applied
+}
+
+// UpdatesPaused reports whether updates are paused.
+func (c *Client) UpdatesPaused() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.paused
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// payloadPool deduplicates identical fig payloads, e.g. unchanged across versions, so
+// they share one backing array. Pooled payloads must be treated as read-only.
+type payloadPool struct {
+	mu        sync.Mutex
+	payloads  map[[sha256.Size]byte][]byte
+	pruneSize int
+}
+
+func newPayloadPool() *payloadPool {
+	return &payloadPool{payloads: make(map[[sha256.Size]byte][]byte), pruneSize: 1024}
+}
+
+// intern replaces the payloads of ff's figs with pooled copies. The Figs slice is copied
+// first since it may be shared with the caller.
+func (p *payloadPool) intern(ff *model.FigFamily) {
+	if len(ff.Figs) == 0 {
+		return
+	}
+	figs := make([]model.Fig, len(ff.Figs))
+	copy(figs, ff.Figs)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range figs {
+		payload := figs[i].Payload
+		if len(payload) == 0 {
+			continue
+		}
+		sum := sha256.Sum256(payload)
+		if pooled, ok := p.payloads[sum]; ok && bytes.Equal(pooled, payload) {
+			figs[i].Payload = pooled
+			continue
+		}
+		p.payloads[sum] = payload
+	}
+	ff.Figs = figs
+}
+
+// prune drops payloads no longer referenced by any family in the given namespaces of s.
+// It only does work once the pool has doubled since the last prune.
+func (p *payloadPool) prune(s store.Store, namespaces []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.payloads) < p.pruneSize {
+		return
+	}
+	live := make(map[[sha256.Size]byte][]byte, len(p.payloads))
+	for _, ns := range namespaces {
+		s.Range(ns, func(ff *model.FigFamily) bool {
+			for _, fig := range ff.Figs {
+				if len(fig.Payload) > 0 {
+					live[sha256.Sum256(fig.Payload)] = fig.Payload
+				}
+			}
+			return true
+		})
+	}
+	p.payloads = live
+	p.pruneSize = max(2*len(live), 1024)
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+func TestPayloadPool(t *testing.T) {
+	p := newPayloadPool()
+	family := func(key string, payloads ...string) model.FigFamily {
+		ff := model.FigFamily{Definition: model.FigDefinition{Key: key, Namespace: "ns"}}
+		for _, payload := range payloads {
+			ff.Figs = append(ff.Figs, model.Fig{Payload: []byte(payload)})
+		}
+		return ff
+	}
+
+	a := family("a", "same", "same", "other")
+	original := a.Figs
+	p.intern(&a)
+	if &a.Figs[0].Payload[0] != &a.Figs[1].Payload[0] {
+		t.Error("Expected identical payloads to share memory")
+	}
+	if &original[1].Payload[0] == &original[0].Payload[0] {
+		t.Error("Expected caller's Figs slice to be left untouched")
+	}
+
+	b := family("b", "other")
+	p.intern(&b)
+	if &b.Figs[0].Payload[0] != &a.Figs[2].Payload[0] {
+		t.Error("Expected identical payloads to share memory across families")
+	}
+
+	// Pruning keeps only payloads referenced by the store
+	st := store.NewMemoryStore()
+	st.Put(b)
+	p.pruneSize = 0
+	p.prune(st, []string{"ns"})
+	if len(p.payloads) != 1 {
+		t.Errorf("Expected 1 live payload after prune, got %d", len(p.payloads))
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// isKillSwitch reports whether key is a kill-switch key, see config.WithKillSwitchKeys.
+func (c *Client) isKillSwitch(key string) bool {
+	if c.killSwitches[key] {
+		return true
+	}
+	for _, prefix := range c.cfg.KillSwitchPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPriority splits updates into those of kill-switch keys and the rest.
+func (c *Client) splitPriority(updates []update) (priority, rest []update) {
+	if len(c.killSwitches) == 0 && len(c.cfg.KillSwitchPrefixes) == 0 {
+		return nil, updates
+	}
+	for _, u := range updates {
+		if c.isKillSwitch(u.family.Definition.Key) {
+			priority = append(priority, u)
+		} else {
+			rest = append(rest, u)
+		}
+	}
+	return priority, rest
+}
+
+// priorityLoop fetches the kill-switch families every PriorityPollingInterval until the
+// client is closed.
+func (c *Client) priorityLoop() {
+	defer c.wg.Done()
+	ticker := c.clock.NewTicker(c.cfg.PriorityPollingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case <-ticker.C():
+			c.pollPriority()
+		}
+	}
+}
+
+// pollPriority fetches the kill-switch families and applies those that changed.
+func (c *Client) pollPriority() {
+	var changed []model.FigFamily
+	for _, ns := range c.cfg.Namespaces {
+		for _, key := range c.priorityKeys(ns) {
+			ff, err := c.transport.FetchFamily(c.ctx, ns, key)
+			if err != nil {
+				if c.ctx.Err() != nil {
+					return
+				}
+				if !errors.Is(err, transport.ErrNotFound) {
+					logging.Printf("Failed to fetch kill-switch family %s/%s: %v", ns, key, err)
+				}
+				continue
+			}
+			// Most fetches find nothing new; don't count them as ignored updates
+			if c.staleReason(*ff) == "" {
+				changed = append(changed, *ff)
+			}
+		}
+	}
+	if len(changed) > 0 {
+		c.applyUpdates(changed, AuditSourcePriority, "")
+	}
+}
+
+// priorityKeys returns the kill-switch keys of a namespace: the configured keys and the
+// held keys under a kill-switch prefix.
+func (c *Client) priorityKeys(namespace string) []string {
+	keys := make([]string, 0, len(c.cfg.KillSwitchKeys))
+	keys = append(keys, c.cfg.KillSwitchKeys...)
+	if len(c.cfg.KillSwitchPrefixes) == 0 {
+		return keys
+	}
+	c.store.Range(namespace, func(ff *model.FigFamily) bool {
+		if !c.killSwitches[ff.Definition.Key] && c.isKillSwitch(ff.Definition.Key) {
+			keys = append(keys, ff.Definition.Key)
+		}
+		return true
+	})
+	return keys
+}
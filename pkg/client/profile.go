@@ -0,0 +1,58 @@
+package client
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/figchain/go-client/pkg/encryption"
+)
+
+// encryptionFor returns the encryption service decrypting the figs of namespace, or nil
+// if the client isn't configured for decryption.
+func (c *Client) encryptionFor(namespace string) *encryption.Service {
+	if svc, ok := c.nsEncryption[namespace]; ok {
+		return svc
+	}
+	return c.encryptionService
+}
+
+// namespacesByPriority returns the configured namespaces, highest profile priority
+// first and otherwise in configured order.
+func (c *Client) namespacesByPriority() []string {
+	namespaces := slices.Clone(c.cfg.Namespaces)
+	slices.SortStableFunc(namespaces, func(a, b string) int {
+		return cmp.Compare(c.cfg.Profile(b).Priority, c.cfg.Profile(a).Priority)
+	})
+	return namespaces
+}
+
+// pollDue reports whether namespace may be polled at now: its profile's polling
+// interval, if any, has passed since it was last polled. Only the poll loop uses it.
+func (c *Client) pollDue(namespace string, now time.Time) bool {
+	return !now.Before(c.nextPoll[namespace])
+}
+
+// polled records that namespace was polled at now.
+func (c *Client) polled(namespace string, now time.Time) {
+	if interval := c.cfg.Profile(namespace).PollingInterval; interval > 0 {
+		c.nextPoll[namespace] = now.Add(interval)
+	}
+}
+
+// waitForNextPoll waits until the first namespace is due to be polled again, or the
+// client is closed.
+func (c *Client) waitForNextPoll() {
+	var next time.Time
+	for _, at := range c.nextPoll {
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	timer := c.clock.NewTimer(next.Sub(c.clock.Now()))
+	defer timer.Stop()
+	select {
+	case <-c.closeCh:
+	case <-timer.C():
+	}
+}
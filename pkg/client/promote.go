@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/figchain/go-client/pkg/encryption"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// Promote copies the fig family at key from the environment of src to that of dst, e.g.
+// so that a CD pipeline promotes configuration from staging to production along with the
+// code. The family is read from the server rather than src's store, and each of its
+// versions is published to dst under the same version ID. The last publish sets the
+// family's rules and default version, so that they only take effect once every version
+// they target exists.
+//
+// Encrypted versions are decrypted with src's key and re-encrypted for the namespace in
+// dst's environment with dst's key, so both clients must then be configured for
+// decryption. Each publish has an idempotency key derived from the source environment,
+// key and version, so a failed promotion can be retried; WithIdempotencyKey sets a prefix
+// for them. Other opts apply to every publish, e.g. DryRun, except that the versions,
+// rules and default version are those of the family. The results of the publishes made
+// are returned, in order, even if one fails.
+func Promote(ctx context.Context, src, dst *Client, key string, opts ...PublishOption) ([]*PublishResult, error) {
+	srcNamespace, srcKey, err := src.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	dstNamespace, dstKey, err := dst.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var o publishOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ff, err := src.transport.FetchFamily(ctx, srcNamespace, srcKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s/%s: %w", srcNamespace, srcKey, err)
+	}
+	if len(ff.Figs) == 0 {
+		return nil, fmt.Errorf("%s/%s has no versions to promote", srcNamespace, srcKey)
+	}
+
+	var results []*PublishResult
+	for i, fig := range ff.Figs {
+		req, err := promotedFig(ctx, src, dst, srcNamespace, dstNamespace, &fig)
+		if err != nil {
+			return results, fmt.Errorf("failed to promote version %s: %w", fig.Version, err)
+		}
+		req.Key = dstKey
+
+		po := o
+		po.version = fig.Version
+		po.idempotencyKey = fmt.Sprintf("%spromote/%s/%s/%s/%s", o.idempotencyKey, src.cfg.EnvironmentID, srcNamespace, srcKey, fig.Version)
+		po.rules, po.asDefault = nil, false
+		if i == len(ff.Figs)-1 {
+			req.Rules = ff.Rules
+			if req.Rules == nil {
+				req.Rules = []model.Rule{}
+			}
+			req.DefaultVersion = ff.DefaultVersion
+		}
+		result, err := dst.publish(ctx, req, po)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			return results, fmt.Errorf("failed to promote version %s: %w", fig.Version, err)
+		}
+	}
+	return results, nil
+}
+
+// promotedFig returns the publish request of fig in dst, re-encrypting it for
+// dstNamespace if it is encrypted.
+func promotedFig(ctx context.Context, src, dst *Client, srcNamespace, dstNamespace string, fig *model.Fig) (*model.PublishRequest, error) {
+	req := &model.PublishRequest{Namespace: dstNamespace, Payload: fig.Payload}
+	if !fig.IsEncrypted {
+		return req, nil
+	}
+	decrypter := src.encryptionFor(srcNamespace)
+	if decrypter == nil {
+		return nil, fmt.Errorf("the version is encrypted but the source client is not configured for decryption")
+	}
+	plaintext, err := decrypter.Decrypt(ctx, fig, srcNamespace)
+	if err != nil {
+		src.stats.decryptFailures.Add(1)
+		return nil, err
+	}
+	defer encryption.Zero(plaintext)
+	req.Payload = plaintext
+	if err := dst.encryptPublish(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
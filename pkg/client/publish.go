@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"slices"
+
+	"github.com/hamba/avro/v2"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// PublishOption configures a Publish call.
+type PublishOption func(*publishOptions)
+
+type publishOptions struct {
+	version        string
+	rules          []model.Rule
+	asDefault      bool
+	dryRun         bool
+	idempotencyKey string
+	encrypt        bool
+}
+
+// WithVersion sets the ID of the published version, a UUID, instead of a random one.
+func WithVersion(version string) PublishOption {
+	return func(o *publishOptions) {
+		o.version = version
+	}
+}
+
+// WithRules replaces the rules of the family. Rules with an empty TargetVersion target
+// the published version. An empty list clears the rules.
+func WithRules(rules ...model.Rule) PublishOption {
+	return func(o *publishOptions) {
+		o.rules = append([]model.Rule{}, rules...)
+	}
+}
+
+// AsDefault makes the published version the default version of the family.
+func AsDefault() PublishOption {
+	return func(o *publishOptions) {
+		o.asDefault = true
+	}
+}
+
+// DryRun makes Publish only validate the request on the server and preview the change,
+// without publishing anything.
+func DryRun() PublishOption {
+	return func(o *publishOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithIdempotencyKey sets the key identifying the publish, so that automation can safely
+// retry it: the server publishes requests with the same key only once. Retries should
+// also set the same version with WithVersion.
+func WithIdempotencyKey(key string) PublishOption {
+	return func(o *publishOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// Encrypted encrypts the published payload end to end, as the server encrypts figs: with
+// a fresh data key, wrapped (RFC 3394) with the namespace key, using AES-GCM. The client
+// must be configured with an encryption private key for the namespace.
+func Encrypted() PublishOption {
+	return func(o *publishOptions) {
+		o.encrypt = true
+	}
+}
+
+// PublishResult describes the outcome of Publish.
+type PublishResult struct {
+	// Version is the ID of the published version.
+	Version string
+	// DryRun is set when nothing was published.
+	DryRun bool
+	// Problems are the server's validation findings. Publish fails if there are any,
+	// unless it is a dry run.
+	Problems []model.PublishProblem
+	// Preview is how the publish changes the family, compared to the family in the
+	// client's store.
+	Preview FamilyChange
+	// PendingApproval is set when the version only takes effect once approved, and
+	// ApprovalID identifies the pending approval.
+	PendingApproval bool
+	ApprovalID      string
+}
+
+// Publish creates a new version of the fig at key with value, e.g. from provisioning
+// tools and migration scripts, and describes the outcome. The rules and default version of the
+// family are kept unless WithRules or AsDefault are given. Publishing requires
+// admin-scoped credentials. The client sees the new version once it polls the update,
+// like any other. Use DryRun to validate and preview a publish first.
+func (c *Client) Publish(ctx context.Context, key string, value AvroRecord, opts ...PublishOption) (*PublishResult, error) {
+	namespace, key, err := c.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var o publishOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	schema, err := avro.Parse(value.Schema())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema of value: %w", err)
+	}
+	var v any = value
+	if generic, ok := value.(*GenericRecord); ok {
+		v = generic.Value
+	}
+	payload, err := c.codec.Marshal(schema, v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal avro: %w", err)
+	}
+	req := &model.PublishRequest{Namespace: namespace, Key: key, Payload: payload}
+	if o.encrypt {
+		if err := c.encryptPublish(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return c.publish(ctx, req, o)
+}
+
+// encryptPublish encrypts the payload of req with the namespace key.
+func (c *Client) encryptPublish(ctx context.Context, req *model.PublishRequest) error {
+	svc := c.encryptionFor(req.Namespace)
+	if svc == nil {
+		return fmt.Errorf("publishing encrypted figs to namespace %s requires an encryption private key", req.Namespace)
+	}
+	fig, err := svc.Encrypt(ctx, req.Namespace, req.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt fig: %w", err)
+	}
+	req.Payload = fig.Payload
+	req.IsEncrypted = true
+	req.WrappedDek = fig.WrappedDek
+	if fig.KeyID != nil {
+		req.KeyID = *fig.KeyID
+	}
+	return nil
+}
+
+// publish completes req, whose payload is set, according to o and publishes it.
+func (c *Client) publish(ctx context.Context, req *model.PublishRequest, o publishOptions) (*PublishResult, error) {
+	req.EnvironmentID = c.cfg.EnvironmentID
+	req.Version = o.version
+	req.DryRun = o.dryRun
+	req.IdempotencyKey = o.idempotencyKey
+	if req.Version == "" {
+		req.Version = newVersionID()
+	}
+	if o.rules != nil {
+		req.Rules = o.rules
+		for i := range req.Rules {
+			if req.Rules[i].TargetVersion == "" {
+				req.Rules[i].TargetVersion = req.Version
+			}
+		}
+	}
+	if o.asDefault {
+		req.DefaultVersion = &req.Version
+	}
+
+	resp, err := c.transport.PublishFig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result := &PublishResult{
+		Version:         resp.Version,
+		DryRun:          o.dryRun,
+		Problems:        resp.Problems,
+		Preview:         c.previewPublish(req),
+		PendingApproval: resp.ApprovalRequired,
+		ApprovalID:      resp.ApprovalID,
+	}
+	if len(resp.Problems) > 0 && !o.dryRun {
+		return result, fmt.Errorf("publish %s/%s rejected: %s", req.Namespace, req.Key, resp.Problems[0].Message)
+	}
+	return result, nil
+}
+
+// previewPublish diffs the stored family of req against the family req would result in.
+func (c *Client) previewPublish(req *model.PublishRequest) FamilyChange {
+	c.mu.RLock()
+	current, _ := c.store.Get(req.Namespace, req.Key)
+	c.mu.RUnlock()
+	next := model.FigFamily{Definition: model.FigDefinition{Namespace: req.Namespace, Key: req.Key}}
+	if current != nil {
+		next = *current
+		next.Figs = slices.DeleteFunc(slices.Clone(current.Figs), func(f model.Fig) bool {
+			return f.Version == req.Version
+		})
+	}
+	next.Figs = append(next.Figs, model.Fig{Version: req.Version, Payload: req.Payload})
+	if req.Rules != nil {
+		next.Rules = req.Rules
+	}
+	if req.DefaultVersion != nil {
+		next.DefaultVersion = req.DefaultVersion
+	}
+	change, _ := model.DiffFamilies(current, &next)
+	return change
+}
+
+// newVersionID returns a random (version 4) UUID.
+func newVersionID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/figchain/go-client/pkg/encryption"
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/metrics"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// QuarantinedFamily describes a fig family update the client rejected. The previous
+// version of the family, if any, keeps being served.
+type QuarantinedFamily struct {
+	Namespace     string    `json:"namespace"`
+	Key           string    `json:"key"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	Reason        string    `json:"reason"`
+	QuarantinedAt time.Time `json:"quarantinedAt"`
+}
+
+// quarantine holds the latest rejected update of each family.
+type quarantine struct {
+	mu       sync.Mutex
+	families map[string]QuarantinedFamily
+}
+
+// checkFamily reports why ff can't be served: it is invalid, one of its figs can't be
+// decrypted, a payload doesn't decode with the schema of a listener for the key, or it
+// fails a validator registered for the key.
+func (c *Client) checkFamily(ff *model.FigFamily) error {
+	if err := ff.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	schema := c.schemas[ff.Definition.Key]
+	validators := c.validators[ff.Definition.Key]
+	if c.cfg.KeyRouting {
+		validators = append(slices.Clip(validators), c.validators[ff.Definition.Namespace+"/"+ff.Definition.Key]...)
+	}
+	c.mu.RUnlock()
+	for i := range ff.Figs {
+		fig := &ff.Figs[i]
+		if !fig.IsEncrypted && schema == nil && len(validators) == 0 {
+			continue
+		}
+		payload := fig.Payload
+		if fig.IsEncrypted {
+			svc := c.encryptionFor(ff.Definition.Namespace)
+			if svc == nil {
+				return fmt.Errorf("fig %s is encrypted but the client is not configured for decryption", fig.Version)
+			}
+			p, err := svc.Decrypt(c.ctx, fig, ff.Definition.Namespace)
+			if err != nil {
+				c.stats.decryptFailures.Add(1)
+				return fmt.Errorf("failed to decrypt fig %s: %w", fig.Version, err)
+			}
+			payload = p
+		}
+		var err error
+		if schema != nil {
+			var v any
+			if err = c.codec.Unmarshal(schema, payload, &v); err != nil {
+				err = fmt.Errorf("failed to decode fig %s: %w", fig.Version, err)
+			}
+		}
+		for _, validate := range validators {
+			if err != nil {
+				break
+			}
+			if err = validate(payload); err != nil {
+				err = fmt.Errorf("fig %s failed validation: %w", fig.Version, err)
+			}
+		}
+		if fig.IsEncrypted {
+			encryption.Zero(payload)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// admit returns the families that pass checkFamily, quarantining the others, and checks
+// their schemas' compatibility with registered types. Admitted families are precompiled
+// if the evaluator supports it. Checks may decrypt payloads, so admit must not be called
+// with c.mu held.
+func (c *Client) admit(families []model.FigFamily) []model.FigFamily {
+	admitted := make([]model.FigFamily, 0, len(families))
+	for _, ff := range families {
+		id := ff.Definition.Namespace + "/" + ff.Definition.Key
+		c.checkCompatibility(&ff)
+		err := c.checkFamily(&ff)
+		c.quarantine.mu.Lock()
+		if err == nil {
+			delete(c.quarantine.families, id)
+		} else {
+			c.quarantine.families[id] = QuarantinedFamily{
+				Namespace:     ff.Definition.Namespace,
+				Key:           ff.Definition.Key,
+				UpdatedAt:     ff.Definition.UpdatedAt,
+				Reason:        err.Error(),
+				QuarantinedAt: c.clock.Now(),
+			}
+		}
+		c.quarantine.mu.Unlock()
+
+		if err == nil {
+			if compiler, ok := c.evaluator.(evaluation.Precompiler); ok {
+				compiler.Precompile(&ff)
+			}
+			admitted = append(admitted, ff)
+			continue
+		}
+		logging.Printf("Quarantined family %s: %v", id, err)
+		c.metrics.IncCounter(metrics.UpdatesIgnored, map[string]string{
+			"namespace": ff.Definition.Namespace,
+			"key":       ff.Definition.Key,
+			"reason":    "quarantined",
+		})
+		c.emit(event.Event{
+			Type:      event.FamilyQuarantined,
+			Namespace: ff.Definition.Namespace,
+			Key:       ff.Definition.Key,
+			Message:   "update quarantined, keeping the last good version",
+			Err:       err,
+		})
+	}
+	return admitted
+}
+
+// Quarantined returns the families whose latest update was rejected, ordered by namespace
+// and key. A family leaves quarantine when a good update for it is applied.
+func (c *Client) Quarantined() []QuarantinedFamily {
+	c.quarantine.mu.Lock()
+	defer c.quarantine.mu.Unlock()
+	families := make([]QuarantinedFamily, 0, len(c.quarantine.families))
+	for _, q := range c.quarantine.families {
+		families = append(families, q)
+	}
+	slices.SortFunc(families, func(a, b QuarantinedFamily) int {
+		if n := strings.Compare(a.Namespace, b.Namespace); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return families
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/figchain/go-client/pkg/bootstrap"
+)
+
+// ErrNotBootstrapped is returned, with config.WithRequireSync, by reads of a namespace
+// that hasn't synced with the server yet.
+var ErrNotBootstrapped = errors.New("namespace not bootstrapped")
+
+// initSync records which namespaces the bootstrap result synced: those fetched from the
+// server, rather than the local cache or vault, and all of them when the client is frozen.
+func (c *Client) initSync(result *bootstrap.Result) {
+	stale := result.Source == bootstrap.SourceCache || result.Source == bootstrap.SourceVault
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ns := range c.cfg.Namespaces {
+		ch := make(chan struct{})
+		if _, ok := result.Cursors[ns]; c.cfg.Frozen || (ok && !stale) {
+			close(ch)
+		}
+		c.synced[ns] = ch
+	}
+}
+
+// markSynced records that namespace is in sync with the server.
+func (c *Client) markSynced(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch, ok := c.synced[namespace]
+	if !ok {
+		return
+	}
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+}
+
+// awaitSync returns nil once namespace is in sync, with config.WithRequireSync. It waits
+// up to the configured SyncWait, or until ctx is done, before failing with
+// ErrNotBootstrapped.
+func (c *Client) awaitSync(ctx context.Context, namespace string) error {
+	if !c.cfg.RequireSync {
+		return nil
+	}
+	c.mu.RLock()
+	ch, ok := c.synced[namespace]
+	c.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case <-ch:
+		return nil
+	default:
+	}
+	if c.cfg.SyncWait > 0 {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		timer := c.clock.NewTimer(c.cfg.SyncWait)
+		defer timer.Stop()
+		select {
+		case <-ch:
+			return nil
+		case <-timer.C():
+		case <-ctx.Done():
+		case <-c.closeCh:
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotBootstrapped, namespace)
+}
+
+// WaitForSync blocks until every namespace is in sync with the server, e.g. for readiness
+// probes of clients bootstrapped from the local cache or vault, or until ctx is done.
+func (c *Client) WaitForSync(ctx context.Context) error {
+	c.mu.RLock()
+	channels := make(map[string]chan struct{}, len(c.synced))
+	for ns, ch := range c.synced {
+		channels[ns] = ch
+	}
+	c.mu.RUnlock()
+	for ns, ch := range channels {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s: %w", ErrNotBootstrapped, ns, ctx.Err())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// ErrFrozen is returned by Refresh when the client is frozen (see config.WithFrozen).
+var ErrFrozen = errors.New("client is frozen")
+
+// Refresh immediately fetches and applies the updates of the given namespaces, or of all
+// configured namespaces if none are given, bypassing the polling interval. It returns the
+// families that changed; listeners and watchers are notified as for polled updates.
+//
+// Refresh is useful after an out-of-band notification such as a webhook. Namespaces that
+// fail to refresh don't prevent the others from being refreshed; their errors are joined.
+// While updates are paused, the fetched updates are queued and none are returned.
+func (c *Client) Refresh(ctx context.Context, namespaces ...string) ([]model.FigFamily, error) {
+	if c.cfg.Frozen {
+		return nil, ErrFrozen
+	}
+	c.mu.RLock()
+	cursors := make(map[string]string)
+	var errs []error
+	if len(namespaces) == 0 {
+		maps.Copy(cursors, c.namespaceCursors)
+	}
+	for _, ns := range namespaces {
+		cursor, ok := c.namespaceCursors[ns]
+		if !ok {
+			errs = append(errs, fmt.Errorf("namespace %s is not configured", ns))
+			continue
+		}
+		cursors[ns] = cursor
+	}
+	c.mu.RUnlock()
+
+	var changed []model.FigFamily
+	for ns, cursor := range cursors {
+		applied, err := c.fetchUpdates(ctx, ns, cursor, AuditSourceRefresh)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to refresh %s: %w", ns, err))
+			continue
+		}
+		changed = append(changed, applied...)
+	}
+	return changed, errors.Join(errs...)
+}
@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// DefaultRelayPollTimeout is how long the relay holds an update request open when there
+// are no changes.
+const DefaultRelayPollTimeout = 30 * time.Second
+
+// RelayOption configures the handler returned by NewRelayHandler.
+type RelayOption func(*relayHandler)
+
+// WithRelayToken requires requests to carry "Authorization: Bearer <token>". Downstream
+// clients send it when configured with config.WithClientSecret(token).
+func WithRelayToken(token string) RelayOption {
+	return func(h *relayHandler) {
+		h.authorize = func(r *http.Request) bool {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+		}
+	}
+}
+
+// WithRelayAuthorizer sets a custom authorization check, e.g. checking the peer
+// credentials of a Unix socket. Requests it rejects get 401 Unauthorized.
+func WithRelayAuthorizer(authorize func(r *http.Request) bool) RelayOption {
+	return func(h *relayHandler) {
+		h.authorize = authorize
+	}
+}
+
+// WithRelayPollTimeout sets how long update requests wait for changes before returning
+// an empty response.
+func WithRelayPollTimeout(d time.Duration) RelayOption {
+	return func(h *relayHandler) {
+		h.pollTimeout = d
+	}
+}
+
+type relayHandler struct {
+	client      *Client
+	epoch       string // distinguishes the revisions of this handler's cursors from others'
+	authorize   func(r *http.Request) bool
+	pollTimeout time.Duration
+	mux         *http.ServeMux
+	// schemas are the response schemas, by record name, parsed once with a cache of their
+	// own: avro's global schema cache isn't safe for concurrent use
+	schemas    map[string]avro.Schema
+	schemasErr error
+}
+
+// NewRelayHandler returns an HTTP handler serving the client's families to co-located
+// processes over the FigChain data protocol, so that they can point their BaseURL at it
+// instead of each connecting to the server:
+//
+//	POST /data/initial  every family of a namespace
+//	POST /data/updates  families changed since a cursor, long-polling for changes
+//	GET  /data/family   a single family
+//
+// Relay cursors are namespace revisions of the client's store, qualified by an epoch
+// unique to the handler, and are unrelated to server cursors. Key endpoints aren't
+// relayed, so downstream clients of encrypted namespaces still need access to their keys.
+// Every request must be authorized with WithRelayToken or WithRelayAuthorizer; without
+// either, all requests are rejected.
+func NewRelayHandler(c *Client, opts ...RelayOption) http.Handler {
+	h := &relayHandler{
+		client:      c,
+		epoch:       newRelayEpoch(),
+		authorize:   func(*http.Request) bool { return false },
+		pollTimeout: DefaultRelayPollTimeout,
+		mux:         http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.schemas, h.schemasErr = namedSchemas("InitialFetchResponse", "UpdateFetchResponse", "FigFamily")
+	h.mux.HandleFunc("POST /data/initial", h.handleInitial)
+	h.mux.HandleFunc("POST /data/updates", h.handleUpdates)
+	h.mux.HandleFunc("GET /data/family", h.handleFamily)
+	return h
+}
+
+// newRelayEpoch returns a random relay epoch.
+func newRelayEpoch() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// cursor returns the relay cursor of rev.
+func (h *relayHandler) cursor(rev uint64) string {
+	return h.epoch + "." + strconv.FormatUint(rev, 10)
+}
+
+// revision returns the revision of a relay cursor of namespace. Cursors of another epoch,
+// e.g. from before the relay restarted, that don't parse or that are ahead of the store
+// return 0, so that everything is resent; downstream clients ignore the duplicates.
+func (h *relayHandler) revision(namespace, cursor string) uint64 {
+	epoch, r, ok := strings.Cut(cursor, ".")
+	if !ok || epoch != h.epoch {
+		return 0
+	}
+	rev, err := strconv.ParseUint(r, 10, 64)
+	if err != nil || rev > h.client.store.Revision(namespace) {
+		return 0
+	}
+	return rev
+}
+
+func (h *relayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// namedSchemas returns the schemas of the named protocol records, by name.
+func namedSchemas(names ...string) (map[string]avro.Schema, error) {
+	scheme, err := avro.ParseWithCache(model.Schema, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	schemas := make(map[string]avro.Schema, len(names))
+	for _, name := range names {
+		schemas[name] = scheme
+		if union, ok := scheme.(*avro.UnionSchema); ok {
+			for _, s := range union.Types() {
+				if ns, ok := s.(avro.NamedSchema); ok && ns.Name() == name {
+					schemas[name] = s
+				}
+			}
+		}
+	}
+	return schemas, nil
+}
+
+// decodeRequest decodes an OCF-encoded request body into v.
+func (h *relayHandler) decodeRequest(r *http.Request, v any) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+	// Parse the writer schema with a cache of its own, holding the protocol's types
+	cache := &avro.SchemaCache{}
+	if _, err := avro.ParseWithCache(model.Schema, "", cache); err != nil {
+		return fmt.Errorf("failed to parse schema: %w", err)
+	}
+	dec, err := ocf.NewDecoder(bytes.NewReader(body), ocf.WithDecoderSchemaCache(cache))
+	if err != nil {
+		return fmt.Errorf("failed to create OCF decoder: %w", err)
+	}
+	if !dec.HasNext() {
+		return fmt.Errorf("empty request")
+	}
+	return dec.Decode(v)
+}
+
+// writeOCF writes v OCF-encoded with the named schema.
+func (h *relayHandler) writeOCF(w http.ResponseWriter, name string, v any) {
+	var buf bytes.Buffer
+	var enc *ocf.Encoder
+	err := h.schemasErr
+	if err == nil {
+		enc, err = ocf.NewEncoderWithSchema(h.schemas[name], &buf)
+	}
+	if err == nil {
+		err = enc.Encode(v)
+	}
+	if err == nil {
+		err = enc.Flush()
+	}
+	if err != nil {
+		logging.Printf("Relay: failed to encode %s: %v", name, err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(buf.Bytes())
+}
+
+// checkNamespace reports whether the request targets a namespace and environment the
+// client serves, writing an error response if not.
+func (h *relayHandler) checkNamespace(w http.ResponseWriter, namespace, environmentID string) bool {
+	if environmentID != "" && environmentID != h.client.cfg.EnvironmentID {
+		http.Error(w, "unknown environment", http.StatusNotFound)
+		return false
+	}
+	if !slices.Contains(h.client.cfg.Namespaces, namespace) {
+		http.Error(w, "unknown namespace", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func (h *relayHandler) handleInitial(w http.ResponseWriter, r *http.Request) {
+	var req model.InitialFetchRequest
+	if err := h.decodeRequest(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.checkNamespace(w, req.Namespace, req.EnvironmentID) {
+		return
+	}
+	if req.AsOfTimestamp != nil {
+		http.Error(w, "as-of fetches are not supported by the relay", http.StatusBadRequest)
+		return
+	}
+	families, rev := h.client.store.ChangedSince(req.Namespace, 0)
+	h.writeOCF(w, "InitialFetchResponse", &model.InitialFetchResponse{
+		FigFamilies:   families,
+		Cursor:        h.cursor(rev),
+		EnvironmentID: h.client.cfg.EnvironmentID,
+	})
+}
+
+func (h *relayHandler) handleUpdates(w http.ResponseWriter, r *http.Request) {
+	var req model.UpdateFetchRequest
+	if err := h.decodeRequest(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.checkNamespace(w, req.Namespace, req.EnvironmentID) {
+		return
+	}
+
+	rev := h.revision(req.Namespace, req.Cursor)
+
+	timeout := time.NewTimer(h.pollTimeout)
+	defer timeout.Stop()
+	for {
+		// Take the broadcast channel before reading so no update is missed in between
+		h.client.mu.RLock()
+		updated := h.client.updated
+		h.client.mu.RUnlock()
+
+		families, current := h.client.store.ChangedSince(req.Namespace, rev)
+		if len(families) > 0 {
+			rev = current
+			h.writeOCF(w, "UpdateFetchResponse", &model.UpdateFetchResponse{
+				FigFamilies: families,
+				Cursor:      h.cursor(rev),
+			})
+			return
+		}
+
+		select {
+		case <-updated:
+		case <-timeout.C:
+			h.writeOCF(w, "UpdateFetchResponse", &model.UpdateFetchResponse{Cursor: h.cursor(rev)})
+			return
+		case <-r.Context().Done():
+			return
+		case <-h.client.closeCh:
+			http.Error(w, "relay closed", http.StatusServiceUnavailable)
+			return
+		}
+	}
+}
+
+func (h *relayHandler) handleFamily(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	namespace, key := q.Get("namespace"), q.Get("key")
+	if !h.checkNamespace(w, namespace, q.Get("environmentId")) {
+		return
+	}
+	ff, ok := h.client.getFamily(namespace, key)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h.writeOCF(w, "FigFamily", ff)
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/figchain/go-client/pkg/encryption"
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/transport"
+	"github.com/figchain/go-client/pkg/util"
+)
+
+// credentialFile is a credential file that reloadLoop reloads when its contents change.
+type credentialFile struct {
+	path   string
+	digest [sha256.Size]byte
+	reload func() error
+}
+
+// readClientSecret reads the client secret from the file at path.
+func readClientSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read client secret: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("client secret file %s is empty", path)
+	}
+	logging.RegisterSecret(secret)
+	return secret, nil
+}
+
+// credentialFiles returns the configured credential files, with the digests of the
+// contents they were loaded with.
+func (c *Client) credentialFiles() []*credentialFile {
+	var files []*credentialFile
+	add := func(path string, reload func() error) {
+		f := &credentialFile{path: path, reload: reload}
+		if data, err := os.ReadFile(path); err == nil {
+			f.digest = sha256.Sum256(data)
+			clear(data)
+		}
+		files = append(files, f)
+	}
+
+	switch {
+	case c.cfg.AuthPrivateKeyPath != "":
+		add(c.cfg.AuthPrivateKeyPath, func() error {
+			key, err := util.LoadRSAPrivateKey(c.cfg.AuthPrivateKeyPath)
+			if err != nil {
+				return err
+			}
+			return c.SwapAuthKey(key)
+		})
+	case c.cfg.ClientSecretFile != "":
+		add(c.cfg.ClientSecretFile, func() error {
+			secret, err := readClientSecret(c.cfg.ClientSecretFile)
+			if err != nil {
+				return err
+			}
+			c.tokens.Swap(transport.NewSharedSecretTokenProvider(secret))
+			return nil
+		})
+	}
+
+	// Services sharing a key file are reloaded together
+	services := make(map[string][]*encryption.Service)
+	if c.encryptionService != nil {
+		services[c.cfg.EncryptionPrivateKeyPath] = append(services[c.cfg.EncryptionPrivateKeyPath], c.encryptionService)
+	}
+	for ns, svc := range c.nsEncryption {
+		path := c.cfg.Profile(ns).EncryptionPrivateKeyPath
+		services[path] = append(services[path], svc)
+	}
+	for path, svcs := range services {
+		add(path, func() error {
+			key, err := encryption.LoadPrivateKey(path)
+			if err != nil {
+				return err
+			}
+			for _, svc := range svcs {
+				svc.SwapPrivateKey(key)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// reloadLoop reloads the credential files whose contents changed every
+// CredentialReloadInterval until the client is closed.
+func (c *Client) reloadLoop(files []*credentialFile) {
+	defer c.wg.Done()
+	ticker := c.clock.NewTicker(c.cfg.CredentialReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case <-ticker.C():
+			for _, f := range files {
+				c.reloadCredentialFile(f)
+			}
+		}
+	}
+}
+
+// reloadCredentialFile reloads f if its contents changed since it was last loaded. A file
+// that fails to load isn't retried until it changes again, e.g. once it is fully written.
+func (c *Client) reloadCredentialFile(f *credentialFile) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		// Rotation may replace the file non-atomically; it is checked again next time
+		return
+	}
+	digest := sha256.Sum256(data)
+	clear(data)
+	if digest == f.digest {
+		return
+	}
+	f.digest = digest
+	if err := f.reload(); err != nil {
+		logging.Printf("Failed to reload credentials from %s: %v", f.path, err)
+		c.emit(event.Event{Type: event.CredentialReloadFailed, Message: "failed to reload " + f.path, Err: err})
+		return
+	}
+	logging.Printf("Reloaded credentials from %s", f.path)
+	c.emit(event.Event{Type: event.CredentialsReloaded, Message: "reloaded " + f.path})
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/figchain/go-client/pkg/config"
+)
+
+// rolloutDelay returns how long the instance delays updates in a staged rollout: a
+// deterministic offset within the window, spreading a fleet's instances evenly over it.
+func rolloutDelay(cfg *config.Config) (time.Duration, error) {
+	if cfg.RolloutWindow <= 0 {
+		return 0, nil
+	}
+	id := cfg.InstanceID
+	if id == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return 0, fmt.Errorf("staged rollout requires an instance ID: %w", err)
+		}
+		id = hostname
+	}
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return time.Duration(h.Sum64() % uint64(cfg.RolloutWindow)), nil
+}
+
+// stagedBatch is a batch of updates waiting out the rollout delay.
+type stagedBatch struct {
+	updates []update
+}
+
+// stageUpdates applies updates once the rollout delay has passed. Staged updates are
+// handed off by Handoff, but otherwise dropped when the client is closed; the local
+// cache's cursor is kept before them, so a client restarted from it fetches them again.
+func (c *Client) stageUpdates(updates []update) {
+	batch := &stagedBatch{updates: updates}
+	c.mu.Lock()
+	c.staged = append(c.staged, batch)
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		timer := c.clock.NewTimer(c.rolloutDelay)
+		defer timer.Stop()
+		select {
+		case <-c.closeCh:
+			return
+		case <-timer.C():
+		}
+
+		c.mu.Lock()
+		defer c.unlock()
+		c.staged = slices.DeleteFunc(c.staged, func(b *stagedBatch) bool { return b == batch })
+		if c.paused {
+			c.pending = append(c.pending, updates...)
+			return
+		}
+		// Saved together with the staged families
+		c.saveKeptCursors()
+		c.applyLocked(updates)
+	}()
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// resolveKey returns the namespace and key addressed by key. Keys are in the first
+// namespace, unless config.WithKeyRouting is set: then namespace/key addresses a key in a
+// configured namespace, and an unqualified key is in the first namespace holding it.
+func (c *Client) resolveKey(key string) (namespace, name string, err error) {
+	if len(c.cfg.Namespaces) == 0 {
+		return "", "", fmt.Errorf("no namespaces configured")
+	}
+	if !c.cfg.KeyRouting {
+		return c.cfg.Namespaces[0], key, nil
+	}
+	if ns, name, ok := strings.Cut(key, "/"); ok && slices.Contains(c.cfg.Namespaces, ns) {
+		return ns, name, nil
+	}
+	for _, ns := range c.cfg.Namespaces {
+		if _, ok := c.store.Get(ns, key); ok {
+			return ns, key, nil
+		}
+	}
+	return c.cfg.Namespaces[0], key, nil
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/figchain/go-client/pkg/clock"
+	"github.com/figchain/go-client/pkg/config"
+)
+
+// maxQuietSkips bounds how many scheduled times next skips for falling in quiet hours.
+const maxQuietSkips = 1000
+
+// pollSchedule decides when the poll loop polls, see config.WithPollSchedule and
+// config.WithQuietHours.
+type pollSchedule struct {
+	cron                 cron.Schedule // nil polls continuously
+	quiet                bool
+	quietStart, quietEnd time.Duration // time of day
+}
+
+// newPollSchedule returns the schedule configured in cfg, or nil if updates are polled
+// continuously.
+func newPollSchedule(cfg *config.Config) (*pollSchedule, error) {
+	if cfg.PollSchedule == "" && cfg.QuietHoursStart == "" && cfg.QuietHoursEnd == "" {
+		return nil, nil
+	}
+	s := &pollSchedule{}
+	if cfg.PollSchedule != "" {
+		sched, err := cron.ParseStandard(cfg.PollSchedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid poll schedule %q: %w", cfg.PollSchedule, err)
+		}
+		s.cron = sched
+	}
+	if cfg.QuietHoursStart != "" || cfg.QuietHoursEnd != "" {
+		start, err := parseTimeOfDay(cfg.QuietHoursStart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours start: %w", err)
+		}
+		end, err := parseTimeOfDay(cfg.QuietHoursEnd)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours end: %w", err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("quiet hours start and end must differ")
+		}
+		s.quiet, s.quietStart, s.quietEnd = true, start, end
+	}
+	if s.next(clock.OrReal(cfg.Clock).Now()).IsZero() {
+		return nil, fmt.Errorf("poll schedule %q never runs outside quiet hours", cfg.PollSchedule)
+	}
+	return s, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day (15:04)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// next returns when to poll after now, or the zero time if never.
+func (s *pollSchedule) next(now time.Time) time.Time {
+	t := now
+	if s.cron != nil {
+		t = s.cron.Next(now)
+	}
+	for range maxQuietSkips {
+		if t.IsZero() {
+			return t
+		}
+		end, ok := s.quietUntil(t)
+		if !ok {
+			return t
+		}
+		if s.cron == nil {
+			return end
+		}
+		// Next is strictly after its argument, so step back to include end itself
+		t = s.cron.Next(end.Add(-time.Nanosecond))
+	}
+	return time.Time{}
+}
+
+// quietUntil reports whether t falls in quiet hours and, if so, when they end.
+func (s *pollSchedule) quietUntil(t time.Time) (time.Time, bool) {
+	if !s.quiet {
+		return time.Time{}, false
+	}
+	start, end := timeOfDay(t, s.quietStart), timeOfDay(t, s.quietEnd)
+	switch {
+	case s.quietStart < s.quietEnd:
+		return end, !t.Before(start) && t.Before(end)
+	case !t.Before(start):
+		// The window spans midnight and t is before midnight
+		return end.AddDate(0, 0, 1), true
+	default:
+		return end, t.Before(end)
+	}
+}
+
+// timeOfDay returns the time d after midnight on t's day, in t's location.
+func timeOfDay(t time.Time, d time.Duration) time.Time {
+	y, m, day := t.Date()
+	return time.Date(y, m, day, int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// waitForSchedule blocks until the next scheduled poll. It returns false if the client
+// was closed first.
+func (c *Client) waitForSchedule() bool {
+	next := c.schedule.next(c.clock.Now())
+	if next.IsZero() {
+		<-c.closeCh
+		return false
+	}
+	timer := c.clock.NewTimer(next.Sub(c.clock.Now()))
+	defer timer.Stop()
+	select {
+	case <-c.closeCh:
+		return false
+	case <-timer.C():
+		return true
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/figchain/go-client/pkg/config"
+)
+
+func TestPollSchedule(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		cron      string
+		quiet     [2]string
+		now, want time.Time
+	}{
+		{"cron", "0 */6 * * *", [2]string{}, at(1, 7, 30), at(1, 12, 0)},
+		{"cron on a scheduled time", "0 */6 * * *", [2]string{}, at(1, 12, 0), at(1, 18, 0)},
+		{"quiet hours outside window", "", [2]string{"01:00", "05:00"}, at(1, 7, 30), at(1, 7, 30)},
+		{"quiet hours inside window", "", [2]string{"01:00", "05:00"}, at(1, 2, 0), at(1, 5, 0)},
+		{"overnight quiet hours before midnight", "", [2]string{"22:00", "06:00"}, at(1, 23, 0), at(2, 6, 0)},
+		{"overnight quiet hours after midnight", "", [2]string{"22:00", "06:00"}, at(2, 1, 0), at(2, 6, 0)},
+		{"overnight quiet hours at their end", "", [2]string{"22:00", "06:00"}, at(2, 6, 0), at(2, 6, 0)},
+		{"cron outside quiet hours", "0 * * * *", [2]string{"22:00", "06:00"}, at(1, 20, 30), at(1, 21, 0)},
+		{"cron skips quiet hours", "0 * * * *", [2]string{"22:00", "06:00"}, at(1, 21, 30), at(2, 6, 0)},
+		{"cron after quiet hours end", "30 */4 * * *", [2]string{"22:00", "06:00"}, at(1, 21, 0), at(2, 8, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newPollSchedule(&config.Config{
+				PollSchedule:    tt.cron,
+				QuietHoursStart: tt.quiet[0],
+				QuietHoursEnd:   tt.quiet[1],
+			})
+			if err != nil {
+				t.Fatalf("newPollSchedule failed: %v", err)
+			}
+			if got := s.next(tt.now); !got.Equal(tt.want) {
+				t.Errorf("next(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unscheduled", func(t *testing.T) {
+		s, err := newPollSchedule(config.DefaultConfig())
+		if err != nil || s != nil {
+			t.Errorf("newPollSchedule = %v, %v; want nil, nil", s, err)
+		}
+	})
+
+	invalid := map[string]*config.Config{
+		"bad cron":          {PollSchedule: "every hour"},
+		"bad time of day":   {QuietHoursStart: "25:00", QuietHoursEnd: "06:00"},
+		"missing end":       {QuietHoursStart: "22:00"},
+		"empty window":      {QuietHoursStart: "22:00", QuietHoursEnd: "22:00"},
+		"always quiet cron": {PollSchedule: "0 23 * * *", QuietHoursStart: "22:00", QuietHoursEnd: "06:00"},
+	}
+	for name, cfg := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newPollSchedule(cfg); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
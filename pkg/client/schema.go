@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"slices"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// Families returns the families the client holds for the given namespaces, or for all
+// configured namespaces if none are given. With a bounded store, only the families
+// currently held are returned.
+func (c *Client) Families(namespaces ...string) []model.FigFamily {
+	return slices.Collect(c.All(namespaces...))
+}
+
+// All returns an iterator over the families the client holds for the given namespaces,
+// or for all configured namespaces if none are given. Unlike Families, it doesn't copy
+// the store, so large stores can be scanned in constant memory.
+func (c *Client) All(namespaces ...string) iter.Seq[model.FigFamily] {
+	if len(namespaces) == 0 {
+		namespaces = c.cfg.Namespaces
+	}
+	return func(yield func(model.FigFamily) bool) {
+		for _, ns := range namespaces {
+			for ff := range store.All(c.store, ns) {
+				if !yield(*ff) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the namespaces and keys of the families the client holds
+// for the given namespaces, or for all configured namespaces if none are given.
+func (c *Client) Keys(namespaces ...string) iter.Seq2[string, string] {
+	if len(namespaces) == 0 {
+		namespaces = c.cfg.Namespaces
+	}
+	return func(yield func(namespace, key string) bool) {
+		for _, ns := range namespaces {
+			for key := range store.Keys(c.store, ns) {
+				if !yield(ns, key) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// FetchSchema fetches the Avro schema at a fig definition's SchemaURI, e.g. to generate
+// AvroRecord implementations with the codegen package.
+func (c *Client) FetchSchema(ctx context.Context, uri string) (string, error) {
+	return c.transport.FetchSchema(ctx, uri)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/source"
+)
+
+// consumeSource applies updates from a message-bus source until the client is closed.
+func (c *Client) consumeSource(src source.UpdateSource) {
+	defer c.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		u, err := src.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, source.ErrClosed) {
+				return
+			}
+			logging.Printf("Failed to receive update from source: %v", err)
+			if !c.sleep(c.cfg.PollingInterval) {
+				return
+			}
+			continue
+		}
+
+		c.applySourceUpdate(ctx, u)
+		if u.Ack != nil {
+			if err := u.Ack(ctx); err != nil {
+				logging.Printf("Failed to acknowledge update for %s: %v", u.Namespace, err)
+			}
+		}
+	}
+}
+
+// applySourceUpdate applies an update received from a bus. Updates that don't follow the
+// namespace's current cursor indicate a gap, e.g. a lost message, and are reconciled by
+// refreshing the namespace from the server instead.
+func (c *Client) applySourceUpdate(ctx context.Context, u *source.Update) {
+	c.mu.RLock()
+	cursor, ok := c.namespaceCursors[u.Namespace]
+	c.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	if u.PrevCursor != "" && u.PrevCursor != cursor {
+		if u.Cursor == cursor {
+			// Already applied, e.g. by the poll loop
+			return
+		}
+		logging.Printf("Gap in updates for %s (at %s, update follows %s), refreshing", u.Namespace, cursor, u.PrevCursor)
+		if _, err := c.Refresh(ctx, u.Namespace); err != nil {
+			logging.Printf("Failed to reconcile %s: %v", u.Namespace, err)
+		}
+		return
+	}
+
+	if len(u.FigFamilies) > 0 {
+		c.applyUpdates(u.FigFamilies, AuditSourceBus, u.Cursor)
+	}
+	// Without a previous cursor the update can't be placed, so the cursor only advances
+	// for updates known to follow it.
+	if u.PrevCursor != "" && u.Cursor != "" {
+		c.mu.Lock()
+		if c.namespaceCursors[u.Namespace] == cursor {
+			c.namespaceCursors[u.Namespace] = u.Cursor
+			c.saveCursor(u.Namespace, u.Cursor)
+		}
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"io"
+	"maps"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats are counters describing the activity of a client since it started, for embedding
+// in an application's own metrics without adopting a metrics.Recorder.
+type Stats struct {
+	// Polls is the number of update fetches, by the poll loop or Refresh, that completed
+	// or failed. Fetches cancelled by Close are not counted.
+	Polls uint64 `json:"polls"`
+	// UpdatesApplied is the number of families updated in the store.
+	UpdatesApplied uint64 `json:"updatesApplied"`
+	// Evaluations is the number of rule evaluations, by GetFig and listeners, by
+	// "namespace/key".
+	Evaluations map[string]uint64 `json:"evaluations"`
+	// DecryptFailures is the number of encrypted figs that couldn't be decrypted.
+	DecryptFailures uint64 `json:"decryptFailures"`
+	// BytesSent and BytesReceived count the HTTP request and response bodies exchanged
+	// with the server.
+	BytesSent     uint64 `json:"bytesSent"`
+	BytesReceived uint64 `json:"bytesReceived"`
+	// Cursors is the current cursor of each namespace.
+	Cursors map[string]string `json:"cursors"`
+}
+
+// clientStats holds the counters reported by Stats. They are updated without locking,
+// since some are on the GetFig path.
+type clientStats struct {
+	polls           atomic.Uint64
+	updatesApplied  atomic.Uint64
+	evaluations     sync.Map // "namespace/key" -> *atomic.Uint64
+	decryptFailures atomic.Uint64
+	bytesSent       atomic.Uint64
+	bytesReceived   atomic.Uint64
+}
+
+// evaluated counts an evaluation of key.
+func (s *clientStats) evaluated(namespace, key string) {
+	id := namespace + "/" + key
+	v, ok := s.evaluations.Load(id)
+	if !ok {
+		v, _ = s.evaluations.LoadOrStore(id, new(atomic.Uint64))
+	}
+	v.(*atomic.Uint64).Add(1)
+}
+
+// Stats returns the client's counters.
+func (c *Client) Stats() Stats {
+	s := Stats{
+		Polls:           c.stats.polls.Load(),
+		UpdatesApplied:  c.stats.updatesApplied.Load(),
+		Evaluations:     make(map[string]uint64),
+		DecryptFailures: c.stats.decryptFailures.Load(),
+		BytesSent:       c.stats.bytesSent.Load(),
+		BytesReceived:   c.stats.bytesReceived.Load(),
+	}
+	c.stats.evaluations.Range(func(k, v any) bool {
+		s.Evaluations[k.(string)] = v.(*atomic.Uint64).Load()
+		return true
+	})
+	c.mu.RLock()
+	s.Cursors = maps.Clone(c.namespaceCursors)
+	c.mu.RUnlock()
+	return s
+}
+
+// countingClient returns a copy of hc counting the bytes of request and response bodies
+// in stats.
+func countingClient(hc *http.Client, stats *clientStats) *http.Client {
+	counted := *hc
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	counted.Transport = &countingRoundTripper{base: base, stats: stats}
+	return &counted
+}
+
+// countingRoundTripper counts the bytes of the bodies it sends and receives.
+type countingRoundTripper struct {
+	base  http.RoundTripper
+	stats *clientStats
+}
+
+func (t *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		// RoundTrip must not modify req, so the body is counted on a shallow copy
+		counted := *req
+		counted.Body = &countingBody{ReadCloser: req.Body, n: &t.stats.bytesSent}
+		req = &counted
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, n: &t.stats.bytesReceived}
+	return resp, nil
+}
+
+// countingBody adds the bytes read from a body to n.
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Uint64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(uint64(n))
+	return n, err
+}
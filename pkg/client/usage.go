@@ -0,0 +1,113 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// keyUsage counts the reads of a key. Reads are counted without locking, since they are
+// on the GetFig path.
+type keyUsage struct {
+	reads    atomic.Uint64
+	reported atomic.Uint64 // reads included in a successful report
+	lastRead atomic.Int64  // Unix nanoseconds
+}
+
+// usageTracker tracks the keys read, by namespace and key.
+type usageTracker struct {
+	keys sync.Map // [2]string -> *keyUsage
+}
+
+// record counts a read of key at now.
+func (u *usageTracker) record(namespace, key string, now time.Time) {
+	v, ok := u.keys.Load([2]string{namespace, key})
+	if !ok {
+		v, _ = u.keys.LoadOrStore([2]string{namespace, key}, &keyUsage{})
+	}
+	ku := v.(*keyUsage)
+	ku.reads.Add(1)
+	ku.lastRead.Store(now.UnixNano())
+}
+
+// snapshot returns the usage of every key read, with the reads since the last report if
+// sinceReport, and the reads it counts by key.
+func (u *usageTracker) snapshot(sinceReport bool) ([]model.KeyUsage, map[[2]string]uint64) {
+	var usage []model.KeyUsage
+	counted := make(map[[2]string]uint64)
+	u.keys.Range(func(k, v any) bool {
+		id, ku := k.([2]string), v.(*keyUsage)
+		reads := ku.reads.Load()
+		counted[id] = reads
+		if sinceReport {
+			reads -= ku.reported.Load()
+		}
+		if reads > 0 {
+			usage = append(usage, model.KeyUsage{
+				Namespace: id[0],
+				Key:       id[1],
+				Reads:     reads,
+				LastRead:  time.Unix(0, ku.lastRead.Load()),
+			})
+		}
+		return true
+	})
+	slices.SortFunc(usage, func(a, b model.KeyUsage) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Key, b.Key))
+	})
+	return usage, counted
+}
+
+// Usage returns how often each key was read with GetFig or GetFigVersion since the
+// client started, and when it was last read, ordered by namespace and key.
+func (c *Client) Usage() []model.KeyUsage {
+	usage, _ := c.usage.snapshot(false)
+	return usage
+}
+
+// usageLoop reports usage every UsageReportInterval until the client is closed. Reads
+// since the last report are not reported when the client is closed.
+func (c *Client) usageLoop() {
+	defer c.wg.Done()
+	ticker := c.clock.NewTicker(c.cfg.UsageReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case <-ticker.C():
+			if err := c.reportUsage(c.ctx); err != nil {
+				logging.Printf("Failed to report usage: %v", err)
+			}
+		}
+	}
+}
+
+// reportUsage reports the keys read since the last successful report. Reads that fail to
+// be reported are included in the next report.
+func (c *Client) reportUsage(ctx context.Context) error {
+	usage, counted := c.usage.snapshot(true)
+	if len(usage) == 0 {
+		return nil
+	}
+	err := c.transport.ReportUsage(ctx, &model.UsageReport{
+		EnvironmentID: c.cfg.EnvironmentID,
+		InstanceID:    c.identity.InstanceID,
+		Keys:          usage,
+	})
+	if err != nil {
+		return err
+	}
+	for id, reads := range counted {
+		if v, ok := c.usage.keys.Load(id); ok {
+			v.(*keyUsage).reported.Store(reads)
+		}
+	}
+	return nil
+}
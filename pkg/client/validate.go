@@ -0,0 +1,51 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+)
+
+// validator checks a decrypted fig payload of a key.
+type validator func(payload []byte) error
+
+// RegisterValidator registers a check run on every fig of key's updates, decoded as T,
+// before the update is applied. Updates with a fig that fails to decode or validate are
+// quarantined and the last good version keeps being served (see Client.Quarantined).
+// PT is the pointer type of T implementing AvroRecord, and is inferred, e.g.
+//
+//	client.RegisterValidator(c, "checkout", func(cfg Checkout) error {
+//		if cfg.MaxItems <= 0 {
+//			return errors.New("max_items must be positive")
+//		}
+//		return nil
+//	})
+//
+// A panicking validator rejects the update. Validators don't apply to the version served
+// when they are registered.
+func RegisterValidator[T any, PT interface {
+	*T
+	AvroRecord
+}](c *Client, key string, validate func(T) error) error {
+	schema, err := avro.Parse(PT(new(T)).Schema())
+	if err != nil {
+		return fmt.Errorf("invalid schema for %s: %w", key, err)
+	}
+	v := func(payload []byte) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("validator panicked: %v", r)
+			}
+		}()
+		value := new(T)
+		if err := c.codec.Unmarshal(schema, payload, value); err != nil {
+			return fmt.Errorf("failed to decode: %w", err)
+		}
+		return validate(*value)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validators[key] = append(c.validators[key], v)
+	return nil
+}
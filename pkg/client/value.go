@@ -0,0 +1,50 @@
+package client
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/figchain/go-client/pkg/evaluation"
+)
+
+// Value is the decoded value of a server-scoped key, kept up to date as updates are
+// applied. Get reads it without locking, so it can be called on every request instead
+// of GetFig.
+//
+// Like RegisterListener, Value evaluates the key with an empty context (plus the default
+// attributes); it should only be used for server-scoped configuration.
+type Value[T any] struct {
+	v atomic.Pointer[T]
+}
+
+// NewValue reads key into a Value that refreshes on every update of the key. PT is the
+// pointer type of T implementing AvroRecord, and is inferred, e.g.
+//
+//	timeouts, err := client.NewValue[Timeouts](c, "timeouts")
+//	...
+//	d := timeouts.Get().RequestTimeout
+//
+// It returns an error if key can't be read yet. An update that fails to decode is logged
+// and the previous value kept.
+func NewValue[T any, PT interface {
+	*T
+	AvroRecord
+}](c *Client, key string) (*Value[T], error) {
+	initial := PT(new(T))
+	if err := c.GetFig(key, initial, evaluation.NewEvaluationContext(nil)); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	v := &Value[T]{}
+	v.v.Store((*T)(initial))
+	// The initial value delivered to the listener is the one read above or newer
+	c.RegisterListener(key, PT(new(T)), func(record AvroRecord) {
+		v.v.Store((*T)(record.(PT)))
+	}, WithInitialValue())
+	return v, nil
+}
+
+// Get returns the latest value. Slices, maps and pointers in it are shared by every
+// caller and must be treated as read-only.
+func (v *Value[T]) Get() T {
+	return *v.v.Load()
+}
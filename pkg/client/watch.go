@@ -0,0 +1,373 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/hamba/avro/v2"
+
+	"github.com/figchain/go-client/pkg/encryption"
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/metrics"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// SubscribeOption configures a Watch or RegisterListener subscription.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	initialValue bool
+	figID        string
+	bufferSize   int
+	coalesce     bool
+}
+
+// WithInitialValue delivers the current value of the key immediately upon subscription,
+// if the key exists. The initial value is guaranteed to be delivered before any update
+// applied after it, so no change is missed between reading and subscribing.
+func WithInitialValue() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.initialValue = true
+	}
+}
+
+// WithFigID restricts a Watch to families whose definition has the given fig ID, e.g. to
+// ignore a key that was deleted and re-created as a different fig.
+func WithFigID(figID string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.figID = figID
+	}
+}
+
+// WithBufferSize sets the channel buffer size of a Watch subscription, overriding
+// config.WithWatchBufferSize.
+func WithBufferSize(size int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.bufferSize = size
+	}
+}
+
+// WithCoalesce makes a Watch subscription keep only the latest updates when its channel
+// is full: the oldest pending update is discarded to make room for the new one instead
+// of dropping the new one.
+func WithCoalesce() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.coalesce = true
+	}
+}
+
+// FamilyUpdate is delivered by WatchChanges: a fig family and how it changed.
+type FamilyUpdate struct {
+	Family model.FigFamily
+	// Change describes what the update changed. It is nil for the initial value.
+	Change *FamilyChange
+}
+
+// subscription is a channel subscription created by Watch or WatchChanges.
+type subscription interface {
+	// matches reports whether ff passes the subscription's filters.
+	matches(ff model.FigFamily) bool
+	// deliver sends an update without blocking. It reports whether it was delivered and,
+	// in coalescing mode, whether a pending update was discarded to make room for it.
+	deliver(ff model.FigFamily, change *FamilyChange) (delivered, coalesced bool)
+	// close closes the channel. It may be called more than once. c.mu must be held, so
+	// that it never races with deliver.
+	close()
+	// unregister stops waiting for the subscription's context, reporting whether it was
+	// still waiting.
+	unregister() bool
+}
+
+// watcher is a subscription delivering each update as a T built by wrap.
+type watcher[T any] struct {
+	ch       chan T
+	wrap     func(model.FigFamily, *FamilyChange) T
+	figID    string
+	coalesce bool
+	closed   bool
+	stop     func() bool // stops the context.AfterFunc removing the watcher
+}
+
+func (w *watcher[T]) deliver(ff model.FigFamily, change *FamilyChange) (delivered, coalesced bool) {
+	item := w.wrap(ff, change)
+	select {
+	case w.ch <- item:
+		return true, false
+	default:
+	}
+	if !w.coalesce {
+		return false, false
+	}
+	select {
+	case <-w.ch:
+		coalesced = true
+	default:
+	}
+	select {
+	case w.ch <- item:
+		return true, coalesced
+	default:
+		return false, coalesced
+	}
+}
+
+func (w *watcher[T]) matches(ff model.FigFamily) bool {
+	return w.figID == "" || ff.Definition.FigID == w.figID
+}
+
+func (w *watcher[T]) close() {
+	if !w.closed {
+		w.closed = true
+		close(w.ch)
+	}
+}
+
+func (w *watcher[T]) unregister() bool {
+	return w.stop()
+}
+
+func newSubscribeOptions(opts []SubscribeOption) subscribeOptions {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// currentFamily returns the stored family for key. Callers must hold c.mu so that the
+// result is ordered with respect to updates applied by the poll loop.
+func (c *Client) currentFamily(key string) (model.FigFamily, bool) {
+	namespace, key, err := c.resolveKey(key)
+	if err != nil {
+		return model.FigFamily{}, false
+	}
+	ff, ok := c.store.Get(namespace, key)
+	if !ok {
+		return model.FigFamily{}, false
+	}
+	return *ff, true
+}
+
+// Watch returns a channel that receives updates for a specific key.
+//
+// Updates are sent without blocking, so a slow reader misses updates rather than stalling
+// the client (see WithBufferSize and WithCoalesce). The channel is closed once ctx is done
+// or the client is closed, whichever comes first, and is never sent to after that. Close
+// returns only once every Watch channel is closed, and Watch on a closed client returns a
+// closed channel.
+func (c *Client) Watch(ctx context.Context, key string, opts ...SubscribeOption) <-chan model.FigFamily {
+	return subscribe(c, ctx, key, opts, func(ff model.FigFamily, _ *FamilyChange) model.FigFamily {
+		return ff
+	})
+}
+
+// WatchChanges is like Watch, but delivers with each family how the update changed it,
+// so that consumers can react only to the changes relevant to them.
+func (c *Client) WatchChanges(ctx context.Context, key string, opts ...SubscribeOption) <-chan FamilyUpdate {
+	return subscribe(c, ctx, key, opts, func(ff model.FigFamily, change *FamilyChange) FamilyUpdate {
+		return FamilyUpdate{Family: ff, Change: change}
+	})
+}
+
+// subscribe registers a watcher for key until ctx is done or the client is closed.
+func subscribe[T any](c *Client, ctx context.Context, key string, opts []SubscribeOption, wrap func(model.FigFamily, *FamilyChange) T) <-chan T {
+	o := newSubscribeOptions(opts)
+	bufferSize := c.cfg.WatchBufferSize
+	if o.bufferSize > 0 {
+		bufferSize = o.bufferSize
+	}
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	ch := make(chan T, bufferSize)
+	w := &watcher[T]{ch: ch, wrap: wrap, figID: o.figID, coalesce: o.coalesce}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watchersClosed {
+		w.close()
+		return ch
+	}
+	c.watchers[key] = append(c.watchers[key], w)
+	if o.initialValue {
+		if ff, ok := c.currentFamily(key); ok && w.matches(ff) {
+			// The channel is new and buffered, so this never blocks
+			ch <- wrap(ff, nil)
+		}
+	}
+
+	// Removal runs on a goroutine of its own only once ctx is done, and is accounted for
+	// in c.unwatching so that closeWatchers can wait for it
+	c.unwatching.Add(1)
+	w.stop = context.AfterFunc(ctx, func() {
+		defer c.unwatching.Done()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.watchers[key] = slices.DeleteFunc(c.watchers[key], func(s subscription) bool {
+			return s == w
+		})
+		if len(c.watchers[key]) == 0 {
+			delete(c.watchers, key)
+		}
+		w.close()
+	})
+	return ch
+}
+
+// closeWatchers closes every Watch channel and rejects new subscriptions, for Close. It
+// waits for the removals of watchers whose context is already done, so that no goroutine
+// started by Watch outlives the client.
+func (c *Client) closeWatchers() {
+	c.mu.Lock()
+	c.watchersClosed = true
+	for _, watchers := range c.watchers {
+		for _, w := range watchers {
+			if w.unregister() {
+				c.unwatching.Done()
+			}
+			w.close()
+		}
+	}
+	clear(c.watchers)
+	c.mu.Unlock()
+	c.unwatching.Wait()
+}
+
+// RegisterListener registers a callback for updates to a specific key.
+// The callback is invoked with the deserialized object when an update occurs. Callbacks
+// run on a worker pool (see config.WithListenerWorkers), never while the client holds its
+// internal lock, so they may safely call back into the client. Callbacks for the same key
+// are invoked one at a time, in update order.
+//
+// IMPORTANT: This feature should be used for SERVER-SCOPED configuration only (e.g. global flags).
+// The update is evaluated with an empty context. If your rules depend on user-specific attributes
+// (like request-scoped context), this listener may receive default values or fail to match rules.
+// For request-scoped configuration, use GetFig() with the appropriate context when needed.
+func (c *Client) RegisterListener(key string, prototype AvroRecord, callback func(AvroRecord), opts ...SubscribeOption) {
+	o := newSubscribeOptions(opts)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// We create a wrapper func that handles the logic
+	wrapper := func(ff model.FigFamily) {
+		// Empty evaluation context (embeds context.Background())
+		ctx := c.withDefaultAttributes(evaluation.NewEvaluationContext(nil))
+		fig, err := c.evaluator.Evaluate(&ff, ctx)
+		c.stats.evaluated(ff.Definition.Namespace, ff.Definition.Key)
+		if err != nil || fig == nil {
+			logging.Printf("Listener evaluation failed for %s: %v", key, err)
+			return
+		}
+
+		// Create new instance of prototype type using reflection
+		// prototype should be a pointer to a struct
+		t := reflect.TypeOf(prototype)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		targetVal := reflect.New(t)
+		target := targetVal.Interface()
+
+		schema, err := avro.Parse(prototype.Schema())
+		if err != nil {
+			logging.Printf("Listener schema parse failed for %s: %v", key, err)
+			return
+		}
+
+		payload := fig.Payload
+		if fig.IsEncrypted {
+			svc := c.encryptionFor(ff.Definition.Namespace)
+			if svc == nil {
+				logging.Printf("Listener received encrypted fig for key '%s' but client is not configured for decryption", key)
+				return
+			}
+			// Use the evaluation context (which implements context.Context)
+			p, err := svc.Decrypt(ctx, fig, ff.Definition.Namespace)
+			if err != nil {
+				c.stats.decryptFailures.Add(1)
+				logging.Printf("Listener decryption failed for %s: %v", key, err)
+				return
+			}
+			defer encryption.Zero(p)
+			payload = p
+		}
+
+		if err := c.codec.Unmarshal(schema, payload, target); err != nil {
+			logging.Printf("Listener unmarshal failed for %s: %v", key, err)
+			return
+		}
+		if err := c.interpolate(ctx, key, target); err != nil {
+			logging.Printf("Listener %v", err)
+			return
+		}
+
+		// Callback with the new object (cast back to interface)
+		if record, ok := target.(AvroRecord); ok {
+			callback(record)
+		} else {
+			logging.Printf("Listener callback failed for key %s: created object of type %T does not implement AvroRecord", key, target)
+		}
+	}
+
+	c.listeners[key] = append(c.listeners[key], wrapper)
+	if schema, err := avro.Parse(prototype.Schema()); err == nil {
+		if _, name, err := c.resolveKey(key); err == nil {
+			c.schemas[name] = schema
+		}
+	}
+
+	if o.initialValue {
+		if ff, ok := c.currentFamily(key); ok {
+			c.notifyListener(key, wrapper, ff)
+		}
+	}
+}
+
+// notifyListener queues a listener callback on the dispatcher. Callers must hold c.mu.
+// A panicking callback is recovered and reported without affecting other listeners.
+func (c *Client) notifyListener(key string, cb func(model.FigFamily), ff model.FigFamily) {
+	c.dispatcher.dispatch(key, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.Printf("Listener for key %s panicked: %v", key, r)
+				c.emit(event.Event{
+					Type:      event.ListenerPanicked,
+					Namespace: ff.Definition.Namespace,
+					Key:       key,
+					Message:   "listener panicked",
+					Err:       fmt.Errorf("listener panic: %v", r),
+				})
+			}
+		}()
+		cb(ff)
+	})
+}
+
+// notifyWatcher delivers ff to w, reporting updates dropped because the channel is full.
+// c.mu must be held and released with unlock.
+func (c *Client) notifyWatcher(w subscription, ff model.FigFamily, change *FamilyChange) {
+	delivered, coalesced := w.deliver(ff, change)
+	labels := map[string]string{"namespace": ff.Definition.Namespace, "key": ff.Definition.Key}
+	if coalesced {
+		c.metrics.IncCounter(metrics.WatchUpdatesCoalesced, labels)
+	}
+	if delivered {
+		return
+	}
+	c.droppedUpdates.Add(1)
+	c.metrics.IncCounter(metrics.WatchUpdatesDropped, labels)
+	c.emitLocked(event.Event{
+		Type:      event.UpdateDropped,
+		Namespace: ff.Definition.Namespace,
+		Key:       ff.Definition.Key,
+		Message:   "watch channel full, update dropped",
+	})
+}
+
+// DroppedUpdates returns the number of updates dropped because a Watch channel was full.
+func (c *Client) DroppedUpdates() uint64 {
+	return c.droppedUpdates.Load()
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/figchain/go-client/pkg/logging"
+)
+
+// Webhook request headers.
+const (
+	WebhookSignatureHeader = "X-FigChain-Signature"
+	WebhookTimestampHeader = "X-FigChain-Timestamp"
+)
+
+const (
+	// webhookTolerance is how far a webhook timestamp may be from the current time, to
+	// limit replays of captured requests.
+	webhookTolerance = 5 * time.Minute
+	maxWebhookBody   = 1 << 20
+)
+
+// webhookPayload is the body of a FigChain webhook.
+type webhookPayload struct {
+	Namespace string `json:"namespace"`
+}
+
+// SignWebhook returns the signature header value for a webhook body sent at timestamp
+// (Unix seconds): "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookHandler returns an HTTP handler for FigChain webhooks signed with secret. A
+// valid webhook triggers an immediate Refresh of the namespace it references, giving
+// push-style freshness without waiting for the next poll.
+//
+// Requests with a missing or invalid signature, or a timestamp more than five minutes
+// off, are rejected with 401 Unauthorized. Webhooks for namespaces the client doesn't
+// hold are acknowledged and ignored.
+func (c *Client) WebhookHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if !verifyWebhook(secret, r.Header, body, c.clock.Now()) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.Namespace == "" {
+			http.Error(w, "body must be JSON with a namespace", http.StatusBadRequest)
+			return
+		}
+
+		c.mu.RLock()
+		_, ok := c.namespaceCursors[payload.Namespace]
+		c.mu.RUnlock()
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if _, err := c.Refresh(r.Context(), payload.Namespace); err != nil && !errors.Is(err, ErrFrozen) {
+			logging.Printf("Webhook refresh of %s failed: %v", payload.Namespace, err)
+			http.Error(w, "refresh failed", http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func verifyWebhook(secret string, header http.Header, body []byte, now time.Time) bool {
+	if secret == "" {
+		return false
+	}
+	timestamp, err := strconv.ParseInt(header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := now.Sub(time.Unix(timestamp, 0)); d > webhookTolerance || d < -webhookTolerance {
+		return false
+	}
+	signature := header.Get(WebhookSignatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(SignWebhook(secret, timestamp, body)))
+}
@@ -0,0 +1,282 @@
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"strconv"
+	"text/template"
+
+	"github.com/ettle/strcase"
+	"github.com/hamba/avro/v2"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// Manifest describes the typed accessors to generate for a namespace's keys, so that
+// applications call methods instead of passing key strings to GetFig.
+type Manifest struct {
+	// Package is the package name of the generated code, which must also hold the
+	// structs Generate generates for the namespace.
+	Package string `yaml:"package"`
+	// Type is the name of the generated accessor struct. Defaults to Figs.
+	Type      string     `yaml:"type"`
+	Namespace string     `yaml:"namespace"`
+	Accessors []Accessor `yaml:"accessors"`
+}
+
+// Accessor is a typed accessor method reading a key.
+type Accessor struct {
+	Key string `yaml:"key"`
+	// Name is the method name. Defaults to the key in Pascal case.
+	Name string `yaml:"name"`
+	// Field is the record field the accessor returns, instead of the whole record.
+	Field string `yaml:"field"`
+	// Unit returns an int or long Field as a time.Duration of this unit: ns, us, ms, s, m
+	// or h.
+	Unit string `yaml:"unit"`
+	// Default is the value of Field returned, with the error, when the fig can't be read.
+	Default any `yaml:"default"`
+}
+
+// durationUnits are the time package constants of the Accessor units.
+var durationUnits = map[string]string{
+	"ns": "time.Nanosecond",
+	"us": "time.Microsecond",
+	"ms": "time.Millisecond",
+	"s":  "time.Second",
+	"m":  "time.Minute",
+	"h":  "time.Hour",
+}
+
+// fieldTypes are the Go types of the record fields accessors can return.
+var fieldTypes = map[avro.Type]string{
+	avro.String:  "string",
+	avro.Boolean: "bool",
+	avro.Int:     "int",
+	avro.Long:    "int64",
+	avro.Float:   "float32",
+	avro.Double:  "float64",
+}
+
+// caser converts Avro and key names to Go names like the avro generator does.
+var caser = strcase.NewCaser(true, nil, nil)
+
+// LoadManifest reads a YAML accessor manifest, e.g.
+//
+//	package: figs
+//	namespace: payments
+//	accessors:
+//	  - key: payments-config
+//	  - key: payments-config
+//	    name: PaymentsTimeout
+//	    field: timeout_ms
+//	    unit: ms
+//	    default: 500
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// accessorTemplate renders the accessor struct and its methods.
+const accessorTemplate = `// Code generated by figchain-gen. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+{{- if .Durations }}
+	"time"
+{{ end }}
+	"github.com/figchain/go-client/pkg/client"
+	"github.com/figchain/go-client/pkg/evaluation"
+)
+
+// {{ .Type }} reads the figs of namespace {{ .Namespace }} into their generated types.
+type {{ .Type }} struct {
+	client *client.Client
+}
+
+// New{{ .Type }} returns a {{ .Type }} reading from c.
+func New{{ .Type }}(c *client.Client) *{{ .Type }} {
+	return &{{ .Type }}{client: c}
+}
+{{ range .Methods }}
+{{- if .Field }}
+// {{ .Name }} reads the {{ .Field }} field of the {{ .Key }} fig.{{ if .Default }} It returns {{ .Default }}, with
+// the error, when the fig can't be read.{{ end }}
+func (f *{{ $.Type }}) {{ .Name }}(ctx *evaluation.EvaluationContext) ({{ .Result }}, error) {
+	var v {{ .Record }}
+	if err := f.client.GetFig({{ printf "%q" .Key }}, &v, ctx); err != nil {
+		return {{ or .Default .Zero }}, err
+	}
+	return {{ .Value }}, nil
+}
+{{ else }}
+// {{ .Name }} reads the {{ .Key }} fig.
+func (f *{{ $.Type }}) {{ .Name }}(ctx *evaluation.EvaluationContext) (*{{ .Record }}, error) {
+	var v {{ .Record }}
+	if err := f.client.GetFig({{ printf "%q" .Key }}, &v, ctx); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+{{ end }}
+{{- end }}`
+
+// method is an accessor as rendered by accessorTemplate.
+type method struct {
+	Name, Key, Record string
+	Field             string // the Avro field name, if any
+	Result            string // the Go result type of a field accessor
+	Value             string // the expression of the result
+	Zero, Default     string // the zero and default results, as Go expressions
+}
+
+// GenerateAccessors writes Go source for the manifest's accessor struct to w. The records
+// the accessors return are named after the schemas of the keys' families, as the structs
+// generated by Generate are.
+func GenerateAccessors(ctx context.Context, m *Manifest, families []model.FigFamily, fetch SchemaFetcher, w io.Writer) error {
+	schemaURIs := make(map[string]string)
+	for _, ff := range families {
+		if ff.Definition.Namespace == m.Namespace {
+			schemaURIs[ff.Definition.Key] = ff.Definition.SchemaURI
+		}
+	}
+
+	data := struct {
+		Package, Type, Namespace string
+		Durations                bool
+		Methods                  []method
+	}{Package: m.Package, Type: m.Type, Namespace: m.Namespace}
+	if data.Type == "" {
+		data.Type = "Figs"
+	}
+	schemas := make(map[string]*avro.RecordSchema)
+	for _, a := range m.Accessors {
+		uri, ok := schemaURIs[a.Key]
+		if !ok || uri == "" {
+			return fmt.Errorf("key %s has no schema in namespace %s", a.Key, m.Namespace)
+		}
+		record, ok := schemas[uri]
+		if !ok {
+			s, err := fetch(ctx, uri)
+			if err != nil {
+				return fmt.Errorf("failed to fetch schema %s: %w", uri, err)
+			}
+			parsed, err := avro.ParseWithCache(s, "", &avro.SchemaCache{})
+			if err != nil {
+				return fmt.Errorf("failed to parse schema %s: %w", uri, err)
+			}
+			if record, ok = parsed.(*avro.RecordSchema); !ok {
+				return fmt.Errorf("schema %s is not a record", uri)
+			}
+			schemas[uri] = record
+		}
+
+		mt := method{Name: a.Name, Key: a.Key, Record: caser.ToPascal(record.Name())}
+		if mt.Name == "" {
+			mt.Name = caser.ToPascal(a.Key)
+		}
+		if a.Field != "" {
+			if err := fieldAccessor(&mt, record, a); err != nil {
+				return fmt.Errorf("accessor %s: %w", mt.Name, err)
+			}
+			data.Durations = data.Durations || a.Unit != ""
+		}
+		data.Methods = append(data.Methods, mt)
+	}
+
+	tmpl, err := template.New("accessors").Parse(accessorTemplate)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// fieldAccessor sets up mt to return the accessor's field of record.
+func fieldAccessor(mt *method, record *avro.RecordSchema, a Accessor) error {
+	var field *avro.Field
+	for _, f := range record.Fields() {
+		if f.Name() == a.Field {
+			field = f
+		}
+	}
+	if field == nil {
+		return fmt.Errorf("record %s has no field %s", record.Name(), a.Field)
+	}
+	typ, ok := fieldTypes[field.Type().Type()]
+	if !ok {
+		return fmt.Errorf("field %s has unsupported type %s", a.Field, field.Type().Type())
+	}
+	mt.Field = a.Field
+	mt.Result = typ
+	mt.Value = "v." + caser.ToPascal(a.Field)
+
+	switch typ {
+	case "string":
+		mt.Zero = `""`
+	case "bool":
+		mt.Zero = "false"
+	default:
+		mt.Zero = "0"
+	}
+	if a.Default != nil {
+		switch d := a.Default.(type) {
+		case string:
+			if typ == "string" {
+				mt.Default = strconv.Quote(d)
+			}
+		case bool:
+			if typ == "bool" {
+				mt.Default = strconv.FormatBool(d)
+			}
+		case int:
+			if typ != "string" && typ != "bool" {
+				mt.Default = strconv.Itoa(d)
+			}
+		case float64:
+			if typ == "float32" || typ == "float64" {
+				mt.Default = strconv.FormatFloat(d, 'g', -1, 64)
+			}
+		}
+		if mt.Default == "" {
+			return fmt.Errorf("default %v is not a valid %s", a.Default, typ)
+		}
+	}
+
+	if a.Unit != "" {
+		unit, ok := durationUnits[a.Unit]
+		if !ok {
+			return fmt.Errorf("unknown unit %q", a.Unit)
+		}
+		if typ != "int" && typ != "int64" {
+			return fmt.Errorf("unit %s requires an int or long field", a.Unit)
+		}
+		mt.Result = "time.Duration"
+		mt.Value = fmt.Sprintf("time.Duration(%s) * %s", mt.Value, unit)
+		if mt.Default != "" {
+			mt.Default = fmt.Sprintf("%s * %s", mt.Default, unit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package codegen generates Go structs implementing client.AvroRecord from the Avro
+// schemas fig definitions reference, so that Schema() strings can't drift from the
+// schemas the server validates payloads against.
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"io"
+	"slices"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/gen"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// SchemaFetcher fetches the schema at a SchemaURI, e.g. client.Client.FetchSchema.
+type SchemaFetcher func(ctx context.Context, uri string) (string, error)
+
+// recordTemplate renders each record as a struct with a Schema method returning its
+// canonical schema.
+const recordTemplate = `// Code generated by figchain-gen. DO NOT EDIT.
+
+package {{ .PackageName }}
+{{ if len .Imports }}
+import (
+{{- range .Imports }}
+	"{{ . }}"
+{{- end }}
+)
+{{ end }}
+{{- range .Typedefs }}
+{{ if len .Doc }}// {{ replace .Doc "\n" "\n// " -1 }}{{ else }}// {{ .Name }} is generated from its Avro schema.{{ end }}
+type {{ .Name }} struct {
+{{- range .Fields }}
+{{- if len .Doc }}
+	// {{ replace .Doc "\n" "\n\t// " -1 }}
+{{- end }}
+	{{ .Name }} {{ .Type }} ` + "`" + `avro:"{{ .AvroFieldName }}"` + "`" + `
+{{- end }}
+}
+
+// Schema returns the Avro schema of {{ .Name }}.
+func (*{{ .Name }}) Schema() string {
+	return {{ printf "%q" .Schema }}
+}
+{{ end }}`
+
+// Generate fetches the schemas referenced by the families' SchemaURIs and writes Go
+// source for package pkg to w, with a struct for every record they define. Records
+// shared by several schemas are generated once.
+func Generate(ctx context.Context, pkg string, families []model.FigFamily, fetch SchemaFetcher, w io.Writer) error {
+	var uris []string
+	for _, ff := range families {
+		if uri := ff.Definition.SchemaURI; uri != "" && !slices.Contains(uris, uri) {
+			uris = append(uris, uri)
+		}
+	}
+	slices.Sort(uris)
+
+	g := gen.NewGenerator(pkg, nil, gen.WithTemplate(recordTemplate))
+	for _, uri := range uris {
+		data, err := fetch(ctx, uri)
+		if err != nil {
+			return fmt.Errorf("failed to fetch schema %s: %w", uri, err)
+		}
+		// Parse each schema on its own so that names can't clash between schemas
+		schema, err := avro.ParseWithCache(data, "", &avro.SchemaCache{})
+		if err != nil {
+			return fmt.Errorf("failed to parse schema %s: %w", uri, err)
+		}
+		if _, ok := schema.(*avro.RecordSchema); !ok {
+			return fmt.Errorf("schema %s is not a record", uri)
+		}
+		g.Parse(schema)
+	}
+
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
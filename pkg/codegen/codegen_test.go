@@ -0,0 +1,135 @@
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestGenerate(t *testing.T) {
+	schemas := map[string]string{
+		"schemas/checkout": `{"type":"record","name":"Checkout","doc":"Checkout settings","fields":[
+			{"name":"enabled","type":"boolean"},
+			{"name":"limits","type":{"type":"record","name":"Limits","fields":[{"name":"max_items","type":"int"}]}}
+		]}`,
+		"schemas/search": `{"type":"record","name":"Search","fields":[
+			{"name":"provider","type":"string","doc":"Backend to query"},
+			{"name":"timeout","type":["null","long"]}
+		]}`,
+	}
+	fetch := func(_ context.Context, uri string) (string, error) {
+		s, ok := schemas[uri]
+		if !ok {
+			return "", fmt.Errorf("unknown schema %s", uri)
+		}
+		return s, nil
+	}
+	families := []model.FigFamily{
+		{Definition: model.FigDefinition{Namespace: "web", Key: "checkout", SchemaURI: "schemas/checkout"}},
+		{Definition: model.FigDefinition{Namespace: "web", Key: "search", SchemaURI: "schemas/search"}},
+		{Definition: model.FigDefinition{Namespace: "web", Key: "checkout-v2", SchemaURI: "schemas/checkout"}},
+		{Definition: model.FigDefinition{Namespace: "web", Key: "untyped"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Generate(context.Background(), "figs", families, fetch, &buf); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	src := buf.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "figs.go", src, 0); err != nil {
+		t.Fatalf("generated code doesn't parse: %v\n%s", err, src)
+	}
+	for _, want := range []string{
+		"package figs",
+		"// Checkout settings.\ntype Checkout struct",
+		"Limits  Limits `avro:\"limits\"`",
+		"MaxItems int `avro:\"max_items\"`",
+		"// Backend to query.",
+		"Timeout  *int64 `avro:\"timeout\"`",
+		"func (*Search) Schema() string",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code is missing %q:\n%s", want, src)
+		}
+	}
+	if n := strings.Count(src, "type Checkout struct"); n != 1 {
+		t.Errorf("Checkout generated %d times, want 1", n)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	families := []model.FigFamily{
+		{Definition: model.FigDefinition{Namespace: "web", Key: "flag", SchemaURI: "schemas/flag"}},
+	}
+	for name, schema := range map[string]string{
+		"invalid":    `{"type":`,
+		"not record": `"string"`,
+	} {
+		fetch := func(context.Context, string) (string, error) { return schema, nil }
+		if err := Generate(context.Background(), "figs", families, fetch, &bytes.Buffer{}); err == nil {
+			t.Errorf("%s: Generate succeeded, want error", name)
+		}
+	}
+}
+
+func TestGenerateAccessors(t *testing.T) {
+	fetch := func(_ context.Context, uri string) (string, error) {
+		return `{"type":"record","name":"payments_config","fields":[
+			{"name":"provider","type":"string"},
+			{"name":"timeout_ms","type":"long"}
+		]}`, nil
+	}
+	families := []model.FigFamily{
+		{Definition: model.FigDefinition{Namespace: "payments", Key: "payments-config", SchemaURI: "schemas/payments"}},
+	}
+	m := &Manifest{
+		Package:   "figs",
+		Type:      "Payments",
+		Namespace: "payments",
+		Accessors: []Accessor{
+			{Key: "payments-config"},
+			{Key: "payments-config", Name: "PaymentsTimeout", Field: "timeout_ms", Unit: "ms", Default: 500},
+			{Key: "payments-config", Name: "Provider", Field: "provider", Default: "stripe"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateAccessors(context.Background(), m, families, fetch, &buf); err != nil {
+		t.Fatalf("GenerateAccessors failed: %v", err)
+	}
+	src := buf.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "accessors.go", src, 0); err != nil {
+		t.Fatalf("generated code doesn't parse: %v\n%s", err, src)
+	}
+	for _, want := range []string{
+		"package figs",
+		"func NewPayments(c *client.Client) *Payments",
+		"func (f *Payments) PaymentsConfig(ctx *evaluation.EvaluationContext) (*PaymentsConfig, error)",
+		"func (f *Payments) PaymentsTimeout(ctx *evaluation.EvaluationContext) (time.Duration, error)",
+		"return 500 * time.Millisecond, err",
+		"return time.Duration(v.TimeoutMs) * time.Millisecond, nil",
+		`return "stripe", err`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code is missing %q:\n%s", want, src)
+		}
+	}
+
+	for name, a := range map[string]Accessor{
+		"unknown key":   {Key: "refunds"},
+		"unknown field": {Key: "payments-config", Field: "retries"},
+		"bad unit":      {Key: "payments-config", Field: "provider", Unit: "ms"},
+		"bad default":   {Key: "payments-config", Field: "timeout_ms", Default: "soon"},
+	} {
+		m.Accessors = []Accessor{a}
+		if err := GenerateAccessors(context.Background(), m, families, fetch, &bytes.Buffer{}); err == nil {
+			t.Errorf("%s: GenerateAccessors succeeded, want error", name)
+		}
+	}
+}
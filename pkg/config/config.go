@@ -1,13 +1,48 @@
 package config
 
 import (
+	"context"
+	"io"
 	"net/http"
-	"strings"
+	"slices"
 	"time"
 
-	"github.com/spf13/viper"
+	"github.com/hamba/avro/v2"
+
+	"github.com/figchain/go-client/pkg/clock"
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/event"
+	"github.com/figchain/go-client/pkg/metrics"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/source"
+	"github.com/figchain/go-client/pkg/store"
+	"github.com/figchain/go-client/pkg/transport"
 )
 
+// VaultFetcher fetches the vault backup encrypted for the key with keyFingerprint. It is
+// vault.VaultFetcher, declared here so that the config can hold one.
+type VaultFetcher interface {
+	FetchBackup(ctx context.Context, keyFingerprint string) (io.ReadCloser, error)
+}
+
+// ApplyDecision is a BeforeApply hook's verdict on an update.
+type ApplyDecision int
+
+const (
+	// ApplyAccept applies the update.
+	ApplyAccept ApplyDecision = iota
+	// ApplyReject doesn't apply the update and reports it with an UpdateRejected event.
+	// It is offered to the hook again on the next poll.
+	ApplyReject
+	// ApplyDefer doesn't apply the update yet. It is offered to the hook again on the
+	// next poll.
+	ApplyDefer
+)
+
+// BeforeApplyFunc decides whether an update of a fig family is applied. old is the
+// family currently held, or the zero FigFamily if there is none.
+type BeforeApplyFunc func(old, new model.FigFamily) ApplyDecision
+
 // BootstrapStrategy defines the strategy for bootstrapping the client.
 type BootstrapStrategy string
 
@@ -18,77 +53,222 @@ const (
 	BootstrapStrategyHybrid      BootstrapStrategy = "hybrid"
 )
 
-// Config holds the client configuration.
-type Config struct {
-	BaseURL           string            `mapstructure:"base_url"`
-	LongPollingURL    string            `mapstructure:"long_polling_url"`
-	EnvironmentID     string            `mapstructure:"environment_id"`
-	TenantID          string            `mapstructure:"tenant_id"`
+// NamespaceProfile overrides global settings for one namespace, since namespaces often
+// have different freshness needs. Zero fields inherit the global setting.
+type NamespaceProfile struct {
+	Name string `mapstructure:"name"`
+	// PollingInterval is the least time between polls of the namespace. Namespaces
+	// without one are polled continuously.
 	PollingInterval   time.Duration     `mapstructure:"polling_interval"`
-	MaxRetries        int               `mapstructure:"max_retries"`
-	RetryDelay        time.Duration     `mapstructure:"retry_delay"`
-	AsOfTimestamp     string            `mapstructure:"as_of_timestamp"`
-	Namespaces        []string          `mapstructure:"namespaces"`
-	HTTPClient        *http.Client      `mapstructure:"-"` // Cannot be configured via yaml/env
-	ClientSecret      string            `mapstructure:"client_secret"`
-	UseLongPolling    bool              `mapstructure:"use_long_polling"`
 	BootstrapStrategy BootstrapStrategy `mapstructure:"bootstrap_strategy"`
-
-	// Vault Configuration
-	VaultBucket              string `mapstructure:"vault_bucket"`
-	VaultPrefix              string `mapstructure:"vault_prefix"`
-	VaultRegion              string `mapstructure:"vault_region"`
-	VaultEndpoint            string `mapstructure:"vault_endpoint"`
-	VaultPathStyle           bool   `mapstructure:"vault_path_style"`
-	VaultPrivateKeyPath      string `mapstructure:"vault_private_key_path"`
-	VaultEnabled             bool   `mapstructure:"vault_enabled"`
+	// EncryptionPrivateKeyPath decrypts the namespace's figs instead of the global key.
 	EncryptionPrivateKeyPath string `mapstructure:"encryption_private_key_path"`
-	AuthPrivateKeyPath       string `mapstructure:"auth_private_key_path"`
-	AuthClientID             string `mapstructure:"auth_client_id"`
-}
-
-// LoadConfig loads configuration from a YAML file and environment variables.
-func LoadConfig(path string) (*Config, error) {
-	v := viper.New()
-
-	if path != "" {
-		v.SetConfigFile(path)
-	} else {
-		v.SetConfigName("figchain")
-		v.SetConfigType("yaml")
-		v.AddConfigPath(".")
-	}
-
-	// Environment variable overrides
-	v.SetEnvPrefix("FIGCHAIN")
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	v.AutomaticEnv()
-
-	// Defaults
-	v.SetDefault("base_url", "https://app.figchain.io/api/")
-	v.SetDefault("polling_interval", "60s")
-	v.SetDefault("max_retries", 3)
-	v.SetDefault("retry_delay", "1s")
-	v.SetDefault("use_long_polling", true)
-	v.SetDefault("vault_enabled", false)
-	v.SetDefault("bootstrap_strategy", string(BootstrapStrategyServer))
-
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, err
-		}
-		// Config file not found is fine, we just rely on defaults/env vars
-	}
-
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return nil, err
-	}
+	// Priority orders namespaces for bootstrap and polling, highest first.
+	Priority int `mapstructure:"priority"`
+}
 
-	// Manual handling for HTTPClient as it's not serializable
-	config.HTTPClient = http.DefaultClient
+// Config holds the client configuration.
+type Config struct {
+	// Env is the name of the environments entry of the config file the config was loaded
+	// with, selected with FIGCHAIN_ENV.
+	Env            string `mapstructure:"env"`
+	BaseURL        string `mapstructure:"base_url"`
+	LongPollingURL string `mapstructure:"long_polling_url"`
+	// FallbackURLs are tried in order when requests to BaseURL fail, e.g. other regions.
+	FallbackURLs []string `mapstructure:"fallback_urls"`
+	// FailoverCooldown is how long a failed endpoint is skipped before it is tried again.
+	FailoverCooldown time.Duration `mapstructure:"failover_cooldown"`
+	EnvironmentID    string        `mapstructure:"environment_id"`
+	TenantID         string        `mapstructure:"tenant_id"`
+	PollingInterval  time.Duration `mapstructure:"polling_interval"`
+	// PollSchedule is a cron expression; when set, updates are polled at the times it
+	// matches instead of continuously. Refresh still fetches updates on demand.
+	PollSchedule string `mapstructure:"poll_schedule"`
+	// QuietHoursStart and QuietHoursEnd ("15:04", local time) bound a daily window in
+	// which updates aren't polled. The window may span midnight.
+	QuietHoursStart string        `mapstructure:"quiet_hours_start"`
+	QuietHoursEnd   string        `mapstructure:"quiet_hours_end"`
+	MaxRetries      int           `mapstructure:"max_retries"`
+	RetryDelay      time.Duration `mapstructure:"retry_delay"`
+	AsOfTimestamp   string        `mapstructure:"as_of_timestamp"`
+	// Frozen pins the client at its bootstrap state, e.g. the AsOfTimestamp: no updates
+	// are fetched or applied afterwards.
+	Frozen     bool     `mapstructure:"frozen"`
+	Namespaces []string `mapstructure:"namespaces"`
+	// NamespaceProfiles are per-namespace settings, by namespace. In the config file,
+	// they are given as namespaces entries of the form {name: payments, ...}.
+	NamespaceProfiles map[string]NamespaceProfile `mapstructure:"-"`
+	// KeyRouting addresses keys as namespace/key, and looks unqualified keys up in every
+	// namespace in order, instead of only the first.
+	KeyRouting bool `mapstructure:"key_routing"`
+	// HTTPClient is used for requests to the FigChain server. When nil, the client builds
+	// one applying the connection settings below.
+	HTTPClient     *http.Client `mapstructure:"-"` // Cannot be configured via yaml/env
+	ClientSecret   string       `mapstructure:"client_secret"`
+	UseLongPolling bool         `mapstructure:"use_long_polling"`
+	// ClientSecretFile is a file holding the client secret, e.g. a projected Kubernetes
+	// secret, read instead of ClientSecret.
+	ClientSecretFile string `mapstructure:"client_secret_file"`
+	// CredentialReloadInterval is how often ClientSecretFile and the private key files are
+	// checked for rotation, to reload them. Zero disables reloading.
+	CredentialReloadInterval time.Duration `mapstructure:"credential_reload_interval"`
+	// MaxIdleConns and MaxConnsPerHost size the connection pool; zero keeps the
+	// net/http defaults.
+	MaxIdleConns    int `mapstructure:"max_idle_conns"`
+	MaxConnsPerHost int `mapstructure:"max_conns_per_host"`
+	// IdleConnTimeout is how long idle connections are kept open.
+	IdleConnTimeout time.Duration `mapstructure:"idle_conn_timeout"`
+	// ForceHTTP2 uses HTTP/2 for every connection, including unencrypted ones (h2c). The
+	// server must support it.
+	ForceHTTP2 bool `mapstructure:"force_http2"`
+	// SingleObjectEncoding sends update polls with Avro single-object encoding instead of
+	// OCF, falling back to OCF if the server doesn't support it.
+	SingleObjectEncoding bool `mapstructure:"single_object_encoding"`
+	// HTTP2PingInterval is how long an HTTP/2 connection may be idle, e.g. during a long
+	// poll, before it is health-checked with a ping. Zero disables pings.
+	HTTP2PingInterval time.Duration     `mapstructure:"http2_ping_interval"`
+	BootstrapStrategy BootstrapStrategy `mapstructure:"bootstrap_strategy"`
+	GlobalAttributes  map[string]string `mapstructure:"global_attributes"`
+	// TenantAttributeKey is the evaluation attribute the request's tenant ID is exposed
+	// as. Tenant support is disabled when empty.
+	TenantAttributeKey string `mapstructure:"tenant_attribute_key"`
+	WatchBufferSize    int    `mapstructure:"watch_buffer_size"`
+	ListenerWorkers    int    `mapstructure:"listener_workers"`
+	MaxFamilies        int    `mapstructure:"max_families"`
+
+	// DecryptedPayloadCacheSize is how many decrypted payloads are cached, by fig version.
+	DecryptedPayloadCacheSize int `mapstructure:"decrypted_payload_cache_size"`
+	// LockKeyMemory locks unwrapped namespace keys into RAM with mlock, best-effort.
+	LockKeyMemory bool `mapstructure:"lock_key_memory"`
+	// AuditLogPath is a file that every applied configuration change is appended to, as
+	// JSON lines. Empty disables the audit log.
+	AuditLogPath string `mapstructure:"audit_log_path"`
+	// AuditWriter receives the audit log instead of AuditLogPath.
+	AuditWriter io.Writer `mapstructure:"-"`
+	// HistoryDepth is how many versions of each fig family are kept for Rollback and
+	// GetFigHistory, including the current one. Zero disables history.
+	HistoryDepth int `mapstructure:"history_depth"`
+	// DiagnosticsBufferSize is how many recent transport exchanges and events are kept
+	// for Client.DumpDiagnostics. Zero disables diagnostics.
+	DiagnosticsBufferSize int `mapstructure:"diagnostics_buffer_size"`
+	// ExpiryGCInterval is how often families whose expiry time has passed are removed
+	// from the store. Zero disables the removal; expired families are never served either way.
+	ExpiryGCInterval time.Duration `mapstructure:"expiry_gc_interval"`
+	// RolloutWindow staggers updates across a fleet: each instance delays applying them by
+	// an offset within the window derived from its InstanceID. Zero applies immediately.
+	RolloutWindow time.Duration `mapstructure:"rollout_window"`
+	// InstanceID identifies the instance to the server and for the staged rollout. Empty
+	// uses the hostname.
+	InstanceID string `mapstructure:"instance_id"`
+	// AppName names the application to the server, which attributes polling traffic to it.
+	AppName string `mapstructure:"app_name"`
+	// UsageReportInterval is how often the keys read since the previous report are
+	// reported to the server. Zero disables reporting; usage is still tracked locally.
+	UsageReportInterval time.Duration `mapstructure:"usage_report_interval"`
+	// RequireSync fails reads of a namespace with client.ErrNotBootstrapped until it is in
+	// sync with the server, instead of serving the possibly stale families it was
+	// bootstrapped with from the local cache or vault.
+	RequireSync bool `mapstructure:"require_sync"`
+	// SyncWait is how long a read waits for its namespace to sync with RequireSync, within
+	// the deadline of its context, before failing.
+	SyncWait time.Duration `mapstructure:"sync_wait"`
+	// KillSwitchKeys and KillSwitchPrefixes select fig keys whose updates are applied
+	// immediately, bypassing the staged rollout and PauseUpdates.
+	KillSwitchKeys     []string `mapstructure:"kill_switch_keys"`
+	KillSwitchPrefixes []string `mapstructure:"kill_switch_prefixes"`
+	// PriorityPollingInterval is how often kill-switch families are fetched on their own,
+	// besides the regular updates. Zero disables the priority channel.
+	PriorityPollingInterval time.Duration `mapstructure:"priority_polling_interval"`
 
-	return &config, nil
+	// Vault Configuration
+	VaultBucket         string `mapstructure:"vault_bucket"`
+	VaultPrefix         string `mapstructure:"vault_prefix"`
+	VaultRegion         string `mapstructure:"vault_region"`
+	VaultEndpoint       string `mapstructure:"vault_endpoint"`
+	VaultPathStyle      bool   `mapstructure:"vault_path_style"`
+	VaultPrivateKeyPath string `mapstructure:"vault_private_key_path"`
+	VaultEnabled        bool   `mapstructure:"vault_enabled"`
+	// VaultFetcher fetches vault backups instead of the default S3 fetcher.
+	VaultFetcher             VaultFetcher `mapstructure:"-"`
+	EncryptionPrivateKeyPath string       `mapstructure:"encryption_private_key_path"`
+	// AutoRegisterPublicKey is the email the encryption key's public key is registered
+	// for on startup, if the server doesn't have it yet. Empty disables registration.
+	AutoRegisterPublicKey string `mapstructure:"auto_register_public_key"`
+	AuthPrivateKeyPath    string `mapstructure:"auth_private_key_path"`
+	AuthClientID          string `mapstructure:"auth_client_id"`
+	// SigningAlgorithm and SigningKey HMAC-sign every request to the FigChain server (see
+	// transport.NewHMACSigner). Signing is disabled when SigningKey is empty.
+	SigningAlgorithm string `mapstructure:"signing_algorithm"`
+	SigningKey       string `mapstructure:"signing_key"`
+
+	// Evaluator overrides the rule evaluator used by the client. Defaults to the
+	// rule-based evaluator when nil.
+	Evaluator evaluation.Evaluator `mapstructure:"-"`
+
+	// ContextProviders supply attributes merged into every evaluation, after
+	// GlobalAttributes and before request-scoped attributes.
+	ContextProviders []evaluation.ContextProvider `mapstructure:"-"`
+
+	// Interpolator expands placeholders in the string values of decoded payloads. Payloads
+	// are used as is when nil.
+	Interpolator evaluation.Interpolator `mapstructure:"-"`
+
+	// BucketingStrategy overrides how the default evaluator buckets SPLIT conditions.
+	BucketingStrategy evaluation.BucketingStrategy `mapstructure:"-"`
+
+	// Clock drives polling, token lifetimes, expiry and backoff. The system clock is used
+	// when nil.
+	Clock clock.Clock `mapstructure:"-"`
+
+	// MetricsRecorder receives internal client metrics. Metrics are discarded when nil.
+	MetricsRecorder metrics.Recorder `mapstructure:"-"`
+
+	// EventHandlers receive client events, in the order they were added.
+	EventHandlers []event.Handler `mapstructure:"-"`
+
+	// FatalHandler is called with the recovered error whenever the poll loop panics.
+	// Polling restarts after the handler returns.
+	FatalHandler func(error) `mapstructure:"-"`
+
+	// BeforeApply, if set, decides whether each update is applied.
+	BeforeApply BeforeApplyFunc `mapstructure:"-"`
+
+	// UpdateSources feed updates to the client from message buses, in addition to polling.
+	UpdateSources []source.UpdateSource `mapstructure:"-"`
+
+	// Store overrides where the client keeps fig families. A *store.RedisStore is also
+	// used to share the bootstrap between processes.
+	Store store.Store `mapstructure:"-"`
+
+	// CachePath is where the client keeps an encrypted local copy of its state, to
+	// bootstrap from when the server (and vault) can't be reached.
+	CachePath string `mapstructure:"cache_path"`
+	// CacheKey encrypts the local cache. It defaults to a key derived from the encryption
+	// private key.
+	CacheKey []byte `mapstructure:"-"`
+	// HandoffState is the state returned by a previous client's Handoff, to start from
+	// instead of bootstrapping.
+	HandoffState []byte `mapstructure:"-"`
+
+	// Types are the Avro schemas of the Go types figs are decoded into, by key. Payloads
+	// are checked against them from bootstrap on, so that incompatibilities are reported
+	// before the first GetFig. Server schemas are fetched and checked in the background.
+	Types map[string]string `mapstructure:"-"`
+
+	// AvroAPI overrides the Avro API fig payloads are decoded with. AvroTypes and
+	// AvroTypeConverters are registered with it.
+	AvroAPI avro.API `mapstructure:"-"`
+	// AvroTypes are the Go types named Avro types decode into when they are members of a
+	// union, so that union fields of type any receive typed values rather than maps.
+	AvroTypes map[string]any `mapstructure:"-"`
+	// AvroTypeConverters convert decoded values of fields of type any, e.g. to decode a
+	// logical type into a custom Go type.
+	AvroTypeConverters []avro.TypeConverter `mapstructure:"-"`
+
+	// RequestInterceptors and ResponseInterceptors wrap every request to the FigChain
+	// server, in the order they were added.
+	RequestInterceptors  []transport.RequestInterceptor  `mapstructure:"-"`
+	ResponseInterceptors []transport.ResponseInterceptor `mapstructure:"-"`
 }
 
 // Option is a functional option for configuring the client.
@@ -108,6 +288,22 @@ func WithLongPollingURL(url string) Option {
 	}
 }
 
+// WithFallbackURLs sets base URLs to fail over to, in order, when the primary BaseURL
+// is unreachable or returns server errors.
+func WithFallbackURLs(urls ...string) Option {
+	return func(c *Config) {
+		c.FallbackURLs = urls
+	}
+}
+
+// WithFailoverCooldown sets how long a failed endpoint is skipped before it is tried
+// again.
+func WithFailoverCooldown(d time.Duration) Option {
+	return func(c *Config) {
+		c.FailoverCooldown = d
+	}
+}
+
 // WithEnvironmentID sets the environment ID.
 func WithEnvironmentID(id string) Option {
 	return func(c *Config) {
@@ -129,6 +325,24 @@ func WithPollingInterval(interval time.Duration) Option {
 	}
 }
 
+// WithPollSchedule polls updates at the times matched by a cron expression, e.g.
+// "0 */6 * * *", instead of continuously. Standard five-field expressions, descriptors
+// such as "@hourly" and a CRON_TZ= prefix are supported.
+func WithPollSchedule(cron string) Option {
+	return func(c *Config) {
+		c.PollSchedule = cron
+	}
+}
+
+// WithQuietHours suspends polling between start and end each day, given as "15:04" in
+// local time. A window whose end is before its start spans midnight.
+func WithQuietHours(start, end string) Option {
+	return func(c *Config) {
+		c.QuietHoursStart = start
+		c.QuietHoursEnd = end
+	}
+}
+
 // WithMaxRetries sets the maximum number of retries.
 func WithMaxRetries(retries int) Option {
 	return func(c *Config) {
@@ -150,6 +364,24 @@ func WithAsOfTimestamp(timestamp string) Option {
 	}
 }
 
+// WithFrozen pins the client at its bootstrap state: updates are neither polled nor
+// accepted from update sources. Combined with WithAsOfTimestamp, it reproduces the
+// configuration served at a point in the past, e.g. for debugging or backtests.
+func WithFrozen() Option {
+	return func(c *Config) {
+		c.Frozen = true
+	}
+}
+
+// WithTimeTravel bootstraps the client as of timestamp (RFC 3339) and keeps it there.
+// It is shorthand for WithAsOfTimestamp(timestamp) and WithFrozen().
+func WithTimeTravel(timestamp string) Option {
+	return func(c *Config) {
+		c.AsOfTimestamp = timestamp
+		c.Frozen = true
+	}
+}
+
 // WithNamespaces sets the namespaces to fetch.
 func WithNamespaces(namespaces ...string) Option {
 	return func(c *Config) {
@@ -157,13 +389,93 @@ func WithNamespaces(namespaces ...string) Option {
 	}
 }
 
-// WithHTTPClient sets the HTTP client.
+// WithKeyRouting lets GetFig, Watch and the other key-based methods address keys as
+// namespace/key, and routes unqualified keys to the first namespace holding them, so
+// call sites keep working when a key moves between the configured namespaces.
+func WithKeyRouting() Option {
+	return func(c *Config) {
+		c.KeyRouting = true
+	}
+}
+
+// WithNamespaceProfiles sets per-namespace settings, adding the namespaces that aren't
+// fetched yet.
+func WithNamespaceProfiles(profiles ...NamespaceProfile) Option {
+	return func(c *Config) {
+		if c.NamespaceProfiles == nil {
+			c.NamespaceProfiles = make(map[string]NamespaceProfile)
+		}
+		for _, profile := range profiles {
+			c.NamespaceProfiles[profile.Name] = profile
+			if !slices.Contains(c.Namespaces, profile.Name) {
+				c.Namespaces = append(c.Namespaces, profile.Name)
+			}
+		}
+	}
+}
+
+// Profile returns the effective settings of namespace: its profile, with the fields it
+// doesn't set inherited from the global settings. PollingInterval is inherited only as
+// zero, i.e. polling continuously.
+func (c *Config) Profile(namespace string) NamespaceProfile {
+	profile := c.NamespaceProfiles[namespace]
+	profile.Name = namespace
+	if profile.BootstrapStrategy == "" {
+		profile.BootstrapStrategy = c.BootstrapStrategy
+	}
+	if profile.EncryptionPrivateKeyPath == "" {
+		profile.EncryptionPrivateKeyPath = c.EncryptionPrivateKeyPath
+	}
+	return profile
+}
+
+// WithHTTPClient sets the HTTP client. Connection settings such as WithConnectionPool
+// don't apply to it.
 func WithHTTPClient(client *http.Client) Option {
 	return func(c *Config) {
 		c.HTTPClient = client
 	}
 }
 
+// WithConnectionPool sets the maximum number of idle connections and of connections per
+// host.
+func WithConnectionPool(maxIdleConns, maxConnsPerHost int) Option {
+	return func(c *Config) {
+		c.MaxIdleConns = maxIdleConns
+		c.MaxConnsPerHost = maxConnsPerHost
+	}
+}
+
+// WithIdleConnTimeout sets how long idle connections are kept open.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.IdleConnTimeout = d
+	}
+}
+
+// WithForceHTTP2 uses HTTP/2 for every connection, including unencrypted ones (h2c).
+func WithForceHTTP2() Option {
+	return func(c *Config) {
+		c.ForceHTTP2 = true
+	}
+}
+
+// WithHTTP2PingInterval health-checks HTTP/2 connections with a ping after they have
+// been idle for d, so that long polls detect dead connections.
+func WithHTTP2PingInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.HTTP2PingInterval = d
+	}
+}
+
+// WithSingleObjectEncoding sends update polls with Avro single-object encoding, which
+// replaces the schema OCF embeds in every message with its fingerprint.
+func WithSingleObjectEncoding() Option {
+	return func(c *Config) {
+		c.SingleObjectEncoding = true
+	}
+}
+
 // WithClientSecret sets the client secret.
 func WithClientSecret(secret string) Option {
 	return func(c *Config) {
@@ -171,6 +483,22 @@ func WithClientSecret(secret string) Option {
 	}
 }
 
+// WithClientSecretFile reads the client secret from the file at path, e.g. a secret
+// mounted into a container.
+func WithClientSecretFile(path string) Option {
+	return func(c *Config) {
+		c.ClientSecretFile = path
+	}
+}
+
+// WithCredentialReload checks the client secret file and the private key files every
+// interval, and reloads them once they are rotated on disk, without restarting the client.
+func WithCredentialReload(interval time.Duration) Option {
+	return func(c *Config) {
+		c.CredentialReloadInterval = interval
+	}
+}
+
 // WithLongPolling enables or disables long polling.
 func WithLongPolling(enable bool) Option {
 	return func(c *Config) {
@@ -220,7 +548,8 @@ func WithVaultPathStyle(enabled bool) Option {
 	}
 }
 
-// WithVaultPrivateKeyPath sets the path to the private key for the Vault.
+// WithVaultPrivateKeyPath sets the path to the private key for the Vault: a PEM encoded
+// RSA key, an age identity or an unencrypted armored OpenPGP key.
 func WithVaultPrivateKeyPath(path string) Option {
 	return func(c *Config) {
 		c.VaultPrivateKeyPath = path
@@ -234,6 +563,15 @@ func WithVaultEnabled(enabled bool) Option {
 	}
 }
 
+// WithVaultFetcher sets where vault backups are fetched from, e.g. an artifact repository
+// or internal blob store, instead of S3. It enables the vault.
+func WithVaultFetcher(f VaultFetcher) Option {
+	return func(c *Config) {
+		c.VaultFetcher = f
+		c.VaultEnabled = true
+	}
+}
+
 // WithEncryptionPrivateKeyPath sets the path to the encryption private key.
 func WithEncryptionPrivateKeyPath(path string) Option {
 	return func(c *Config) {
@@ -241,6 +579,14 @@ func WithEncryptionPrivateKeyPath(path string) Option {
 	}
 }
 
+// WithAutoRegisterPublicKey registers the public key of the encryption private key for
+// email when the client starts, unless the server already has it.
+func WithAutoRegisterPublicKey(email string) Option {
+	return func(c *Config) {
+		c.AutoRegisterPublicKey = email
+	}
+}
+
 // WithAuthPrivateKeyPath sets the path to the authentication private key.
 func WithAuthPrivateKeyPath(path string) Option {
 	return func(c *Config) {
@@ -255,17 +601,371 @@ func WithAuthClientID(id string) Option {
 	}
 }
 
+// WithEvaluator sets a custom evaluator, e.g. to wrap the rule-based evaluator
+// with logging or caching, or to substitute a different rule engine.
+func WithEvaluator(e evaluation.Evaluator) Option {
+	return func(c *Config) {
+		c.Evaluator = e
+	}
+}
+
+// WithGlobalAttributes sets static attributes, such as service name or region, that are
+// merged into every evaluation. Request-scoped attributes take precedence.
+func WithGlobalAttributes(attributes map[string]string) Option {
+	return func(c *Config) {
+		c.GlobalAttributes = attributes
+	}
+}
+
+// WithTenantAttributeKey enables tenant-scoped evaluation. The tenant ID carried by the
+// evaluation context (see evaluation.ContextWithTenant) is exposed to rules as the
+// attribute key, and per-tenant overrides (see Client.TenantOverrides) are applied.
+func WithTenantAttributeKey(key string) Option {
+	return func(c *Config) {
+		c.TenantAttributeKey = key
+	}
+}
+
+// WithContextProvider adds a provider whose attributes are merged into every evaluation.
+// Providers are applied in the order they are added.
+func WithContextProvider(provider evaluation.ContextProvider) Option {
+	return func(c *Config) {
+		c.ContextProviders = append(c.ContextProviders, provider)
+	}
+}
+
+// WithClock sets the clock driving polling, token lifetimes, expiry and backoff, e.g. a
+// figtest.FakeClock so that tests advance time instead of sleeping.
+func WithClock(c clock.Clock) Option {
+	return func(cfg *Config) {
+		cfg.Clock = c
+	}
+}
+
+// WithInterpolator expands placeholders in the string values of the payloads read by
+// GetFig and listeners, at evaluation time, e.g. with
+// evaluation.NewInterpolator(nil) an endpoint of "https://api.${env:REGION}.example.com"
+// or a resource name of "orders-${attr:tenant}".
+func WithInterpolator(i evaluation.Interpolator) Option {
+	return func(c *Config) {
+		c.Interpolator = i
+	}
+}
+
+// WithBucketingStrategy sets the strategy the default evaluator uses to bucket values
+// for SPLIT conditions, e.g. to stay consistent with other FigChain SDKs or with an
+// existing experimentation system. Ignored when a custom evaluator is configured.
+func WithBucketingStrategy(strategy evaluation.BucketingStrategy) Option {
+	return func(c *Config) {
+		c.BucketingStrategy = strategy
+	}
+}
+
+// WithMetricsRecorder sets the recorder that receives internal client metrics.
+func WithMetricsRecorder(r metrics.Recorder) Option {
+	return func(c *Config) {
+		c.MetricsRecorder = r
+	}
+}
+
+// WithEventHandler adds a handler that receives client events.
+func WithEventHandler(h event.Handler) Option {
+	return func(c *Config) {
+		c.EventHandlers = append(c.EventHandlers, h)
+	}
+}
+
+// WithBeforeApply sets a hook deciding whether each update is applied, e.g. to hold back
+// updates that bump a schema's major version until they are approved. The hook is called
+// with the client's lock held and must not call the client.
+func WithBeforeApply(fn BeforeApplyFunc) Option {
+	return func(c *Config) {
+		c.BeforeApply = fn
+	}
+}
+
+// WithFatalHandler sets a handler called when the poll loop recovers from a panic, e.g.
+// to alert or to terminate the process.
+func WithFatalHandler(fn func(error)) Option {
+	return func(c *Config) {
+		c.FatalHandler = fn
+	}
+}
+
+// WithUpdateSource adds a message-bus source of updates. The client consumes it until
+// Close, which also closes the source. Polling continues as a fallback; updates that don't
+// follow the client's cursor trigger a refresh from the server.
+func WithUpdateSource(src source.UpdateSource) Option {
+	return func(c *Config) {
+		c.UpdateSources = append(c.UpdateSources, src)
+	}
+}
+
+// WithStore sets the store the client keeps fig families in, e.g. a shared
+// store.RedisStore. The caller owns the store and closes it after the client.
+func WithStore(s store.Store) Option {
+	return func(c *Config) {
+		c.Store = s
+	}
+}
+
+// WithLocalCache keeps an encrypted local copy of the client's state at path, to
+// bootstrap from when the server (and vault) can't be reached. It is encrypted with the
+// key set by WithCacheKey or, by default, one derived from the encryption private key.
+func WithLocalCache(path string) Option {
+	return func(c *Config) {
+		c.CachePath = path
+	}
+}
+
+// WithCacheKey sets the store.CacheKeySize-byte key the local cache is encrypted with,
+// e.g. one kept in the OS keyring.
+func WithCacheKey(key []byte) Option {
+	return func(c *Config) {
+		c.CacheKey = key
+	}
+}
+
+// WithHandoffState starts the client from the state returned by a previous client's
+// Handoff instead of bootstrapping. Namespaces the previous client didn't have are
+// bootstrapped as usual.
+func WithHandoffState(state []byte) Option {
+	return func(c *Config) {
+		c.HandoffState = state
+	}
+}
+
+// WithWatchBufferSize sets the default channel buffer size for Watch subscriptions.
+func WithWatchBufferSize(size int) Option {
+	return func(c *Config) {
+		c.WatchBufferSize = size
+	}
+}
+
+// WithListenerWorkers sets the number of workers that invoke RegisterListener callbacks.
+// Callbacks for the same key always run on the same worker, in update order.
+func WithListenerWorkers(n int) Option {
+	return func(c *Config) {
+		c.ListenerWorkers = n
+	}
+}
+
+// WithMaxFamilies bounds the number of fig families the client holds in memory. When
+// set, least recently used families are evicted and families that aren't held are
+// fetched from the server on demand, trading latency for memory. Zero holds everything.
+func WithMaxFamilies(n int) Option {
+	return func(c *Config) {
+		c.MaxFamilies = n
+	}
+}
+
+// WithDecryptedPayloadCacheSize sets how many decrypted payloads are cached. Cached
+// payloads are zeroed when evicted and on Close. Zero disables caching.
+func WithDecryptedPayloadCacheSize(size int) Option {
+	return func(c *Config) {
+		c.DecryptedPayloadCacheSize = size
+	}
+}
+
+// WithLockedKeyMemory locks unwrapped namespace keys into RAM with mlock, so that they
+// are never written to swap. Locking is best-effort: on failure, e.g. because of
+// RLIMIT_MEMLOCK, a warning is logged and the keys are used unlocked.
+func WithLockedKeyMemory() Option {
+	return func(c *Config) {
+		c.LockKeyMemory = true
+	}
+}
+
+// WithHistoryDepth sets how many versions of each fig family are kept for rollback,
+// including the current one. Zero disables history.
+func WithHistoryDepth(depth int) Option {
+	return func(c *Config) {
+		c.HistoryDepth = depth
+	}
+}
+
+// WithDiagnostics records the last size transport exchanges and client events, redacted,
+// for Client.DumpDiagnostics, e.g. to attach to a bug report.
+func WithDiagnostics(size int) Option {
+	return func(c *Config) {
+		c.DiagnosticsBufferSize = size
+	}
+}
+
+// WithExpiryGCInterval sets how often expired families are removed from the store. Zero
+// keeps them, although they are not served.
+func WithExpiryGCInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.ExpiryGCInterval = interval
+	}
+}
+
+// WithStagedRollout delays applying updates by an offset within window that is derived
+// from the instance ID, so that a bad change reaches a fleet gradually rather than all at
+// once. Updates of kill-switch keys are applied immediately.
+func WithStagedRollout(window time.Duration) Option {
+	return func(c *Config) {
+		c.RolloutWindow = window
+	}
+}
+
+// WithInstanceID sets the ID the instance is identified by, to the server and in events,
+// and that the staged rollout offset is derived from. It defaults to the hostname.
+func WithInstanceID(id string) Option {
+	return func(c *Config) {
+		c.InstanceID = id
+	}
+}
+
+// WithAppName sets the application name sent to the server with every request, so that
+// server-side dashboards attribute polling traffic and staleness to the application.
+func WithAppName(name string) Option {
+	return func(c *Config) {
+		c.AppName = name
+	}
+}
+
+// WithUsageReporting reports the keys read, with how often and when they were last read,
+// to the server every interval, so that flags that are no longer read anywhere can be
+// found and cleaned up.
+func WithUsageReporting(interval time.Duration) Option {
+	return func(c *Config) {
+		c.UsageReportInterval = interval
+	}
+}
+
+// WithRequireSync makes GetFig and GetFigVersion fail with client.ErrNotBootstrapped
+// until the key's namespace has synced with the server, after waiting up to wait for it.
+// Namespaces bootstrapped from the server are synced from the start; those bootstrapped
+// from the local cache or vault sync on their first successful poll.
+func WithRequireSync(wait time.Duration) Option {
+	return func(c *Config) {
+		c.RequireSync = true
+		c.SyncWait = wait
+	}
+}
+
+// WithKillSwitchKeys sets fig keys, e.g. flags that disable a feature in an emergency,
+// whose updates are applied immediately, bypassing the staged rollout and PauseUpdates.
+func WithKillSwitchKeys(keys ...string) Option {
+	return func(c *Config) {
+		c.KillSwitchKeys = keys
+	}
+}
+
+// WithKillSwitchPrefixes treats fig keys starting with any of prefixes as kill-switch
+// keys, see WithKillSwitchKeys.
+func WithKillSwitchPrefixes(prefixes ...string) Option {
+	return func(c *Config) {
+		c.KillSwitchPrefixes = prefixes
+	}
+}
+
+// WithPriorityPollingInterval fetches the kill-switch families every interval, in
+// addition to the regular updates, so that emergency changes propagate quickly even
+// when updates are scheduled or slow to arrive. Families under a kill-switch prefix are
+// fetched once the client holds them.
+func WithPriorityPollingInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.PriorityPollingInterval = interval
+	}
+}
+
+// WithAuditLog appends a JSON line to path for every configuration change the client
+// applies, recording what configuration the process actually ran with.
+func WithAuditLog(path string) Option {
+	return func(c *Config) {
+		c.AuditLogPath = path
+	}
+}
+
+// WithAuditWriter writes the audit log to w, e.g. a log shipper, instead of a file.
+func WithAuditWriter(w io.Writer) Option {
+	return func(c *Config) {
+		c.AuditWriter = w
+	}
+}
+
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
-		BaseURL:           "https://app.figchain.io/api/",
-		PollingInterval:   60 * time.Second,
-		MaxRetries:        3,
-		RetryDelay:        1 * time.Second,
-		HTTPClient:        http.DefaultClient,
-		UseLongPolling:    true,
-		VaultEnabled:      false,
-		BootstrapStrategy: BootstrapStrategyServer,
+		BaseURL:                   "https://app.figchain.io/api/",
+		FailoverCooldown:          30 * time.Second,
+		PollingInterval:           60 * time.Second,
+		MaxRetries:                3,
+		RetryDelay:                1 * time.Second,
+		UseLongPolling:            true,
+		WatchBufferSize:           1,
+		ListenerWorkers:           4,
+		ExpiryGCInterval:          time.Minute,
+		DecryptedPayloadCacheSize: 1024,
+		HistoryDepth:              3,
+		SigningAlgorithm:          "sha256",
+		VaultEnabled:              false,
+		BootstrapStrategy:         BootstrapStrategyServer,
+	}
+}
+
+// WithType registers the Go type the figs of key are decoded into. Listeners register
+// their type automatically, but only once the client has bootstrapped.
+func WithType(key string, prototype interface{ Schema() string }) Option {
+	return func(c *Config) {
+		if c.Types == nil {
+			c.Types = make(map[string]string)
+		}
+		c.Types[key] = prototype.Schema()
+	}
+}
+
+// WithRequestInterceptor adds an interceptor called with every request to the FigChain
+// server before it is sent, e.g. to add correlation ID headers.
+func WithRequestInterceptor(interceptor transport.RequestInterceptor) Option {
+	return func(c *Config) {
+		c.RequestInterceptors = append(c.RequestInterceptors, interceptor)
+	}
+}
+
+// WithResponseInterceptor adds an interceptor called with the outcome of every request
+// to the FigChain server.
+func WithResponseInterceptor(interceptor transport.ResponseInterceptor) Option {
+	return func(c *Config) {
+		c.ResponseInterceptors = append(c.ResponseInterceptors, interceptor)
+	}
+}
+
+// WithRequestSigning HMAC-signs every request to the FigChain server with key, using
+// transport.HMACSHA256 or transport.HMACSHA512.
+func WithRequestSigning(algorithm string, key []byte) Option {
+	return func(c *Config) {
+		c.SigningAlgorithm = algorithm
+		c.SigningKey = string(key)
+	}
+}
+
+// WithAvroAPI sets the Avro API fig payloads are decoded with, e.g. to change decoding
+// limits.
+func WithAvroAPI(api avro.API) Option {
+	return func(c *Config) {
+		c.AvroAPI = api
+	}
+}
+
+// WithAvroType decodes union members of the named Avro type (its full name) into values
+// of obj's type.
+func WithAvroType(name string, obj any) Option {
+	return func(c *Config) {
+		if c.AvroTypes == nil {
+			c.AvroTypes = make(map[string]any)
+		}
+		c.AvroTypes[name] = obj
+	}
+}
+
+// WithAvroTypeConverters adds conversions applied to values decoded into fields of type
+// any.
+func WithAvroTypeConverters(conv ...avro.TypeConverter) Option {
+	return func(c *Config) {
+		c.AvroTypeConverters = append(c.AvroTypeConverters, conv...)
 	}
 }
 
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_Environments(t *testing.T) {
+	path := writeConfig(t, `
+environment_id: env-dev
+polling_interval: 5s
+namespaces: [default]
+environments:
+  prod:
+    aliases: [production]
+    environment_id: env-prod
+    namespaces: [default, payments]
+  staging:
+    environment_id: env-staging
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Env != "" || cfg.EnvironmentID != "env-dev" {
+		t.Errorf("LoadConfig() without FIGCHAIN_ENV = %q, %q, want the top-level settings", cfg.Env, cfg.EnvironmentID)
+	}
+
+	t.Setenv("FIGCHAIN_ENV", "production")
+	cfg, err = LoadConfigStrict(path)
+	if err != nil {
+		t.Fatalf("LoadConfigStrict() error = %v", err)
+	}
+	if cfg.Env != "prod" || cfg.EnvironmentID != "env-prod" || len(cfg.Namespaces) != 2 {
+		t.Errorf("LoadConfigStrict() = %q, %q, %v, want the prod settings", cfg.Env, cfg.EnvironmentID, cfg.Namespaces)
+	}
+	if cfg.PollingInterval != 5*time.Second {
+		t.Errorf("PollingInterval = %s, want the top-level 5s", cfg.PollingInterval)
+	}
+
+	// Environment variables take precedence over the environment's settings
+	t.Setenv("FIGCHAIN_ENVIRONMENT_ID", "env-override")
+	if cfg, err = LoadConfig(path); err != nil || cfg.EnvironmentID != "env-override" {
+		t.Errorf("LoadConfig() = %v, %v, want the environment variable", cfg, err)
+	}
+
+	t.Setenv("FIGCHAIN_ENV", "qa")
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), `unknown environment "qa"`) {
+		t.Errorf("LoadConfig() with an unknown environment error = %v", err)
+	}
+
+	// Unknown keys within an environment are rejected too
+	t.Setenv("FIGCHAIN_ENV", "staging")
+	path = writeConfig(t, "environments:\n  staging:\n    pollig_interval: 5s\n")
+	if _, err := LoadConfigStrict(path); err == nil || !strings.Contains(err.Error(), "pollig_interval") {
+		t.Errorf("LoadConfigStrict() with an unknown key error = %v, want it reported", err)
+	}
+}
+
+type fakeSecretsManager map[string]string
+
+func (sm fakeSecretsManager) GetSecretString(_ context.Context, id string) (string, error) {
+	secret, ok := sm[id]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return secret, nil
+}
+
+func TestLoadConfig_SecretReferences(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "signing-key")
+	if err := os.WriteFile(secretFile, []byte("key-from-file\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	t.Setenv("TEST_FIGCHAIN_SECRET", "secret-from-env")
+	RegisterSecretResolver("test-sm", NewAWSSecretsManagerResolver(fakeSecretsManager{
+		"figchain": `{"client_id": "client-1"}`,
+	}))
+
+	path := writeConfig(t, `
+client_secret: ${env:TEST_FIGCHAIN_SECRET}
+signing_key: ${file:`+secretFile+`}
+auth_client_id: ${test-sm:figchain#client_id}
+global_attributes:
+  service: checkout-${env:TEST_FIGCHAIN_SECRET}
+`)
+	cfg, err := LoadConfigStrict(path)
+	if err != nil {
+		t.Fatalf("LoadConfigStrict() error = %v", err)
+	}
+	if cfg.ClientSecret != "secret-from-env" || cfg.SigningKey != "key-from-file" || cfg.AuthClientID != "client-1" {
+		t.Errorf("LoadConfigStrict() = %q, %q, %q, want the resolved secrets", cfg.ClientSecret, cfg.SigningKey, cfg.AuthClientID)
+	}
+	if got := cfg.GlobalAttributes["service"]; got != "checkout-secret-from-env" {
+		t.Errorf("GlobalAttributes[service] = %q, want the embedded reference resolved", got)
+	}
+
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"unset variable", "client_secret: ${env:TEST_FIGCHAIN_UNSET}", "client_secret: failed to resolve env secret"},
+		{"unknown scheme", "client_secret: ${vault:figchain}", `client_secret: unknown secret scheme "vault"`},
+		{"missing field", "client_secret: ${test-sm:figchain#secret}", "has no string field secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfig(writeConfig(t, tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfig() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// redacted replaces secrets in DumpEffectiveConfig.
+const redacted = "REDACTED"
+
+// secretKeys are the config keys DumpEffectiveConfig redacts.
+var secretKeys = map[string]bool{
+	"client_secret": true,
+	"signing_key":   true,
+}
+
+// Validate checks the formats of the configured URLs, durations, counts and times,
+// reporting every invalid setting by its config key.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.BaseURL == "" {
+		invalid("base_url", "is required")
+	}
+	urls := map[string][]string{
+		"base_url":         {c.BaseURL},
+		"long_polling_url": {c.LongPollingURL},
+		"fallback_urls":    c.FallbackURLs,
+		"vault_endpoint":   {c.VaultEndpoint},
+	}
+	for key, values := range urls {
+		for _, raw := range values {
+			if raw == "" {
+				continue
+			}
+			if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				invalid(key, "%q is not an http(s) URL", raw)
+			}
+		}
+	}
+
+	if c.PollingInterval <= 0 {
+		invalid("polling_interval", "must be positive, got %s", c.PollingInterval)
+	}
+	durations := map[string]time.Duration{
+		"failover_cooldown":          c.FailoverCooldown,
+		"retry_delay":                c.RetryDelay,
+		"idle_conn_timeout":          c.IdleConnTimeout,
+		"http2_ping_interval":        c.HTTP2PingInterval,
+		"expiry_gc_interval":         c.ExpiryGCInterval,
+		"rollout_window":             c.RolloutWindow,
+		"priority_polling_interval":  c.PriorityPollingInterval,
+		"credential_reload_interval": c.CredentialReloadInterval,
+		"usage_report_interval":      c.UsageReportInterval,
+		"sync_wait":                  c.SyncWait,
+	}
+	for key, d := range durations {
+		if d < 0 {
+			invalid(key, "must not be negative, got %s", d)
+		}
+	}
+	counts := map[string]int{
+		"max_retries":                  c.MaxRetries,
+		"max_idle_conns":               c.MaxIdleConns,
+		"max_conns_per_host":           c.MaxConnsPerHost,
+		"watch_buffer_size":            c.WatchBufferSize,
+		"listener_workers":             c.ListenerWorkers,
+		"max_families":                 c.MaxFamilies,
+		"decrypted_payload_cache_size": c.DecryptedPayloadCacheSize,
+		"history_depth":                c.HistoryDepth,
+		"diagnostics_buffer_size":      c.DiagnosticsBufferSize,
+	}
+	for key, n := range counts {
+		if n < 0 {
+			invalid(key, "must not be negative, got %d", n)
+		}
+	}
+
+	if c.AsOfTimestamp != "" {
+		if _, err := time.Parse(time.RFC3339, c.AsOfTimestamp); err != nil {
+			invalid("as_of_timestamp", "%q is not an RFC 3339 timestamp", c.AsOfTimestamp)
+		}
+	}
+	if (c.QuietHoursStart == "") != (c.QuietHoursEnd == "") {
+		invalid("quiet_hours_start", "quiet_hours_start and quiet_hours_end must be set together")
+	}
+	for key, value := range map[string]string{"quiet_hours_start": c.QuietHoursStart, "quiet_hours_end": c.QuietHoursEnd} {
+		if _, err := time.Parse("15:04", value); value != "" && err != nil {
+			invalid(key, "%q is not a time of day (15:04)", value)
+		}
+	}
+	if !validBootstrapStrategy(c.BootstrapStrategy) {
+		invalid("bootstrap_strategy", "unknown strategy %q", c.BootstrapStrategy)
+	}
+	for name, profile := range c.NamespaceProfiles {
+		if profile.PollingInterval < 0 {
+			invalid("namespaces", "%s: polling_interval must not be negative, got %s", name, profile.PollingInterval)
+		}
+		if !validBootstrapStrategy(profile.BootstrapStrategy) {
+			invalid("namespaces", "%s: unknown bootstrap strategy %q", name, profile.BootstrapStrategy)
+		}
+	}
+
+	// Report in a stable order, since the maps above are iterated randomly
+	slices.SortFunc(errs, func(a, b error) int {
+		return strings.Compare(a.Error(), b.Error())
+	})
+	return errors.Join(errs...)
+}
+
+func validBootstrapStrategy(strategy BootstrapStrategy) bool {
+	switch strategy {
+	case "", BootstrapStrategyServer, BootstrapStrategyServerFirst, BootstrapStrategyVault, BootstrapStrategyHybrid:
+		return true
+	}
+	return false
+}
+
+// DumpEffectiveConfig writes the settings of cfg that can be set in the config file, as
+// YAML by config key, e.g. to check how the file and environment variables were merged.
+// Secrets are redacted.
+func DumpEffectiveConfig(w io.Writer, cfg *Config) error {
+	settings := map[string]any{}
+	v := reflect.ValueOf(cfg).Elem()
+	for i := range v.NumField() {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("mapstructure"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		value := v.Field(i).Interface()
+		switch {
+		case secretKeys[key]:
+			if !v.Field(i).IsZero() {
+				value = redacted
+			}
+		case v.Field(i).Type() == reflect.TypeFor[time.Duration]():
+			value = value.(time.Duration).String()
+		}
+		settings[key] = value
+	}
+	// Namespaces with a profile are written in profile form
+	if len(cfg.NamespaceProfiles) > 0 {
+		namespaces := make([]any, 0, len(cfg.Namespaces))
+		for _, ns := range cfg.Namespaces {
+			profile, ok := cfg.NamespaceProfiles[ns]
+			if !ok {
+				namespaces = append(namespaces, ns)
+				continue
+			}
+			namespaces = append(namespaces, map[string]any{
+				"name":                        ns,
+				"polling_interval":            profile.PollingInterval.String(),
+				"bootstrap_strategy":          profile.BootstrapStrategy,
+				"encryption_private_key_path": profile.EncryptionPrivateKeyPath,
+				"priority":                    profile.Priority,
+			})
+		}
+		settings["namespaces"] = namespaces
+	}
+
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(settings); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return enc.Close()
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "figchain.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigStrict(t *testing.T) {
+	path := writeConfig(t, "environment_id: env-1\npolling_interval: 5s\nnamespaces: [default]\n")
+	cfg, err := LoadConfigStrict(path)
+	if err != nil {
+		t.Fatalf("LoadConfigStrict() error = %v", err)
+	}
+	if cfg.PollingInterval != 5*time.Second || cfg.EnvironmentID != "env-1" {
+		t.Errorf("LoadConfigStrict() = %+v, want the file's settings", cfg)
+	}
+
+	// A misspelt key is rejected, though LoadConfig ignores it
+	path = writeConfig(t, "environment_id: env-1\npollig_interval: 5s\n")
+	if _, err := LoadConfigStrict(path); err == nil || !strings.Contains(err.Error(), "pollig_interval") {
+		t.Errorf("LoadConfigStrict() with an unknown key error = %v, want it reported", err)
+	}
+	if _, err := LoadConfig(path); err != nil {
+		t.Errorf("LoadConfig() with an unknown key error = %v", err)
+	}
+
+	path = writeConfig(t, "polling_interval: -5s\n")
+	if _, err := LoadConfigStrict(path); err == nil || !strings.Contains(err.Error(), "polling_interval") {
+		t.Errorf("LoadConfigStrict() with an invalid value error = %v, want it reported", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		cfg := DefaultConfig()
+		cfg.BaseURL = "https://app.figchain.io/api/"
+		return cfg
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Validate() of the defaults error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"missing base URL", func(c *Config) { c.BaseURL = "" }, "base_url: is required"},
+		{"invalid URL", func(c *Config) { c.FallbackURLs = []string{"ftp://backup"} }, `fallback_urls: "ftp://backup" is not an http(s) URL`},
+		{"zero polling interval", func(c *Config) { c.PollingInterval = 0 }, "polling_interval: must be positive"},
+		{"negative duration", func(c *Config) { c.RetryDelay = -time.Second }, "retry_delay: must not be negative"},
+		{"negative count", func(c *Config) { c.MaxRetries = -1 }, "max_retries: must not be negative"},
+		{"invalid timestamp", func(c *Config) { c.AsOfTimestamp = "yesterday" }, "as_of_timestamp"},
+		{"half quiet hours", func(c *Config) { c.QuietHoursStart = "22:00" }, "must be set together"},
+		{"invalid quiet hours", func(c *Config) { c.QuietHoursStart, c.QuietHoursEnd = "22:00", "6am" }, `quiet_hours_end: "6am" is not a time of day`},
+		{"unknown strategy", func(c *Config) { c.BootstrapStrategy = "cache" }, `bootstrap_strategy: unknown strategy "cache"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Every invalid setting is reported, in the same order every time
+	cfg := valid()
+	cfg.RetryDelay = -time.Second
+	cfg.IdleConnTimeout = -time.Second
+	cfg.MaxRetries = -1
+	cfg.ListenerWorkers = -1
+	cfg.LongPollingURL = "not a url"
+	want := cfg.Validate().Error()
+	for range 10 {
+		if got := cfg.Validate().Error(); got != want {
+			t.Fatalf("Validate() error = %q, want the same order as %q", got, want)
+		}
+	}
+	lines := strings.Split(want, "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Validate() reported %d errors, want 5:\n%s", len(lines), want)
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i-1] > lines[i] {
+			t.Errorf("Validate() errors are not sorted:\n%s", want)
+		}
+	}
+}
+
+func TestDumpEffectiveConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BaseURL = "https://app.figchain.io/api/"
+	cfg.ClientSecret = "s3cret"
+	cfg.SigningKey = "k3y"
+	cfg.PollingInterval = 5 * time.Second
+
+	var out strings.Builder
+	if err := DumpEffectiveConfig(&out, cfg); err != nil {
+		t.Fatalf("DumpEffectiveConfig() error = %v", err)
+	}
+	dump := out.String()
+	for _, secret := range []string{"s3cret", "k3y"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump contains the secret %q:\n%s", secret, dump)
+		}
+	}
+	for _, want := range []string{
+		"client_secret: " + redacted,
+		"signing_key: " + redacted,
+		"polling_interval: 5s",
+		"base_url: https://app.figchain.io/api/",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump is missing %q:\n%s", want, dump)
+		}
+	}
+
+	// Unset secrets aren't reported as set
+	cfg.SigningKey = ""
+	out.Reset()
+	DumpEffectiveConfig(&out, cfg)
+	if strings.Contains(out.String(), "signing_key: "+redacted) {
+		t.Errorf("dump redacts an unset signing key:\n%s", out.String())
+	}
+}
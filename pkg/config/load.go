@@ -0,0 +1,206 @@
+//go:build !figchain_noviper && !tinygo
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// LoadConfig loads configuration from a YAML file and environment variables. Secret
+// references in values, e.g. ${env:FIGCHAIN_SECRET}, are resolved (see ResolveSecrets).
+func LoadConfig(path string) (*Config, error) {
+	v, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := readEnvironment(v); err != nil {
+		return nil, err
+	}
+	profiles, err := readNamespaceProfiles(v, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := unmarshal(v, &config, false); err != nil {
+		return nil, err
+	}
+	config.NamespaceProfiles = profiles
+	if err := ResolveSecrets(context.Background(), &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// LoadConfigStrict loads configuration like LoadConfig, but rejects unknown keys, e.g.
+// a misspelt pollig_interval that LoadConfig would silently ignore, and values that fail
+// Validate.
+func LoadConfigStrict(path string) (*Config, error) {
+	v, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := readEnvironment(v); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	profiles, err := readNamespaceProfiles(v, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	var config Config
+	if err := unmarshal(v, &config, true); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	config.NamespaceProfiles = profiles
+	if err := ResolveSecrets(context.Background(), &config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &config, nil
+}
+
+// readEnvironment applies the environments entry selected by the env setting, usually
+// FIGCHAIN_ENV, over the top-level settings of the config file. Entries are selected by
+// name or by one of their aliases; environment variables still take precedence.
+func readEnvironment(v *viper.Viper) error {
+	name := v.GetString("env")
+	if name == "" {
+		return nil
+	}
+	environments, _ := v.Get("environments").(map[string]any)
+	for key, entry := range environments {
+		settings, ok := entry.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid environment %q: not a map of settings", key)
+		}
+		aliases, _ := settings["aliases"].([]any)
+		if !strings.EqualFold(key, name) && !slices.ContainsFunc(aliases, func(alias any) bool {
+			s, ok := alias.(string)
+			return ok && strings.EqualFold(s, name)
+		}) {
+			continue
+		}
+		settings = maps.Clone(settings)
+		delete(settings, "aliases")
+		delete(settings, "environments")
+		if err := v.MergeConfigMap(settings); err != nil {
+			return err
+		}
+		v.Set("env", key)
+		return nil
+	}
+	return fmt.Errorf("unknown environment %q", name)
+}
+
+// unmarshal decodes the settings of v into config like v.Unmarshal, or v.UnmarshalExact
+// with exact, leaving out the environments, which readEnvironment has applied.
+func unmarshal(v *viper.Viper, config *Config, exact bool) error {
+	settings := v.AllSettings()
+	delete(settings, "environments")
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		ErrorUnused:      exact,
+		Result:           config,
+	})
+	if err != nil {
+		return err
+	}
+	return dec.Decode(settings)
+}
+
+// readNamespaceProfiles decodes the namespaces entries given as profiles rather than
+// names, leaving just the names in v. With exact, unknown profile keys are rejected.
+func readNamespaceProfiles(v *viper.Viper, exact bool) (map[string]NamespaceProfile, error) {
+	entries, ok := v.Get("namespaces").([]any)
+	if !ok {
+		return nil, nil
+	}
+	var names []string
+	var profiles map[string]NamespaceProfile
+	for _, entry := range entries {
+		if name, ok := entry.(string); ok {
+			names = append(names, name)
+			continue
+		}
+		var profile NamespaceProfile
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:  mapstructure.StringToTimeDurationHookFunc(),
+			ErrorUnused: exact,
+			Result:      &profile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := dec.Decode(entry); err != nil {
+			return nil, fmt.Errorf("invalid namespace profile: %w", err)
+		}
+		if profile.Name == "" {
+			return nil, fmt.Errorf("invalid namespace profile: name is required")
+		}
+		if profiles == nil {
+			profiles = make(map[string]NamespaceProfile)
+		}
+		profiles[profile.Name] = profile
+		names = append(names, profile.Name)
+	}
+	v.Set("namespaces", names)
+	return profiles, nil
+}
+
+// readConfig reads the YAML file at path, or ./figchain.yaml if path is empty, with
+// FIGCHAIN_* environment variable overrides and defaults.
+func readConfig(path string) (*viper.Viper, error) {
+	v := viper.New()
+
+	if path != "" {
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName("figchain")
+		v.SetConfigType("yaml")
+		v.AddConfigPath(".")
+	}
+
+	// Environment variable overrides
+	v.SetEnvPrefix("FIGCHAIN")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	// Defaults
+	v.SetDefault("base_url", "https://app.figchain.io/api/")
+	v.SetDefault("failover_cooldown", "30s")
+	v.SetDefault("polling_interval", "60s")
+	v.SetDefault("max_retries", 3)
+	v.SetDefault("retry_delay", "1s")
+	v.SetDefault("use_long_polling", true)
+	v.SetDefault("watch_buffer_size", 1)
+	v.SetDefault("listener_workers", 4)
+	v.SetDefault("expiry_gc_interval", "1m")
+	v.SetDefault("decrypted_payload_cache_size", 1024)
+	v.SetDefault("history_depth", 3)
+	v.SetDefault("signing_algorithm", "sha256")
+	v.SetDefault("vault_enabled", false)
+	v.SetDefault("bootstrap_strategy", string(BootstrapStrategyServer))
+
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, err
+		}
+		// Config file not found is fine, we just rely on defaults/env vars
+	}
+	return v, nil
+}
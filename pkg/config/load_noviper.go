@@ -0,0 +1,18 @@
+//go:build figchain_noviper || tinygo
+
+package config
+
+import "errors"
+
+var errLoadUnsupported = errors.New("config files are not supported in this build (figchain_noviper or TinyGo); configure the client with options")
+
+// LoadConfig returns an error: loading config files needs viper, which isn't included in
+// this build.
+func LoadConfig(path string) (*Config, error) {
+	return nil, errLoadUnsupported
+}
+
+// LoadConfigStrict returns an error, like LoadConfig.
+func LoadConfigStrict(path string) (*Config, error) {
+	return nil, errLoadUnsupported
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SecretResolver resolves the references of one scheme in config values, so that secrets
+// don't have to be written into the config file. A reference ${scheme:ref} is replaced by
+// what the scheme's resolver returns for ref.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to a SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// ResolveSecret calls f.
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// secretRef matches a reference to a secret, e.g. ${file:/var/run/secrets/fc}.
+var secretRef = regexp.MustCompile(`\$\{([a-z][a-z0-9-]*):([^}]*)\}`)
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]SecretResolver{
+		"env":  SecretResolverFunc(resolveEnv),
+		"file": SecretResolverFunc(resolveFile),
+	}
+)
+
+// RegisterSecretResolver sets the resolver of the references of scheme in config values,
+// replacing any previous one. The env and file schemes are registered by default. It must
+// be called before the config is loaded, e.g.
+//
+//	config.RegisterSecretResolver("aws-sm", config.NewAWSSecretsManagerResolver(sm))
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[scheme] = r
+}
+
+// resolveEnv resolves ${env:NAME} to the environment variable NAME, which must be set.
+func resolveEnv(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveFile resolves ${file:path} to the contents of the file at path, without the
+// trailing newline.
+func resolveFile(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// AWSSecretsManager is the subset of a Secrets Manager client the resolver needs. It is a
+// thin wrapper around the AWS SDK's GetSecretValue, returning the SecretString.
+type AWSSecretsManager interface {
+	GetSecretString(ctx context.Context, secretID string) (string, error)
+}
+
+// NewAWSSecretsManagerResolver creates a SecretResolver fetching secrets, by name or ARN,
+// from AWS Secrets Manager. A reference ending in #field, e.g.
+// ${aws-sm:arn:aws:secretsmanager:eu-west-1:123456789012:secret:figchain#client_secret},
+// resolves to that field of a secret holding a JSON object.
+func NewAWSSecretsManagerResolver(sm AWSSecretsManager) SecretResolver {
+	return SecretResolverFunc(func(ctx context.Context, ref string) (string, error) {
+		id, field, hasField := strings.Cut(ref, "#")
+		secret, err := sm.GetSecretString(ctx, id)
+		if err != nil || !hasField {
+			return secret, err
+		}
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+			return "", fmt.Errorf("secret %s is not a JSON object", id)
+		}
+		value, ok := fields[field].(string)
+		if !ok {
+			return "", fmt.Errorf("secret %s has no string field %s", id, field)
+		}
+		return value, nil
+	})
+}
+
+// ResolveSecrets replaces the secret references in the settings of cfg that can be set in
+// the config file by the values their resolvers return. LoadConfig and LoadConfigStrict
+// call it; errors name the config key, not the value.
+func ResolveSecrets(ctx context.Context, cfg *Config) error {
+	resolve := func(key, value string) (string, error) {
+		var err error
+		resolved := secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+			if err != nil {
+				return ""
+			}
+			m := secretRef.FindStringSubmatch(ref)
+			resolversMu.RLock()
+			r, ok := resolvers[m[1]]
+			resolversMu.RUnlock()
+			if !ok {
+				err = fmt.Errorf("%s: unknown secret scheme %q", key, m[1])
+				return ""
+			}
+			var secret string
+			if secret, err = r.ResolveSecret(ctx, m[2]); err != nil {
+				err = fmt.Errorf("%s: failed to resolve %s secret: %w", key, m[1], err)
+			}
+			return secret
+		})
+		return resolved, err
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	for i := range v.NumField() {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("mapstructure"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.String:
+			s, err := resolve(key, field.String())
+			if err != nil {
+				return err
+			}
+			field.SetString(s)
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+			for j := range field.Len() {
+				s, err := resolve(key, field.Index(j).String())
+				if err != nil {
+					return err
+				}
+				field.Index(j).SetString(s)
+			}
+		case field.Kind() == reflect.Map && field.Type().Elem().Kind() == reflect.String:
+			for _, k := range field.MapKeys() {
+				s, err := resolve(key, field.MapIndex(k).String())
+				if err != nil {
+					return err
+				}
+				field.SetMapIndex(k, reflect.ValueOf(s).Convert(field.Type().Elem()))
+			}
+		}
+	}
+	for name, profile := range cfg.NamespaceProfiles {
+		path, err := resolve("namespaces", profile.EncryptionPrivateKeyPath)
+		if err != nil {
+			return err
+		}
+		profile.EncryptionPrivateKeyPath = path
+		cfg.NamespaceProfiles[name] = profile
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package encryption
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultPayloadCacheSize is the default number of decrypted payloads a Service caches.
+const DefaultPayloadCacheSize = 1024
+
+// payloadKey identifies the ciphertext of a fig version. The wrapped DEK is part of the
+// key so that a re-encrypted version never serves a stale plaintext.
+type payloadKey struct {
+	namespace  string
+	figID      string
+	version    string
+	wrappedDek string
+}
+
+type cachedPayload struct {
+	key       payloadKey
+	plaintext []byte
+}
+
+// payloadCache is an LRU cache of decrypted payloads. Plaintexts are zeroed when they
+// are evicted or the cache is cleared, and never handed out directly.
+type payloadCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // of *cachedPayload, most recently used first
+	entries  map[payloadKey]*list.Element
+}
+
+func newPayloadCache(capacity int) *payloadCache {
+	return &payloadCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[payloadKey]*list.Element),
+	}
+}
+
+// get returns a copy of the cached plaintext for key.
+func (c *payloadCache) get(key payloadKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return clone(el.Value.(*cachedPayload).plaintext), true
+}
+
+// put caches a copy of plaintext under key.
+func (c *payloadCache) put(key payloadKey, plaintext []byte) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cachedPayload{key: key, plaintext: clone(plaintext)})
+	for c.order.Len() > c.capacity {
+		c.evict(c.order.Back())
+	}
+}
+
+// clear zeroes and removes every cached plaintext.
+func (c *payloadCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.order.Len() > 0 {
+		c.evict(c.order.Back())
+	}
+}
+
+func (c *payloadCache) evict(el *list.Element) {
+	p := c.order.Remove(el).(*cachedPayload)
+	delete(c.entries, p.key)
+	Zero(p.plaintext)
+}
+
+// Zero overwrites b with zeros, e.g. to scrub a decrypted payload once it's no longer needed.
+func Zero(b []byte) {
+	clear(b)
+}
+
+func clone(b []byte) []byte {
+	return append(make([]byte, 0, len(b)), b...)
+}
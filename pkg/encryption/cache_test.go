@@ -0,0 +1,45 @@
+package encryption
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPayloadCache(t *testing.T) {
+	c := newPayloadCache(1)
+	k1 := payloadKey{namespace: "ns", figID: "fig", version: "v1"}
+	k2 := payloadKey{namespace: "ns", figID: "fig", version: "v2"}
+
+	plaintext := []byte("secret")
+	c.put(k1, plaintext)
+
+	got, ok := c.get(k1)
+	if !ok || !bytes.Equal(got, plaintext) {
+		t.Fatalf("get(k1) = %q, %v, want %q, true", got, ok, plaintext)
+	}
+
+	// Returned payloads are copies the caller may scrub
+	Zero(got)
+	if again, _ := c.get(k1); !bytes.Equal(again, plaintext) {
+		t.Errorf("get(k1) after zeroing copy = %q, want %q", again, plaintext)
+	}
+
+	// Evicted plaintexts are zeroed
+	cached := c.entries[k1].Value.(*cachedPayload).plaintext
+	c.put(k2, []byte("other"))
+	if _, ok := c.get(k1); ok {
+		t.Error("get(k1) returned true after eviction")
+	}
+	if !bytes.Equal(cached, make([]byte, len(cached))) {
+		t.Errorf("evicted plaintext = %q, want zeroed", cached)
+	}
+
+	cached = c.entries[k2].Value.(*cachedPayload).plaintext
+	c.clear()
+	if _, ok := c.get(k2); ok {
+		t.Error("get(k2) returned true after clear")
+	}
+	if !bytes.Equal(cached, make([]byte, len(cached))) {
+		t.Errorf("cleared plaintext = %q, want zeroed", cached)
+	}
+}
@@ -44,6 +44,67 @@ func DecryptAESGCM(cipherText []byte, key []byte) ([]byte, error) {
 	return aesgcm.Open(nil, iv, actualCipher, nil)
 }
 
+// EncryptAESGCM encrypts plainText with key, prefixing the result with a random 12-byte
+// nonce, as DecryptAESGCM expects.
+func EncryptAESGCM(plainText []byte, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCMWithNonceSize(block, 12)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, 12, 12+len(plainText)+aesgcm.Overhead())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	return aesgcm.Seal(iv, iv, plainText, nil), nil
+}
+
+// WrapAESKey implements RFC 3394 AES Key Wrap.
+func WrapAESKey(key, kek []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, errors.New("invalid key length")
+	}
+	n := len(key) / 8
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := make([]byte, len(key)+8)
+	a := wrapped[:8]
+	binary.BigEndian.PutUint64(a, 0xA6A6A6A6A6A6A6A6)
+	r := wrapped[8:]
+	copy(r, key)
+
+	// Scratch buffers hold key material, so they are scrubbed before returning
+	input := make([]byte, 16)
+	output := make([]byte, 16)
+	defer Zero(input)
+	defer Zero(output)
+
+	for j := 0; j <= 5; j++ {
+		for i := 1; i <= n; i++ {
+			t := uint64(n*j + i)
+
+			// B = AES_ENC(K, A | R[i])
+			offset := (i - 1) * 8
+			copy(input[:8], a)
+			copy(input[8:], r[offset:offset+8])
+			block.Encrypt(output, input)
+
+			// A = MSB(64, B) ^ t
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(output[:8])^t)
+			// R[i] = LSB(64, B)
+			copy(r[offset:offset+8], output[8:])
+		}
+	}
+	return wrapped, nil
+}
+
 // UnwrapAESKey implements RFC 3394 AES Key Unwrap.
 func UnwrapAESKey(wrappedKey, kek []byte) ([]byte, error) {
 	if len(wrappedKey)%8 != 0 {
@@ -65,6 +126,12 @@ func UnwrapAESKey(wrappedKey, kek []byte) ([]byte, error) {
 	r := make([]byte, len(wrappedKey)-8)
 	copy(r, wrappedKey[8:])
 
+	// Scratch buffers hold key material, so they are scrubbed before returning
+	input := make([]byte, 16)
+	output := make([]byte, 16)
+	defer Zero(input)
+	defer Zero(output)
+
 	for j := 5; j >= 0; j-- {
 		for i := n; i >= 1; i-- {
 			t := uint64(n*j + i)
@@ -76,11 +143,9 @@ func UnwrapAESKey(wrappedKey, kek []byte) ([]byte, error) {
 			// B = AES_DEC(K, A | R[i])
 			offset := (i - 1) * 8
 
-			input := make([]byte, 16)
 			copy(input[:8], a)
 			copy(input[8:], r[offset:offset+8])
 
-			output := make([]byte, 16)
 			block.Decrypt(output, input)
 
 			// A = MSB(64, B)
@@ -92,6 +157,7 @@ func UnwrapAESKey(wrappedKey, kek []byte) ([]byte, error) {
 
 	// Check IV (0xA6A6A6A6A6A6A6A6)
 	if binary.BigEndian.Uint64(a) != 0xA6A6A6A6A6A6A6A6 {
+		Zero(r)
 		return nil, fmt.Errorf("%w: integrity check failed", ErrUnwrap)
 	}
 
@@ -0,0 +1,45 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestWrapAESKey(t *testing.T) {
+	// RFC 3394, section 4.1: wrap 128 bits of key data with a 128-bit KEK
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF")
+	want, _ := hex.DecodeString("1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5")
+
+	wrapped, err := WrapAESKey(key, kek)
+	if err != nil {
+		t.Fatalf("WrapAESKey failed: %v", err)
+	}
+	if !bytes.Equal(wrapped, want) {
+		t.Errorf("WrapAESKey() = %X, want %X", wrapped, want)
+	}
+	unwrapped, err := UnwrapAESKey(wrapped, kek)
+	if err != nil || !bytes.Equal(unwrapped, key) {
+		t.Errorf("UnwrapAESKey() = %X, %v, want %X", unwrapped, err, key)
+	}
+	if _, err := WrapAESKey(key[:12], kek); err == nil {
+		t.Error("Expected WrapAESKey to reject a key that isn't a multiple of 64 bits")
+	}
+}
+
+func TestEncryptAESGCM(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	plaintext := []byte("payload")
+	a, err := EncryptAESGCM(plaintext, key)
+	if err != nil {
+		t.Fatalf("EncryptAESGCM failed: %v", err)
+	}
+	b, _ := EncryptAESGCM(plaintext, key)
+	if bytes.Equal(a, b) {
+		t.Error("Expected a fresh nonce for every encryption")
+	}
+	if decrypted, err := DecryptAESGCM(a, key); err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("DecryptAESGCM() = %q, %v, want %q", decrypted, err, plaintext)
+	}
+}
@@ -0,0 +1,222 @@
+package encryption
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/transport"
+	"github.com/figchain/go-client/pkg/util"
+)
+
+// Key algorithms generated by KeyManager.
+const (
+	AlgorithmRSA = "RSA"
+	AlgorithmEC  = "EC"
+)
+
+// DefaultRSABits is the size of RSA keys generated by KeyManager.
+const DefaultRSABits = 4096
+
+// KeyManager generates, persists and registers a user's keypair, automating the
+// onboarding of a client for encrypted namespaces or private key authentication.
+type KeyManager struct {
+	transport transport.Transport
+	email     string
+	algorithm string
+	rsaBits   int
+}
+
+// KeyManagerOption is a functional option for configuring a KeyManager.
+type KeyManagerOption func(*KeyManager)
+
+// WithKeyAlgorithm sets the algorithm of generated keys, AlgorithmRSA (the default) or
+// AlgorithmEC. Only RSA keys can decrypt encrypted namespaces.
+func WithKeyAlgorithm(algorithm string) KeyManagerOption {
+	return func(m *KeyManager) {
+		m.algorithm = algorithm
+	}
+}
+
+// WithRSABits sets the size of generated RSA keys. Defaults to DefaultRSABits.
+func WithRSABits(bits int) KeyManagerOption {
+	return func(m *KeyManager) {
+		m.rsaBits = bits
+	}
+}
+
+// NewKeyManager creates a KeyManager registering keys for email.
+func NewKeyManager(t transport.Transport, email string, opts ...KeyManagerOption) *KeyManager {
+	m := &KeyManager{
+		transport: t,
+		email:     email,
+		algorithm: AlgorithmRSA,
+		rsaBits:   DefaultRSABits,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Generate creates a new private key.
+func (m *KeyManager) Generate() (crypto.Signer, error) {
+	switch m.algorithm {
+	case AlgorithmRSA:
+		return rsa.GenerateKey(rand.Reader, m.rsaBits)
+	case AlgorithmEC:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", m.algorithm)
+	}
+}
+
+// EnsureKey loads the private key at path, generating and saving a new one if the file
+// doesn't exist, and registers its public key unless it already is.
+func (m *KeyManager) EnsureKey(ctx context.Context, path string) (crypto.Signer, error) {
+	key, err := LoadSigner(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if key, err = m.Generate(); err != nil {
+			return nil, fmt.Errorf("failed to generate key: %w", err)
+		}
+		if err = SaveKey(path, key); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.EnsureRegistered(ctx, key.Public()); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EnsureRegistered registers a public key unless it already is.
+func (m *KeyManager) EnsureRegistered(ctx context.Context, pub crypto.PublicKey) error {
+	encoded, err := encodePublicKey(pub)
+	if err != nil {
+		return err
+	}
+	registered, err := m.Keys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, k := range registered {
+		if k.PublicKey == encoded {
+			return nil
+		}
+	}
+	return m.Register(ctx, pub)
+}
+
+// Register uploads public keys for the manager's email. Every key is attempted; the
+// errors of those that fail are joined.
+func (m *KeyManager) Register(ctx context.Context, keys ...crypto.PublicKey) error {
+	var errs []error
+	for _, pub := range keys {
+		encoded, err := encodePublicKey(pub)
+		if err == nil {
+			err = m.transport.UploadPublicKey(ctx, &model.UserPublicKey{
+				Email:     m.email,
+				PublicKey: encoded,
+				Algorithm: keyAlgorithm(pub),
+			})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to register public key: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Keys lists the public keys registered for the manager's email.
+func (m *KeyManager) Keys(ctx context.Context) ([]*model.UserPublicKey, error) {
+	keys, err := m.transport.ListPublicKeys(ctx, m.email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list public keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke deletes registered public keys, e.g. after rotating to a new key.
+func (m *KeyManager) Revoke(ctx context.Context, keyIDs ...string) error {
+	var errs []error
+	for _, id := range keyIDs {
+		if err := m.transport.DeletePublicKey(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("failed to revoke public key %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SaveKey writes a private key to path as PKCS8 PEM, readable only by its owner. It
+// doesn't overwrite an existing file.
+func SaveKey(path string, key crypto.Signer) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return f.Close()
+}
+
+// LoadSigner loads an RSA or EC private key from a PEM file in PKCS8, PKCS1 or SEC 1
+// format.
+func LoadSigner(path string) (crypto.Signer, error) {
+	data, err := util.ReadKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("decode pem failed")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("failed to parse private key (tried PKCS8, PKCS1 and SEC 1)")
+}
+
+// encodePublicKey encodes a public key as PKIX PEM.
+func encodePublicKey(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func keyAlgorithm(pub crypto.PublicKey) string {
+	if _, ok := pub.(*ecdsa.PublicKey); ok {
+		return AlgorithmEC
+	}
+	return AlgorithmRSA
+}
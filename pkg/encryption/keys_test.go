@@ -0,0 +1,160 @@
+package encryption
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// newKeyServer serves an in-memory public key registry.
+func newKeyServer(t *testing.T) (*httptest.Server, *[]*model.UserPublicKey) {
+	t.Helper()
+	var mu sync.Mutex
+	var keys []*model.UserPublicKey
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/keys/public":
+			var k model.UserPublicKey
+			if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			k.KeyID = "key-" + strconv.Itoa(len(keys)+1)
+			keys = append(keys, &k)
+		case r.Method == "GET" && r.URL.Path == "/keys/public":
+			var found []*model.UserPublicKey
+			for _, k := range keys {
+				if k.Email == r.URL.Query().Get("email") {
+					found = append(found, k)
+				}
+			}
+			json.NewEncoder(w).Encode(found)
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/keys/public/"):
+			id := strings.TrimPrefix(r.URL.Path, "/keys/public/")
+			for i, k := range keys {
+				if k.KeyID == id {
+					keys = append(keys[:i], keys[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &keys
+}
+
+func TestKeyManager_EnsureKey(t *testing.T) {
+	server, keys := newKeyServer(t)
+	tr := transport.NewHTTPTransport(server.Client(), server.URL, transport.NewSharedSecretTokenProvider("secret"), "env-1")
+	m := NewKeyManager(tr, "dev@example.com", WithKeyAlgorithm(AlgorithmEC))
+	path := filepath.Join(t.TempDir(), "key.pem")
+
+	key, err := m.EnsureKey(context.Background(), path)
+	if err != nil {
+		t.Fatalf("EnsureKey failed: %v", err)
+	}
+	if _, ok := key.(*ecdsa.PrivateKey); !ok {
+		t.Errorf("EnsureKey generated %T, want *ecdsa.PrivateKey", key)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("key file = %v, %v, want mode 0600", info, err)
+	}
+	if len(*keys) != 1 || (*keys)[0].Algorithm != AlgorithmEC || (*keys)[0].Email != "dev@example.com" {
+		t.Fatalf("registered keys = %+v, want one EC key", *keys)
+	}
+
+	// The saved key is reused and not registered again
+	again, err := m.EnsureKey(context.Background(), path)
+	if err != nil {
+		t.Fatalf("second EnsureKey failed: %v", err)
+	}
+	if !again.(*ecdsa.PrivateKey).Equal(key) {
+		t.Error("second EnsureKey returned a different key")
+	}
+	if len(*keys) != 1 {
+		t.Errorf("second EnsureKey registered the key again: %d keys", len(*keys))
+	}
+}
+
+func TestKeyManager_RegisterAndRevoke(t *testing.T) {
+	server, _ := newKeyServer(t)
+	tr := transport.NewHTTPTransport(server.Client(), server.URL, transport.NewSharedSecretTokenProvider("secret"), "env-1")
+	m := NewKeyManager(tr, "dev@example.com", WithRSABits(2048))
+
+	rsaKey, err := m.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, ok := rsaKey.(*rsa.PrivateKey); !ok {
+		t.Fatalf("Generate returned %T, want *rsa.PrivateKey", rsaKey)
+	}
+	ecKey, err := NewKeyManager(tr, "dev@example.com", WithKeyAlgorithm(AlgorithmEC)).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := m.Register(context.Background(), rsaKey.Public(), ecKey.Public()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	keys, err := m.Keys(context.Background())
+	if err != nil {
+		t.Fatalf("Keys failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Algorithm != AlgorithmRSA || keys[1].Algorithm != AlgorithmEC {
+		t.Fatalf("Keys() = %+v, want an RSA and an EC key", keys)
+	}
+
+	if err := m.Revoke(context.Background(), keys[0].KeyID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if keys, _ := m.Keys(context.Background()); len(keys) != 1 {
+		t.Errorf("Keys() after Revoke = %+v, want 1 key", keys)
+	}
+	if err := m.Revoke(context.Background(), "missing"); !errors.Is(err, transport.ErrNotFound) {
+		t.Errorf("Revoke(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	key, err := NewKeyManager(nil, "", WithRSABits(2048)).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := SaveKey(path, key); err != nil {
+		t.Fatalf("SaveKey failed: %v", err)
+	}
+	if err := SaveKey(path, key); err == nil {
+		t.Error("SaveKey overwrote an existing key")
+	}
+	loaded, err := LoadSigner(path)
+	if err != nil {
+		t.Fatalf("LoadSigner failed: %v", err)
+	}
+	if !loaded.(*rsa.PrivateKey).Equal(key) {
+		t.Error("LoadSigner returned a different key")
+	}
+	// RSA keys saved by SaveKey are usable for decryption
+	if _, err := LoadPrivateKey(path); err != nil {
+		t.Errorf("LoadPrivateKey failed: %v", err)
+	}
+}
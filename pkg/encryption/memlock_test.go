@@ -0,0 +1,13 @@
+package encryption
+
+import "testing"
+
+func TestLockMemory(t *testing.T) {
+	key := make([]byte, 32)
+	if err := lockMemory(key); err != nil {
+		t.Skipf("memory locking unavailable: %v", err)
+	}
+	if err := unlockMemory(key); err != nil {
+		t.Errorf("unlockMemory failed: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package encryption
+
+import "syscall"
+
+// lockMemory locks b into RAM, so that it is never written to swap.
+func lockMemory(b []byte) error {
+	return syscall.Mlock(b)
+}
+
+// unlockMemory undoes lockMemory.
+func unlockMemory(b []byte) error {
+	return syscall.Munlock(b)
+}
@@ -2,33 +2,111 @@ package encryption
 
 import (
 	"context"
+	"crypto"
+	"crypto/hkdf"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
-	"log"
 	"sync"
+	"sync/atomic"
 
+	"github.com/figchain/go-client/pkg/logging"
 	"github.com/figchain/go-client/pkg/model"
 	"github.com/figchain/go-client/pkg/transport"
 )
 
 type Service struct {
 	transport  transport.Transport
-	privateKey *rsa.PrivateKey
+	privateKey atomic.Pointer[rsa.PrivateKey]
 	nskCache   sync.Map
+	// inUse is held for reading while cached keys are in use, so that Close doesn't zero
+	// them under a concurrent Decrypt or Encrypt.
+	inUse      sync.RWMutex
+	payloads   *payloadCache
+	lockKeys   bool
+	lockFailed sync.Once
 }
 
-func NewService(t transport.Transport, privateKeyPath string) (*Service, error) {
+// ServiceOption is a functional option for configuring a Service.
+type ServiceOption func(*Service)
+
+// WithPayloadCacheSize sets how many decrypted payloads are cached, by fig version.
+// Zero disables caching. Defaults to DefaultPayloadCacheSize.
+func WithPayloadCacheSize(size int) ServiceOption {
+	return func(s *Service) {
+		s.payloads = newPayloadCache(size)
+	}
+}
+
+// WithLockedKeys locks unwrapped namespace keys into RAM with mlock, so that they are
+// never written to swap. It is best-effort: if locking fails, e.g. because of
+// RLIMIT_MEMLOCK, a warning is logged and the keys are used unlocked.
+func WithLockedKeys() ServiceOption {
+	return func(s *Service) {
+		s.lockKeys = true
+	}
+}
+
+func NewService(t transport.Transport, privateKeyPath string, opts ...ServiceOption) (*Service, error) {
 	pk, err := LoadPrivateKey(privateKeyPath)
 	if err != nil {
 		return nil, err
 	}
-	return &Service{
-		transport:  t,
-		privateKey: pk,
-	}, nil
+	s := &Service{
+		transport: t,
+		payloads:  newPayloadCache(DefaultPayloadCacheSize),
+	}
+	s.privateKey.Store(pk)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// PublicKey returns the public key of the service's private key.
+func (s *Service) PublicKey() crypto.PublicKey {
+	return s.privateKey.Load().Public()
+}
+
+// SwapPrivateKey replaces the service's private key, e.g. once it has been rotated on
+// disk. Namespace keys already unwrapped stay cached; new ones are unwrapped with pk.
+func (s *Service) SwapPrivateKey(pk *rsa.PrivateKey) {
+	s.privateKey.Store(pk)
+}
+
+// DeriveKey derives a size-byte symmetric key from the service's private key, e.g. to
+// encrypt data at rest. Keys derived with different info are independent.
+func (s *Service) DeriveKey(info string, size int) ([]byte, error) {
+	secret, err := x509.MarshalPKCS8PrivateKey(s.privateKey.Load())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	defer Zero(secret)
+	return hkdf.Key(sha256.New, secret, nil, info, size)
+}
+
+// Close zeroes and drops all cached keys and decrypted payloads. It waits for Decrypt and
+// Encrypt calls using cached keys to return.
+func (s *Service) Close() {
+	s.inUse.Lock()
+	defer s.inUse.Unlock()
+	s.payloads.clear()
+	s.nskCache.Range(func(k, v any) bool {
+		nsk := v.([]byte)
+		Zero(nsk)
+		if s.lockKeys {
+			unlockMemory(nsk)
+		}
+		s.nskCache.Delete(k)
+		return true
+	})
 }
 
+// Decrypt returns the plaintext payload of fig. Decrypted payloads are cached by fig
+// version; each call returns a fresh copy that the caller may Zero once done with it.
 func (s *Service) Decrypt(ctx context.Context, fig *model.Fig, namespace string) ([]byte, error) {
 	if !fig.IsEncrypted {
 		return fig.Payload, nil
@@ -39,10 +117,20 @@ func (s *Service) Decrypt(ctx context.Context, fig *model.Fig, namespace string)
 		keyID = *fig.KeyID
 	}
 
-	nsk, err := s.getNSK(ctx, namespace, keyID)
+	cacheKey := payloadKey{namespace: namespace, figID: fig.FigID, version: fig.Version, wrappedDek: string(fig.WrappedDek)}
+	if payload, ok := s.payloads.get(cacheKey); ok {
+		return payload, nil
+	}
+
+	s.inUse.RLock()
+	defer s.inUse.RUnlock()
+	nsk, cached, err := s.getNSK(ctx, namespace, keyID)
 	if err != nil {
 		return nil, fmt.Errorf("get nsk: %w", err)
 	}
+	if !cached {
+		defer Zero(nsk)
+	}
 
 	wrappedDek := fig.WrappedDek
 	if len(wrappedDek) == 0 {
@@ -55,26 +143,72 @@ func (s *Service) Decrypt(ctx context.Context, fig *model.Fig, namespace string)
 	}
 
 	payload, err := DecryptAESGCM(fig.Payload, dek)
+	Zero(dek)
 	if err != nil {
 		return nil, fmt.Errorf("decrypt payload: %w", err)
 	}
-
-	log.Printf("DEBUG Decryption: encrypted=%d bytes, decrypted=%d bytes",
-		len(fig.Payload), len(payload))
+	s.payloads.put(cacheKey, payload)
 
 	return payload, nil
 }
 
-func (s *Service) getNSK(ctx context.Context, namespace, keyID string) ([]byte, error) {
+// Encrypt encrypts plaintext for namespace, returning a fig with the encrypted payload,
+// the wrapped data key and the ID of the namespace key that wraps it, which Decrypt, with
+// access to the namespace key, can decrypt. A fresh data key is generated for every call.
+// Namespaces with several keys are encrypted with the first key the server lists, its
+// current one.
+func (s *Service) Encrypt(ctx context.Context, namespace string, plaintext []byte) (*model.Fig, error) {
+	nsKeys, err := s.transport.GetNamespaceKey(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("get nsk: %w", err)
+	}
+	if len(nsKeys) == 0 {
+		return nil, fmt.Errorf("no keys found for namespace %s", namespace)
+	}
+	keyID := nsKeys[0].KeyID
+	s.inUse.RLock()
+	defer s.inUse.RUnlock()
+	nsk, cached, err := s.getNSK(ctx, namespace, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get nsk: %w", err)
+	}
+	if !cached {
+		defer Zero(nsk)
+	}
+
+	dek := make([]byte, 32)
+	defer Zero(dek)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("generate dek: %w", err)
+	}
+	wrappedDek, err := WrapAESKey(dek, nsk)
+	if err != nil {
+		return nil, fmt.Errorf("wrap dek: %w", err)
+	}
+	payload, err := EncryptAESGCM(plaintext, dek)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt payload: %w", err)
+	}
+
+	fig := &model.Fig{Payload: payload, IsEncrypted: true, WrappedDek: wrappedDek}
+	if keyID != "" {
+		fig.KeyID = &keyID
+	}
+	return fig, nil
+}
+
+// getNSK returns the unwrapped namespace key with keyID, and whether it is cached. Keys
+// that aren't cached should be zeroed after use.
+func (s *Service) getNSK(ctx context.Context, namespace, keyID string) ([]byte, bool, error) {
 	if keyID != "" {
 		if val, ok := s.nskCache.Load(keyID); ok {
-			return val.([]byte), nil
+			return val.([]byte), true, nil
 		}
 	}
 
 	nsKeys, err := s.transport.GetNamespaceKey(ctx, namespace)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	var matchingKey *model.NamespaceKey
@@ -92,28 +226,40 @@ func (s *Service) getNSK(ctx context.Context, namespace, keyID string) ([]byte,
 				matchingKey = nsKeys[0]
 			} else if len(nsKeys) > 1 {
 				// Multiple keys exist but fig has no keyID - this is ambiguous and unsafe
-				return nil, fmt.Errorf("namespace %s has %d keys but fig has no keyId specified; cannot determine which key to use", namespace, len(nsKeys))
+				return nil, false, fmt.Errorf("namespace %s has %d keys but fig has no keyId specified; cannot determine which key to use", namespace, len(nsKeys))
 			} else {
-				return nil, fmt.Errorf("no keys found for namespace %s", namespace)
+				return nil, false, fmt.Errorf("no keys found for namespace %s", namespace)
 			}
 		} else {
-			return nil, fmt.Errorf("no matching key found for namespace %s and keyId %s", namespace, keyID)
+			return nil, false, fmt.Errorf("no matching key found for namespace %s and keyId %s", namespace, keyID)
 		}
 	}
 
 	wrappedKeyBytes, err := base64.StdEncoding.DecodeString(matchingKey.WrappedKey)
 	if err != nil {
-		return nil, fmt.Errorf("decode nsk: %w", err)
+		return nil, false, fmt.Errorf("decode nsk: %w", err)
 	}
 
-	unwrappedNsk, err := DecryptRSAOAEP(wrappedKeyBytes, s.privateKey)
+	unwrappedNsk, err := DecryptRSAOAEP(wrappedKeyBytes, s.privateKey.Load())
 	if err != nil {
-		return nil, fmt.Errorf("decrypt nsk: %w", err)
+		return nil, false, fmt.Errorf("decrypt nsk: %w", err)
 	}
 
 	if matchingKey.KeyID != "" {
-		s.nskCache.Store(matchingKey.KeyID, unwrappedNsk)
+		if cached, loaded := s.nskCache.LoadOrStore(matchingKey.KeyID, unwrappedNsk); loaded {
+			// Fetched concurrently; keep the cached copy
+			Zero(unwrappedNsk)
+			return cached.([]byte), true, nil
+		}
+		if s.lockKeys {
+			if err := lockMemory(unwrappedNsk); err != nil {
+				s.lockFailed.Do(func() {
+					logging.Printf("Warning: failed to lock namespace keys into memory: %v", err)
+				})
+			}
+		}
+		return unwrappedNsk, true, nil
 	}
 
-	return unwrappedNsk, nil
+	return unwrappedNsk, false, nil
 }
@@ -0,0 +1,175 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// newTestService returns a Service whose namespace "default" has the keys "current" and
+// "previous", both the same key. If fetched is set, it is called before the keys are
+// served.
+func newTestService(t *testing.T, fetched func(), opts ...ServiceOption) *Service {
+	t.Helper()
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	key, err := NewKeyManager(nil, "", WithRSABits(2048)).Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := SaveKey(keyPath, key); err != nil {
+		t.Fatalf("SaveKey failed: %v", err)
+	}
+	nsk := bytes.Repeat([]byte{7}, 32)
+	wrappedNsk, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key.Public().(*rsa.PublicKey), nsk, nil)
+	if err != nil {
+		t.Fatalf("EncryptOAEP failed: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/keys/namespace/default" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if fetched != nil {
+			fetched()
+		}
+		json.NewEncoder(w).Encode([]model.NamespaceKey{
+			{WrappedKey: base64.StdEncoding.EncodeToString(wrappedNsk), KeyID: "current"},
+			{WrappedKey: base64.StdEncoding.EncodeToString(wrappedNsk), KeyID: "previous"},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	tr := transport.NewHTTPTransport(server.Client(), server.URL, transport.NewSharedSecretTokenProvider("secret"), "env-1")
+	s, err := NewService(tr, keyPath, opts...)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	return s
+}
+
+func TestService_Encrypt(t *testing.T) {
+	s := newTestService(t, nil, WithPayloadCacheSize(0))
+	defer s.Close()
+
+	plaintext := []byte("\x06foo")
+	fig, err := s.Encrypt(context.Background(), "default", plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !fig.IsEncrypted || fig.KeyID == nil || *fig.KeyID != "current" || bytes.Contains(fig.Payload, plaintext) {
+		t.Errorf("Encrypt() = %+v, want a payload encrypted with the current key", fig)
+	}
+	fig.Version = "v1"
+	decrypted, err := s.Decrypt(context.Background(), fig, "default")
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decrypt() = %q, %v, want %q", decrypted, err, plaintext)
+	}
+	if _, err := s.Encrypt(context.Background(), "missing", plaintext); err == nil {
+		t.Error("Expected Encrypt to fail for a namespace without keys")
+	}
+}
+
+func TestService_Zeroization(t *testing.T) {
+	s := newTestService(t, nil, WithPayloadCacheSize(1))
+	ctx := context.Background()
+
+	var figs []*model.Fig
+	for _, version := range []string{"v1", "v2"} {
+		fig, err := s.Encrypt(ctx, "default", []byte("secret "+version))
+		if err != nil {
+			t.Fatalf("Encrypt failed: %v", err)
+		}
+		fig.Version = version
+		figs = append(figs, fig)
+	}
+	cachedPayload := func(fig *model.Fig) []byte {
+		el := s.payloads.entries[payloadKey{namespace: "default", version: fig.Version, wrappedDek: string(fig.WrappedDek)}]
+		return el.Value.(*cachedPayload).plaintext
+	}
+	zeroed := func(b []byte) bool { return bytes.Equal(b, make([]byte, len(b))) }
+
+	if _, err := s.Decrypt(ctx, figs[0], "default"); err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	first := cachedPayload(figs[0])
+	if _, err := s.Decrypt(ctx, figs[1], "default"); err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !zeroed(first) {
+		t.Errorf("evicted payload = %q, want zeroed", first)
+	}
+
+	second := cachedPayload(figs[1])
+	value, ok := s.nskCache.Load("current")
+	if !ok {
+		t.Fatal("namespace key wasn't cached")
+	}
+	nsk := value.([]byte)
+	s.Close()
+	if !zeroed(second) {
+		t.Errorf("cached payload after Close = %q, want zeroed", second)
+	}
+	if !zeroed(nsk) {
+		t.Error("cached namespace key wasn't zeroed by Close")
+	}
+}
+
+func TestService_CloseDuringDecrypt(t *testing.T) {
+	var gated atomic.Bool
+	fetching, release := make(chan struct{}), make(chan struct{})
+	s := newTestService(t, func() {
+		if gated.Load() {
+			close(fetching)
+			<-release
+		}
+	}, WithPayloadCacheSize(0))
+	ctx := context.Background()
+	plaintext := []byte("secret")
+	fig, err := s.Encrypt(ctx, "default", plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	s.Close()
+
+	// A Decrypt fetching and using the namespace key holds Close back, so that the key
+	// isn't zeroed under it
+	gated.Store(true)
+	decrypted := make(chan error, 1)
+	go func() {
+		got, err := s.Decrypt(ctx, fig, "default")
+		if err == nil && !bytes.Equal(got, plaintext) {
+			err = fmt.Errorf("got %q, want %q", got, plaintext)
+		}
+		decrypted <- err
+	}()
+	<-fetching
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Error("Close returned during a Decrypt")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-decrypted; err != nil {
+		t.Errorf("Decrypt() error = %v", err)
+	}
+	<-closed
+}
@@ -0,0 +1,116 @@
+package evaluation
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"math/bits"
+)
+
+// BucketingStrategy maps an attribute value to a bucket in [0, 100) for SPLIT conditions.
+// Implementations must be deterministic so that a value always lands in the same bucket.
+type BucketingStrategy interface {
+	Bucket(value string) int
+}
+
+// BucketingStrategyFunc adapts a function to the BucketingStrategy interface.
+type BucketingStrategyFunc func(value string) int
+
+// Bucket implements BucketingStrategy.
+func (f BucketingStrategyFunc) Bucket(value string) int {
+	return f(value)
+}
+
+// FNV1aBucketing buckets values by their 32-bit FNV-1a hash. This is the default strategy.
+type FNV1aBucketing struct{}
+
+// Bucket implements BucketingStrategy.
+func (FNV1aBucketing) Bucket(value string) int {
+	hash := uint32(0x811c9dc5)
+	const prime = 0x01000193
+	for i := 0; i < len(value); i++ {
+		hash ^= uint32(value[i])
+		hash *= prime
+	}
+	return int(hash % 100)
+}
+
+// SHA1Bucketing buckets values by the first 60 bits of their SHA-1 digest scaled to
+// [0, 100), the scheme used by several other feature flag SDKs.
+type SHA1Bucketing struct{}
+
+// Bucket implements BucketingStrategy.
+func (SHA1Bucketing) Bucket(value string) int {
+	sum := sha1.Sum([]byte(value))
+	n := binary.BigEndian.Uint64(sum[:8]) >> 4
+	return int(float64(n) / float64(uint64(1)<<60) * 100)
+}
+
+// Murmur3Bucketing buckets values by their 32-bit MurmurHash3 (x86) hash with Seed.
+type Murmur3Bucketing struct {
+	Seed uint32
+}
+
+// Bucket implements BucketingStrategy.
+func (m Murmur3Bucketing) Bucket(value string) int {
+	return int(murmur3(m.Seed, []byte(value)) % 100)
+}
+
+// SaltedBucketing prefixes values with Salt before bucketing them with Strategy, so that
+// independent rollouts don't bucket the same values identically. A nil Strategy uses
+// FNV1aBucketing.
+type SaltedBucketing struct {
+	Salt     string
+	Strategy BucketingStrategy
+}
+
+// Bucket implements BucketingStrategy.
+func (s SaltedBucketing) Bucket(value string) int {
+	strategy := s.Strategy
+	if strategy == nil {
+		strategy = FNV1aBucketing{}
+	}
+	return strategy.Bucket(s.Salt + value)
+}
+
+func murmur3(seed uint32, data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	h := seed
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[n*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
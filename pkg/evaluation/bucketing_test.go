@@ -0,0 +1,68 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		seed  uint32
+		input string
+		want  uint32
+	}{
+		{0, "", 0},
+		{1, "", 0x514e28b7},
+		{0, "hello", 0x248bfa47},
+		{0, "The quick brown fox jumps over the lazy dog", 0x2e4ff723},
+	}
+	for _, tt := range tests {
+		if got := murmur3(tt.seed, []byte(tt.input)); got != tt.want {
+			t.Errorf("murmur3(%d, %q) = %#x, want %#x", tt.seed, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestBucketingStrategies_Range(t *testing.T) {
+	strategies := map[string]BucketingStrategy{
+		"fnv1a":   FNV1aBucketing{},
+		"sha1":    SHA1Bucketing{},
+		"murmur3": Murmur3Bucketing{},
+		"salted":  SaltedBucketing{Salt: "experiment-1"},
+	}
+	for name, strategy := range strategies {
+		for _, value := range []string{"", "user-1", "user-2", "a much longer user identifier"} {
+			bucket := strategy.Bucket(value)
+			if bucket < 0 || bucket >= 100 {
+				t.Errorf("%s: Bucket(%q) = %d, want [0, 100)", name, value, bucket)
+			}
+			if again := strategy.Bucket(value); again != bucket {
+				t.Errorf("%s: Bucket(%q) not deterministic: %d != %d", name, value, bucket, again)
+			}
+		}
+	}
+}
+
+func TestRuleBasedEvaluator_BucketingStrategy(t *testing.T) {
+	v1 := "v1"
+	figFamily := &model.FigFamily{
+		DefaultVersion: &v1,
+		Figs:           []model.Fig{{Version: "v1"}, {Version: "v2"}},
+		Rules: []model.Rule{
+			{TargetVersion: "v2", Conditions: []model.Condition{{Variable: "user_id", Operator: "SPLIT", Values: []string{"50"}}}},
+		},
+	}
+	ctx := NewEvaluationContext(map[string]string{"user_id": "user-1"})
+
+	for bucket, want := range map[int]string{10: "v2", 90: "v1"} {
+		evaluator := NewRuleBasedEvaluator(WithBucketingStrategy(BucketingStrategyFunc(func(string) int { return bucket })))
+		got, err := evaluator.Evaluate(figFamily, ctx)
+		if err != nil {
+			t.Fatalf("Evaluate() error = %v", err)
+		}
+		if got.Version != want {
+			t.Errorf("Evaluate() with bucket %d = %v, want %v", bucket, got.Version, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package cel provides a CEL (Common Expression Language) engine for expression conditions.
+package cel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	celgo "github.com/google/cel-go/cel"
+
+	"github.com/figchain/go-client/pkg/evaluation"
+)
+
+// Engine evaluates CEL expressions against the attributes of an EvaluationContext.
+//
+// Dotted attribute names are exposed as nested maps, so the attributes
+// {"user.plan": "pro", "req.region": "eu-1"} can be addressed as
+//
+//	user.plan == "pro" && req.region in ["eu-1", "eu-2"]
+//
+// Attribute values are strings; use CEL conversions such as int(user.age) > 18 for
+// numeric comparisons. Compiled programs are cached per expression.
+type Engine struct {
+	env      *celgo.Env
+	programs sync.Map
+}
+
+// NewEngine creates a new Engine.
+func NewEngine() (*Engine, error) {
+	env, err := celgo.NewEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cel environment: %w", err)
+	}
+	return &Engine{env: env}, nil
+}
+
+// Eval evaluates the expression and reports whether it yielded true.
+func (e *Engine) Eval(expression string, context *evaluation.EvaluationContext) (bool, error) {
+	prg, err := e.program(expression)
+	if err != nil {
+		return false, err
+	}
+
+	out, _, err := prg.ContextEval(context, activation(context))
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate expression %q: %w", expression, err)
+	}
+
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q returned %s, want bool", expression, out.Type().TypeName())
+	}
+	return matched, nil
+}
+
+func (e *Engine) program(expression string) (celgo.Program, error) {
+	if prg, ok := e.programs.Load(expression); ok {
+		return prg.(celgo.Program), nil
+	}
+
+	// Expressions are parsed but not type-checked: identifiers are resolved from the
+	// evaluation context at runtime since the attribute set varies per request.
+	ast, iss := e.env.Parse(expression)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("failed to parse expression %q: %w", expression, iss.Err())
+	}
+	prg, err := e.env.Program(ast, celgo.InterruptCheckFrequency(100))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build program for %q: %w", expression, err)
+	}
+
+	actual, _ := e.programs.LoadOrStore(expression, prg)
+	return actual.(celgo.Program), nil
+}
+
+// activation converts flat attributes into CEL variables, nesting dotted names.
+func activation(context *evaluation.EvaluationContext) map[string]any {
+	vars := make(map[string]any)
+	if context == nil {
+		return vars
+	}
+	for name, value := range context.Attributes {
+		parts := strings.Split(name, ".")
+		current := vars
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := current[part].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				current[part] = next
+			}
+			current = next
+		}
+		last := parts[len(parts)-1]
+		if _, isMap := current[last].(map[string]any); !isMap {
+			current[last] = value
+		}
+	}
+	return vars
+}
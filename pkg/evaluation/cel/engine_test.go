@@ -0,0 +1,90 @@
+package cel
+
+import (
+	"testing"
+
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestEngine_Eval(t *testing.T) {
+	engine, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	ctx := evaluation.NewEvaluationContext(map[string]string{
+		"user.plan":  "pro",
+		"user.age":   "42",
+		"req.region": "eu-2",
+		"tier":       "gold",
+	})
+
+	tests := []struct {
+		name       string
+		expression string
+		want       bool
+		wantErr    bool
+	}{
+		{"nested equality", `user.plan == "pro" && req.region in ["eu-1","eu-2"]`, true, false},
+		{"nested mismatch", `user.plan == "free"`, false, false},
+		{"top level", `tier.startsWith("go")`, true, false},
+		{"numeric conversion", `int(user.age) > 18`, true, false},
+		{"missing attribute", `user.country == "DE"`, false, true},
+		{"non bool result", `user.plan`, false, true},
+		{"parse error", `user.plan ==`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := engine.Eval(tt.expression, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_RuleBasedEvaluator(t *testing.T) {
+	engine, err := NewEngine()
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	evaluator := evaluation.NewRuleBasedEvaluator(evaluation.WithExpressionEngine(engine))
+
+	defaultVersion := "v1"
+	figFamily := &model.FigFamily{
+		DefaultVersion: &defaultVersion,
+		Figs: []model.Fig{
+			{Version: "v1"},
+			{Version: "v2"},
+		},
+		Rules: []model.Rule{
+			{
+				TargetVersion: "v2",
+				Conditions: []model.Condition{
+					{Operator: evaluation.OperatorCEL, Values: []string{`user.plan == "pro"`}},
+				},
+			},
+		},
+	}
+
+	fig, err := evaluator.Evaluate(figFamily, evaluation.NewEvaluationContext(map[string]string{"user.plan": "pro"}))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if fig.Version != "v2" {
+		t.Errorf("Evaluate() = %s, want v2", fig.Version)
+	}
+
+	fig, err = evaluator.Evaluate(figFamily, evaluation.NewEvaluationContext(nil))
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if fig.Version != "v1" {
+		t.Errorf("Evaluate() without attributes = %s, want v1", fig.Version)
+	}
+}
@@ -0,0 +1,183 @@
+package evaluation
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// Precompiler is implemented by evaluators that compile the rules of families ahead of
+// evaluation. The client precompiles the families it receives before storing them, so
+// that the first evaluation after an update doesn't pay for compilation.
+type Precompiler interface {
+	Precompile(figFamily *model.FigFamily)
+}
+
+// smallSet is the number of values up to which IN and NOT_IN conditions scan their
+// values rather than building a set.
+const smallSet = 8
+
+// familyID identifies the family a compiled form is cached under.
+type familyID struct {
+	namespace, key string
+}
+
+// compiledFamily is the compiled form of the rules of a family.
+type compiledFamily struct {
+	// source is the first of the compiled rules. Stored families are immutable, so a
+	// family with the same rules slice has the same rules.
+	source *model.Rule
+	rules  []compiledRule
+}
+
+// compiledRule holds the matchers of a rule's conditions.
+type compiledRule []matcher
+
+// matcher reports whether a compiled condition matches a context.
+type matcher func(namespace string, context *EvaluationContext) bool
+
+func (r compiledRule) matches(namespace string, context *EvaluationContext) bool {
+	for _, m := range r {
+		if !m(namespace, context) {
+			return false
+		}
+	}
+	return true
+}
+
+// Precompile compiles the rules of figFamily and caches them for its evaluations.
+// Evaluate compiles families that weren't precompiled on first use.
+func (e *RuleBasedEvaluator) Precompile(figFamily *model.FigFamily) {
+	if figFamily != nil {
+		e.compiledRules(figFamily)
+	}
+}
+
+// compiledRules returns the compiled rules of figFamily, from the cache if the family's
+// rules were compiled already. Each family key caches the compiled form of its latest
+// rules only.
+func (e *RuleBasedEvaluator) compiledRules(figFamily *model.FigFamily) []compiledRule {
+	if len(figFamily.Rules) == 0 {
+		return nil
+	}
+	id := familyID{figFamily.Definition.Namespace, figFamily.Definition.Key}
+	source := &figFamily.Rules[0]
+	if cached, ok := e.compiled.Load(id); ok {
+		if cf := cached.(*compiledFamily); cf.source == source && len(cf.rules) == len(figFamily.Rules) {
+			return cf.rules
+		}
+	}
+	cf := &compiledFamily{source: source, rules: make([]compiledRule, len(figFamily.Rules))}
+	for i, rule := range figFamily.Rules {
+		cf.rules[i] = make(compiledRule, len(rule.Conditions))
+		for j, condition := range rule.Conditions {
+			cf.rules[i][j] = e.compileCondition(condition)
+		}
+	}
+	e.compiled.Store(id, cf)
+	return cf.rules
+}
+
+// compileCondition compiles condition into a matcher with its values parsed, matching as
+// matchesCondition does.
+func (e *RuleBasedEvaluator) compileCondition(condition model.Condition) matcher {
+	switch condition.Operator {
+	case OperatorCEL:
+		return func(_ string, context *EvaluationContext) bool {
+			return e.matchesExpression(condition, context)
+		}
+	case OperatorInSegment:
+		return func(namespace string, context *EvaluationContext) bool {
+			return e.matchesSegment(namespace, condition, context)
+		}
+	}
+
+	values := condition.Values
+	var match func(val string) bool
+	switch condition.Operator {
+	case "EQUALS":
+		if len(values) > 0 {
+			want := values[0]
+			match = func(val string) bool { return val == want }
+		}
+	case "NOT_EQUALS":
+		if len(values) > 0 {
+			want := values[0]
+			match = func(val string) bool { return val != want }
+		}
+	case "IN":
+		in := compileSet(values)
+		match = in
+	case "NOT_IN":
+		in := compileSet(values)
+		match = func(val string) bool { return !in(val) }
+	case "CONTAINS":
+		if len(values) > 0 {
+			substr := values[0]
+			match = func(val string) bool { return strings.Contains(val, substr) }
+		}
+	case "GREATER_THAN":
+		if len(values) == 1 {
+			cmp := compileCompare(values[0])
+			match = func(val string) bool { return cmp(val) > 0 }
+		}
+	case "LESS_THAN":
+		if len(values) == 1 {
+			cmp := compileCompare(values[0])
+			match = func(val string) bool { return cmp(val) < 0 }
+		}
+	case "SPLIT":
+		if len(values) > 0 {
+			if threshold, err := strconv.Atoi(values[0]); err == nil {
+				match = func(val string) bool { return e.getBucket(val) < threshold }
+			}
+		}
+	}
+	if match == nil {
+		return func(string, *EvaluationContext) bool { return false }
+	}
+	variable := condition.Variable
+	return func(_ string, context *EvaluationContext) bool {
+		val, ok := context.Attributes[variable]
+		return ok && match(val)
+	}
+}
+
+// compileSet returns a membership test of values.
+func compileSet(values []string) func(val string) bool {
+	if len(values) <= smallSet {
+		return func(val string) bool { return slices.Contains(values, val) }
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return func(val string) bool {
+		_, ok := set[val]
+		return ok
+	}
+}
+
+// compileCompare returns a comparison of values with bound, like compare, with bound
+// parsed once.
+func compileCompare(bound string) func(val string) int {
+	f2, err := strconv.ParseFloat(bound, 64)
+	if err != nil {
+		return func(val string) int { return strings.Compare(val, bound) }
+	}
+	return func(val string) int {
+		f1, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return strings.Compare(val, bound)
+		}
+		switch {
+		case f1 < f2:
+			return -1
+		case f1 > f2:
+			return 1
+		}
+		return 0
+	}
+}
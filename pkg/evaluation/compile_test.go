@@ -0,0 +1,154 @@
+package evaluation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestRuleBasedEvaluator_CompiledConditions(t *testing.T) {
+	var many []string
+	for i := range 20 {
+		many = append(many, fmt.Sprintf("user%d", i))
+	}
+	conditions := []model.Condition{
+		{Variable: "v", Operator: "EQUALS", Values: []string{"10"}},
+		{Variable: "v", Operator: "EQUALS"},
+		{Variable: "v", Operator: "NOT_EQUALS", Values: []string{"10"}},
+		{Variable: "v", Operator: "IN", Values: []string{"10", "user3"}},
+		{Variable: "v", Operator: "IN", Values: many},
+		{Variable: "v", Operator: "NOT_IN", Values: many},
+		{Variable: "v", Operator: "CONTAINS", Values: []string{"ser1"}},
+		{Variable: "v", Operator: "GREATER_THAN", Values: []string{"9.5"}},
+		{Variable: "v", Operator: "GREATER_THAN", Values: []string{"m"}},
+		{Variable: "v", Operator: "LESS_THAN", Values: []string{"100"}},
+		{Variable: "v", Operator: "LESS_THAN", Values: []string{"1", "2"}},
+		{Variable: "v", Operator: "SPLIT", Values: []string{"50"}},
+		{Variable: "v", Operator: "SPLIT", Values: []string{"half"}},
+		{Variable: "v", Operator: "UNKNOWN", Values: []string{"10"}},
+	}
+	contexts := []*EvaluationContext{
+		NewEvaluationContext(map[string]string{"v": "10"}),
+		NewEvaluationContext(map[string]string{"v": "9"}),
+		NewEvaluationContext(map[string]string{"v": "1e3"}),
+		NewEvaluationContext(map[string]string{"v": "user1"}),
+		NewEvaluationContext(map[string]string{"v": "user15"}),
+		NewEvaluationContext(map[string]string{"v": "zzz"}),
+		NewEvaluationContext(map[string]string{"v": ""}),
+		NewEvaluationContext(map[string]string{"other": "10"}),
+	}
+
+	e := NewRuleBasedEvaluator()
+	for _, c := range conditions {
+		m := e.compileCondition(c)
+		for _, ctx := range contexts {
+			if got, want := m("ns", ctx), e.matchesCondition("ns", c, ctx); got != want {
+				t.Errorf("compiled %s %v for %v = %v, want %v", c.Operator, c.Values, ctx.Attributes, got, want)
+			}
+		}
+	}
+}
+
+func TestRuleBasedEvaluator_CompileCache(t *testing.T) {
+	v1, v2 := "v1", "v2"
+	family := func(value string) *model.FigFamily {
+		return &model.FigFamily{
+			Definition:     model.FigDefinition{Namespace: "ns", Key: "key"},
+			Figs:           []model.Fig{{Version: v1}, {Version: v2}},
+			DefaultVersion: &v1,
+			Rules: []model.Rule{{
+				TargetVersion: v2,
+				Conditions:    []model.Condition{{Variable: "plan", Operator: "EQUALS", Values: []string{value}}},
+			}},
+		}
+	}
+	e := NewRuleBasedEvaluator()
+	beta := NewEvaluationContext(map[string]string{"plan": "beta"})
+
+	ff := family("beta")
+	e.Precompile(ff)
+	compiled := e.compiledRules(ff)
+	copied := *ff
+	if got := e.compiledRules(&copied); &got[0] != &compiled[0] {
+		t.Error("compiledRules() recompiled a copy of a precompiled family")
+	}
+	if got, err := e.Evaluate(&copied, beta); err != nil || got.Version != v2 {
+		t.Errorf("Evaluate() = %v, %v, want v2", got, err)
+	}
+
+	// An update of the family replaces its compiled rules
+	if got, err := e.Evaluate(family("gold"), beta); err != nil || got.Version != v1 {
+		t.Errorf("Evaluate() of the updated family = %v, %v, want v1", got, err)
+	}
+	if got, err := e.Evaluate(ff, beta); err != nil || got.Version != v2 {
+		t.Errorf("Evaluate() of the previous family = %v, %v, want v2", got, err)
+	}
+}
+
+// benchmarkFamily returns a family with n rules, each with an allowlist of 200 users, a
+// numeric and a split condition, of which only the last rule matches benchmarkContext.
+func benchmarkFamily(n int) *model.FigFamily {
+	defaultVersion := "v0"
+	ff := &model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: "ns", Key: "key"},
+		Figs:           []model.Fig{{Version: defaultVersion}},
+		DefaultVersion: &defaultVersion,
+	}
+	for i := range n {
+		version := fmt.Sprintf("v%d", i+1)
+		var users []string
+		for j := range 200 {
+			users = append(users, fmt.Sprintf("user-%03d-%03d", i, j))
+		}
+		if i == n-1 {
+			users[199] = "user-000-999"
+		}
+		ff.Figs = append(ff.Figs, model.Fig{Version: version})
+		ff.Rules = append(ff.Rules, model.Rule{
+			TargetVersion: version,
+			Conditions: []model.Condition{
+				{Variable: "user_id", Operator: "IN", Values: users},
+				{Variable: "age", Operator: "GREATER_THAN", Values: []string{"18"}},
+				{Variable: "bucket", Operator: "SPLIT", Values: []string{"100"}},
+			},
+		})
+	}
+	return ff
+}
+
+var benchmarkContext = NewEvaluationContext(map[string]string{"user_id": "user-000-999", "age": "30", "bucket": "b1"})
+
+func BenchmarkRuleBasedEvaluator_Evaluate(b *testing.B) {
+	for _, n := range []int{10, 100, 500} {
+		ff := benchmarkFamily(n)
+		e := NewRuleBasedEvaluator()
+		want := ff.Rules[n-1].TargetVersion
+
+		b.Run(fmt.Sprintf("interpreted/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				var matched string
+				for _, rule := range ff.Rules {
+					if e.matchesRule("ns", rule, benchmarkContext) {
+						matched = rule.TargetVersion
+						break
+					}
+				}
+				if matched != want {
+					b.Fatalf("matched %q, want %q", matched, want)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("compiled/%d", n), func(b *testing.B) {
+			e.Precompile(ff)
+			b.ReportAllocs()
+			for b.Loop() {
+				fig, err := e.Evaluate(ff, benchmarkContext)
+				if err != nil || fig.Version != want {
+					b.Fatalf("Evaluate() = %v, %v, want %s", fig, err, want)
+				}
+			}
+		})
+	}
+}
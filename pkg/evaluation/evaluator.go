@@ -2,11 +2,13 @@ package evaluation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"maps"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/figchain/go-client/pkg/model"
@@ -43,6 +45,9 @@ import (
 type EvaluationContext struct {
 	ctx        context.Context
 	Attributes map[string]string
+	memo       *Memo
+	segments   *segmentCache
+	derived    *derivedSegments // shared by the contexts derived with WithDefaultAttributes
 }
 
 // NewEvaluationContext creates a new EvaluationContext with context.Background().
@@ -68,6 +73,8 @@ func NewEvaluationContextWithContext(ctx context.Context, attributes map[string]
 	return &EvaluationContext{
 		ctx:        ctx,
 		Attributes: attributes,
+		segments:   &segmentCache{},
+		derived:    &derivedSegments{},
 	}
 }
 
@@ -104,6 +111,7 @@ func (c *EvaluationContext) Value(key interface{}) interface{} {
 }
 
 // Merge merges another context into this one, preserving the original context.Context.
+// The memo is not carried over, since results may differ for the merged attributes.
 func (c *EvaluationContext) Merge(other *EvaluationContext) *EvaluationContext {
 	merged := make(map[string]string)
 	maps.Copy(merged, c.Attributes)
@@ -113,52 +121,309 @@ func (c *EvaluationContext) Merge(other *EvaluationContext) *EvaluationContext {
 	return &EvaluationContext{
 		ctx:        c.ctx,
 		Attributes: merged,
+		segments:   &segmentCache{},
+		derived:    &derivedSegments{},
 	}
 }
 
+// segmentCache caches segment membership for a single EvaluationContext.
+type segmentCache struct {
+	mu      sync.Mutex
+	members map[string]bool
+}
+
+func (c *segmentCache) load(key string) (bool, bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	member, ok := c.members[key]
+	return member, ok
+}
+
+func (c *segmentCache) store(key string, member bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.members == nil {
+		c.members = make(map[string]bool)
+	}
+	c.members[key] = member
+}
+
 // Evaluator defines the interface for evaluating rollouts.
 type Evaluator interface {
 	Evaluate(figFamily *model.FigFamily, context *EvaluationContext) (*model.Fig, error)
 }
 
-// RuleBasedEvaluator implements rule-based rollout evaluation.
-type RuleBasedEvaluator struct{}
+// RuleMatch is a rule that matched an evaluation context, with the fig it targets.
+type RuleMatch struct {
+	// Index is the position of the rule in the family's rules.
+	Index int
+	Rule  model.Rule
+	Fig   *model.Fig
+}
+
+// MultiEvaluator is implemented by evaluators that can return every rule matching a
+// context rather than just the first, for diagnostics and for families where several
+// rules contribute, e.g. composing a list of enabled plugins.
+type MultiEvaluator interface {
+	EvaluateAll(figFamily *model.FigFamily, context *EvaluationContext) ([]RuleMatch, error)
+}
+
+// OperatorCEL is the condition operator for CEL expression conditions. The expression
+// is carried in the first condition value; the condition variable is ignored.
+const OperatorCEL = model.OperatorCEL
+
+// ExpressionEngine evaluates expression conditions (such as CEL) against an evaluation context.
+type ExpressionEngine interface {
+	Eval(expression string, context *EvaluationContext) (bool, error)
+}
+
+// OperatorInSegment is the condition operator matching contexts that belong to any of
+// the segments listed in the condition values.
+const OperatorInSegment = model.OperatorInSegment
+
+// SegmentKeyPrefix is the key prefix of fig families that define segments. A segment's
+// conditions are the rules of its family: a context is a member if any rule matches.
+const SegmentKeyPrefix = "segments/"
+
+// ErrMultiEvaluationUnsupported is returned when all matching rules are requested from an
+// evaluator that doesn't implement MultiEvaluator.
+var ErrMultiEvaluationUnsupported = errors.New("evaluator does not support evaluating all matching rules")
+
+// ErrPrerequisiteCycle is returned when fig family prerequisites form a cycle.
+var ErrPrerequisiteCycle = errors.New("prerequisite cycle detected")
+
+// FamilyLookup resolves other fig families during evaluation, e.g. for prerequisites and segments.
+// store.Store satisfies this interface.
+type FamilyLookup interface {
+	Get(namespace, key string) (*model.FigFamily, bool)
+}
+
+// FamilyLookupFunc adapts a function to the FamilyLookup interface.
+type FamilyLookupFunc func(namespace, key string) (*model.FigFamily, bool)
+
+// Get implements FamilyLookup.
+func (f FamilyLookupFunc) Get(namespace, key string) (*model.FigFamily, bool) {
+	return f(namespace, key)
+}
+
+// RuleBasedEvaluator implements rule-based rollout evaluation. It compiles the rules of
+// each family it evaluates, parsing their values once, and caches the compiled form until
+// the family's rules change.
+type RuleBasedEvaluator struct {
+	expressions ExpressionEngine
+	families    FamilyLookup
+	bucketing   BucketingStrategy
+	now         func() time.Time
+	compiled    sync.Map // familyID → *compiledFamily
+}
+
+// Option is a functional option for configuring a RuleBasedEvaluator.
+type Option func(*RuleBasedEvaluator)
+
+// WithExpressionEngine sets the engine used for expression conditions. Without one,
+// expression conditions never match.
+func WithExpressionEngine(engine ExpressionEngine) Option {
+	return func(e *RuleBasedEvaluator) {
+		e.expressions = engine
+	}
+}
+
+// WithFamilyLookup sets the lookup used to resolve prerequisite and segment families.
+// Without one, families with prerequisites always serve their default version and
+// segment conditions never match.
+func WithFamilyLookup(lookup FamilyLookup) Option {
+	return func(e *RuleBasedEvaluator) {
+		e.families = lookup
+	}
+}
+
+// WithBucketingStrategy sets the strategy used to bucket values for SPLIT conditions.
+// Defaults to FNV1aBucketing.
+func WithBucketingStrategy(strategy BucketingStrategy) Option {
+	return func(e *RuleBasedEvaluator) {
+		e.bucketing = strategy
+	}
+}
+
+// WithClock sets the clock that fig and family expiry times are compared to. Defaults
+// to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(e *RuleBasedEvaluator) {
+		e.now = now
+	}
+}
 
 // NewRuleBasedEvaluator creates a new RuleBasedEvaluator.
-func NewRuleBasedEvaluator() *RuleBasedEvaluator {
-	return &RuleBasedEvaluator{}
+func NewRuleBasedEvaluator(opts ...Option) *RuleBasedEvaluator {
+	e := &RuleBasedEvaluator{bucketing: FNV1aBucketing{}, now: time.Now}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
+// Evaluate returns the fig of figFamily served to context. It returns the error of
+// context once it is cancelled or its deadline passes, checking between rules so that
+// families with many rules stop promptly.
 func (e *RuleBasedEvaluator) Evaluate(figFamily *model.FigFamily, context *EvaluationContext) (*model.Fig, error) {
+	return e.evaluate(figFamily, context, nil)
+}
+
+// evaluate evaluates figFamily; path holds the keys whose prerequisites are being
+// resolved, for cycle detection.
+func (e *RuleBasedEvaluator) evaluate(figFamily *model.FigFamily, context *EvaluationContext, path []string) (*model.Fig, error) {
 	if figFamily == nil {
 		return nil, fmt.Errorf("figFamily cannot be nil")
 	}
+	if err := contextErr(context); err != nil {
+		return nil, err
+	}
+	// Expired families serve nothing; expired figs are skipped as if their rule didn't match
+	now := e.now()
+	if figFamily.Expired(now) {
+		return nil, nil
+	}
+
+	// 1. Check prerequisites; unmet prerequisites serve the default version
+	met, err := e.prerequisitesMet(figFamily, context, path)
+	if err != nil {
+		return nil, err
+	}
 
-	// 1. Check rules
-	for _, rule := range figFamily.Rules {
-		if e.matchesRule(rule, context) {
-			return e.findFigByVersion(figFamily, rule.TargetVersion)
+	// 2. Check rules
+	if met {
+		compiled := e.compiledRules(figFamily)
+		for i, rule := range figFamily.Rules {
+			if err := contextErr(context); err != nil {
+				return nil, err
+			}
+			if compiled[i].matches(figFamily.Definition.Namespace, context) {
+				fig, err := e.findFigByVersion(figFamily, rule.TargetVersion)
+				if err != nil || !fig.Expired(now) {
+					return fig, err
+				}
+			}
 		}
 	}
 
-	// 2. Return default version
+	// 3. Return default version
 	if figFamily.DefaultVersion != nil {
-		return e.findFigByVersion(figFamily, *figFamily.DefaultVersion)
+		fig, err := e.findFigByVersion(figFamily, *figFamily.DefaultVersion)
+		if err != nil || !fig.Expired(now) {
+			return fig, err
+		}
 	}
 
 	return nil, nil
 }
 
-func (e *RuleBasedEvaluator) matchesRule(rule model.Rule, context *EvaluationContext) bool {
+// EvaluateAll returns the rules of figFamily that match context, in order, with their
+// target figs. Rules whose target fig has expired are skipped, and no rules match when
+// the family has expired or its prerequisites aren't met. The default version isn't
+// included. Like Evaluate, it returns the error of context once it ends.
+func (e *RuleBasedEvaluator) EvaluateAll(figFamily *model.FigFamily, context *EvaluationContext) ([]RuleMatch, error) {
+	if figFamily == nil {
+		return nil, fmt.Errorf("figFamily cannot be nil")
+	}
+	if err := contextErr(context); err != nil {
+		return nil, err
+	}
+	now := e.now()
+	if figFamily.Expired(now) {
+		return nil, nil
+	}
+	met, err := e.prerequisitesMet(figFamily, context, nil)
+	if err != nil || !met {
+		return nil, err
+	}
+
+	var matches []RuleMatch
+	compiled := e.compiledRules(figFamily)
+	for i, rule := range figFamily.Rules {
+		if err := contextErr(context); err != nil {
+			return nil, err
+		}
+		if !compiled[i].matches(figFamily.Definition.Namespace, context) {
+			continue
+		}
+		fig, err := e.findFigByVersion(figFamily, rule.TargetVersion)
+		if err != nil {
+			return nil, err
+		}
+		if !fig.Expired(now) {
+			matches = append(matches, RuleMatch{Index: i, Rule: rule, Fig: fig})
+		}
+	}
+	return matches, nil
+}
+
+func (e *RuleBasedEvaluator) prerequisitesMet(figFamily *model.FigFamily, context *EvaluationContext, path []string) (bool, error) {
+	if len(figFamily.Prerequisites) == 0 {
+		return true, nil
+	}
+
+	namespace := figFamily.Definition.Namespace
+	path = append(path, figFamily.Definition.Key)
+	for _, prereq := range figFamily.Prerequisites {
+		if slices.Contains(path, prereq.Key) {
+			return false, fmt.Errorf("%w: %s -> %s", ErrPrerequisiteCycle, strings.Join(path, " -> "), prereq.Key)
+		}
+		if e.families == nil {
+			return false, nil
+		}
+		prereqFamily, ok := e.families.Get(namespace, prereq.Key)
+		if !ok {
+			return false, nil
+		}
+		fig, err := e.evaluate(prereqFamily, context, path)
+		if err != nil {
+			// Cycles and ended contexts fail the evaluation; other failures leave the
+			// prerequisite unmet
+			if errors.Is(err, ErrPrerequisiteCycle) || contextErr(context) != nil {
+				return false, err
+			}
+			return false, nil
+		}
+		if fig == nil || fig.Version != prereq.Version {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// contextErr returns the error of context, which may be nil, once it has ended.
+func contextErr(context *EvaluationContext) error {
+	if context == nil {
+		return nil
+	}
+	return context.Err()
+}
+
+// matchesRule interprets the conditions of rule without compiling them, for one-off
+// evaluations such as the explanations of a TestSuite.
+func (e *RuleBasedEvaluator) matchesRule(namespace string, rule model.Rule, context *EvaluationContext) bool {
 	for _, condition := range rule.Conditions {
-		if !e.matchesCondition(condition, context) {
+		if !e.matchesCondition(namespace, condition, context) {
 			return false
 		}
 	}
 	return true
 }
 
-func (e *RuleBasedEvaluator) matchesCondition(condition model.Condition, context *EvaluationContext) bool {
+func (e *RuleBasedEvaluator) matchesCondition(namespace string, condition model.Condition, context *EvaluationContext) bool {
+	switch condition.Operator {
+	case OperatorCEL:
+		return e.matchesExpression(condition, context)
+	case OperatorInSegment:
+		return e.matchesSegment(namespace, condition, context)
+	}
+
 	val, ok := context.Attributes[condition.Variable]
 	if !ok {
 		return false
@@ -209,14 +474,65 @@ func (e *RuleBasedEvaluator) matchesCondition(condition model.Condition, context
 	}
 }
 
-func (e *RuleBasedEvaluator) getBucket(key string) int {
-	hash := uint32(0x811c9dc5)
-	const prime = 0x01000193
-	for i := 0; i < len(key); i++ {
-		hash ^= uint32(key[i])
-		hash *= prime
+func (e *RuleBasedEvaluator) matchesExpression(condition model.Condition, context *EvaluationContext) bool {
+	if e.expressions == nil || len(condition.Values) == 0 {
+		return false
+	}
+	matched, err := e.expressions.Eval(condition.Values[0], context)
+	if err != nil {
+		// Missing attributes and type errors are treated as a non-match
+		return false
+	}
+	return matched
+}
+
+func (e *RuleBasedEvaluator) matchesSegment(namespace string, condition model.Condition, context *EvaluationContext) bool {
+	for _, segmentID := range condition.Values {
+		if e.isSegmentMember(namespace, segmentID, context) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSegmentMember reports whether the context matches any rule of the segment family.
+// Membership is cached on the context for the rest of the request.
+func (e *RuleBasedEvaluator) isSegmentMember(namespace, segmentID string, context *EvaluationContext) bool {
+	if context.segments == nil {
+		// Contexts built as struct literals have no cache, which also guards against
+		// cycles; resolve with one of their own
+		scoped := *context
+		scoped.segments = &segmentCache{}
+		context = &scoped
 	}
-	return int(hash % 100)
+	cacheKey := namespace + ":" + segmentID
+	if member, ok := context.segments.load(cacheKey); ok {
+		return member
+	}
+	if e.families == nil {
+		return false
+	}
+	segment, ok := e.families.Get(namespace, SegmentKeyPrefix+segmentID)
+	if !ok {
+		return false
+	}
+
+	// Mark as non-member while resolving so that segments referencing each other
+	// terminate instead of recursing forever.
+	context.segments.store(cacheKey, false)
+	member := false
+	for _, rule := range e.compiledRules(segment) {
+		if rule.matches(namespace, context) {
+			member = true
+			break
+		}
+	}
+	context.segments.store(cacheKey, member)
+	return member
+}
+
+func (e *RuleBasedEvaluator) getBucket(key string) int {
+	return e.bucketing.Bucket(key)
 }
 
 func (e *RuleBasedEvaluator) compare(a, b string) int {
@@ -2,6 +2,8 @@ package evaluation
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -194,3 +196,360 @@ func TestEvaluationContext_Merge(t *testing.T) {
 	}
 }
 
+type stubExpressionEngine struct {
+	results map[string]bool
+}
+
+func (s *stubExpressionEngine) Eval(expression string, _ *EvaluationContext) (bool, error) {
+	return s.results[expression], nil
+}
+
+func TestRuleBasedEvaluator_ExpressionCondition(t *testing.T) {
+	defaultVersion := "v1"
+	figFamily := &model.FigFamily{
+		DefaultVersion: &defaultVersion,
+		Figs: []model.Fig{
+			{Version: "v1"},
+			{Version: "v2"},
+		},
+		Rules: []model.Rule{
+			{
+				TargetVersion: "v2",
+				Conditions: []model.Condition{
+					{Operator: OperatorCEL, Values: []string{"matches"}},
+				},
+			},
+		},
+	}
+
+	ctx := NewEvaluationContext(nil)
+
+	// Without an engine, expression conditions never match
+	got, err := NewRuleBasedEvaluator().Evaluate(figFamily, ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if got.Version != "v1" {
+		t.Errorf("Evaluate() without engine = %v, want v1", got.Version)
+	}
+
+	engine := &stubExpressionEngine{results: map[string]bool{"matches": true}}
+	got, err = NewRuleBasedEvaluator(WithExpressionEngine(engine)).Evaluate(figFamily, ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if got.Version != "v2" {
+		t.Errorf("Evaluate() with engine = %v, want v2", got.Version)
+	}
+}
+
+type mapFamilyLookup map[string]*model.FigFamily
+
+func (m mapFamilyLookup) Get(namespace, key string) (*model.FigFamily, bool) {
+	ff, ok := m[namespace+":"+key]
+	return ff, ok
+}
+
+func TestRuleBasedEvaluator_Prerequisites(t *testing.T) {
+	on, off := "on", "off"
+	killSwitch := &model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: "ns", Key: "kill-switch"},
+		Figs:           []model.Fig{{Version: on}, {Version: off}},
+		DefaultVersion: &on,
+	}
+	v1 := "v1"
+	feature := &model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: "ns", Key: "feature"},
+		Figs:           []model.Fig{{Version: "v1"}, {Version: "v2"}},
+		DefaultVersion: &v1,
+		Rules: []model.Rule{
+			{TargetVersion: "v2", Conditions: []model.Condition{{Variable: "plan", Operator: "EQUALS", Values: []string{"pro"}}}},
+		},
+		Prerequisites: []model.Prerequisite{{Key: "kill-switch", Version: on}},
+	}
+	lookup := mapFamilyLookup{"ns:kill-switch": killSwitch, "ns:feature": feature}
+	evaluator := NewRuleBasedEvaluator(WithFamilyLookup(lookup))
+	ctx := NewEvaluationContext(map[string]string{"plan": "pro"})
+
+	got, err := evaluator.Evaluate(feature, ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if got.Version != "v2" {
+		t.Errorf("Evaluate() with prerequisite met = %v, want v2", got.Version)
+	}
+
+	// Flip the kill switch: rules are skipped and the default is served
+	killSwitch.DefaultVersion = &off
+	got, err = evaluator.Evaluate(feature, ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if got.Version != "v1" {
+		t.Errorf("Evaluate() with prerequisite unmet = %v, want v1", got.Version)
+	}
+
+	// Without a lookup prerequisites cannot be met
+	got, err = NewRuleBasedEvaluator().Evaluate(feature, ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if got.Version != "v1" {
+		t.Errorf("Evaluate() without lookup = %v, want v1", got.Version)
+	}
+
+	// Cycles are reported
+	killSwitch.Prerequisites = []model.Prerequisite{{Key: "feature", Version: "v2"}}
+	if _, err := evaluator.Evaluate(feature, ctx); !errors.Is(err, ErrPrerequisiteCycle) {
+		t.Errorf("Evaluate() with cycle error = %v, want ErrPrerequisiteCycle", err)
+	}
+}
+
+func TestRuleBasedEvaluator_Segments(t *testing.T) {
+	betaTesters := &model.FigFamily{
+		Definition: model.FigDefinition{Namespace: "ns", Key: SegmentKeyPrefix + "beta-testers"},
+		Rules: []model.Rule{
+			{Conditions: []model.Condition{{Variable: "user_id", Operator: "IN", Values: []string{"1", "2"}}}},
+			{Conditions: []model.Condition{{Variable: "email", Operator: "CONTAINS", Values: []string{"@figchain.io"}}}},
+		},
+	}
+	v1 := "v1"
+	feature := &model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: "ns", Key: "feature"},
+		Figs:           []model.Fig{{Version: "v1"}, {Version: "v2"}},
+		DefaultVersion: &v1,
+		Rules: []model.Rule{
+			{TargetVersion: "v2", Conditions: []model.Condition{{Operator: OperatorInSegment, Values: []string{"missing", "beta-testers"}}}},
+		},
+	}
+	evaluator := NewRuleBasedEvaluator(WithFamilyLookup(mapFamilyLookup{"ns:" + betaTesters.Definition.Key: betaTesters}))
+
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		want       string
+	}{
+		{"member by first rule", map[string]string{"user_id": "2"}, "v2"},
+		{"member by second rule", map[string]string{"email": "dev@figchain.io"}, "v2"},
+		{"not a member", map[string]string{"user_id": "3"}, "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewEvaluationContext(tt.attributes)
+			got, err := evaluator.Evaluate(feature, ctx)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if got.Version != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got.Version, tt.want)
+			}
+			// Membership is cached on the context
+			if _, ok := ctx.segments.load("ns:beta-testers"); !ok {
+				t.Errorf("Expected segment membership to be cached on the context")
+			}
+		})
+	}
+
+	// Contexts derived with the same defaults share membership, other defaults don't
+	ctx := NewEvaluationContext(map[string]string{"user_id": "2"})
+	if _, err := evaluator.Evaluate(feature, ctx.WithDefaultAttributes(map[string]string{"region": "eu"})); err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if _, ok := ctx.WithDefaultAttributes(map[string]string{"region": "eu"}).segments.load("ns:beta-testers"); !ok {
+		t.Errorf("Expected segment membership to be shared by contexts derived with the same defaults")
+	}
+	if _, ok := ctx.WithDefaultAttributes(map[string]string{"region": "us"}).segments.load("ns:beta-testers"); ok {
+		t.Errorf("Expected segment membership not to be shared by contexts derived with other defaults")
+	}
+
+	// Self-referencing segments terminate
+	betaTesters.Rules = append(betaTesters.Rules, model.Rule{
+		Conditions: []model.Condition{{Operator: OperatorInSegment, Values: []string{"beta-testers"}}},
+	})
+	got, err := evaluator.Evaluate(feature, NewEvaluationContext(map[string]string{"user_id": "3"}))
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if got.Version != "v1" {
+		t.Errorf("Evaluate() with self-referencing segment = %v, want v1", got.Version)
+	}
+
+	// So do segments referencing each other, for contexts without a segment cache
+	betaTesters.Rules = append(betaTesters.Rules, model.Rule{
+		Conditions: []model.Condition{{Operator: OperatorInSegment, Values: []string{"staff"}}},
+	})
+	staff := &model.FigFamily{
+		Definition: model.FigDefinition{Namespace: "ns", Key: SegmentKeyPrefix + "staff"},
+		Rules: []model.Rule{
+			{Conditions: []model.Condition{{Operator: OperatorInSegment, Values: []string{"beta-testers"}}}},
+		},
+	}
+	evaluator = NewRuleBasedEvaluator(WithFamilyLookup(mapFamilyLookup{
+		"ns:" + betaTesters.Definition.Key: betaTesters,
+		"ns:" + staff.Definition.Key:       staff,
+	}))
+	got, err = evaluator.Evaluate(feature, &EvaluationContext{Attributes: map[string]string{"user_id": "3"}})
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if got.Version != "v1" {
+		t.Errorf("Evaluate() with segments referencing each other = %v, want v1", got.Version)
+	}
+}
+
+func TestRuleBasedEvaluator_Expiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+	evaluator := NewRuleBasedEvaluator(WithClock(func() time.Time { return now }))
+
+	defaultVersion := "v1"
+	newFamily := func() *model.FigFamily {
+		return &model.FigFamily{
+			DefaultVersion: &defaultVersion,
+			Figs: []model.Fig{
+				{Version: "v1", Payload: []byte("v1")},
+				{Version: "v2", Payload: []byte("v2")},
+			},
+			Rules: []model.Rule{{
+				TargetVersion: "v2",
+				Conditions:    []model.Condition{{Variable: "user_id", Operator: "EQUALS", Values: []string{"123"}}},
+			}},
+		}
+	}
+	ctx := NewEvaluationContext(map[string]string{"user_id": "123"})
+
+	ff := newFamily()
+	ff.Figs[1].ExpiresAt = &future
+	if got, err := evaluator.Evaluate(ff, ctx); err != nil || got == nil || got.Version != "v2" {
+		t.Errorf("Evaluate() with unexpired target = %v, %v, want v2", got, err)
+	}
+
+	// An expired rule target falls through to the default
+	ff.Figs[1].ExpiresAt = &past
+	if got, err := evaluator.Evaluate(ff, ctx); err != nil || got == nil || got.Version != "v1" {
+		t.Errorf("Evaluate() with expired target = %v, %v, want v1", got, err)
+	}
+
+	ff.Figs[0].ExpiresAt = &past
+	if got, err := evaluator.Evaluate(ff, ctx); err != nil || got != nil {
+		t.Errorf("Evaluate() with expired default = %v, %v, want nil", got, err)
+	}
+
+	ff = newFamily()
+	ff.Definition.ExpiresAt = &now
+	if got, err := evaluator.Evaluate(ff, ctx); err != nil || got != nil {
+		t.Errorf("Evaluate() of expired family = %v, %v, want nil", got, err)
+	}
+}
+
+func TestRuleBasedEvaluator_EvaluateAll(t *testing.T) {
+	defaultVersion := "none"
+	rule := func(version, plan string) model.Rule {
+		return model.Rule{
+			TargetVersion: version,
+			Conditions:    []model.Condition{{Variable: "plan", Operator: "IN", Values: []string{plan}}},
+		}
+	}
+	figFamily := &model.FigFamily{
+		DefaultVersion: &defaultVersion,
+		Figs:           []model.Fig{{Version: "none"}, {Version: "audit"}, {Version: "sso"}},
+		Rules:          []model.Rule{rule("audit", "enterprise"), rule("sso", "business"), rule("sso", "enterprise")},
+	}
+	evaluator := NewRuleBasedEvaluator()
+
+	tests := []struct {
+		name string
+		plan string
+		want []int
+	}{
+		{"several rules", "enterprise", []int{0, 2}},
+		{"one rule", "business", []int{1}},
+		{"no rules", "free", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluator.EvaluateAll(figFamily, NewEvaluationContext(map[string]string{"plan": tt.plan}))
+			if err != nil {
+				t.Fatalf("EvaluateAll() error = %v", err)
+			}
+			var indexes []int
+			for _, m := range got {
+				indexes = append(indexes, m.Index)
+				if m.Fig.Version != figFamily.Rules[m.Index].TargetVersion {
+					t.Errorf("EvaluateAll() rule %d fig = %s, want %s", m.Index, m.Fig.Version, figFamily.Rules[m.Index].TargetVersion)
+				}
+			}
+			if !slices.Equal(indexes, tt.want) {
+				t.Errorf("EvaluateAll() matched rules %v, want %v", indexes, tt.want)
+			}
+		})
+	}
+
+	if _, err := evaluator.EvaluateAll(nil, NewEvaluationContext(nil)); err == nil {
+		t.Error("EvaluateAll(nil) error = nil, want error")
+	}
+}
+
+// cancellingEngine cancels the context of the evaluation once it has evaluated an
+// expression.
+type cancellingEngine struct {
+	cancel context.CancelFunc
+	evals  int
+}
+
+func (e *cancellingEngine) Eval(string, *EvaluationContext) (bool, error) {
+	e.evals++
+	e.cancel()
+	return false, nil
+}
+
+func TestRuleBasedEvaluator_ContextEnded(t *testing.T) {
+	defaultVersion := "v1"
+	figFamily := &model.FigFamily{
+		DefaultVersion: &defaultVersion,
+		Figs:           []model.Fig{{Version: "v1"}, {Version: "v2"}},
+	}
+	for range 100 {
+		figFamily.Rules = append(figFamily.Rules, model.Rule{
+			TargetVersion: "v2",
+			Conditions:    []model.Condition{{Operator: OperatorCEL, Values: []string{"false"}}},
+		})
+	}
+
+	base, cancel := context.WithCancel(context.Background())
+	engine := &cancellingEngine{cancel: cancel}
+	evaluator := NewRuleBasedEvaluator(WithExpressionEngine(engine))
+	ctx := NewEvaluationContextWithContext(base, nil)
+	if got, err := evaluator.Evaluate(figFamily, ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Evaluate() = %v, %v once cancelled, want context.Canceled", got, err)
+	}
+	if engine.evals != 1 {
+		t.Errorf("Evaluate() evaluated %d rules, want it to stop after the first once cancelled", engine.evals)
+	}
+	if _, err := evaluator.EvaluateAll(figFamily, ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("EvaluateAll() error = %v, want context.Canceled", err)
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := evaluator.Evaluate(figFamily, NewEvaluationContextWithContext(expired, nil)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Evaluate() error = %v past the deadline, want context.DeadlineExceeded", err)
+	}
+
+	// Prerequisites evaluated under an ended context fail the evaluation rather than
+	// being unmet
+	dependent := &model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: "ns", Key: "dependent"},
+		Figs:           []model.Fig{{Version: "v1"}},
+		DefaultVersion: &defaultVersion,
+		Prerequisites:  []model.Prerequisite{{Key: "prereq", Version: "v1"}},
+	}
+	figFamily.Definition = model.FigDefinition{Namespace: "ns", Key: "prereq"}
+	base, cancel = context.WithCancel(context.Background())
+	engine.cancel, engine.evals = cancel, 0
+	evaluator = NewRuleBasedEvaluator(WithExpressionEngine(engine), WithFamilyLookup(mapFamilyLookup{"ns:prereq": figFamily}))
+	if got, err := evaluator.Evaluate(dependent, NewEvaluationContextWithContext(base, nil)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Evaluate() = %v, %v when cancelled during a prerequisite, want context.Canceled", got, err)
+	}
+}
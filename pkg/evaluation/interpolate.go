@@ -0,0 +1,139 @@
+package evaluation
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+)
+
+// Interpolator expands the placeholders in the string values of decoded payloads, e.g.
+// an endpoint URL that varies by deployment. It must be safe for concurrent use.
+type Interpolator interface {
+	Interpolate(ctx *EvaluationContext, s string) (string, error)
+}
+
+// PlaceholderResolver resolves the reference of a ${scheme:ref} placeholder.
+type PlaceholderResolver func(ctx *EvaluationContext, ref string) (string, error)
+
+// placeholder matches a placeholder, e.g. ${env:REGION}.
+var placeholder = regexp.MustCompile(`\$\{([a-z][a-z0-9-]*):([^}]*)\}`)
+
+// placeholderInterpolator expands placeholders by scheme.
+type placeholderInterpolator struct {
+	resolvers map[string]PlaceholderResolver
+}
+
+// NewInterpolator creates an Interpolator expanding ${env:NAME} to the environment
+// variable NAME and ${attr:NAME} to the evaluation attribute NAME, plus the placeholders
+// of the given schemes, which may override env and attr. A placeholder whose scheme is
+// unknown, or whose value is missing, fails the read instead of leaving it unexpanded.
+func NewInterpolator(resolvers map[string]PlaceholderResolver) Interpolator {
+	i := &placeholderInterpolator{resolvers: map[string]PlaceholderResolver{
+		"env":  resolveEnv,
+		"attr": resolveAttr,
+	}}
+	maps.Copy(i.resolvers, resolvers)
+	return i
+}
+
+func (i *placeholderInterpolator) Interpolate(ctx *EvaluationContext, s string) (string, error) {
+	var err error
+	expanded := placeholder.ReplaceAllStringFunc(s, func(p string) string {
+		if err != nil {
+			return ""
+		}
+		m := placeholder.FindStringSubmatch(p)
+		resolve, ok := i.resolvers[m[1]]
+		if !ok {
+			err = fmt.Errorf("unknown placeholder scheme %q", m[1])
+			return ""
+		}
+		var value string
+		if value, err = resolve(ctx, m[2]); err != nil {
+			err = fmt.Errorf("failed to expand %s: %w", p, err)
+		}
+		return value
+	})
+	return expanded, err
+}
+
+func resolveEnv(_ *EvaluationContext, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func resolveAttr(ctx *EvaluationContext, name string) (string, error) {
+	var value string
+	var ok bool
+	if ctx != nil {
+		value, ok = ctx.Attributes[name]
+	}
+	if !ok {
+		return "", fmt.Errorf("attribute %s is not set", name)
+	}
+	return value, nil
+}
+
+// InterpolateValue expands the placeholders in every string reachable from v, a pointer
+// to a decoded payload, through exported struct fields, pointers, interfaces, slices,
+// arrays and map values.
+func InterpolateValue(i Interpolator, ctx *EvaluationContext, v any) error {
+	return interpolate(i, ctx, reflect.ValueOf(v))
+}
+
+func interpolate(i Interpolator, ctx *EvaluationContext, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		s, err := i.Interpolate(ctx, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return interpolate(i, ctx, v.Elem())
+		}
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return nil
+		}
+		// The dynamic value isn't settable, so it is expanded in a copy
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := interpolate(i, ctx, elem); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		for j := range v.NumField() {
+			if err := interpolate(i, ctx, v.Field(j)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for j := range v.Len() {
+			if err := interpolate(i, ctx, v.Index(j)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			if err := interpolate(i, ctx, elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+	return nil
+}
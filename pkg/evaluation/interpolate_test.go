@@ -0,0 +1,51 @@
+package evaluation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterpolateValue(t *testing.T) {
+	t.Setenv("FIGCHAIN_TEST_REGION", "eu-west-1")
+	i := NewInterpolator(map[string]PlaceholderResolver{
+		"upper": func(_ *EvaluationContext, ref string) (string, error) {
+			return strings.ToUpper(ref), nil
+		},
+	})
+	ctx := NewEvaluationContext(map[string]string{"tenant": "acme"})
+
+	type endpoint struct {
+		URL  string
+		Tags []string
+		Meta map[string]any
+		Next *endpoint
+	}
+	v := endpoint{
+		URL:  "https://api.${env:FIGCHAIN_TEST_REGION}.example.com",
+		Tags: []string{"orders-${attr:tenant}", "${upper:x}"},
+		Meta: map[string]any{"bucket": "${attr:tenant}-data", "count": 3},
+		Next: &endpoint{URL: "plain"},
+	}
+	if err := InterpolateValue(i, ctx, &v); err != nil {
+		t.Fatalf("InterpolateValue() error = %v", err)
+	}
+	if v.URL != "https://api.eu-west-1.example.com" {
+		t.Errorf("URL = %q", v.URL)
+	}
+	if v.Tags[0] != "orders-acme" || v.Tags[1] != "X" {
+		t.Errorf("Tags = %v", v.Tags)
+	}
+	if v.Meta["bucket"] != "acme-data" || v.Meta["count"] != 3 {
+		t.Errorf("Meta = %v", v.Meta)
+	}
+	if v.Next.URL != "plain" {
+		t.Errorf("Next.URL = %q", v.Next.URL)
+	}
+
+	for _, s := range []string{"${attr:user_id}", "${env:FIGCHAIN_TEST_UNSET}", "${vault:secret}"} {
+		v := endpoint{URL: s}
+		if err := InterpolateValue(i, ctx, &v); err == nil {
+			t.Errorf("InterpolateValue(%q) succeeded, want error", s)
+		}
+	}
+}
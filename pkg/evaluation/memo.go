@@ -0,0 +1,62 @@
+package evaluation
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Memo caches evaluation results for the lifetime of a single EvaluationContext, so that
+// repeated lookups of the same key within one request (e.g. while rendering a template)
+// evaluate rules and decode payloads only once.
+//
+// A Memo is safe for concurrent use.
+type Memo struct {
+	mu      sync.Mutex
+	entries map[any]any
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+// Load returns the value memoized under key. Keys must be comparable.
+func (m *Memo) Load(key any) (any, bool) {
+	m.mu.Lock()
+	v, ok := m.entries[key]
+	m.mu.Unlock()
+	if ok {
+		m.hits.Add(1)
+	} else {
+		m.misses.Add(1)
+	}
+	return v, ok
+}
+
+// Store memoizes value under key.
+func (m *Memo) Store(key, value any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = make(map[any]any)
+	}
+	m.entries[key] = value
+}
+
+// Stats returns the number of memo hits and misses so far.
+func (m *Memo) Stats() (hits, misses uint64) {
+	return m.hits.Load(), m.misses.Load()
+}
+
+// WithMemo enables memoization on the context and returns it. Calling WithMemo on a
+// context that already has a memo keeps the existing one.
+//
+//	ctx := evaluation.NewEvaluationContext(attrs).WithMemo()
+func (c *EvaluationContext) WithMemo() *EvaluationContext {
+	if c.memo == nil {
+		c.memo = &Memo{}
+	}
+	return c
+}
+
+// Memo returns the context's memo, or nil if memoization is not enabled.
+func (c *EvaluationContext) Memo() *Memo {
+	return c.memo
+}
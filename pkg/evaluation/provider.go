@@ -0,0 +1,73 @@
+package evaluation
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// ContextProvider supplies attributes that are merged into every evaluation, such as the
+// service name, region, pod or app version. It is called once per evaluation and must be
+// safe for concurrent use.
+type ContextProvider interface {
+	Attributes(ctx context.Context) map[string]string
+}
+
+// ContextProviderFunc adapts a function to the ContextProvider interface.
+type ContextProviderFunc func(ctx context.Context) map[string]string
+
+// Attributes implements ContextProvider.
+func (f ContextProviderFunc) Attributes(ctx context.Context) map[string]string {
+	return f(ctx)
+}
+
+// StaticAttributes is a ContextProvider that always returns the same attributes.
+type StaticAttributes map[string]string
+
+// Attributes implements ContextProvider.
+func (s StaticAttributes) Attributes(context.Context) map[string]string {
+	return s
+}
+
+// WithDefaultAttributes returns a copy of the context whose attributes are defaults
+// overlaid with the context's own attributes, so request-scoped attributes take
+// precedence. The underlying context.Context and memo are shared with the original, and
+// so is segment membership among the copies made with the same defaults.
+func (c *EvaluationContext) WithDefaultAttributes(defaults map[string]string) *EvaluationContext {
+	if len(defaults) == 0 {
+		return c
+	}
+	merged := make(map[string]string, len(defaults)+len(c.Attributes))
+	maps.Copy(merged, defaults)
+	maps.Copy(merged, c.Attributes)
+	return &EvaluationContext{
+		ctx:        c.ctx,
+		Attributes: merged,
+		memo:       c.memo,
+		segments:   c.derived.cache(defaults),
+		derived:    &derivedSegments{},
+	}
+}
+
+// derivedSegments holds the segment cache of the contexts derived from one context with
+// WithDefaultAttributes, so that membership is resolved once per request rather than on
+// every lookup. It is replaced when the defaults change, since membership may too.
+type derivedSegments struct {
+	mu       sync.Mutex
+	defaults map[string]string
+	segments *segmentCache
+}
+
+// cache returns the segment cache for contexts derived with defaults.
+func (d *derivedSegments) cache(defaults map[string]string) *segmentCache {
+	if d == nil {
+		return &segmentCache{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.segments == nil || !maps.Equal(d.defaults, defaults) {
+		d.defaults = maps.Clone(defaults)
+		d.segments = &segmentCache{}
+	}
+	return d.segments
+}
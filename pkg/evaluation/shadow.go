@@ -0,0 +1,162 @@
+package evaluation
+
+import (
+	"maps"
+	"sync"
+
+	"github.com/figchain/go-client/pkg/metrics"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// CandidateSource supplies the candidate FigFamily to shadow-evaluate alongside a family.
+// Returning nil skips shadow evaluation for that family.
+type CandidateSource interface {
+	Candidate(figFamily *model.FigFamily) *model.FigFamily
+}
+
+// CandidateSet is a CandidateSource holding candidate families by namespace and key,
+// e.g. the next version fetched with an AsOfTimestamp in the future or a draft ruleset.
+// A CandidateSet is safe for concurrent use.
+type CandidateSet struct {
+	mu         sync.RWMutex
+	candidates map[string]model.FigFamily
+}
+
+// NewCandidateSet creates a new, empty CandidateSet.
+func NewCandidateSet() *CandidateSet {
+	return &CandidateSet{candidates: make(map[string]model.FigFamily)}
+}
+
+// Put adds or replaces the candidate for the family's namespace and key.
+func (s *CandidateSet) Put(figFamily model.FigFamily) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.candidates[figFamily.Definition.Namespace+":"+figFamily.Definition.Key] = figFamily
+}
+
+// Delete removes the candidate for namespace and key.
+func (s *CandidateSet) Delete(namespace, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.candidates, namespace+":"+key)
+}
+
+// Candidate implements CandidateSource.
+func (s *CandidateSet) Candidate(figFamily *model.FigFamily) *model.FigFamily {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	candidate, ok := s.candidates[figFamily.Definition.Namespace+":"+figFamily.Definition.Key]
+	if !ok {
+		return nil
+	}
+	return &candidate
+}
+
+// Divergence describes a shadow evaluation whose result differed from the primary one.
+type Divergence struct {
+	Namespace        string
+	Key              string
+	PrimaryVersion   string
+	CandidateVersion string
+	// CandidateErr is set when the candidate failed to evaluate.
+	CandidateErr error
+	Attributes   map[string]string
+}
+
+// ShadowEvaluator evaluates every family with a primary evaluator and, when a candidate
+// is available, also evaluates the candidate and reports divergences. The primary result
+// is always the one returned, so candidates never affect served values.
+type ShadowEvaluator struct {
+	primary      Evaluator
+	candidate    Evaluator
+	source       CandidateSource
+	onDivergence func(Divergence)
+	metrics      metrics.Recorder
+}
+
+// ShadowOption is a functional option for configuring a ShadowEvaluator.
+type ShadowOption func(*ShadowEvaluator)
+
+// WithCandidateEvaluator sets the evaluator used for candidates. Defaults to the primary.
+func WithCandidateEvaluator(e Evaluator) ShadowOption {
+	return func(s *ShadowEvaluator) {
+		s.candidate = e
+	}
+}
+
+// WithDivergenceHandler sets a callback invoked for every divergence. It is called
+// synchronously on the evaluation path and should return quickly.
+func WithDivergenceHandler(fn func(Divergence)) ShadowOption {
+	return func(s *ShadowEvaluator) {
+		s.onDivergence = fn
+	}
+}
+
+// WithShadowMetrics sets the recorder for shadow evaluation and divergence counters.
+func WithShadowMetrics(r metrics.Recorder) ShadowOption {
+	return func(s *ShadowEvaluator) {
+		s.metrics = r
+	}
+}
+
+// NewShadowEvaluator creates a new ShadowEvaluator.
+func NewShadowEvaluator(primary Evaluator, source CandidateSource, opts ...ShadowOption) *ShadowEvaluator {
+	s := &ShadowEvaluator{
+		primary: primary,
+		source:  source,
+		metrics: metrics.NopRecorder{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.candidate == nil {
+		s.candidate = primary
+	}
+	return s
+}
+
+func (s *ShadowEvaluator) Evaluate(figFamily *model.FigFamily, context *EvaluationContext) (*model.Fig, error) {
+	fig, err := s.primary.Evaluate(figFamily, context)
+	if err != nil || figFamily == nil {
+		return fig, err
+	}
+
+	candidate := s.source.Candidate(figFamily)
+	if candidate == nil {
+		return fig, nil
+	}
+
+	labels := map[string]string{"namespace": figFamily.Definition.Namespace, "key": figFamily.Definition.Key}
+	s.metrics.IncCounter(metrics.ShadowEvaluations, labels)
+
+	candidateFig, candidateErr := s.candidate.Evaluate(candidate, context)
+	primaryVersion := figVersion(fig)
+	candidateVersion := figVersion(candidateFig)
+	if candidateErr == nil && primaryVersion == candidateVersion {
+		return fig, nil
+	}
+
+	s.metrics.IncCounter(metrics.ShadowDivergences, labels)
+	if s.onDivergence != nil {
+		var attributes map[string]string
+		if context != nil {
+			attributes = maps.Clone(context.Attributes)
+		}
+		s.onDivergence(Divergence{
+			Namespace:        figFamily.Definition.Namespace,
+			Key:              figFamily.Definition.Key,
+			PrimaryVersion:   primaryVersion,
+			CandidateVersion: candidateVersion,
+			CandidateErr:     candidateErr,
+			Attributes:       attributes,
+		})
+	}
+	return fig, nil
+}
+
+func figVersion(fig *model.Fig) string {
+	if fig == nil {
+		return ""
+	}
+	return fig.Version
+}
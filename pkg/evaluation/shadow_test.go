@@ -0,0 +1,67 @@
+package evaluation
+
+import (
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestShadowEvaluator_Evaluate(t *testing.T) {
+	defaultVersion := "v1"
+	primary := model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: "ns", Key: "key"},
+		DefaultVersion: &defaultVersion,
+		Figs:           []model.Fig{{Version: "v1"}, {Version: "v2"}},
+	}
+	candidate := primary
+	candidate.Rules = []model.Rule{
+		{
+			TargetVersion: "v2",
+			Conditions: []model.Condition{
+				{Variable: "plan", Operator: "EQUALS", Values: []string{"pro"}},
+			},
+		},
+	}
+
+	candidates := NewCandidateSet()
+	var divergences []Divergence
+	evaluator := NewShadowEvaluator(NewRuleBasedEvaluator(), candidates, WithDivergenceHandler(func(d Divergence) {
+		divergences = append(divergences, d)
+	}))
+
+	ctx := NewEvaluationContext(map[string]string{"plan": "pro"})
+
+	// No candidate: no shadow evaluation
+	fig, err := evaluator.Evaluate(&primary, ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if fig.Version != "v1" || len(divergences) != 0 {
+		t.Errorf("Evaluate() = %s with %d divergences, want v1 with none", fig.Version, len(divergences))
+	}
+
+	candidates.Put(candidate)
+
+	// Candidate matches rule: primary result is still returned, divergence reported
+	fig, err = evaluator.Evaluate(&primary, ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if fig.Version != "v1" {
+		t.Errorf("Evaluate() = %s, want primary version v1", fig.Version)
+	}
+	if len(divergences) != 1 {
+		t.Fatalf("Expected 1 divergence, got %d", len(divergences))
+	}
+	if d := divergences[0]; d.Key != "key" || d.PrimaryVersion != "v1" || d.CandidateVersion != "v2" {
+		t.Errorf("Unexpected divergence: %+v", d)
+	}
+
+	// Candidate agrees for other contexts
+	if _, err := evaluator.Evaluate(&primary, NewEvaluationContext(map[string]string{"plan": "free"})); err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if len(divergences) != 1 {
+		t.Errorf("Expected no new divergence, got %d total", len(divergences))
+	}
+}
@@ -0,0 +1,177 @@
+package evaluation
+
+import (
+	"context"
+	"sync"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx carrying tenantID, e.g. set by middleware for
+// every incoming request. Use it as the parent of a request's EvaluationContext so the
+// tenant flows into evaluation via TenantAttributes.
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant ID carried by ctx, if any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// TenantAttributes returns a ContextProvider that sets attributeKey to the tenant ID
+// carried by the context (see ContextWithTenant).
+func TenantAttributes(attributeKey string) ContextProvider {
+	return ContextProviderFunc(func(ctx context.Context) map[string]string {
+		tenantID, ok := TenantFromContext(ctx)
+		if !ok {
+			return nil
+		}
+		return map[string]string{attributeKey: tenantID}
+	})
+}
+
+// TenantOverrides pins fig versions per tenant, on top of the rules served for everyone
+// else. It is safe for concurrent use.
+type TenantOverrides struct {
+	mu        sync.RWMutex
+	overrides map[string]map[string]string // tenant ID -> key -> version
+	observers []func(OverrideChange)
+}
+
+// OverrideChange describes a change to TenantOverrides. An empty Version means the
+// override was removed. An empty Key means every override of the tenant was cleared, or
+// of every tenant if TenantID is also empty.
+type OverrideChange struct {
+	TenantID string
+	Key      string
+	Version  string
+	// Previous is the version that was pinned before the change, if any.
+	Previous string
+}
+
+// NewTenantOverrides creates a new, empty TenantOverrides.
+func NewTenantOverrides() *TenantOverrides {
+	return &TenantOverrides{overrides: make(map[string]map[string]string)}
+}
+
+// OnChange registers fn to be called after every change, e.g. for auditing. It is called
+// synchronously, outside the overrides' lock.
+func (o *TenantOverrides) OnChange(fn func(OverrideChange)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.observers = append(o.observers, fn)
+}
+
+// update applies fn under the lock, then notifies observers of the change it returns.
+func (o *TenantOverrides) update(fn func() OverrideChange) {
+	o.mu.Lock()
+	change := fn()
+	observers := o.observers
+	o.mu.Unlock()
+	for _, observe := range observers {
+		observe(change)
+	}
+}
+
+// Set serves version of key to tenantID, regardless of rules.
+func (o *TenantOverrides) Set(tenantID, key, version string) {
+	o.update(func() OverrideChange {
+		if o.overrides[tenantID] == nil {
+			o.overrides[tenantID] = make(map[string]string)
+		}
+		previous := o.overrides[tenantID][key]
+		o.overrides[tenantID][key] = version
+		return OverrideChange{TenantID: tenantID, Key: key, Version: version, Previous: previous}
+	})
+}
+
+// Delete removes the override of key for tenantID.
+func (o *TenantOverrides) Delete(tenantID, key string) {
+	o.update(func() OverrideChange {
+		previous := o.overrides[tenantID][key]
+		delete(o.overrides[tenantID], key)
+		if len(o.overrides[tenantID]) == 0 {
+			delete(o.overrides, tenantID)
+		}
+		return OverrideChange{TenantID: tenantID, Key: key, Previous: previous}
+	})
+}
+
+// Clear removes all overrides of tenantID.
+func (o *TenantOverrides) Clear(tenantID string) {
+	o.update(func() OverrideChange {
+		delete(o.overrides, tenantID)
+		return OverrideChange{TenantID: tenantID}
+	})
+}
+
+// ClearAll removes all overrides of all tenants.
+func (o *TenantOverrides) ClearAll() {
+	o.update(func() OverrideChange {
+		clear(o.overrides)
+		return OverrideChange{}
+	})
+}
+
+// Version returns the version of key pinned for tenantID, if any.
+func (o *TenantOverrides) Version(tenantID, key string) (string, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	version, ok := o.overrides[tenantID][key]
+	return version, ok
+}
+
+// TenantOverrideEvaluator serves tenant overrides ahead of another Evaluator. The tenant
+// is read from the evaluation context attribute attributeKey.
+type TenantOverrideEvaluator struct {
+	next         Evaluator
+	overrides    *TenantOverrides
+	attributeKey string
+}
+
+// NewTenantOverrideEvaluator creates a new TenantOverrideEvaluator delegating to next
+// when the tenant has no override for a family, or the overridden version doesn't exist.
+func NewTenantOverrideEvaluator(next Evaluator, overrides *TenantOverrides, attributeKey string) *TenantOverrideEvaluator {
+	return &TenantOverrideEvaluator{next: next, overrides: overrides, attributeKey: attributeKey}
+}
+
+func (e *TenantOverrideEvaluator) Evaluate(figFamily *model.FigFamily, context *EvaluationContext) (*model.Fig, error) {
+	if figFamily != nil && context != nil {
+		if tenantID := context.Attributes[e.attributeKey]; tenantID != "" {
+			if version, ok := e.overrides.Version(tenantID, figFamily.Definition.Key); ok {
+				for i := range figFamily.Figs {
+					if figFamily.Figs[i].Version == version {
+						return &figFamily.Figs[i], nil
+					}
+				}
+			}
+		}
+	}
+	return e.next.Evaluate(figFamily, context)
+}
+
+// EvaluateAll implements MultiEvaluator by delegating to the next evaluator; tenant
+// overrides pin the evaluated fig but don't change which rules match. It fails if the
+// next evaluator isn't a MultiEvaluator.
+func (e *TenantOverrideEvaluator) EvaluateAll(figFamily *model.FigFamily, context *EvaluationContext) ([]RuleMatch, error) {
+	next, ok := e.next.(MultiEvaluator)
+	if !ok {
+		return nil, ErrMultiEvaluationUnsupported
+	}
+	return next.EvaluateAll(figFamily, context)
+}
+
+// Precompile implements Precompiler by delegating to the next evaluator, if it is a
+// Precompiler.
+func (e *TenantOverrideEvaluator) Precompile(figFamily *model.FigFamily) {
+	if next, ok := e.next.(Precompiler); ok {
+		next.Precompile(figFamily)
+	}
+}
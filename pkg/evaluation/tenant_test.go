@@ -0,0 +1,106 @@
+package evaluation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestTenantAttributes(t *testing.T) {
+	provider := TenantAttributes("tenant")
+	if attrs := provider.Attributes(context.Background()); len(attrs) != 0 {
+		t.Errorf("Attributes() without tenant = %v, want none", attrs)
+	}
+
+	ctx := NewEvaluationContextWithContext(ContextWithTenant(context.Background(), "acme"), nil)
+	if attrs := provider.Attributes(ctx); attrs["tenant"] != "acme" {
+		t.Errorf("Attributes() = %v, want tenant=acme", attrs)
+	}
+}
+
+func TestTenantOverrideEvaluator(t *testing.T) {
+	v1 := "v1"
+	figFamily := &model.FigFamily{
+		Definition:     model.FigDefinition{Key: "key"},
+		DefaultVersion: &v1,
+		Figs:           []model.Fig{{Version: "v1"}, {Version: "v2"}},
+	}
+	overrides := NewTenantOverrides()
+	evaluator := NewTenantOverrideEvaluator(NewRuleBasedEvaluator(), overrides, "tenant")
+
+	tests := []struct {
+		name  string
+		setup func()
+		attrs map[string]string
+		want  string
+	}{
+		{"no override", func() {}, map[string]string{"tenant": "acme"}, "v1"},
+		{"override", func() { overrides.Set("acme", "key", "v2") }, map[string]string{"tenant": "acme"}, "v2"},
+		{"other tenant", func() {}, map[string]string{"tenant": "globex"}, "v1"},
+		{"no tenant", func() {}, nil, "v1"},
+		{"missing version", func() { overrides.Set("acme", "key", "v9") }, map[string]string{"tenant": "acme"}, "v1"},
+		{"cleared", func() { overrides.Set("acme", "key", "v2"); overrides.Clear("acme") }, map[string]string{"tenant": "acme"}, "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			got, err := evaluator.Evaluate(figFamily, NewEvaluationContext(tt.attrs))
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if got.Version != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantOverrideEvaluator_Precompile(t *testing.T) {
+	v1 := "v1"
+	figFamily := &model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: "ns", Key: "key"},
+		DefaultVersion: &v1,
+		Figs:           []model.Fig{{Version: "v1"}, {Version: "v2"}},
+		Rules: []model.Rule{{
+			TargetVersion: "v2",
+			Conditions:    []model.Condition{{Variable: "plan", Operator: "EQUALS", Values: []string{"beta"}}},
+		}},
+	}
+	next := NewRuleBasedEvaluator()
+	var evaluator Evaluator = NewTenantOverrideEvaluator(next, NewTenantOverrides(), "tenant")
+	compiler, ok := evaluator.(Precompiler)
+	if !ok {
+		t.Fatal("TenantOverrideEvaluator doesn't implement Precompiler")
+	}
+	compiler.Precompile(figFamily)
+	if _, ok := next.compiled.Load(familyID{"ns", "key"}); !ok {
+		t.Error("Precompile() didn't compile the rules of the next evaluator")
+	}
+}
+
+func TestTenantOverrides_OnChange(t *testing.T) {
+	o := NewTenantOverrides()
+	var changes []OverrideChange
+	o.OnChange(func(c OverrideChange) { changes = append(changes, c) })
+
+	o.Set("acme", "key", "v1")
+	o.Set("acme", "key", "v2")
+	o.Delete("acme", "key")
+	o.ClearAll()
+
+	want := []OverrideChange{
+		{TenantID: "acme", Key: "key", Version: "v1"},
+		{TenantID: "acme", Key: "key", Version: "v2", Previous: "v1"},
+		{TenantID: "acme", Key: "key", Previous: "v2"},
+		{},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
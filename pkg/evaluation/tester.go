@@ -0,0 +1,230 @@
+package evaluation
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// TestSuite is a fig family's targeting rules and the versions they are expected to
+// serve for a table of contexts, so that rules can be unit-tested before publishing.
+// Suites are usually written in YAML or JSON and loaded with ParseTestSuite:
+//
+//	key: checkout-flow
+//	namespace: web
+//	versions: [v1, v2]
+//	defaultVersion: v1
+//	rules:
+//	  - description: beta users
+//	    conditions:
+//	      - {variable: plan, operator: EQUALS, values: [beta]}
+//	    targetVersion: v2
+//	cases:
+//	  - {name: beta user, attributes: {plan: beta}, expect: v2}
+//	  - {name: everyone else, attributes: {plan: free}, expect: v1}
+type TestSuite struct {
+	Key            string     `yaml:"key" json:"key"`
+	Namespace      string     `yaml:"namespace" json:"namespace"`
+	Versions       []string   `yaml:"versions" json:"versions"`
+	DefaultVersion string     `yaml:"defaultVersion" json:"defaultVersion"`
+	Rules          []TestRule `yaml:"rules" json:"rules"`
+	Cases          []TestCase `yaml:"cases" json:"cases"`
+}
+
+// TestRule is a rule of a TestSuite.
+type TestRule struct {
+	Description   string            `yaml:"description" json:"description"`
+	Conditions    []model.Condition `yaml:"conditions" json:"conditions"`
+	TargetVersion string            `yaml:"targetVersion" json:"targetVersion"`
+}
+
+// TestCase is an evaluation context and the version it is expected to be served. An
+// empty Expect expects no fig to be served.
+type TestCase struct {
+	Name       string            `yaml:"name" json:"name"`
+	Attributes map[string]string `yaml:"attributes" json:"attributes"`
+	Expect     string            `yaml:"expect" json:"expect"`
+}
+
+// ParseTestSuite parses a TestSuite from YAML or JSON.
+func ParseTestSuite(data []byte) (*TestSuite, error) {
+	var suite TestSuite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse test suite: %w", err)
+	}
+	return &suite, nil
+}
+
+// Family returns the fig family the suite describes. Figs have no payload.
+func (s *TestSuite) Family() *model.FigFamily {
+	ff := &model.FigFamily{
+		Definition: model.FigDefinition{Namespace: s.Namespace, Key: s.Key},
+	}
+	for _, v := range s.Versions {
+		ff.Figs = append(ff.Figs, model.Fig{Version: v})
+	}
+	for _, r := range s.Rules {
+		rule := model.Rule{Conditions: r.Conditions, TargetVersion: r.TargetVersion}
+		if r.Description != "" {
+			rule.Description = &r.Description
+		}
+		ff.Rules = append(ff.Rules, rule)
+	}
+	if s.DefaultVersion != "" {
+		ff.DefaultVersion = &s.DefaultVersion
+	}
+	return ff
+}
+
+// Tester runs TestSuites against the rule-based evaluator.
+type Tester struct {
+	evaluator *RuleBasedEvaluator
+}
+
+// NewTester creates a Tester evaluating with a RuleBasedEvaluator configured by opts,
+// e.g. WithExpressionEngine for CEL conditions or WithFamilyLookup for segments and
+// prerequisites.
+func NewTester(opts ...Option) *Tester {
+	return &Tester{evaluator: NewRuleBasedEvaluator(opts...)}
+}
+
+// TestResult is the outcome of one TestCase.
+type TestResult struct {
+	Case TestCase
+	// Got is the version served, empty if none.
+	Got string
+	// Rule is the index of the rule that matched, or -1 if none did.
+	Rule int
+	// Err is the evaluation error, if any.
+	Err error
+	// Explanation describes, for failed cases, why each rule did or didn't match.
+	Explanation []string
+}
+
+// Passed reports whether the case was served the expected version.
+func (r *TestResult) Passed() bool {
+	return r.Err == nil && r.Got == r.Case.Expect
+}
+
+// TestReport is the outcome of a TestSuite.
+type TestReport struct {
+	Suite   *TestSuite
+	Results []TestResult
+}
+
+// Passed reports whether every case passed.
+func (r *TestReport) Passed() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures returns the results of the cases that failed.
+func (r *TestReport) Failures() []TestResult {
+	var failed []TestResult
+	for _, res := range r.Results {
+		if !res.Passed() {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// String describes every failure, or reports that all cases passed.
+func (r *TestReport) String() string {
+	var b strings.Builder
+	failures := r.Failures()
+	if len(failures) == 0 {
+		fmt.Fprintf(&b, "%s/%s: all %d cases passed", r.Suite.Namespace, r.Suite.Key, len(r.Results))
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%s/%s: %d of %d cases failed", r.Suite.Namespace, r.Suite.Key, len(failures), len(r.Results))
+	for _, res := range failures {
+		fmt.Fprintf(&b, "\n  case %q %v:", res.Case.Name, res.Case.Attributes)
+		if res.Err != nil {
+			fmt.Fprintf(&b, " error: %v", res.Err)
+		} else {
+			fmt.Fprintf(&b, " expected %s, got %s", versionOrNone(res.Case.Expect), versionOrNone(res.Got))
+		}
+		for _, line := range res.Explanation {
+			b.WriteString("\n    " + line)
+		}
+	}
+	return b.String()
+}
+
+func versionOrNone(v string) string {
+	if v == "" {
+		return "no fig"
+	}
+	return v
+}
+
+// Run evaluates every case of the suite.
+func (t *Tester) Run(suite *TestSuite) *TestReport {
+	ff := suite.Family()
+	report := &TestReport{Suite: suite}
+	for _, tc := range suite.Cases {
+		ctx := NewEvaluationContext(tc.Attributes)
+		res := TestResult{Case: tc, Rule: -1}
+		fig, err := t.evaluator.Evaluate(ff, ctx)
+		res.Err = err
+		if fig != nil {
+			res.Got = fig.Version
+		}
+		for i, rule := range ff.Rules {
+			if t.evaluator.matchesRule(ff.Definition.Namespace, rule, ctx) {
+				res.Rule = i
+				break
+			}
+		}
+		if !res.Passed() {
+			res.Explanation = t.explain(ff, ctx)
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+// explain describes which conditions of each rule failed for ctx, up to the first rule
+// that matched.
+func (t *Tester) explain(ff *model.FigFamily, ctx *EvaluationContext) []string {
+	var lines []string
+	for i, rule := range ff.Rules {
+		name := fmt.Sprintf("rule %d", i+1)
+		if rule.Description != nil {
+			name += fmt.Sprintf(" (%s)", *rule.Description)
+		}
+		var failed []string
+		for _, c := range rule.Conditions {
+			if !t.evaluator.matchesCondition(ff.Definition.Namespace, c, ctx) {
+				failed = append(failed, describeCondition(c, ctx))
+			}
+		}
+		if len(failed) == 0 {
+			lines = append(lines, fmt.Sprintf("%s -> %s: matched", name, rule.TargetVersion))
+			return lines
+		}
+		lines = append(lines, fmt.Sprintf("%s -> %s: not matched: %s", name, rule.TargetVersion, strings.Join(failed, "; ")))
+	}
+	if ff.DefaultVersion != nil {
+		lines = append(lines, fmt.Sprintf("default -> %s", *ff.DefaultVersion))
+	} else {
+		lines = append(lines, "no default version")
+	}
+	return lines
+}
+
+// describeCondition describes a condition that failed for ctx.
+func describeCondition(c model.Condition, ctx *EvaluationContext) string {
+	switch c.Operator {
+	case OperatorCEL, OperatorInSegment:
+		return fmt.Sprintf("%s %v is false", c.Operator, c.Values)
+	}
+	val, ok := ctx.Attributes[c.Variable]
+	if !ok {
+		return fmt.Sprintf("%s %s %v: attribute missing", c.Variable, c.Operator, c.Values)
+	}
+	return fmt.Sprintf("%s %s %v: value is %q", c.Variable, c.Operator, c.Values, val)
+}
@@ -0,0 +1,65 @@
+package evaluation
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSuiteYAML = `
+key: checkout-flow
+namespace: web
+versions: [v1, v2]
+defaultVersion: v1
+rules:
+  - description: beta users
+    conditions:
+      - {variable: plan, operator: EQUALS, values: [beta]}
+      - {variable: country, operator: IN, values: [US, CA]}
+    targetVersion: v2
+cases:
+  - {name: beta user, attributes: {plan: beta, country: US}, expect: v2}
+  - {name: free user, attributes: {plan: free, country: US}, expect: v1}
+  - {name: beta user abroad, attributes: {plan: beta}, expect: v2}
+`
+
+func TestTester(t *testing.T) {
+	suite, err := ParseTestSuite([]byte(testSuiteYAML))
+	if err != nil {
+		t.Fatalf("ParseTestSuite() error = %v", err)
+	}
+
+	report := NewTester().Run(suite)
+	if report.Passed() {
+		t.Fatal("Passed() = true, want the third case to fail")
+	}
+	if len(report.Results) != 3 || report.Results[0].Rule != 0 || report.Results[1].Rule != -1 {
+		t.Errorf("Results = %+v", report.Results)
+	}
+
+	failures := report.Failures()
+	if len(failures) != 1 || failures[0].Case.Name != "beta user abroad" || failures[0].Got != "v1" {
+		t.Fatalf("Failures() = %+v", failures)
+	}
+	out := report.String()
+	for _, want := range []string{
+		`1 of 3 cases failed`,
+		`expected v2, got v1`,
+		`rule 1 (beta users) -> v2: not matched: country IN [US CA]: attribute missing`,
+		`default -> v1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("String() = %s\nwant it to contain %q", out, want)
+		}
+	}
+}
+
+func TestParseTestSuite_JSON(t *testing.T) {
+	suite, err := ParseTestSuite([]byte(`{"key": "k", "versions": ["v1"], "defaultVersion": "v1",
+		"cases": [{"name": "default", "expect": "v1"}]}`))
+	if err != nil {
+		t.Fatalf("ParseTestSuite() error = %v", err)
+	}
+	if report := NewTester().Run(suite); !report.Passed() {
+		t.Errorf("Run() failed:\n%s", report)
+	}
+}
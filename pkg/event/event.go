@@ -0,0 +1,66 @@
+// Package event defines the events the client emits about its internal state changes.
+package event
+
+import (
+	"time"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// Type identifies the kind of an Event.
+type Type string
+
+const (
+	// UpdateDropped is emitted when an update could not be delivered to a full Watch channel.
+	UpdateDropped Type = "update_dropped"
+	// ListenerPanicked is emitted when a RegisterListener callback panics.
+	ListenerPanicked Type = "listener_panicked"
+	// PollPanicked is emitted when the poll loop recovers from a panic.
+	PollPanicked Type = "poll_panicked"
+	// FamilyQuarantined is emitted when an update is rejected as invalid or undecodable.
+	FamilyQuarantined Type = "family_quarantined"
+	// SchemaIncompatible is emitted when a fig's schema can't be read into the Go type
+	// registered for its key.
+	SchemaIncompatible Type = "schema_incompatible"
+	// UpdateApplied is emitted for each update applied to a fig family, with its Change.
+	UpdateApplied Type = "update_applied"
+	// UpdateRejected is emitted when a BeforeApply hook rejects an update.
+	UpdateRejected Type = "update_rejected"
+	// UpdatesPaused is emitted when PauseUpdates starts queuing updates.
+	UpdatesPaused Type = "updates_paused"
+	// UpdatesResumed is emitted when ResumeUpdates applies the queued updates.
+	UpdatesResumed Type = "updates_resumed"
+	// CredentialsReloaded is emitted when a rotated credential file has been reloaded.
+	CredentialsReloaded Type = "credentials_reloaded"
+	// CredentialReloadFailed is emitted when a rotated credential file can't be loaded.
+	// The previous credentials stay in use.
+	CredentialReloadFailed Type = "credential_reload_failed"
+	// KeyDeprecated is emitted the first time a deprecated or archived key is read, with
+	// its ReplacementKey, if any.
+	KeyDeprecated Type = "key_deprecated"
+)
+
+// Event describes something that happened inside the client.
+type Event struct {
+	Type      Type
+	Time      time.Time
+	Namespace string
+	Key       string
+	// Message is a human-readable description of the event.
+	Message string
+	// Err is the error that caused the event, if any.
+	Err error
+	// Change describes how the family changed, for UpdateApplied events.
+	Change *model.FamilyChange
+	// ReplacementKey is the key to use instead, for KeyDeprecated events.
+	ReplacementKey string
+	// App and Instance identify the client that emitted the event, see
+	// client.Identity.
+	App      string
+	Instance string
+}
+
+// Handler receives client events. Handlers are called synchronously and must return
+// quickly and be safe for concurrent use. They are never called while the client holds
+// its internal lock, so they may call back into the client.
+type Handler func(Event)
@@ -0,0 +1,189 @@
+// Package figchain provides RawClient, a thin, stateless client of the FigChain API for
+// tooling such as Terraform providers, CLIs and migrations. Unlike client.Client, it
+// keeps no store, doesn't poll and starts no goroutines: every method is a single
+// request to the server.
+package figchain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/transport"
+	"github.com/figchain/go-client/pkg/util"
+)
+
+// RawClient is a stateless client of the FigChain API. It is safe for concurrent use.
+type RawClient struct {
+	transport     transport.Transport
+	environmentID string
+}
+
+// New creates a RawClient from the connection and authentication options of client.New:
+// the base and fallback URLs, environment, credentials, HTTP client, interceptors,
+// request signing and encoding. Options concerning the streaming runtime, e.g. polling,
+// are ignored.
+func New(opts ...config.Option) (*RawClient, error) {
+	cfg := config.DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("BaseURL is required")
+	}
+	if cfg.EnvironmentID == "" {
+		return nil, fmt.Errorf("EnvironmentID is required")
+	}
+	logging.RegisterSecret(cfg.ClientSecret)
+	logging.RegisterSecret(cfg.SigningKey)
+
+	var tokens transport.TokenProvider
+	switch {
+	case cfg.AuthPrivateKeyPath != "":
+		pk, err := util.LoadRSAPrivateKey(cfg.AuthPrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load auth private key: %w", err)
+		}
+		serviceAccountID := cfg.EnvironmentID
+		if cfg.AuthClientID != "" {
+			serviceAccountID = cfg.AuthClientID
+		}
+		namespace := ""
+		if len(cfg.Namespaces) > 0 {
+			namespace = cfg.Namespaces[0]
+		}
+		tokens = transport.NewPrivateKeyTokenProvider(pk, serviceAccountID, cfg.TenantID, namespace, "", transport.WithTokenClock(cfg.Clock))
+	case cfg.ClientSecretFile != "":
+		data, err := os.ReadFile(cfg.ClientSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client secret: %w", err)
+		}
+		secret := strings.TrimSpace(string(data))
+		if secret == "" {
+			return nil, fmt.Errorf("client secret file %s is empty", cfg.ClientSecretFile)
+		}
+		logging.RegisterSecret(secret)
+		tokens = transport.NewSharedSecretTokenProvider(secret)
+	case cfg.ClientSecret != "":
+		tokens = transport.NewSharedSecretTokenProvider(cfg.ClientSecret)
+	default:
+		return nil, fmt.Errorf("an authentication method must be configured. Please provide either a ClientSecret or an AuthPrivateKeyPath")
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	interceptors := cfg.RequestInterceptors
+	if cfg.SigningKey != "" {
+		signer, err := transport.NewHMACSigner(cfg.SigningAlgorithm, []byte(cfg.SigningKey), transport.WithSignerClock(cfg.Clock))
+		if err != nil {
+			return nil, fmt.Errorf("invalid request signing configuration: %w", err)
+		}
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], signer)
+	}
+	tr := transport.NewHTTPTransport(httpClient, cfg.BaseURL, tokens, cfg.EnvironmentID,
+		transport.WithRequestInterceptors(interceptors...),
+		transport.WithResponseInterceptors(cfg.ResponseInterceptors...),
+		transport.WithFallbackURLs(cfg.FallbackURLs...),
+		transport.WithFailoverCooldown(cfg.FailoverCooldown),
+		transport.WithClock(cfg.Clock),
+		transport.WithSingleObjectEncoding(cfg.SingleObjectEncoding),
+	)
+	return NewWithTransport(tr, cfg.EnvironmentID), nil
+}
+
+// NewWithTransport creates a RawClient for environmentID sending its requests with t,
+// e.g. a transport shared with other tools, or a fake one in tests.
+func NewWithTransport(t transport.Transport, environmentID string) *RawClient {
+	return &RawClient{transport: t, environmentID: environmentID}
+}
+
+// EnvironmentID returns the ID of the client's environment.
+func (c *RawClient) EnvironmentID() string {
+	return c.environmentID
+}
+
+// GetFamily fetches the fig family at key. It returns an error matching
+// transport.ErrNotFound if the key doesn't exist. Encrypted payloads are returned as is.
+func (c *RawClient) GetFamily(ctx context.Context, namespace, key string) (*model.FigFamily, error) {
+	return c.transport.FetchFamily(ctx, namespace, key)
+}
+
+// ListFamilies fetches every fig family of a namespace, with the cursor to pass to
+// ListChanges for the changes made since.
+func (c *RawClient) ListFamilies(ctx context.Context, namespace string) ([]model.FigFamily, string, error) {
+	resp, err := c.transport.FetchInitial(ctx, &model.InitialFetchRequest{Namespace: namespace, EnvironmentID: c.environmentID})
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.FigFamilies, resp.Cursor, nil
+}
+
+// ListChanges fetches the fig families of a namespace changed after cursor, with the
+// cursor of the changes returned. The server may hold the request open until there are
+// changes, so ctx should carry a deadline.
+func (c *RawClient) ListChanges(ctx context.Context, namespace, cursor string) ([]model.FigFamily, string, error) {
+	resp, err := c.transport.FetchUpdate(ctx, &model.UpdateFetchRequest{Namespace: namespace, Cursor: cursor, EnvironmentID: c.environmentID})
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.FigFamilies, resp.Cursor, nil
+}
+
+// PublishFig creates a fig version, in the client's environment unless
+// req.EnvironmentID is set. The payload must already be encoded, and encrypted if the
+// namespace requires it. It requires admin-scoped credentials.
+func (c *RawClient) PublishFig(ctx context.Context, req model.PublishRequest) (*model.PublishResponse, error) {
+	if req.EnvironmentID == "" {
+		req.EnvironmentID = c.environmentID
+	}
+	return c.transport.PublishFig(ctx, &req)
+}
+
+// ListNamespaces lists the namespaces of the client's environment.
+func (c *RawClient) ListNamespaces(ctx context.Context) ([]*model.NamespaceInfo, error) {
+	return c.transport.ListNamespaces(ctx)
+}
+
+// GetNamespace fetches the metadata of a namespace. It returns an error matching
+// transport.ErrNotFound if the namespace doesn't exist.
+func (c *RawClient) GetNamespace(ctx context.Context, name string) (*model.NamespaceInfo, error) {
+	return c.transport.GetNamespace(ctx, name)
+}
+
+// CreateNamespace creates a namespace, in the client's environment unless
+// req.EnvironmentID is set. It returns an error matching transport.ErrConflict if the
+// namespace already exists.
+func (c *RawClient) CreateNamespace(ctx context.Context, req model.CreateNamespaceRequest) (*model.NamespaceInfo, error) {
+	if req.EnvironmentID == "" {
+		req.EnvironmentID = c.environmentID
+	}
+	return c.transport.CreateNamespace(ctx, &req)
+}
+
+// ListPublicKeys lists the encryption public keys registered for email.
+func (c *RawClient) ListPublicKeys(ctx context.Context, email string) ([]*model.UserPublicKey, error) {
+	return c.transport.ListPublicKeys(ctx, email)
+}
+
+// UploadPublicKey registers an encryption public key.
+func (c *RawClient) UploadPublicKey(ctx context.Context, key *model.UserPublicKey) error {
+	return c.transport.UploadPublicKey(ctx, key)
+}
+
+// DeletePublicKey deletes a registered public key. It returns an error matching
+// transport.ErrNotFound if the key doesn't exist.
+func (c *RawClient) DeletePublicKey(ctx context.Context, keyID string) error {
+	return c.transport.DeletePublicKey(ctx, keyID)
+}
+
+// Close releases the client's resources.
+func (c *RawClient) Close() error {
+	return c.transport.Close()
+}
@@ -0,0 +1,106 @@
+package figchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+
+	"github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// writeOCF writes v as an OCF file of the named record of the FigChain schema.
+func writeOCF(t *testing.T, w http.ResponseWriter, name string, v any) {
+	t.Helper()
+	union := avro.MustParse(model.Schema).(*avro.UnionSchema)
+	for _, s := range union.Types() {
+		if s.(avro.NamedSchema).Name() != name {
+			continue
+		}
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(s.String(), &buf)
+		enc.Encode(v)
+		enc.Flush()
+		w.Write(buf.Bytes())
+		return
+	}
+	t.Fatalf("No schema named %s", name)
+}
+
+func TestRawClient(t *testing.T) {
+	family := model.FigFamily{
+		Definition:     model.FigDefinition{Key: "raw-key", Namespace: "default"},
+		Figs:           []model.Fig{{Version: "v1", Payload: []byte("\x06foo")}},
+		DefaultVersion: ptr("v1"),
+	}
+	var published model.PublishRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected Authorization header Bearer secret, got %s", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/data/family":
+			if r.URL.Query().Get("key") != "raw-key" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeOCF(t, w, "FigFamily", &family)
+		case "/data/initial":
+			writeOCF(t, w, "InitialFetchResponse", &model.InitialFetchResponse{Cursor: "1", FigFamilies: []model.FigFamily{family}})
+		case "/admin/figs/default/raw-key/versions":
+			var body struct {
+				EnvironmentID string `json:"environmentId"`
+				Version       string `json:"version"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			published = model.PublishRequest{EnvironmentID: body.EnvironmentID, Version: body.Version}
+			json.NewEncoder(w).Encode(model.PublishResponse{Version: body.Version})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	if _, err := New(config.WithBaseURL(server.URL), config.WithEnvironmentID("env-1")); err == nil {
+		t.Error("Expected New to fail without credentials")
+	}
+	c, err := New(config.WithBaseURL(server.URL), config.WithEnvironmentID("env-1"), config.WithClientSecret("secret"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	ff, err := c.GetFamily(ctx, "default", "raw-key")
+	if err != nil || *ff.DefaultVersion != "v1" {
+		t.Errorf("GetFamily() = %+v, %v", ff, err)
+	}
+	if _, err := c.GetFamily(ctx, "default", "missing"); !errors.Is(err, transport.ErrNotFound) {
+		t.Errorf("GetFamily(missing) error = %v, want ErrNotFound", err)
+	}
+
+	families, cursor, err := c.ListFamilies(ctx, "default")
+	if err != nil || len(families) != 1 || cursor != "1" {
+		t.Errorf("ListFamilies() = %+v, %q, %v", families, cursor, err)
+	}
+
+	resp, err := c.PublishFig(ctx, model.PublishRequest{Namespace: "default", Key: "raw-key", Version: "v2", Payload: []byte("\x06bar")})
+	if err != nil || resp.Version != "v2" {
+		t.Fatalf("PublishFig() = %+v, %v", resp, err)
+	}
+	if published.EnvironmentID != "env-1" || published.Version != "v2" {
+		t.Errorf("Published %+v, want v2 in env-1", published)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
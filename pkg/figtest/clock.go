@@ -0,0 +1,148 @@
+// Package figtest provides helpers for testing code that uses the FigChain client.
+package figtest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/figchain/go-client/pkg/clock"
+)
+
+// FakeClock is a clock.Clock whose time only moves when Advance is called, firing the
+// timers and tickers that fall due in order. Use it with config.WithClock to test polling,
+// expiry and backoff without sleeping, e.g.
+//
+//	clk := figtest.NewFakeClock(time.Now())
+//	c, _ := client.New(config.WithClock(clk), config.WithPollingInterval(time.Minute), ...)
+//	clk.BlockUntil(1) // the poll loop is waiting
+//	clk.Advance(time.Minute)
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending timer or ticker.
+type fakeWaiter struct {
+	clock  *FakeClock
+	at     time.Time
+	period time.Duration // zero for timers
+	ch     chan time.Time
+}
+
+// NewFakeClock creates a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the clock's time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer firing once the clock has advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	return c.add(d, 0)
+}
+
+// NewTicker creates a ticker firing every time the clock has advanced by d. Like a
+// time.Ticker, it drops ticks the receiver isn't ready for.
+func (c *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("figtest: non-positive interval for NewTicker")
+	}
+	return fakeTicker{c.add(d, d)}
+}
+
+func (c *FakeClock) add(d, period time.Duration) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{clock: c, at: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	if d <= 0 && period == 0 {
+		w.ch <- c.now
+		return w
+	}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers that fall due, in
+// the order they do.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for {
+		i := -1
+		for j, w := range c.waiters {
+			if !w.at.After(end) && (i < 0 || w.at.Before(c.waiters[i].at)) {
+				i = j
+			}
+		}
+		if i < 0 {
+			break
+		}
+		w := c.waiters[i]
+		c.now = w.at
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.waiters = slices.Delete(c.waiters, i, i+1)
+		}
+	}
+	c.now = end
+}
+
+// Waiters returns the number of pending timers and tickers.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until at least n timers and tickers are pending, e.g. until the
+// goroutine under test is waiting on the clock, so that Advance doesn't race with it.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+// Stop removes the timer or ticker, reporting whether it was pending.
+func (w *fakeWaiter) Stop() bool {
+	c := w.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.Index(c.waiters, w)
+	if i < 0 {
+		return false
+	}
+	c.waiters = slices.Delete(c.waiters, i, i+1)
+	return true
+}
+
+// fakeTicker adapts a fakeWaiter to clock.Ticker.
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeWaiter.Stop()
+}
@@ -0,0 +1,59 @@
+package figtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := NewFakeClock(start)
+
+	timer := clk.NewTimer(time.Minute)
+	stopped := clk.NewTimer(time.Minute)
+	ticker := clk.NewTicker(20 * time.Second)
+	if n := clk.Waiters(); n != 3 {
+		t.Fatalf("Waiters() = %d, want 3", n)
+	}
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Stop() should report only the first stop of a pending timer")
+	}
+
+	clk.Advance(30 * time.Second)
+	if got := clk.Now(); !got.Equal(start.Add(30 * time.Second)) {
+		t.Errorf("Now() = %v after Advance", got)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("Timer fired early")
+	default:
+	}
+	if tick := <-ticker.C(); !tick.Equal(start.Add(20 * time.Second)) {
+		t.Errorf("First tick at %v", tick)
+	}
+
+	clk.Advance(30 * time.Second)
+	if fired := <-timer.C(); !fired.Equal(start.Add(time.Minute)) {
+		t.Errorf("Timer fired at %v", fired)
+	}
+	// The tick at 40s was dropped since the one at 60s was not received yet
+	if tick := <-ticker.C(); !tick.Equal(start.Add(40 * time.Second)) {
+		t.Errorf("Second tick at %v", tick)
+	}
+	ticker.Stop()
+	if n := clk.Waiters(); n != 0 {
+		t.Errorf("Waiters() = %d after the timer fired and the ticker stopped", n)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		clk.BlockUntil(1)
+		close(done)
+	}()
+	clk.NewTimer(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("BlockUntil() didn't return once a timer was pending")
+	}
+}
@@ -0,0 +1,89 @@
+// Package local evaluates fig families from a locally supplied snapshot, without a
+// client, transport or store, so that edge functions and WASM builds can embed rule
+// evaluation alone. It only builds on the model and evaluation packages, which import no
+// configuration, cloud or authentication libraries, so embedding it doesn't pull in
+// viper, the AWS SDK or JWT.
+//
+// Families can come from anywhere, e.g. the state of a client.Handoff decoded with
+// store.UnmarshalSnapshot at build time and shipped with the function:
+//
+//	e := local.New(families)
+//	fig, err := e.Evaluate("web", "checkout-flow", evaluation.NewEvaluationContext(attrs))
+//
+// Evaluate returns the fig's Avro payload undecoded, so the embedding code chooses its
+// Avro library, or none if it only needs the served version.
+package local
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// ErrNotFound is returned when the snapshot has no family at a key.
+var ErrNotFound = errors.New("fig not found")
+
+// familyID identifies a family of the snapshot.
+type familyID struct {
+	namespace, key string
+}
+
+// Evaluator evaluates the families of a snapshot. Prerequisites and segments resolve to
+// families of the same snapshot. It is safe for concurrent use, including with Load.
+type Evaluator struct {
+	evaluator *evaluation.RuleBasedEvaluator
+	families  atomic.Pointer[map[familyID]*model.FigFamily]
+}
+
+// New creates an Evaluator of families. opts configure its evaluation.RuleBasedEvaluator,
+// e.g. with evaluation.WithExpressionEngine to evaluate CEL conditions.
+func New(families []model.FigFamily, opts ...evaluation.Option) *Evaluator {
+	e := &Evaluator{}
+	opts = append([]evaluation.Option{evaluation.WithFamilyLookup(e)}, opts...)
+	e.evaluator = evaluation.NewRuleBasedEvaluator(opts...)
+	e.Load(families)
+	return e
+}
+
+// Load replaces the snapshot with families, e.g. once a newer snapshot is deployed.
+// Evaluations in progress complete against the previous snapshot. Of several families
+// with the same namespace and key, the last is kept.
+func (e *Evaluator) Load(families []model.FigFamily) {
+	snapshot := make(map[familyID]*model.FigFamily, len(families))
+	for i := range families {
+		ff := families[i]
+		e.evaluator.Precompile(&ff)
+		snapshot[familyID{ff.Definition.Namespace, ff.Definition.Key}] = &ff
+	}
+	e.families.Store(&snapshot)
+}
+
+// Get returns the family at key. It implements evaluation.FamilyLookup.
+func (e *Evaluator) Get(namespace, key string) (*model.FigFamily, bool) {
+	ff, ok := (*e.families.Load())[familyID{namespace, key}]
+	return ff, ok
+}
+
+// Evaluate returns the fig served for the family at key to ctx, or nil if the family
+// serves nothing, e.g. once expired. It returns ErrNotFound if there is no such family.
+func (e *Evaluator) Evaluate(namespace, key string, ctx *evaluation.EvaluationContext) (*model.Fig, error) {
+	ff, ok := e.Get(namespace, key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, namespace, key)
+	}
+	return e.evaluator.Evaluate(ff, ctx)
+}
+
+// EvaluateAll returns every rule of the family at key that matches ctx, in order, like
+// evaluation.RuleBasedEvaluator.EvaluateAll. It returns ErrNotFound if there is no such
+// family.
+func (e *Evaluator) EvaluateAll(namespace, key string, ctx *evaluation.EvaluationContext) ([]evaluation.RuleMatch, error) {
+	ff, ok := e.Get(namespace, key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, namespace, key)
+	}
+	return e.evaluator.EvaluateAll(ff, ctx)
+}
@@ -0,0 +1,101 @@
+package local
+
+import (
+	"errors"
+	"go/build"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/evaluation"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestEvaluator(t *testing.T) {
+	v1, v2 := "v1", "v2"
+	families := []model.FigFamily{
+		{
+			Definition:     model.FigDefinition{Namespace: "web", Key: "checkout"},
+			Figs:           []model.Fig{{Version: v1, Payload: []byte("\x02a")}, {Version: v2, Payload: []byte("\x02b")}},
+			DefaultVersion: &v1,
+			Rules: []model.Rule{{
+				TargetVersion: v2,
+				Conditions:    []model.Condition{{Operator: evaluation.OperatorInSegment, Values: []string{"beta"}}},
+			}},
+		},
+		{
+			Definition: model.FigDefinition{Namespace: "web", Key: evaluation.SegmentKeyPrefix + "beta"},
+			Rules: []model.Rule{{
+				Conditions: []model.Condition{{Variable: "plan", Operator: model.OperatorEquals, Values: []string{"beta"}}},
+			}},
+		},
+	}
+	e := New(families)
+	beta := evaluation.NewEvaluationContext(map[string]string{"plan": "beta"})
+
+	// Segments resolve to families of the snapshot
+	fig, err := e.Evaluate("web", "checkout", beta)
+	if err != nil || fig.Version != v2 || string(fig.Payload) != "\x02b" {
+		t.Errorf("Evaluate() = %+v, %v, want v2", fig, err)
+	}
+	fig, err = e.Evaluate("web", "checkout", evaluation.NewEvaluationContext(nil))
+	if err != nil || fig.Version != v1 {
+		t.Errorf("Evaluate() = %+v, %v, want v1", fig, err)
+	}
+	if matches, err := e.EvaluateAll("web", "checkout", beta); err != nil || len(matches) != 1 {
+		t.Errorf("EvaluateAll() = %+v, %v, want the segment rule", matches, err)
+	}
+	if _, err := e.Evaluate("web", "missing", beta); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Evaluate(missing) error = %v, want ErrNotFound", err)
+	}
+
+	// Loading a new snapshot replaces the families
+	families[0].DefaultVersion = &v2
+	families[0].Rules = nil
+	e.Load(families[:1])
+	if fig, err := e.Evaluate("web", "checkout", evaluation.NewEvaluationContext(nil)); err != nil || fig.Version != v2 {
+		t.Errorf("Evaluate() = %+v, %v after Load, want v2", fig, err)
+	}
+	if _, ok := e.Get("web", evaluation.SegmentKeyPrefix+"beta"); ok {
+		t.Error("Get() found a family of the previous snapshot")
+	}
+}
+
+// allowedImports are the modules outside the standard library that the package may
+// depend on.
+var allowedImports = []string{"go.yaml.in/yaml/v3"}
+
+// TestDependencies checks that the package stays embeddable: it imports nothing outside
+// the standard library but allowedImports, directly or through other packages of the
+// module.
+func TestDependencies(t *testing.T) {
+	const module = "github.com/figchain/go-client/"
+	_, file, _, _ := runtime.Caller(0)
+	root := filepath.Join(filepath.Dir(file), "..", "..")
+
+	seen := map[string]bool{}
+	var visit func(path, from string)
+	visit = func(path, from string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		if !strings.HasPrefix(path, module) {
+			first, _, _ := strings.Cut(path, "/")
+			if strings.Contains(first, ".") && !slices.Contains(allowedImports, path) {
+				t.Errorf("%s imports %s", from, path)
+			}
+			return
+		}
+		pkg, err := build.ImportDir(filepath.Join(root, strings.TrimPrefix(path, module)), 0)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		for _, imp := range pkg.Imports {
+			visit(imp, path)
+		}
+	}
+	visit(module+"pkg/local", "")
+}
@@ -0,0 +1,65 @@
+// Package logging is the client's only way to write log messages. Every message is
+// redacted before it reaches the standard logger: registered secrets and anything shaped
+// like a credential, i.e. a JWT, an Authorization header value or a PEM private key, are
+// replaced by Redacted. A test enforces that the rest of the client logs through it, and
+// never passes payloads or credentials as log arguments.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces sensitive data in log messages.
+const Redacted = "[REDACTED]"
+
+// minSecretLen is the length below which registered secrets are ignored, so that short
+// values don't redact ordinary words.
+const minSecretLen = 8
+
+// patterns match credentials that need no registration.
+var patterns = []*regexp.Regexp{
+	// PEM private keys
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(-----END [A-Z ]*PRIVATE KEY-----|$)`),
+	// Authorization header values
+	regexp.MustCompile(`(?i)\b(Bearer|Basic)\s+[A-Za-z0-9._~+/=-]+`),
+	// JWTs
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+}
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = make(map[string]bool)
+)
+
+// RegisterSecret redacts secret, e.g. a client secret, from every later log message.
+// Secrets shorter than 8 bytes are ignored.
+func RegisterSecret(secret string) {
+	if len(secret) < minSecretLen {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets[secret] = true
+}
+
+// Redact returns s with registered secrets and credentials replaced by Redacted.
+func Redact(s string) string {
+	secretsMu.RLock()
+	for secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	secretsMu.RUnlock()
+	for _, p := range patterns {
+		s = p.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+// Printf writes a redacted message to the standard logger, like log.Printf.
+func Printf(format string, args ...any) {
+	log.Output(2, Redact(fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sensitiveNames are identifiers and fields holding payloads or credentials, which must
+// never be passed to Printf.
+var sensitiveNames = map[string]bool{
+	"Payload":       true,
+	"payload":       true,
+	"plaintext":     true,
+	"ClientSecret":  true,
+	"secret":        true,
+	"SigningKey":    true,
+	"privateKey":    true,
+	"pk":            true,
+	"token":         true,
+	"Authorization": true,
+	"data":          true,
+}
+
+// TestPolicy enforces the logging policy on the client's packages: they log only through
+// this package, and never with a payload or credential as an argument. Commands may log
+// with the standard logger.
+func TestPolicy(t *testing.T) {
+	root := filepath.Join("..", "..", "pkg")
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			pkg, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			pos := fset.Position(call.Pos())
+			switch {
+			case pkg.Name == "log", pkg.Name == "fmt" && strings.HasPrefix(sel.Sel.Name, "Print"):
+				if f.Name.Name != "logging" {
+					t.Errorf("%s: %s.%s bypasses the logging policy, use logging.Printf", pos, pkg.Name, sel.Sel.Name)
+				}
+			case pkg.Name == "logging" && sel.Sel.Name == "Printf":
+				for _, arg := range call.Args[1:] {
+					if name := sensitiveName(arg); name != "" {
+						t.Errorf("%s: logging.Printf is passed %s", pos, name)
+					}
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sensitiveName returns the sensitive identifier or field arg refers to, if any.
+func sensitiveName(arg ast.Expr) string {
+	var name string
+	ast.Inspect(arg, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			// Only the field matters, e.g. fig.Payload but not payload.Namespace
+			if sensitiveNames[n.Sel.Name] {
+				name = n.Sel.Name
+			}
+			return false
+		case *ast.Ident:
+			if sensitiveNames[n.Name] {
+				name = n.Name
+			}
+		case *ast.CallExpr:
+			// The results of calls, e.g. len(payload), are checked by review
+			return false
+		}
+		return name == ""
+	})
+	return name
+}
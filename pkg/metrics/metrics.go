@@ -0,0 +1,39 @@
+// Package metrics defines the hooks through which the client reports internal metrics.
+package metrics
+
+import "time"
+
+// Metric names emitted by the client.
+const (
+	EvaluationMemoHits    = "figchain_evaluation_memo_hits_total"
+	EvaluationMemoMisses  = "figchain_evaluation_memo_misses_total"
+	ShadowEvaluations     = "figchain_shadow_evaluations_total"
+	ShadowDivergences     = "figchain_shadow_divergences_total"
+	WatchUpdatesDropped   = "figchain_watch_updates_dropped_total"
+	WatchUpdatesCoalesced = "figchain_watch_updates_coalesced_total"
+	UpdatesIgnored        = "figchain_updates_ignored_total"
+	PollErrors            = "figchain_poll_errors_total"
+	UpdatesApplied        = "figchain_update_apply_total"
+	StoreFamilies         = "figchain_store_families"
+	EvaluationDuration    = "figchain_evaluation_duration_seconds"
+	BootstrapDuration     = "figchain_bootstrap_duration_seconds"
+	SchemaIncompatible    = "figchain_schema_incompatible_total"
+	// ClientInfo is always 1, labelled with the client's identity: sdk_version, app,
+	// instance_id and hostname.
+	ClientInfo = "figchain_client_info"
+)
+
+// Recorder receives metrics emitted by the client. Implementations must be safe for
+// concurrent use.
+type Recorder interface {
+	IncCounter(name string, labels map[string]string)
+	ObserveDuration(name string, d time.Duration, labels map[string]string)
+	SetGauge(name string, value float64, labels map[string]string)
+}
+
+// NopRecorder discards all metrics.
+type NopRecorder struct{}
+
+func (NopRecorder) IncCounter(string, map[string]string)                     {}
+func (NopRecorder) ObserveDuration(string, time.Duration, map[string]string) {}
+func (NopRecorder) SetGauge(string, float64, map[string]string)              {}
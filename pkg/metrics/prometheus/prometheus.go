@@ -0,0 +1,145 @@
+// Package prometheus exports client metrics to a Prometheus registry.
+package prometheus
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/metrics"
+)
+
+// WithPrometheusRegistry returns a config option that records client metrics in reg.
+// Metrics are served by exposing reg with promhttp.HandlerFor.
+func WithPrometheusRegistry(reg prom.Registerer) config.Option {
+	return config.WithMetricsRecorder(NewRecorder(reg))
+}
+
+// Recorder is a metrics.Recorder backed by Prometheus collectors. Collectors for the
+// core client metrics are registered up front; any other metric is registered the first
+// time it is recorded, with the label names it was first recorded with. Recording a
+// metric that conflicts with one already in the registry panics, as MustRegister does.
+type Recorder struct {
+	reg        prom.Registerer
+	mu         sync.Mutex
+	counters   map[string]*prom.CounterVec
+	histograms map[string]*prom.HistogramVec
+	gauges     map[string]*prom.GaugeVec
+	labels     map[string][]string
+}
+
+// NewRecorder creates a Recorder registering its collectors with reg. It panics if reg
+// holds a conflicting metric of the same name as a core client metric.
+func NewRecorder(reg prom.Registerer) *Recorder {
+	r := &Recorder{
+		reg:        reg,
+		counters:   make(map[string]*prom.CounterVec),
+		histograms: make(map[string]*prom.HistogramVec),
+		gauges:     make(map[string]*prom.GaugeVec),
+		labels:     make(map[string][]string),
+	}
+	r.counter(metrics.PollErrors, "Number of failed update polls.", []string{"namespace"})
+	r.counter(metrics.UpdatesApplied, "Number of fig family updates applied to the store.", []string{"namespace"})
+	r.gauge(metrics.StoreFamilies, "Number of fig families held in the store.", nil)
+	r.histogram(metrics.EvaluationDuration, "Time spent evaluating rules for a fig.", []string{"namespace"})
+	r.histogram(metrics.BootstrapDuration, "Time spent bootstrapping the client.", nil)
+	r.gauge(metrics.ClientInfo, "Identity of the client, always 1.", []string{"app", "hostname", "instance_id", "sdk_version"})
+	return r
+}
+
+func (r *Recorder) IncCounter(name string, labels map[string]string) {
+	r.mu.Lock()
+	vec, ok := r.counters[name]
+	if !ok {
+		vec = r.counter(name, name, labelNames(labels))
+	}
+	names := r.labels[name]
+	r.mu.Unlock()
+	vec.With(labelValues(names, labels)).Inc()
+}
+
+func (r *Recorder) ObserveDuration(name string, d time.Duration, labels map[string]string) {
+	r.mu.Lock()
+	vec, ok := r.histograms[name]
+	if !ok {
+		vec = r.histogram(name, name, labelNames(labels))
+	}
+	names := r.labels[name]
+	r.mu.Unlock()
+	vec.With(labelValues(names, labels)).Observe(d.Seconds())
+}
+
+func (r *Recorder) SetGauge(name string, value float64, labels map[string]string) {
+	r.mu.Lock()
+	vec, ok := r.gauges[name]
+	if !ok {
+		vec = r.gauge(name, name, labelNames(labels))
+	}
+	names := r.labels[name]
+	r.mu.Unlock()
+	vec.With(labelValues(names, labels)).Set(value)
+}
+
+// counter, histogram and gauge create and register a collector. Callers other than
+// NewRecorder must hold r.mu.
+func (r *Recorder) counter(name, help string, labels []string) *prom.CounterVec {
+	vec := prom.NewCounterVec(prom.CounterOpts{Name: name, Help: help}, labels)
+	r.counters[name] = register(r.reg, vec)
+	r.labels[name] = labels
+	return r.counters[name]
+}
+
+func (r *Recorder) histogram(name, help string, labels []string) *prom.HistogramVec {
+	vec := prom.NewHistogramVec(prom.HistogramOpts{Name: name, Help: help, Buckets: prom.DefBuckets}, labels)
+	r.histograms[name] = register(r.reg, vec)
+	r.labels[name] = labels
+	return r.histograms[name]
+}
+
+func (r *Recorder) gauge(name, help string, labels []string) *prom.GaugeVec {
+	vec := prom.NewGaugeVec(prom.GaugeOpts{Name: name, Help: help}, labels)
+	r.gauges[name] = register(r.reg, vec)
+	r.labels[name] = labels
+	return r.gauges[name]
+}
+
+// register registers c with reg, reusing an identical collector that is already
+// registered, e.g. by another client sharing the registry. Like MustRegister, it panics
+// if c can't be registered, e.g. because reg holds a different metric of the same name:
+// its values would otherwise be silently dropped.
+func register[C prom.Collector](reg prom.Registerer, c C) C {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prom.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(C); ok {
+			return existing
+		}
+	}
+	panic(err)
+}
+
+func labelNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// labelValues maps labels onto the label names a collector was created with: missing
+// labels are empty and unknown labels are dropped, so a mismatched call never panics.
+func labelValues(names []string, labels map[string]string) prom.Labels {
+	values := make(prom.Labels, len(names))
+	for _, name := range names {
+		values[name] = labels[name]
+	}
+	return values
+}
@@ -0,0 +1,53 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/figchain/go-client/pkg/metrics"
+)
+
+func TestRecorder(t *testing.T) {
+	reg := prom.NewRegistry()
+	r := NewRecorder(reg)
+
+	r.IncCounter(metrics.PollErrors, map[string]string{"namespace": "ns"})
+	r.IncCounter(metrics.PollErrors, map[string]string{"namespace": "ns"})
+	r.SetGauge(metrics.StoreFamilies, 3, nil)
+	r.ObserveDuration(metrics.EvaluationDuration, time.Millisecond, map[string]string{"namespace": "ns", "unknown": "x"})
+	r.IncCounter("figchain_custom_total", map[string]string{"key": "k"})
+
+	if got := testutil.ToFloat64(r.counters[metrics.PollErrors].WithLabelValues("ns")); got != 2 {
+		t.Errorf("poll errors = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(r.gauges[metrics.StoreFamilies].WithLabelValues()); got != 3 {
+		t.Errorf("store families = %v, want 3", got)
+	}
+	if got := testutil.CollectAndCount(r.histograms[metrics.EvaluationDuration]); got != 1 {
+		t.Errorf("evaluation duration series = %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(r.counters["figchain_custom_total"].WithLabelValues("k")); got != 1 {
+		t.Errorf("custom counter = %v, want 1", got)
+	}
+
+	// A second recorder on the same registry shares the collectors
+	NewRecorder(reg).IncCounter(metrics.PollErrors, map[string]string{"namespace": "ns"})
+	if got := testutil.ToFloat64(r.counters[metrics.PollErrors].WithLabelValues("ns")); got != 3 {
+		t.Errorf("poll errors after shared registration = %v, want 3", got)
+	}
+}
+
+func TestRecorder_Conflict(t *testing.T) {
+	reg := prom.NewRegistry()
+	reg.MustRegister(prom.NewGauge(prom.GaugeOpts{Name: metrics.PollErrors, Help: "Something else."}))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("NewRecorder succeeded with a conflicting metric registered")
+		}
+	}()
+	NewRecorder(reg)
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+)
+
+// Kinds of FamilyChange.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// FamilyChange describes how a fig family differs between two points in time.
+type FamilyChange struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	// Kind is ChangeAdded, ChangeRemoved or ChangeModified.
+	Kind              string   `json:"kind"`
+	OldDefaultVersion string   `json:"oldDefaultVersion,omitempty"`
+	NewDefaultVersion string   `json:"newDefaultVersion,omitempty"`
+	AddedVersions     []string `json:"addedVersions,omitempty"`
+	RemovedVersions   []string `json:"removedVersions,omitempty"`
+	// ChangedVersions are versions present in both whose payload differs.
+	ChangedVersions []string `json:"changedVersions,omitempty"`
+	// OldRules and NewRules are set when the rules or prerequisites differ.
+	OldRules []Rule `json:"oldRules,omitempty"`
+	NewRules []Rule `json:"newRules,omitempty"`
+}
+
+// DiffFamilies compares two versions of a family, either of which may be nil if the
+// family didn't exist, and reports whether they differ.
+func DiffFamilies(old, next *FigFamily) (FamilyChange, bool) {
+	switch {
+	case old == nil && next == nil:
+		return FamilyChange{}, false
+	case old == nil:
+		return FamilyChange{
+			Namespace:         next.Definition.Namespace,
+			Key:               next.Definition.Key,
+			Kind:              ChangeAdded,
+			NewDefaultVersion: defaultVersion(next),
+			AddedVersions:     figVersions(next),
+			NewRules:          next.Rules,
+		}, true
+	case next == nil:
+		return FamilyChange{
+			Namespace:         old.Definition.Namespace,
+			Key:               old.Definition.Key,
+			Kind:              ChangeRemoved,
+			OldDefaultVersion: defaultVersion(old),
+		}, true
+	}
+
+	change := FamilyChange{
+		Namespace:         next.Definition.Namespace,
+		Key:               next.Definition.Key,
+		Kind:              ChangeModified,
+		OldDefaultVersion: defaultVersion(old),
+		NewDefaultVersion: defaultVersion(next),
+	}
+	changed := change.OldDefaultVersion != change.NewDefaultVersion
+
+	oldFigs := make(map[string]*Fig, len(old.Figs))
+	for i := range old.Figs {
+		oldFigs[old.Figs[i].Version] = &old.Figs[i]
+	}
+	for i := range next.Figs {
+		fig := &next.Figs[i]
+		prev, ok := oldFigs[fig.Version]
+		delete(oldFigs, fig.Version)
+		switch {
+		case !ok:
+			change.AddedVersions = append(change.AddedVersions, fig.Version)
+		case !bytes.Equal(prev.Payload, fig.Payload) || !bytes.Equal(prev.WrappedDek, fig.WrappedDek):
+			change.ChangedVersions = append(change.ChangedVersions, fig.Version)
+		}
+	}
+	for v := range oldFigs {
+		change.RemovedVersions = append(change.RemovedVersions, v)
+	}
+	slices.Sort(change.RemovedVersions)
+	changed = changed || len(change.AddedVersions) > 0 || len(change.RemovedVersions) > 0 || len(change.ChangedVersions) > 0
+
+	if !reflect.DeepEqual(old.Rules, next.Rules) || !reflect.DeepEqual(old.Prerequisites, next.Prerequisites) {
+		change.OldRules, change.NewRules = old.Rules, next.Rules
+		changed = true
+	}
+	return change, changed
+}
+
+// defaultVersion returns the default version of ff, or "" if it has none.
+func defaultVersion(ff *FigFamily) string {
+	if ff.DefaultVersion == nil {
+		return ""
+	}
+	return *ff.DefaultVersion
+}
+
+// figVersions returns the versions of ff's figs.
+func figVersions(ff *FigFamily) []string {
+	versions := make([]string, 0, len(ff.Figs))
+	for _, fig := range ff.Figs {
+		versions = append(versions, fig.Version)
+	}
+	return versions
+}
@@ -1,6 +1,10 @@
 package model
 
+import "time"
+
 type UserPublicKey struct {
+	// KeyID is assigned by the server when the key is uploaded.
+	KeyID     string `json:"keyId,omitempty"`
 	Email     string `json:"email"`
 	PublicKey string `json:"publicKey"`
 	Algorithm string `json:"algorithm"`
@@ -10,3 +14,84 @@ type NamespaceKey struct {
 	WrappedKey string `json:"wrappedKey"`
 	KeyID      string `json:"keyId"`
 }
+
+// KeyUsage is how often a fig key was read by a client.
+type KeyUsage struct {
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Reads     uint64    `json:"reads"`
+	LastRead  time.Time `json:"lastRead"`
+}
+
+// UsageReport reports the keys a client instance read since its previous report.
+type UsageReport struct {
+	EnvironmentID string     `json:"environmentId"`
+	InstanceID    string     `json:"instanceId,omitempty"`
+	Keys          []KeyUsage `json:"keys"`
+}
+
+// PublishRequest creates a new version of a fig and, optionally, replaces the rules and
+// default version of its family.
+type PublishRequest struct {
+	Namespace     string
+	Key           string
+	EnvironmentID string
+	// Version is the ID of the new version, a UUID chosen by the publisher so that Rules
+	// and DefaultVersion can target it.
+	Version string
+	// Payload is the Avro-encoded value of the new version.
+	Payload []byte
+	// IsEncrypted is set when Payload is encrypted with a data key, WrappedDek, itself
+	// wrapped with the namespace key KeyID.
+	IsEncrypted bool
+	WrappedDek  []byte
+	KeyID       string
+	// Rules replace the family's rules, unless nil.
+	Rules []Rule
+	// DefaultVersion replaces the family's default version, unless nil.
+	DefaultVersion *string
+	// DryRun asks the server to validate the request without publishing anything.
+	DryRun bool
+	// IdempotencyKey identifies the request, so that the server publishes a retried
+	// request only once.
+	IdempotencyKey string
+}
+
+// PublishResponse describes a published fig version.
+type PublishResponse struct {
+	Version string `json:"version"`
+	// Problems are the server's validation findings. A request with problems isn't
+	// published; a dry run reports them instead of failing.
+	Problems []PublishProblem `json:"problems,omitempty"`
+	// ApprovalRequired is set when the namespace requires the version to be approved
+	// before it takes effect. ApprovalID identifies the pending approval.
+	ApprovalRequired bool   `json:"approvalRequired,omitempty"`
+	ApprovalID       string `json:"approvalId,omitempty"`
+}
+
+// PublishProblem is a validation finding about a publish request.
+type PublishProblem struct {
+	// Field is the part of the request concerned, e.g. "payload" or "rules[0]".
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// NamespaceInfo describes a namespace of an environment.
+type NamespaceInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// KeyCount is the number of fig keys in the namespace.
+	KeyCount int `json:"keyCount"`
+	// LastUpdated is when a fig of the namespace was last published, or zero if none was.
+	LastUpdated time.Time `json:"lastUpdated"`
+	// Encrypted is set when the namespace's figs are encrypted.
+	Encrypted bool `json:"encrypted"`
+}
+
+// CreateNamespaceRequest creates a namespace in an environment.
+type CreateNamespaceRequest struct {
+	EnvironmentID string `json:"environmentId"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	Encrypted     bool   `json:"encrypted"`
+}
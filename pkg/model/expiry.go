@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Expired reports whether the family's expiry time, if any, has passed at now.
+func (ff *FigFamily) Expired(now time.Time) bool {
+	return ff.Definition.ExpiresAt != nil && !now.Before(*ff.Definition.ExpiresAt)
+}
+
+// Expired reports whether the fig's expiry time, if any, has passed at now.
+func (f *Fig) Expired(now time.Time) bool {
+	return f.ExpiresAt != nil && !now.Before(*f.ExpiresAt)
+}
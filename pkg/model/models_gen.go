@@ -25,32 +25,49 @@ type Rule struct {
 
 // FigDefinition is a generated struct.
 type FigDefinition struct {
-	Namespace     string    `avro:"namespace"`
-	Key           string    `avro:"key"`
-	FigID         string    `avro:"figId"`
-	SchemaURI     string    `avro:"schemaUri"`
-	SchemaVersion string    `avro:"schemaVersion"`
-	CreatedAt     time.Time `avro:"createdAt"`
-	UpdatedAt     time.Time `avro:"updatedAt"`
+	Namespace     string       `avro:"namespace"`
+	Key           string       `avro:"key"`
+	FigID         string       `avro:"figId"`
+	SchemaURI     string       `avro:"schemaUri"`
+	SchemaVersion string       `avro:"schemaVersion"`
+	CreatedAt     time.Time    `avro:"createdAt"`
+	UpdatedAt     time.Time    `avro:"updatedAt"`
+	ExpiresAt     *time.Time   `avro:"expiresAt"`
+	Deprecation   *Deprecation `avro:"deprecation"`
+}
+
+// Deprecation is a generated struct.
+type Deprecation struct {
+	ReplacementKey *string `avro:"replacementKey"`
+	Message        *string `avro:"message"`
+	Archived       bool    `avro:"archived"`
 }
 
 // Fig is a generated struct.
 type Fig struct {
-	FigID   string `avro:"figId"`
+	FigID               string     `avro:"figId"`
+	Version             string     `avro:"version"`
+	Payload             []byte     `avro:"payload"`
+	IsEncrypted         bool       `avro:"isEncrypted"`
+	WrappedDek          []byte     `avro:"wrappedDek"`
+	EncryptionAlgorithm *string    `avro:"encryptionAlgorithm"`
+	KeyID               *string    `avro:"keyId"`
+	ExpiresAt           *time.Time `avro:"expiresAt"`
+}
+
+// Prerequisite is a generated struct.
+type Prerequisite struct {
+	Key     string `avro:"key"`
 	Version string `avro:"version"`
-	Payload             []byte  `avro:"payload"`
-	IsEncrypted         bool    `avro:"isEncrypted"`
-	WrappedDek          []byte  `avro:"wrappedDek"`
-	EncryptionAlgorithm *string `avro:"encryptionAlgorithm"`
-	KeyID               *string `avro:"keyId"`
 }
 
 // FigFamily is a generated struct.
 type FigFamily struct {
-	Definition     FigDefinition `avro:"definition"`
-	Figs           []Fig         `avro:"figs"`
-	Rules          []Rule        `avro:"rules"`
-	DefaultVersion *string       `avro:"defaultVersion"`
+	Definition     FigDefinition  `avro:"definition"`
+	Figs           []Fig          `avro:"figs"`
+	Rules          []Rule         `avro:"rules"`
+	DefaultVersion *string        `avro:"defaultVersion"`
+	Prerequisites  []Prerequisite `avro:"prerequisites"`
 }
 
 // InitialFetchRequest is a generated struct.
@@ -0,0 +1,167 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Condition operators.
+const (
+	OperatorEquals      = "EQUALS"
+	OperatorNotEquals   = "NOT_EQUALS"
+	OperatorIn          = "IN"
+	OperatorNotIn       = "NOT_IN"
+	OperatorContains    = "CONTAINS"
+	OperatorGreaterThan = "GREATER_THAN"
+	OperatorLessThan    = "LESS_THAN"
+	OperatorSplit       = "SPLIT"
+	OperatorCEL         = "CEL"
+	OperatorInSegment   = "IN_SEGMENT"
+)
+
+// ErrInvalid is wrapped by every validation error.
+var ErrInvalid = errors.New("invalid")
+
+// NewCondition creates a condition.
+func NewCondition(variable, operator string, values ...string) Condition {
+	return Condition{Variable: variable, Operator: operator, Values: values}
+}
+
+// Validate checks that the condition has the number of values its operator requires.
+// Unknown operators are accepted, so that families using operators added to the server
+// later still validate; they never match.
+func (c Condition) Validate() error {
+	switch c.Operator {
+	case OperatorEquals, OperatorNotEquals, OperatorContains, OperatorGreaterThan, OperatorLessThan:
+		if len(c.Values) != 1 {
+			return fmt.Errorf("%w condition: %s takes 1 value, got %d", ErrInvalid, c.Operator, len(c.Values))
+		}
+	case OperatorSplit:
+		if len(c.Values) != 1 {
+			return fmt.Errorf("%w condition: %s takes 1 value, got %d", ErrInvalid, c.Operator, len(c.Values))
+		}
+		if n, err := strconv.Atoi(c.Values[0]); err != nil || n < 0 || n > 100 {
+			return fmt.Errorf("%w condition: %s threshold %q is not a percentage", ErrInvalid, c.Operator, c.Values[0])
+		}
+	case OperatorCEL:
+		if len(c.Values) != 1 || c.Values[0] == "" {
+			return fmt.Errorf("%w condition: %s takes 1 expression", ErrInvalid, c.Operator)
+		}
+		return nil
+	case OperatorIn, OperatorNotIn, OperatorInSegment:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("%w condition: %s takes at least 1 value", ErrInvalid, c.Operator)
+		}
+	case "":
+		return fmt.Errorf("%w condition: missing operator", ErrInvalid)
+	}
+	if c.Variable == "" && c.Operator != OperatorInSegment {
+		return fmt.Errorf("%w condition: %s has no variable", ErrInvalid, c.Operator)
+	}
+	return nil
+}
+
+// NewRule creates a rule serving targetVersion when all conditions match.
+func NewRule(targetVersion string, conditions ...Condition) Rule {
+	return Rule{TargetVersion: targetVersion, Conditions: conditions}
+}
+
+// Validate checks that the rule has a target version and valid conditions. Whether the
+// target version exists is checked by FigFamily.Validate.
+func (r Rule) Validate() error {
+	if r.TargetVersion == "" {
+		return fmt.Errorf("%w rule: missing target version", ErrInvalid)
+	}
+	for i, c := range r.Conditions {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("condition %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// FamilyOption configures a FigFamily created by NewFigFamily.
+type FamilyOption func(*FigFamily)
+
+// WithFig adds a fig version.
+func WithFig(version string, payload []byte) FamilyOption {
+	return func(ff *FigFamily) {
+		ff.Figs = append(ff.Figs, Fig{Version: version, Payload: payload})
+	}
+}
+
+// WithRule adds a rule. Rules are evaluated in the order they are added.
+func WithRule(rule Rule) FamilyOption {
+	return func(ff *FigFamily) {
+		ff.Rules = append(ff.Rules, rule)
+	}
+}
+
+// WithDefaultVersion sets the version served when no rule matches.
+func WithDefaultVersion(version string) FamilyOption {
+	return func(ff *FigFamily) {
+		ff.DefaultVersion = &version
+	}
+}
+
+// WithPrerequisite requires the family key to evaluate to version before rules apply.
+func WithPrerequisite(key, version string) FamilyOption {
+	return func(ff *FigFamily) {
+		ff.Prerequisites = append(ff.Prerequisites, Prerequisite{Key: key, Version: version})
+	}
+}
+
+// NewFigFamily creates a fig family and validates it.
+func NewFigFamily(namespace, key string, opts ...FamilyOption) (*FigFamily, error) {
+	ff := &FigFamily{Definition: FigDefinition{Namespace: namespace, Key: key}}
+	for _, opt := range opts {
+		opt(ff)
+	}
+	if err := ff.Validate(); err != nil {
+		return nil, err
+	}
+	return ff, nil
+}
+
+// Validate checks the invariants of a fig family: it has a namespace and key, fig
+// versions are unique, and the default version and every rule's target version exist.
+// All problems found are joined.
+func (ff *FigFamily) Validate() error {
+	var errs []error
+	if ff.Definition.Namespace == "" {
+		errs = append(errs, fmt.Errorf("%w family: missing namespace", ErrInvalid))
+	}
+	if ff.Definition.Key == "" {
+		errs = append(errs, fmt.Errorf("%w family: missing key", ErrInvalid))
+	}
+
+	versions := make(map[string]bool, len(ff.Figs))
+	for _, fig := range ff.Figs {
+		if fig.Version == "" {
+			errs = append(errs, fmt.Errorf("%w family: fig without version", ErrInvalid))
+			continue
+		}
+		if versions[fig.Version] {
+			errs = append(errs, fmt.Errorf("%w family: duplicate version %s", ErrInvalid, fig.Version))
+		}
+		versions[fig.Version] = true
+	}
+
+	if ff.DefaultVersion != nil && !versions[*ff.DefaultVersion] {
+		errs = append(errs, fmt.Errorf("%w family: default version %s does not exist", ErrInvalid, *ff.DefaultVersion))
+	}
+	for i, rule := range ff.Rules {
+		if err := rule.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i+1, err))
+		} else if !versions[rule.TargetVersion] {
+			errs = append(errs, fmt.Errorf("rule %d: %w rule: target version %s does not exist", i+1, ErrInvalid, rule.TargetVersion))
+		}
+	}
+	for _, p := range ff.Prerequisites {
+		if p.Key == "" || p.Version == "" {
+			errs = append(errs, fmt.Errorf("%w family: prerequisite needs a key and version", ErrInvalid))
+		}
+	}
+	return errors.Join(errs...)
+}
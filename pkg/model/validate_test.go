@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCondition_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Condition
+		wantErr   bool
+	}{
+		{"equals", NewCondition("plan", OperatorEquals, "beta"), false},
+		{"equals without value", NewCondition("plan", OperatorEquals), true},
+		{"equals with two values", NewCondition("plan", OperatorEquals, "a", "b"), true},
+		{"in", NewCondition("country", OperatorIn, "US", "CA"), false},
+		{"in without values", NewCondition("country", OperatorIn), true},
+		{"split", NewCondition("userId", OperatorSplit, "50"), false},
+		{"split out of range", NewCondition("userId", OperatorSplit, "150"), true},
+		{"cel ignores variable", NewCondition("", OperatorCEL, "plan == 'beta'"), false},
+		{"segment", NewCondition("", OperatorInSegment, "beta-testers"), false},
+		{"missing variable", NewCondition("", OperatorEquals, "x"), true},
+		{"missing operator", NewCondition("plan", ""), true},
+		{"unknown operator", NewCondition("plan", "MATCHES_REGEX", "a", "b"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.condition.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalid) {
+				t.Errorf("Validate() error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestNewFigFamily(t *testing.T) {
+	ff, err := NewFigFamily("ns", "key",
+		WithFig("v1", nil),
+		WithFig("v2", nil),
+		WithRule(NewRule("v2", NewCondition("plan", OperatorEquals, "beta"))),
+		WithDefaultVersion("v1"),
+	)
+	if err != nil {
+		t.Fatalf("NewFigFamily() error = %v", err)
+	}
+	if len(ff.Figs) != 2 || len(ff.Rules) != 1 || *ff.DefaultVersion != "v1" {
+		t.Errorf("NewFigFamily() = %+v", ff)
+	}
+
+	tests := []struct {
+		name string
+		opts []FamilyOption
+	}{
+		{"missing default version", []FamilyOption{WithFig("v1", nil), WithDefaultVersion("v2")}},
+		{"missing target version", []FamilyOption{WithFig("v1", nil), WithRule(NewRule("v2"))}},
+		{"duplicate version", []FamilyOption{WithFig("v1", nil), WithFig("v1", nil)}},
+		{"invalid condition", []FamilyOption{WithFig("v1", nil), WithRule(NewRule("v1", NewCondition("plan", OperatorEquals)))}},
+		{"incomplete prerequisite", []FamilyOption{WithPrerequisite("other", "")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFigFamily("ns", "key", tt.opts...); !errors.Is(err, ErrInvalid) {
+				t.Errorf("NewFigFamily() error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+
+	if _, err := NewFigFamily("", "", WithFig("v1", nil)); err == nil {
+		t.Error("NewFigFamily() without namespace and key succeeded")
+	}
+}
@@ -0,0 +1,44 @@
+package source
+
+import "context"
+
+// KafkaRecord is a Kafka record, as returned by a KafkaConsumer.
+type KafkaRecord struct {
+	Value   []byte
+	Headers map[string][]byte
+	// Offset identifies the record to Commit; its meaning is up to the consumer.
+	Offset any
+}
+
+// KafkaConsumer is the subset of a Kafka consumer group reader the source needs. It is a
+// thin wrapper around, e.g., kafka-go's Reader.FetchMessage and CommitMessages.
+type KafkaConsumer interface {
+	Fetch(ctx context.Context) (KafkaRecord, error)
+	Commit(ctx context.Context, record KafkaRecord) error
+	Close() error
+}
+
+// NewKafkaSource creates an UpdateSource consuming update records from Kafka. Records
+// are committed once their update has been applied.
+func NewKafkaSource(consumer KafkaConsumer) UpdateSource {
+	return &messageSource{
+		receive: func(ctx context.Context) (*Message, error) {
+			record, err := consumer.Fetch(ctx)
+			if err != nil {
+				return nil, err
+			}
+			headers := make(map[string]string, len(record.Headers))
+			for k, v := range record.Headers {
+				headers[k] = string(v)
+			}
+			return &Message{
+				Data:    record.Value,
+				Headers: headers,
+				Ack: func(ctx context.Context) error {
+					return consumer.Commit(ctx, record)
+				},
+			}, nil
+		},
+		close: consumer.Close,
+	}
+}
@@ -0,0 +1,42 @@
+package source
+
+import "context"
+
+// NATSMessage is a NATS message, as returned by a NATSSubscription.
+type NATSMessage struct {
+	Data   []byte
+	Header map[string][]string
+	// Ack acknowledges the message on JetStream. It may be nil for core NATS.
+	Ack func() error
+}
+
+// NATSSubscription is the subset of a NATS subscription the source needs. It is a thin
+// wrapper around, e.g., nats.go's Subscription.NextMsgWithContext or a JetStream consumer.
+type NATSSubscription interface {
+	Next(ctx context.Context) (NATSMessage, error)
+	Unsubscribe() error
+}
+
+// NewNATSSource creates an UpdateSource receiving update messages from NATS.
+func NewNATSSource(sub NATSSubscription) UpdateSource {
+	return &messageSource{
+		receive: func(ctx context.Context) (*Message, error) {
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			headers := make(map[string]string, len(msg.Header))
+			for k, v := range msg.Header {
+				if len(v) > 0 {
+					headers[k] = v[0]
+				}
+			}
+			m := &Message{Data: msg.Data, Headers: headers}
+			if msg.Ack != nil {
+				m.Ack = func(context.Context) error { return msg.Ack() }
+			}
+			return m, nil
+		},
+		close: sub.Unsubscribe,
+	}
+}
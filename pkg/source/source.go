@@ -0,0 +1,104 @@
+// Package source feeds fig updates to the client from message buses, for environments
+// that fan out configuration changes instead of having every client poll the server.
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hamba/avro/v2/ocf"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// Message headers carried by update messages on every bus.
+const (
+	// NamespaceHeader is the namespace the update belongs to.
+	NamespaceHeader = "figchain-namespace"
+	// PrevCursorHeader is the cursor the update follows. The client uses it to detect
+	// gaps, e.g. from lost or reordered messages, and reconciles with the server.
+	PrevCursorHeader = "figchain-prev-cursor"
+)
+
+// ErrClosed is returned by UpdateSource.Next once the source is closed.
+var ErrClosed = errors.New("update source closed")
+
+// Update is a batch of changed families received from a bus.
+type Update struct {
+	Namespace string
+	// PrevCursor is the cursor this update follows, empty if unknown.
+	PrevCursor string
+	// Cursor is the server cursor after this update.
+	Cursor      string
+	FigFamilies []model.FigFamily
+	// Ack acknowledges the message once the update has been applied. It may be nil.
+	Ack func(ctx context.Context) error
+}
+
+// UpdateSource delivers updates to the client.
+type UpdateSource interface {
+	// Next blocks until the next update is available or ctx is done. It returns ErrClosed
+	// once the source is closed.
+	Next(ctx context.Context) (*Update, error)
+	Close() error
+}
+
+// Message is a bus message carrying an update. Data is an Avro OCF-encoded
+// UpdateFetchResponse, as returned by the server's update endpoint.
+type Message struct {
+	Data    []byte
+	Headers map[string]string
+	Ack     func(ctx context.Context) error
+}
+
+// Decode decodes the update carried by m.
+func (m *Message) Decode() (*Update, error) {
+	namespace := m.Headers[NamespaceHeader]
+	if namespace == "" {
+		return nil, fmt.Errorf("message has no %s header", NamespaceHeader)
+	}
+	dec, err := ocf.NewDecoder(bytes.NewReader(m.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCF decoder: %w", err)
+	}
+	var resp model.UpdateFetchResponse
+	if !dec.HasNext() {
+		return nil, fmt.Errorf("empty message")
+	}
+	if err := dec.Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return &Update{
+		Namespace:   namespace,
+		PrevCursor:  m.Headers[PrevCursorHeader],
+		Cursor:      resp.Cursor,
+		FigFamilies: resp.FigFamilies,
+		Ack:         m.Ack,
+	}, nil
+}
+
+// receiver returns the next message from a bus.
+type receiver func(ctx context.Context) (*Message, error)
+
+// messageSource is an UpdateSource decoding messages from a receiver.
+type messageSource struct {
+	receive receiver
+	close   func() error
+}
+
+func (s *messageSource) Next(ctx context.Context) (*Update, error) {
+	msg, err := s.receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Decode()
+}
+
+func (s *messageSource) Close() error {
+	if s.close == nil {
+		return nil
+	}
+	return s.close()
+}
@@ -0,0 +1,150 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func encodeUpdate(t *testing.T, resp *model.UpdateFetchResponse) []byte {
+	t.Helper()
+	scheme, _ := avro.Parse(model.Schema)
+	var schema avro.Schema = scheme
+	if union, ok := scheme.(*avro.UnionSchema); ok {
+		for _, s := range union.Types() {
+			if ns, ok := s.(avro.NamedSchema); ok && ns.Name() == "UpdateFetchResponse" {
+				schema = s
+			}
+		}
+	}
+	var buf bytes.Buffer
+	enc, err := ocf.NewEncoder(schema.String(), &buf)
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v", err)
+	}
+	if err := enc.Encode(resp); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	enc.Flush()
+	return buf.Bytes()
+}
+
+var testResp = &model.UpdateFetchResponse{
+	Cursor:      "c2",
+	FigFamilies: []model.FigFamily{{Definition: model.FigDefinition{Key: "k", Namespace: "ns"}}},
+}
+
+func checkUpdate(t *testing.T, u *Update, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if u.Namespace != "ns" || u.PrevCursor != "c1" || u.Cursor != "c2" || len(u.FigFamilies) != 1 {
+		t.Errorf("Next() = %+v", u)
+	}
+	if err := u.Ack(context.Background()); err != nil {
+		t.Errorf("Ack() error = %v", err)
+	}
+}
+
+type fakeKafka struct {
+	records   []KafkaRecord
+	committed []any
+}
+
+func (f *fakeKafka) Fetch(context.Context) (KafkaRecord, error) {
+	r := f.records[0]
+	f.records = f.records[1:]
+	return r, nil
+}
+
+func (f *fakeKafka) Commit(_ context.Context, r KafkaRecord) error {
+	f.committed = append(f.committed, r.Offset)
+	return nil
+}
+
+func (f *fakeKafka) Close() error { return nil }
+
+func TestKafkaSource(t *testing.T) {
+	consumer := &fakeKafka{records: []KafkaRecord{{
+		Value:   encodeUpdate(t, testResp),
+		Headers: map[string][]byte{NamespaceHeader: []byte("ns"), PrevCursorHeader: []byte("c1")},
+		Offset:  42,
+	}}}
+	u, err := NewKafkaSource(consumer).Next(context.Background())
+	checkUpdate(t, u, err)
+	if len(consumer.committed) != 1 || consumer.committed[0] != 42 {
+		t.Errorf("committed = %v, want [42]", consumer.committed)
+	}
+}
+
+type fakeNATS struct {
+	msg   NATSMessage
+	acked bool
+}
+
+func (f *fakeNATS) Next(context.Context) (NATSMessage, error) { return f.msg, nil }
+func (f *fakeNATS) Unsubscribe() error                        { return nil }
+
+func TestNATSSource(t *testing.T) {
+	sub := &fakeNATS{}
+	sub.msg = NATSMessage{
+		Data:   encodeUpdate(t, testResp),
+		Header: map[string][]string{NamespaceHeader: {"ns"}, PrevCursorHeader: {"c1"}},
+		Ack:    func() error { sub.acked = true; return nil },
+	}
+	u, err := NewNATSSource(sub).Next(context.Background())
+	checkUpdate(t, u, err)
+	if !sub.acked {
+		t.Error("expected message to be acked")
+	}
+}
+
+type fakeSQS struct {
+	batches [][]SQSMessage
+	deleted []string
+}
+
+func (f *fakeSQS) Receive(context.Context) ([]SQSMessage, error) {
+	b := f.batches[0]
+	f.batches = f.batches[1:]
+	return b, nil
+}
+
+func (f *fakeSQS) Delete(_ context.Context, receiptHandle string) error {
+	f.deleted = append(f.deleted, receiptHandle)
+	return nil
+}
+
+func TestSQSSource(t *testing.T) {
+	msg := func(handle string) SQSMessage {
+		return SQSMessage{
+			Body:          base64.StdEncoding.EncodeToString(encodeUpdate(t, testResp)),
+			ReceiptHandle: handle,
+			Attributes:    map[string]string{NamespaceHeader: "ns", PrevCursorHeader: "c1"},
+		}
+	}
+	// Empty receives are skipped; batches are delivered one message at a time
+	queue := &fakeSQS{batches: [][]SQSMessage{nil, {msg("a"), msg("b")}}}
+	src := NewSQSSource(queue)
+	for range 2 {
+		u, err := src.Next(context.Background())
+		checkUpdate(t, u, err)
+	}
+	if len(queue.deleted) != 2 || queue.deleted[0] != "a" || queue.deleted[1] != "b" {
+		t.Errorf("deleted = %v, want [a b]", queue.deleted)
+	}
+}
+
+func TestMessage_DecodeRequiresNamespace(t *testing.T) {
+	m := &Message{Data: encodeUpdate(t, testResp)}
+	if _, err := m.Decode(); err == nil {
+		t.Error("expected error for missing namespace header")
+	}
+}
@@ -0,0 +1,53 @@
+package source
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+)
+
+// SQSMessage is an SQS message, as returned by an SQSQueue.
+type SQSMessage struct {
+	// Body is the base64-encoded update, since SQS bodies must be text.
+	Body          string
+	ReceiptHandle string
+	// Attributes are the message attributes of type String.
+	Attributes map[string]string
+}
+
+// SQSQueue is the subset of an SQS client the source needs. It is a thin wrapper around
+// the AWS SDK's ReceiveMessage (with long polling) and DeleteMessage.
+type SQSQueue interface {
+	Receive(ctx context.Context) ([]SQSMessage, error)
+	Delete(ctx context.Context, receiptHandle string) error
+}
+
+// NewSQSSource creates an UpdateSource receiving update messages from SQS. Messages are
+// deleted once their update has been applied.
+func NewSQSSource(queue SQSQueue) UpdateSource {
+	var pending []SQSMessage
+	return &messageSource{
+		receive: func(ctx context.Context) (*Message, error) {
+			for len(pending) == 0 {
+				msgs, err := queue.Receive(ctx)
+				if err != nil {
+					return nil, err
+				}
+				pending = msgs
+			}
+			msg := pending[0]
+			pending = pending[1:]
+			data, err := base64.StdEncoding.DecodeString(msg.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode message body: %w", err)
+			}
+			return &Message{
+				Data:    data,
+				Headers: msg.Attributes,
+				Ack: func(ctx context.Context) error {
+					return queue.Delete(ctx, msg.ReceiptHandle)
+				},
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package store_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/store"
+	"github.com/figchain/go-client/pkg/store/storetest"
+)
+
+func TestMemoryStore_Conformance(t *testing.T) {
+	storetest.TestStore(t, func(*testing.T) store.Store {
+		return store.NewMemoryStore()
+	})
+}
+
+func TestLRUStore_Conformance(t *testing.T) {
+	storetest.TestStore(t, func(*testing.T) store.Store {
+		return store.NewLRUStore(1000)
+	})
+}
+
+func TestFileStore_Conformance(t *testing.T) {
+	storetest.TestStore(t, func(t *testing.T) store.Store {
+		s, err := store.NewFileStore(filepath.Join(t.TempDir(), "cache"), make([]byte, store.CacheKeySize))
+		if err != nil {
+			t.Fatalf("NewFileStore() error = %v", err)
+		}
+		return s
+	})
+}
+
+func TestRedisStore_Conformance(t *testing.T) {
+	storetest.TestStore(t, func(t *testing.T) store.Store {
+		s, err := store.NewRedisStore(store.NewFakeRedis(), "test")
+		if err != nil {
+			t.Fatalf("NewRedisStore() error = %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
@@ -0,0 +1,4 @@
+package store
+
+// NewFakeRedis exposes the fake RedisClient to the conformance tests.
+func NewFakeRedis() RedisClient { return newFakeRedis() }
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// ErrCacheIntegrity is returned when a local cache file was modified, truncated or
+// encrypted with another key.
+var ErrCacheIntegrity = errors.New("local cache failed integrity check")
+
+// cacheMagic prefixes local cache files and versions their format.
+var cacheMagic = []byte("FCC1")
+
+// CacheKeySize is the size of the keys local caches are encrypted with (AES-256).
+const CacheKeySize = 32
+
+// FileStore is a Store persisted to a local file, so that a client can start from its
+// last known state when neither the server nor the vault is reachable.
+//
+// The file is encrypted with AES-GCM, so payloads never reach the disk in plaintext, and
+// authenticated, so a modified file is rejected on load. Families are kept in memory and
+// the whole file is rewritten on every Put.
+type FileStore struct {
+	path  string
+	aead  cipher.AEAD
+	local *MemoryStore
+
+	mu      sync.Mutex // serializes writes
+	cursors map[string]string
+}
+
+// NewFileStore creates a FileStore persisted to path and encrypted with key, which must be
+// CacheKeySize bytes. Call Load to read the families saved by a previous process.
+func NewFileStore(path string, key []byte) (*FileStore, error) {
+	if len(key) != CacheKeySize {
+		return nil, fmt.Errorf("cache key must be %d bytes, got %d", CacheKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &FileStore{
+		path:    path,
+		aead:    aead,
+		local:   NewMemoryStore(),
+		cursors: make(map[string]string),
+	}, nil
+}
+
+// Load reads the families and cursors saved in the cache file. It returns an error
+// wrapping os.ErrNotExist if nothing was saved yet and ErrCacheIntegrity if the file
+// doesn't authenticate.
+func (s *FileStore) Load() ([]model.FigFamily, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local cache: %w", err)
+	}
+	nonceSize := s.aead.NonceSize()
+	if len(data) < len(cacheMagic)+nonceSize || !bytes.Equal(data[:len(cacheMagic)], cacheMagic) {
+		return nil, ErrCacheIntegrity
+	}
+	nonce := data[len(cacheMagic) : len(cacheMagic)+nonceSize]
+	plaintext, err := s.aead.Open(nil, nonce, data[len(cacheMagic)+nonceSize:], cacheMagic)
+	if err != nil {
+		return nil, ErrCacheIntegrity
+	}
+
+	snapshot, err := UnmarshalSnapshot(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode local cache: %w", err)
+	}
+	s.mu.Lock()
+	maps.Copy(s.cursors, snapshot.Cursors)
+	s.mu.Unlock()
+	return snapshot.Families, nil
+}
+
+// Cursor returns the cursor saved for a namespace.
+func (s *FileStore) Cursor(namespace string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cursor, ok := s.cursors[namespace]
+	return cursor, ok
+}
+
+// SetCursor sets the cursor the families of a namespace are current as of. It is saved
+// with the families on the next Put, so that a saved cursor always has its families.
+func (s *FileStore) SetCursor(namespace, cursor string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors[namespace] = cursor
+}
+
+// save writes the store to the cache file, replacing it atomically.
+func (s *FileStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plaintext, err := MarshalSnapshot(&Snapshot{Cursors: s.cursors, Families: s.local.GetAll()})
+	if err != nil {
+		return fmt.Errorf("failed to encode local cache: %w", err)
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	data := append(append(bytes.Clone(cacheMagic), nonce...), s.aead.Seal(nil, nonce, plaintext, cacheMagic)...)
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write local cache: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write local cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write local cache: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write local cache: %w", err)
+	}
+	return nil
+}
+
+// Put stores a family and saves the cache file.
+func (s *FileStore) Put(figFamily model.FigFamily) {
+	s.PutAll([]model.FigFamily{figFamily})
+}
+
+// PutAll stores families and saves the cache file once.
+func (s *FileStore) PutAll(figFamilies []model.FigFamily) {
+	s.local.PutAll(figFamilies)
+	if err := s.save(); err != nil {
+		logging.Printf("Failed to save local cache: %v", err)
+	}
+}
+
+// Delete removes families and saves the cache file.
+func (s *FileStore) Delete(namespace string, keys ...string) {
+	s.local.Delete(namespace, keys...)
+	if err := s.save(); err != nil {
+		logging.Printf("Failed to save local cache: %v", err)
+	}
+}
+
+// Get returns a family.
+func (s *FileStore) Get(namespace, key string) (*model.FigFamily, bool) {
+	return s.local.Get(namespace, key)
+}
+
+// GetAll returns a copy of every family.
+func (s *FileStore) GetAll() []model.FigFamily {
+	return s.local.GetAll()
+}
+
+// Range calls fn for each family of a namespace.
+func (s *FileStore) Range(namespace string, fn func(figFamily *model.FigFamily) bool) {
+	s.local.Range(namespace, fn)
+}
+
+// Len returns the number of families in a namespace.
+func (s *FileStore) Len(namespace string) int {
+	return s.local.Len(namespace)
+}
+
+// Revision returns the revision of a namespace.
+func (s *FileStore) Revision(namespace string) uint64 {
+	return s.local.Revision(namespace)
+}
+
+// ChangedSince returns the families put after rev.
+func (s *FileStore) ChangedSince(namespace string, rev uint64) ([]model.FigFamily, uint64) {
+	return s.local.ChangedSince(namespace, rev)
+}
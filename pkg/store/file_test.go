@@ -0,0 +1,77 @@
+package store
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	key := make([]byte, CacheKeySize)
+	rand.Read(key)
+	v := "v1"
+	family := model.FigFamily{
+		Definition:     model.FigDefinition{Key: "key1", Namespace: "ns1"},
+		Figs:           []model.Fig{{Version: "v1", Payload: []byte("secret-payload")}},
+		DefaultVersion: &v,
+	}
+
+	s, err := NewFileStore(path, key)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	if _, err := s.Load(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load() of a missing cache error = %v, want os.ErrNotExist", err)
+	}
+	s.SetCursor("ns1", "c1")
+	s.Put(family)
+	if ff, ok := s.Get("ns1", "key1"); !ok || ff.Definition.Key != "key1" {
+		t.Errorf("Get() = %v, %v", ff, ok)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cache file not written: %v", err)
+	}
+	if bytes.Contains(data, []byte("secret-payload")) {
+		t.Error("cache file contains the payload in plaintext")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	reopened, _ := NewFileStore(path, key)
+	families, err := reopened.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(families) != 1 || string(families[0].Figs[0].Payload) != "secret-payload" {
+		t.Errorf("Load() = %+v", families)
+	}
+	if cursor, ok := reopened.Cursor("ns1"); !ok || cursor != "c1" {
+		t.Errorf("Cursor() = %q, %v, want c1", cursor, ok)
+	}
+
+	otherKey := make([]byte, CacheKeySize)
+	rand.Read(otherKey)
+	wrongKey, _ := NewFileStore(path, otherKey)
+	if _, err := wrongKey.Load(); !errors.Is(err, ErrCacheIntegrity) {
+		t.Errorf("Load() with the wrong key error = %v, want ErrCacheIntegrity", err)
+	}
+
+	data[len(data)-1] ^= 1
+	os.WriteFile(path, data, 0600)
+	if _, err := reopened.Load(); !errors.Is(err, ErrCacheIntegrity) {
+		t.Errorf("Load() of a modified cache error = %v, want ErrCacheIntegrity", err)
+	}
+
+	if _, err := NewFileStore(path, key[:16]); err == nil {
+		t.Error("NewFileStore() accepted a short key")
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// HistoryEntry is a version of a fig family recorded by History.
+type HistoryEntry struct {
+	Family    model.FigFamily
+	AppliedAt time.Time
+}
+
+// History keeps the most recent versions of each fig family, so that a family can be
+// rolled back to its last known good version. It is safe for concurrent use.
+type History struct {
+	mu      sync.Mutex
+	depth   int
+	entries map[string][]HistoryEntry // newest first
+}
+
+// NewHistory creates a History keeping up to depth versions of each family, including
+// the current one.
+func NewHistory(depth int) *History {
+	return &History{
+		depth:   depth,
+		entries: make(map[string][]HistoryEntry),
+	}
+}
+
+func historyKey(namespace, key string) string {
+	return namespace + "\x00" + key
+}
+
+// Record records ff as the current version of its family.
+func (h *History) Record(ff model.FigFamily, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	k := historyKey(ff.Definition.Namespace, ff.Definition.Key)
+	entries := append([]HistoryEntry{{Family: ff, AppliedAt: at}}, h.entries[k]...)
+	if len(entries) > h.depth {
+		entries = entries[:h.depth]
+	}
+	h.entries[k] = entries
+}
+
+// Get returns the recorded versions of a family, newest first. The families are shared
+// with the History and must not be modified.
+func (h *History) Get(namespace, key string) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]HistoryEntry(nil), h.entries[historyKey(namespace, key)]...)
+}
+
+// Rollback drops the current version of a family and returns the previous one, which
+// becomes current. It returns false if there is no previous version.
+func (h *History) Rollback(namespace, key string) (model.FigFamily, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	k := historyKey(namespace, key)
+	entries := h.entries[k]
+	if len(entries) < 2 {
+		return model.FigFamily{}, false
+	}
+	h.entries[k] = entries[1:]
+	return entries[1].Family, true
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(3)
+	family := func(version string) model.FigFamily {
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "key1", Namespace: "ns1"},
+			DefaultVersion: &version,
+		}
+	}
+	start := time.Now()
+	for i, v := range []string{"v1", "v2", "v3", "v4"} {
+		h.Record(family(v), start.Add(time.Duration(i)*time.Minute))
+	}
+
+	entries := h.Get("ns1", "key1")
+	if len(entries) != 3 {
+		t.Fatalf("Get() returned %d entries, want 3", len(entries))
+	}
+	for i, want := range []string{"v4", "v3", "v2"} {
+		if got := *entries[i].Family.DefaultVersion; got != want {
+			t.Errorf("entry %d = %s, want %s", i, got, want)
+		}
+	}
+
+	ff, ok := h.Rollback("ns1", "key1")
+	if !ok || *ff.DefaultVersion != "v3" {
+		t.Fatalf("Rollback() = %v, %v, want v3", ff.DefaultVersion, ok)
+	}
+	if _, ok := h.Rollback("ns1", "key1"); !ok {
+		t.Fatal("second Rollback() returned false, want v2")
+	}
+	if _, ok := h.Rollback("ns1", "key1"); ok {
+		t.Error("Rollback() past the oldest version returned true")
+	}
+	if entries := h.Get("ns1", "missing"); len(entries) != 0 {
+		t.Errorf("Get(missing) = %v, want none", entries)
+	}
+}
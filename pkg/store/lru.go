@@ -0,0 +1,153 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// LRUStore is a bounded Store that holds at most a fixed number of families, evicting
+// the least recently used one when full. It is meant for namespaces too large to hold in
+// memory, with misses fetched on demand by the caller.
+//
+// Unlike MemoryStore, every Get updates recency and takes a lock.
+type LRUStore struct {
+	mu        sync.Mutex
+	capacity  int
+	order     *list.List // of *lruEntry, most recently used first
+	data      map[string]*list.Element
+	revisions map[string]uint64
+}
+
+type lruEntry struct {
+	key string
+	entry
+}
+
+// NewLRUStore creates a new LRUStore holding at most capacity families.
+func NewLRUStore(capacity int) *LRUStore {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUStore{
+		capacity:  capacity,
+		order:     list.New(),
+		data:      make(map[string]*list.Element),
+		revisions: make(map[string]uint64),
+	}
+}
+
+func (s *LRUStore) Put(figFamily model.FigFamily) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(figFamily)
+}
+
+func (s *LRUStore) PutAll(figFamilies []model.FigFamily) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ff := range figFamilies {
+		s.put(ff)
+	}
+}
+
+func (s *LRUStore) put(figFamily model.FigFamily) {
+	ns := figFamily.Definition.Namespace
+	s.revisions[ns]++
+	e := entry{family: figFamily, revision: s.revisions[ns]}
+	key := s.makeKey(ns, figFamily.Definition.Key)
+	if el, ok := s.data[key]; ok {
+		el.Value.(*lruEntry).entry = e
+		s.order.MoveToFront(el)
+		return
+	}
+	s.data[key] = s.order.PushFront(&lruEntry{key: key, entry: e})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.data, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (s *LRUStore) Delete(namespace string, keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		k := s.makeKey(namespace, key)
+		if el, ok := s.data[k]; ok {
+			s.order.Remove(el)
+			delete(s.data, k)
+		}
+	}
+}
+
+func (s *LRUStore) Get(namespace, key string) (*model.FigFamily, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.data[s.makeKey(namespace, key)]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(el)
+	ff := el.Value.(*lruEntry).family
+	return &ff, true
+}
+
+func (s *LRUStore) GetAll() []model.FigFamily {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make([]model.FigFamily, 0, s.order.Len())
+	for el := s.order.Front(); el != nil; el = el.Next() {
+		all = append(all, el.Value.(*lruEntry).family)
+	}
+	return all
+}
+
+// Range calls fn for each cached family of a namespace. It iterates a copy of the
+// namespace, so fn may call back into the store.
+func (s *LRUStore) Range(namespace string, fn func(figFamily *model.FigFamily) bool) {
+	families, _ := s.ChangedSince(namespace, 0)
+	for i := range families {
+		if !fn(&families[i]) {
+			return
+		}
+	}
+}
+
+func (s *LRUStore) Len(namespace string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for el := s.order.Front(); el != nil; el = el.Next() {
+		if el.Value.(*lruEntry).family.Definition.Namespace == namespace {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *LRUStore) Revision(namespace string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revisions[namespace]
+}
+
+// ChangedSince returns the cached families of a namespace put after rev. Evicted
+// families are not reported.
+func (s *LRUStore) ChangedSince(namespace string, rev uint64) ([]model.FigFamily, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changed []model.FigFamily
+	for el := s.order.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*lruEntry)
+		if e.family.Definition.Namespace == namespace && e.revision > rev {
+			changed = append(changed, e.family)
+		}
+	}
+	return changed, s.revisions[namespace]
+}
+
+func (s *LRUStore) makeKey(namespace, key string) string {
+	return namespace + ":" + key
+}
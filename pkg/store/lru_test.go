@@ -0,0 +1,52 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestLRUStore(t *testing.T) {
+	s := NewLRUStore(2)
+	family := func(key string) model.FigFamily {
+		return model.FigFamily{Definition: model.FigDefinition{Key: key, Namespace: "ns1"}}
+	}
+
+	s.Put(family("key1"))
+	s.Put(family("key2"))
+
+	// Touch key1 so key2 becomes the least recently used
+	if _, ok := s.Get("ns1", "key1"); !ok {
+		t.Fatal("Get(key1) returned false, want true")
+	}
+	s.Put(family("key3"))
+
+	if _, ok := s.Get("ns1", "key2"); ok {
+		t.Error("Get(key2) returned true, want evicted")
+	}
+	for _, key := range []string{"key1", "key3"} {
+		if _, ok := s.Get("ns1", key); !ok {
+			t.Errorf("Get(%s) returned false, want true", key)
+		}
+	}
+	if n := s.Len("ns1"); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+	if rev := s.Revision("ns1"); rev != 3 {
+		t.Errorf("Revision() = %d, want 3", rev)
+	}
+
+	changed, rev := s.ChangedSince("ns1", 2)
+	if len(changed) != 1 || changed[0].Definition.Key != "key3" || rev != 3 {
+		t.Errorf("ChangedSince(ns1, 2) = %v at rev %d, want [key3] at rev 3", changed, rev)
+	}
+
+	visited := 0
+	s.Range("ns1", func(*model.FigFamily) bool {
+		visited++
+		return true
+	})
+	if visited != 2 {
+		t.Errorf("Range() visited %d families, want 2", visited)
+	}
+}
@@ -0,0 +1,337 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+
+	"github.com/figchain/go-client/pkg/logging"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// RedisClient is the subset of a Redis client the RedisStore needs. It is a thin wrapper
+// around, e.g., go-redis. Missing keys and fields are reported with ok == false rather
+// than an error.
+type RedisClient interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte) error
+	HGet(ctx context.Context, key, field string) (value []byte, ok bool, err error)
+	HGetAll(ctx context.Context, key string) (map[string][]byte, error)
+	// Eval runs a Lua script with EVAL, returning its result as go-redis does: arrays as
+	// []any, bulk strings as string and integers as int64.
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe delivers messages published to channel until ctx is done.
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+// RedisStore is a Store shared through Redis, so that many short-lived processes can
+// share one bootstrap instead of each fetching from the server.
+//
+// Families are cached in a local MemoryStore. Get reads through to Redis on a miss, and
+// Put writes through and publishes an invalidation so that other processes reload the
+// family. Writes and reloads compare the families' UpdatedAt, so that a lagging process
+// can't roll the shared state back. Range, Len, Revision and ChangedSince reflect the
+// local cache; call Load to populate it with every family of a namespace.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+	id     string
+	local  *MemoryStore
+	mu     sync.Mutex // serializes updates of local
+	schema avro.Schema
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRedisStore creates a RedisStore keeping its data under prefix (e.g. "figchain:env-1")
+// and subscribes to invalidations from other processes. Call Close to unsubscribe.
+func NewRedisStore(client RedisClient, prefix string) (*RedisStore, error) {
+	schema, err := familySchema()
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	s := &RedisStore{
+		client: client,
+		prefix: prefix,
+		id:     hex.EncodeToString(id),
+		local:  NewMemoryStore(),
+		schema: schema,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs, err := client.Subscribe(ctx, s.channel())
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to subscribe to invalidations: %w", err)
+	}
+	s.cancel = cancel
+	s.wg.Add(1)
+	go s.invalidations(ctx, msgs)
+	return s, nil
+}
+
+// familySchema returns the Avro schema families are stored with.
+func familySchema() (avro.Schema, error) {
+	scheme, err := avro.Parse(model.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if union, ok := scheme.(*avro.UnionSchema); ok {
+		for _, s := range union.Types() {
+			if ns, ok := s.(avro.NamedSchema); ok && ns.Name() == "FigFamily" {
+				return s, nil
+			}
+		}
+	}
+	return scheme, nil
+}
+
+// Close stops listening for invalidations.
+func (s *RedisStore) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *RedisStore) familiesKey(namespace string) string {
+	return s.prefix + ":families:" + namespace
+}
+
+// updatedKey is the hash holding the UpdatedAt of the families of familiesKey, in Unix
+// milliseconds, for putIfNewerScript to compare.
+func (s *RedisStore) updatedKey(namespace string) string {
+	return s.prefix + ":updated:" + namespace
+}
+
+func (s *RedisStore) cursorKey(namespace string) string {
+	return s.prefix + ":cursor:" + namespace
+}
+
+func (s *RedisStore) channel() string {
+	return s.prefix + ":invalidate"
+}
+
+// invalidations reloads families put by other processes.
+func (s *RedisStore) invalidations(ctx context.Context, msgs <-chan []byte) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			// Messages are "<publisher id>\n<namespace>\n<key>"
+			parts := strings.SplitN(string(msg), "\n", 3)
+			if len(parts) != 3 || parts[0] == s.id {
+				continue
+			}
+			s.reload(ctx, parts[1], parts[2])
+		}
+	}
+}
+
+// reload caches the family at key from Redis, unless the cached family is newer.
+func (s *RedisStore) reload(ctx context.Context, namespace, key string) {
+	ff, err := s.fetch(ctx, namespace, key)
+	if err != nil {
+		logging.Printf("Failed to reload %s/%s from Redis: %v", namespace, key, err)
+		return
+	}
+	if ff == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.local.Get(namespace, key); ok && current.Definition.UpdatedAt.After(ff.Definition.UpdatedAt) {
+		return
+	}
+	s.local.Put(*ff)
+}
+
+// fetch reads a family from Redis, or returns nil if it isn't there.
+func (s *RedisStore) fetch(ctx context.Context, namespace, key string) (*model.FigFamily, error) {
+	data, ok, err := s.client.HGet(ctx, s.familiesKey(namespace), key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var ff model.FigFamily
+	if err := avro.Unmarshal(s.schema, data, &ff); err != nil {
+		return nil, fmt.Errorf("failed to decode family: %w", err)
+	}
+	return &ff, nil
+}
+
+// Load reads every family of a namespace from Redis into the local cache and returns them.
+func (s *RedisStore) Load(ctx context.Context, namespace string) ([]model.FigFamily, error) {
+	values, err := s.client.HGetAll(ctx, s.familiesKey(namespace))
+	if err != nil {
+		return nil, err
+	}
+	families := make([]model.FigFamily, 0, len(values))
+	for key, data := range values {
+		var ff model.FigFamily
+		if err := avro.Unmarshal(s.schema, data, &ff); err != nil {
+			return nil, fmt.Errorf("failed to decode family %s: %w", key, err)
+		}
+		families = append(families, ff)
+	}
+	s.mu.Lock()
+	s.local.PutAll(families)
+	s.mu.Unlock()
+	return families, nil
+}
+
+// Cursor returns the cursor saved for a namespace by SaveCursor.
+func (s *RedisStore) Cursor(ctx context.Context, namespace string) (string, bool, error) {
+	data, ok, err := s.client.Get(ctx, s.cursorKey(namespace))
+	return string(data), ok, err
+}
+
+// SaveCursor saves the cursor the shared families of a namespace are current as of. The
+// families must be put first, so that a saved cursor always has its families available.
+// A lagging process may save an older cursor; processes bootstrapping from it fetch the
+// updates since, whose writes are ignored where the shared families are newer.
+func (s *RedisStore) SaveCursor(ctx context.Context, namespace, cursor string) error {
+	return s.client.Set(ctx, s.cursorKey(namespace), []byte(cursor))
+}
+
+// Put stores a family locally and in Redis, unless it is unchanged.
+func (s *RedisStore) Put(figFamily model.FigFamily) {
+	s.PutAll([]model.FigFamily{figFamily})
+}
+
+// putIfNewerScript sets families in the hash KEYS[1] unless the one there was updated
+// after them, as recorded in the hash KEYS[2]. ARGV holds key, UpdatedAt and encoded
+// family triples. It returns the keys that were set.
+const putIfNewerScript = `
+local set = {}
+for i = 1, #ARGV, 3 do
+	local current = redis.call('HGET', KEYS[2], ARGV[i])
+	if not current or tonumber(current) <= tonumber(ARGV[i + 1]) then
+		redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 2])
+		redis.call('HSET', KEYS[2], ARGV[i], ARGV[i + 1])
+		set[#set + 1] = ARGV[i]
+	end
+end
+return set
+`
+
+// PutAll stores families locally and in Redis. Families identical to the cached ones are
+// skipped, so that processes sharing a bootstrap don't rewrite it. Families Redis has a
+// newer version of aren't written; the newer version is cached instead.
+func (s *RedisStore) PutAll(figFamilies []model.FigFamily) {
+	ctx := context.Background()
+	changed := make(map[string][]any)
+	var families []model.FigFamily
+	s.mu.Lock()
+	for _, ff := range figFamilies {
+		ns, key := ff.Definition.Namespace, ff.Definition.Key
+		if current, ok := s.local.Get(ns, key); ok && reflect.DeepEqual(*current, ff) {
+			continue
+		}
+		data, err := avro.Marshal(s.schema, ff)
+		if err != nil {
+			logging.Printf("Failed to encode %s/%s for Redis: %v", ns, key, err)
+			continue
+		}
+		updatedAt := strconv.FormatInt(ff.Definition.UpdatedAt.UnixMilli(), 10)
+		changed[ns] = append(changed[ns], key, updatedAt, data)
+		families = append(families, ff)
+	}
+	s.local.PutAll(families)
+	s.mu.Unlock()
+
+	for ns, args := range changed {
+		result, err := s.client.Eval(ctx, putIfNewerScript, []string{s.familiesKey(ns), s.updatedKey(ns)}, args...)
+		if err != nil {
+			logging.Printf("Failed to write %d families of %s to Redis: %v", len(args)/3, ns, err)
+			continue
+		}
+		set := make(map[string]bool)
+		if keys, ok := result.([]any); ok {
+			for _, key := range keys {
+				if key, ok := key.(string); ok {
+					set[key] = true
+				}
+			}
+		}
+		for i := 0; i < len(args); i += 3 {
+			key := args[i].(string)
+			if !set[key] {
+				// Another process put a newer version
+				s.reload(ctx, ns, key)
+				continue
+			}
+			msg := []byte(s.id + "\n" + ns + "\n" + key)
+			if err := s.client.Publish(ctx, s.channel(), msg); err != nil {
+				logging.Printf("Failed to publish invalidation for %s/%s: %v", ns, key, err)
+			}
+		}
+	}
+}
+
+// Delete removes families from the local cache. They are left in Redis, where other
+// processes may still read them.
+func (s *RedisStore) Delete(namespace string, keys ...string) {
+	s.local.Delete(namespace, keys...)
+}
+
+// Get returns a family from the local cache, reading through to Redis on a miss.
+func (s *RedisStore) Get(namespace, key string) (*model.FigFamily, bool) {
+	if ff, ok := s.local.Get(namespace, key); ok {
+		return ff, true
+	}
+	ff, err := s.fetch(context.Background(), namespace, key)
+	if err != nil {
+		logging.Printf("Failed to read %s/%s from Redis: %v", namespace, key, err)
+	}
+	if ff == nil {
+		return nil, false
+	}
+	// Cache the family unless it was put while it was read
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.local.Get(namespace, key); ok {
+		return current, true
+	}
+	s.local.Put(*ff)
+	return ff, true
+}
+
+// GetAll returns a copy of every locally cached family.
+func (s *RedisStore) GetAll() []model.FigFamily {
+	return s.local.GetAll()
+}
+
+// Range calls fn for each locally cached family of a namespace.
+func (s *RedisStore) Range(namespace string, fn func(figFamily *model.FigFamily) bool) {
+	s.local.Range(namespace, fn)
+}
+
+// Len returns the number of locally cached families in a namespace.
+func (s *RedisStore) Len(namespace string) int {
+	return s.local.Len(namespace)
+}
+
+// Revision returns the local revision of a namespace.
+func (s *RedisStore) Revision(namespace string) uint64 {
+	return s.local.Revision(namespace)
+}
+
+// ChangedSince returns the locally cached families put after rev.
+func (s *RedisStore) ChangedSince(namespace string, rev uint64) ([]model.FigFamily, uint64) {
+	return s.local.ChangedSince(namespace, rev)
+}
@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// fakeRedis is an in-memory RedisClient shared by several stores.
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string][]byte
+	hashes  map[string]map[string][]byte
+	subs    map[string][]chan []byte
+	hsets   int
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		strings: make(map[string][]byte),
+		hashes:  make(map[string]map[string][]byte),
+		subs:    make(map[string][]chan []byte),
+	}
+}
+
+func (f *fakeRedis) Get(_ context.Context, key string) ([]byte, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.strings[key]
+	return v, ok, nil
+}
+
+func (f *fakeRedis) Set(_ context.Context, key string, value []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.strings[key] = value
+	return nil
+}
+
+func (f *fakeRedis) HGet(_ context.Context, key, field string) ([]byte, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.hashes[key][field]
+	return v, ok, nil
+}
+
+func (f *fakeRedis) HGetAll(_ context.Context, key string) (map[string][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return maps.Clone(f.hashes[key]), nil
+}
+
+// Eval emulates putIfNewerScript, the only script the store runs.
+func (f *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	if script != putIfNewerScript {
+		return nil, fmt.Errorf("unknown script")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hsets++
+	for _, key := range keys {
+		if f.hashes[key] == nil {
+			f.hashes[key] = make(map[string][]byte)
+		}
+	}
+	families, updated := f.hashes[keys[0]], f.hashes[keys[1]]
+	var set []any
+	for i := 0; i < len(args); i += 3 {
+		key, updatedAt := args[i].(string), args[i+1].(string)
+		if current, ok := updated[key]; ok {
+			c, _ := strconv.ParseInt(string(current), 10, 64)
+			n, _ := strconv.ParseInt(updatedAt, 10, 64)
+			if c > n {
+				continue
+			}
+		}
+		families[key] = args[i+2].([]byte)
+		updated[key] = []byte(updatedAt)
+		set = append(set, key)
+	}
+	return set, nil
+}
+
+func (f *fakeRedis) Publish(_ context.Context, channel string, message []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.subs[channel] {
+		ch <- message
+	}
+	return nil
+}
+
+func (f *fakeRedis) Subscribe(_ context.Context, channel string) (<-chan []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan []byte, 16)
+	f.subs[channel] = append(f.subs[channel], ch)
+	return ch, nil
+}
+
+func TestRedisStore(t *testing.T) {
+	redis := newFakeRedis()
+	family := func(key, version string) model.FigFamily {
+		v := version
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: key, Namespace: "ns1"},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("payload")}},
+			DefaultVersion: &v,
+		}
+	}
+
+	a, err := NewRedisStore(redis, "test")
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	defer a.Close()
+	b, err := NewRedisStore(redis, "test")
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	defer b.Close()
+
+	a.PutAll([]model.FigFamily{family("key1", "v1"), family("key2", "v1")})
+	if err := a.SaveCursor(context.Background(), "ns1", "c1"); err != nil {
+		t.Fatalf("SaveCursor() error = %v", err)
+	}
+
+	// b reads through to Redis
+	if ff, ok := b.Get("ns1", "key1"); !ok || *ff.DefaultVersion != "v1" {
+		t.Fatalf("Get(key1) = %v, %v, want v1", ff, ok)
+	}
+	if cursor, ok, _ := b.Cursor(context.Background(), "ns1"); !ok || cursor != "c1" {
+		t.Errorf("Cursor() = %q, %v, want c1", cursor, ok)
+	}
+	families, err := b.Load(context.Background(), "ns1")
+	if err != nil || len(families) != 2 || b.Len("ns1") != 2 {
+		t.Errorf("Load() = %d families, %v; Len() = %d, want 2", len(families), err, b.Len("ns1"))
+	}
+
+	// Unchanged families aren't rewritten
+	hsets := redis.hsets
+	b.PutAll(families)
+	if redis.hsets != hsets {
+		t.Errorf("PutAll of unchanged families wrote to Redis")
+	}
+
+	// An update from a invalidates b's cached copy
+	a.Put(family("key1", "v2"))
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if ff, _ := b.Get("ns1", "key1"); *ff.DefaultVersion == "v2" {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ff, _ := b.Get("ns1", "key1"); *ff.DefaultVersion != "v2" {
+		t.Errorf("Get(key1) after invalidation = %s, want v2", *ff.DefaultVersion)
+	}
+
+	if _, ok := b.Get("ns1", "missing"); ok {
+		t.Error("Get(missing) returned true, want false")
+	}
+}
+
+func TestRedisStore_StaleWrites(t *testing.T) {
+	redis := newFakeRedis()
+	now := time.Now().Truncate(time.Millisecond)
+	family := func(version string, updatedAt time.Time) model.FigFamily {
+		v := version
+		return model.FigFamily{
+			Definition:     model.FigDefinition{Key: "key1", Namespace: "ns1", UpdatedAt: updatedAt},
+			Figs:           []model.Fig{{Version: version, Payload: []byte("payload")}},
+			DefaultVersion: &v,
+		}
+	}
+
+	a, err := NewRedisStore(redis, "test")
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	defer a.Close()
+	b, err := NewRedisStore(redis, "test")
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	defer b.Close()
+
+	// A lagging process doesn't roll the shared family back, and caches the newer one
+	a.Put(family("v2", now))
+	b.Put(family("v1", now.Add(-time.Minute)))
+	if ff, _ := b.Get("ns1", "key1"); *ff.DefaultVersion != "v2" {
+		t.Errorf("Get() after a stale Put = %s, want v2", *ff.DefaultVersion)
+	}
+	c, err := NewRedisStore(redis, "test")
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	defer c.Close()
+	if ff, _ := c.Get("ns1", "key1"); *ff.DefaultVersion != "v2" {
+		t.Errorf("Get() from Redis after a stale Put = %s, want v2", *ff.DefaultVersion)
+	}
+
+	// Invalidations don't replace newer cached families
+	a.local.Put(family("v3", now.Add(time.Minute)))
+	a.reload(context.Background(), "ns1", "key1")
+	if ff, _ := a.Get("ns1", "key1"); *ff.DefaultVersion != "v3" {
+		t.Errorf("Get() after a stale invalidation = %s, want v3", *ff.DefaultVersion)
+	}
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// Snapshot is a copy of a client's families together with the cursors of their
+// namespaces, from which a client can resume without bootstrapping.
+type Snapshot struct {
+	Cursors  map[string]string `avro:"cursors"`
+	Families []model.FigFamily `avro:"families"`
+}
+
+var snapshotSchema = sync.OnceValues(func() (avro.Schema, error) {
+	families, err := familySchema()
+	if err != nil {
+		return nil, err
+	}
+	cursors, err := avro.NewField("cursors", avro.NewMapSchema(avro.NewPrimitiveSchema(avro.String, nil)))
+	if err != nil {
+		return nil, err
+	}
+	items, err := avro.NewField("families", avro.NewArraySchema(families))
+	if err != nil {
+		return nil, err
+	}
+	schema, err := avro.NewRecordSchema("Snapshot", "io.figchain.client", []*avro.Field{cursors, items})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot schema: %w", err)
+	}
+	return schema, nil
+})
+
+// MarshalSnapshot encodes a snapshot with Avro.
+func MarshalSnapshot(s *Snapshot) ([]byte, error) {
+	schema, err := snapshotSchema()
+	if err != nil {
+		return nil, err
+	}
+	return avro.Marshal(schema, s)
+}
+
+// UnmarshalSnapshot decodes a snapshot encoded by MarshalSnapshot.
+func UnmarshalSnapshot(data []byte) (*Snapshot, error) {
+	schema, err := snapshotSchema()
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := avro.Unmarshal(schema, data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
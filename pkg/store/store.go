@@ -1,7 +1,10 @@
 package store
 
 import (
+	"iter"
+	"maps"
 	"sync"
+	"sync/atomic"
 
 	"github.com/figchain/go-client/pkg/model"
 )
@@ -9,51 +12,227 @@ import (
 // Store defines the interface for storing FigFamilies.
 type Store interface {
 	Put(figFamily model.FigFamily)
+	// PutAll puts every family, as if by calling Put for each in order.
+	PutAll(figFamilies []model.FigFamily)
 	Get(namespace, key string) (*model.FigFamily, bool)
+	// GetAll returns a copy of every family in the store. Prefer Range, or the All and
+	// Keys iterators, for large stores.
 	GetAll() []model.FigFamily
+	// Range calls fn for each family of a namespace, in no particular order, until fn
+	// returns false. It iterates a consistent snapshot without copying it.
+	Range(namespace string, fn func(figFamily *model.FigFamily) bool)
+	// Len returns the number of families in a namespace.
+	Len(namespace string) int
+	// Revision returns the current revision of a namespace. Revisions start at 0 and
+	// increase by one for every Put into that namespace.
+	Revision(namespace string) uint64
+	// ChangedSince returns the families of a namespace that were put after rev, together
+	// with the namespace revision they were read at.
+	ChangedSince(namespace string, rev uint64) ([]model.FigFamily, uint64)
 }
 
-// MemoryStore is an in-memory implementation of the Store interface.
+// Deleter is implemented by stores that families can be removed from.
+type Deleter interface {
+	// Delete removes the families with the given keys from a namespace. It doesn't change
+	// the namespace's revision.
+	Delete(namespace string, keys ...string)
+}
+
+// All returns an iterator over the families of a namespace in s, built on Range. Like
+// Range, it doesn't copy the store, and the families must not be modified.
+func All(s Store, namespace string) iter.Seq[*model.FigFamily] {
+	return func(yield func(*model.FigFamily) bool) {
+		s.Range(namespace, yield)
+	}
+}
+
+// Keys returns an iterator over the keys of the families of a namespace in s.
+func Keys(s Store, namespace string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		s.Range(namespace, func(ff *model.FigFamily) bool {
+			return yield(ff.Definition.Key)
+		})
+	}
+}
+
+type entry struct {
+	family   model.FigFamily
+	revision uint64
+}
+
+// namespaceData is an immutable snapshot of one namespace.
+type namespaceData struct {
+	families map[string]*entry
+	revision uint64
+}
+
+// partition holds one namespace. Writers to different partitions never contend.
+type partition struct {
+	mu       sync.Mutex // serializes writers
+	snapshot atomic.Pointer[namespaceData]
+}
+
+// MemoryStore is an in-memory implementation of the Store interface, partitioned by
+// namespace.
+//
+// Reads are lock-free: they load an immutable snapshot of the namespace through an
+// atomic pointer, so Get doesn't allocate or contend with other readers. Writes copy the
+// affected namespace and publish a new snapshot. Families returned by Get and passed to
+// Range are shared with the store and must not be modified.
 type MemoryStore struct {
-	mu   sync.RWMutex
-	data map[string]model.FigFamily
+	mu         sync.Mutex // serializes partition creation
+	partitions atomic.Pointer[map[string]*partition]
 }
 
 // NewMemoryStore creates a new MemoryStore.
 func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{
-		data: make(map[string]model.FigFamily),
+	s := &MemoryStore{}
+	s.partitions.Store(&map[string]*partition{})
+	return s
+}
+
+// load returns the current snapshot of namespace, or nil if it has no families.
+func (s *MemoryStore) load(namespace string) *namespaceData {
+	p, ok := (*s.partitions.Load())[namespace]
+	if !ok {
+		return nil
 	}
+	return p.snapshot.Load()
 }
 
-func (s *MemoryStore) Put(figFamily model.FigFamily) {
+// partition returns the partition for namespace, creating it if needed.
+func (s *MemoryStore) partition(namespace string) *partition {
+	if p, ok := (*s.partitions.Load())[namespace]; ok {
+		return p
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	key := s.makeKey(figFamily.Definition.Namespace, figFamily.Definition.Key)
-	s.data[key] = figFamily
+	current := *s.partitions.Load()
+	if p, ok := current[namespace]; ok {
+		return p
+	}
+	p := &partition{}
+	p.snapshot.Store(&namespaceData{families: map[string]*entry{}})
+	next := maps.Clone(current)
+	next[namespace] = p
+	s.partitions.Store(&next)
+	return p
+}
+
+func (s *MemoryStore) Put(figFamily model.FigFamily) {
+	s.PutAll([]model.FigFamily{figFamily})
+}
+
+func (s *MemoryStore) PutAll(figFamilies []model.FigFamily) {
+	byNamespace := make(map[string][]model.FigFamily)
+	var order []string
+	for _, ff := range figFamilies {
+		ns := ff.Definition.Namespace
+		if _, ok := byNamespace[ns]; !ok {
+			order = append(order, ns)
+		}
+		byNamespace[ns] = append(byNamespace[ns], ff)
+	}
+	for _, ns := range order {
+		s.partition(ns).putAll(byNamespace[ns])
+	}
+}
+
+// putAll copies the partition's snapshot once and publishes it with all families added.
+func (p *partition) putAll(figFamilies []model.FigFamily) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := p.snapshot.Load()
+	next := &namespaceData{
+		families: make(map[string]*entry, len(current.families)+len(figFamilies)),
+		revision: current.revision,
+	}
+	maps.Copy(next.families, current.families)
+	for _, ff := range figFamilies {
+		next.revision++
+		next.families[ff.Definition.Key] = &entry{family: ff, revision: next.revision}
+	}
+	p.snapshot.Store(next)
+}
+
+func (s *MemoryStore) Delete(namespace string, keys ...string) {
+	if s.load(namespace) == nil {
+		return
+	}
+	s.partition(namespace).delete(keys)
+}
+
+// delete publishes a copy of the partition's snapshot without keys.
+func (p *partition) delete(keys []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := p.snapshot.Load()
+	next := &namespaceData{families: maps.Clone(current.families), revision: current.revision}
+	for _, key := range keys {
+		delete(next.families, key)
+	}
+	p.snapshot.Store(next)
 }
 
 func (s *MemoryStore) Get(namespace, key string) (*model.FigFamily, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	k := s.makeKey(namespace, key)
-	val, ok := s.data[k]
+	nsData := s.load(namespace)
+	if nsData == nil {
+		return nil, false
+	}
+	e, ok := nsData.families[key]
 	if !ok {
 		return nil, false
 	}
-	return &val, true
+	return &e.family, true
 }
 
 func (s *MemoryStore) GetAll() []model.FigFamily {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
 	var all []model.FigFamily
-	for _, v := range s.data {
-		all = append(all, v)
+	for _, p := range *s.partitions.Load() {
+		for _, e := range p.snapshot.Load().families {
+			all = append(all, e.family)
+		}
 	}
 	return all
 }
 
-func (s *MemoryStore) makeKey(namespace, key string) string {
-	return namespace + ":" + key
+func (s *MemoryStore) Range(namespace string, fn func(figFamily *model.FigFamily) bool) {
+	nsData := s.load(namespace)
+	if nsData == nil {
+		return
+	}
+	for _, e := range nsData.families {
+		if !fn(&e.family) {
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) Len(namespace string) int {
+	nsData := s.load(namespace)
+	if nsData == nil {
+		return 0
+	}
+	return len(nsData.families)
+}
+
+func (s *MemoryStore) Revision(namespace string) uint64 {
+	if nsData := s.load(namespace); nsData != nil {
+		return nsData.revision
+	}
+	return 0
+}
+
+func (s *MemoryStore) ChangedSince(namespace string, rev uint64) ([]model.FigFamily, uint64) {
+	nsData := s.load(namespace)
+	if nsData == nil {
+		return nil, 0
+	}
+	var changed []model.FigFamily
+	for _, e := range nsData.families {
+		if e.revision > rev {
+			changed = append(changed, e.family)
+		}
+	}
+	return changed, nsData.revision
 }
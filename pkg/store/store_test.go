@@ -1,7 +1,9 @@
 package store
 
 import (
+	"fmt"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/figchain/go-client/pkg/model"
@@ -44,3 +46,204 @@ func TestMemoryStore(t *testing.T) {
 		t.Errorf("GetAll()[0] = %v, want %v", all[0], figFamily)
 	}
 }
+
+func TestMemoryStore_ChangedSince(t *testing.T) {
+	s := NewMemoryStore()
+
+	if rev := s.Revision("ns1"); rev != 0 {
+		t.Errorf("Revision() = %d, want 0", rev)
+	}
+
+	s.Put(model.FigFamily{Definition: model.FigDefinition{Key: "key1", Namespace: "ns1"}})
+	s.Put(model.FigFamily{Definition: model.FigDefinition{Key: "key2", Namespace: "ns1"}})
+	s.Put(model.FigFamily{Definition: model.FigDefinition{Key: "key1", Namespace: "ns2"}})
+
+	if rev := s.Revision("ns1"); rev != 2 {
+		t.Errorf("Revision(ns1) = %d, want 2", rev)
+	}
+	if rev := s.Revision("ns2"); rev != 1 {
+		t.Errorf("Revision(ns2) = %d, want 1", rev)
+	}
+
+	changed, rev := s.ChangedSince("ns1", 0)
+	if len(changed) != 2 || rev != 2 {
+		t.Errorf("ChangedSince(ns1, 0) returned %d items at rev %d, want 2 at rev 2", len(changed), rev)
+	}
+
+	changed, rev = s.ChangedSince("ns1", 1)
+	if len(changed) != 1 || changed[0].Definition.Key != "key2" {
+		t.Errorf("ChangedSince(ns1, 1) = %v, want [key2]", changed)
+	}
+
+	// Updating key1 moves it past the previous revision
+	s.Put(model.FigFamily{Definition: model.FigDefinition{Key: "key1", Namespace: "ns1"}})
+	changed, rev = s.ChangedSince("ns1", rev)
+	if len(changed) != 1 || changed[0].Definition.Key != "key1" || rev != 3 {
+		t.Errorf("ChangedSince(ns1, 2) = %v at rev %d, want [key1] at rev 3", changed, rev)
+	}
+
+	changed, _ = s.ChangedSince("ns1", rev)
+	if len(changed) != 0 {
+		t.Errorf("ChangedSince(ns1, %d) returned %d items, want 0", rev, len(changed))
+	}
+}
+
+func newBenchmarkStore(n int) *MemoryStore {
+	s := NewMemoryStore()
+	for i := 0; i < n; i++ {
+		s.Put(model.FigFamily{Definition: model.FigDefinition{Key: fmt.Sprintf("key%d", i), Namespace: "ns1"}})
+	}
+	return s
+}
+
+func BenchmarkMemoryStore_Get(b *testing.B) {
+	s := newBenchmarkStore(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := s.Get("ns1", "key500"); !ok {
+			b.Fatal("Get() returned false")
+		}
+	}
+}
+
+func BenchmarkMemoryStore_GetParallel(b *testing.B) {
+	s := newBenchmarkStore(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, ok := s.Get("ns1", "key500"); !ok {
+				b.Fatal("Get() returned false")
+			}
+		}
+	})
+}
+
+func BenchmarkMemoryStore_GetWhileWriting(b *testing.B) {
+	s := newBenchmarkStore(1000)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				s.Put(model.FigFamily{Definition: model.FigDefinition{Key: fmt.Sprintf("key%d", i%1000), Namespace: "ns1"}})
+			}
+		}
+	}()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, ok := s.Get("ns1", "key500"); !ok {
+				b.Fatal("Get() returned false")
+			}
+		}
+	})
+}
+
+func TestMemoryStore_GetDoesNotAllocate(t *testing.T) {
+	s := newBenchmarkStore(10)
+	allocs := testing.AllocsPerRun(100, func() {
+		s.Get("ns1", "key5")
+	})
+	if allocs != 0 {
+		t.Errorf("Get() allocated %v times per call, want 0", allocs)
+	}
+}
+
+func TestMemoryStore_RangeAndLen(t *testing.T) {
+	s := NewMemoryStore()
+	s.PutAll([]model.FigFamily{
+		{Definition: model.FigDefinition{Key: "key1", Namespace: "ns1"}},
+		{Definition: model.FigDefinition{Key: "key2", Namespace: "ns1"}},
+		{Definition: model.FigDefinition{Key: "key1", Namespace: "ns2"}},
+	})
+
+	if n := s.Len("ns1"); n != 2 {
+		t.Errorf("Len(ns1) = %d, want 2", n)
+	}
+	if n := s.Len("missing"); n != 0 {
+		t.Errorf("Len(missing) = %d, want 0", n)
+	}
+
+	keys := map[string]bool{}
+	s.Range("ns1", func(ff *model.FigFamily) bool {
+		keys[ff.Definition.Key] = true
+		return true
+	})
+	if len(keys) != 2 || !keys["key1"] || !keys["key2"] {
+		t.Errorf("Range(ns1) visited %v, want key1 and key2", keys)
+	}
+
+	visited := 0
+	s.Range("ns1", func(*model.FigFamily) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Range() visited %d families after returning false, want 1", visited)
+	}
+
+	// Writes to one namespace don't affect another's revision
+	if rev := s.Revision("ns2"); rev != 1 {
+		t.Errorf("Revision(ns2) = %d, want 1", rev)
+	}
+}
+
+func TestMemoryStore_Delete(t *testing.T) {
+	s := NewMemoryStore()
+	s.PutAll([]model.FigFamily{
+		{Definition: model.FigDefinition{Key: "key1", Namespace: "ns1"}},
+		{Definition: model.FigDefinition{Key: "key2", Namespace: "ns1"}},
+	})
+	rev := s.Revision("ns1")
+
+	s.Delete("ns1", "key1", "missing")
+	s.Delete("missing", "key1")
+
+	if _, ok := s.Get("ns1", "key1"); ok {
+		t.Error("Get(key1) found a deleted family")
+	}
+	if _, ok := s.Get("ns1", "key2"); !ok {
+		t.Error("Get(key2) = not found, want found")
+	}
+	if n := s.Len("ns1"); n != 1 {
+		t.Errorf("Len(ns1) = %d, want 1", n)
+	}
+	if got := s.Revision("ns1"); got != rev {
+		t.Errorf("Revision(ns1) = %d after Delete, want %d", got, rev)
+	}
+}
+
+func TestAllAndKeys(t *testing.T) {
+	s := NewMemoryStore()
+	s.PutAll([]model.FigFamily{
+		{Definition: model.FigDefinition{Key: "key1", Namespace: "ns1"}},
+		{Definition: model.FigDefinition{Key: "key2", Namespace: "ns1"}},
+		{Definition: model.FigDefinition{Key: "key1", Namespace: "ns2"}},
+	})
+
+	keys := slices.Sorted(Keys(s, "ns1"))
+	if !slices.Equal(keys, []string{"key1", "key2"}) {
+		t.Errorf("Keys(ns1) = %v, want [key1 key2]", keys)
+	}
+
+	visited := 0
+	for ff := range All(s, "ns1") {
+		if ff.Definition.Namespace != "ns1" {
+			t.Errorf("All(ns1) yielded a family of namespace %s", ff.Definition.Namespace)
+		}
+		visited++
+		break
+	}
+	if visited != 1 {
+		t.Errorf("All(ns1) visited %d families after break, want 1", visited)
+	}
+	for range All(s, "missing") {
+		t.Error("All(missing) yielded a family")
+	}
+}
@@ -0,0 +1,294 @@
+// Package storetest checks that store.Store implementations honour the contract of the
+// interface, so that third-party stores, e.g. backed by Redis or a database, can verify
+// themselves in their own tests:
+//
+//	func TestMyStore(t *testing.T) {
+//		storetest.TestStore(t, func(t *testing.T) store.Store {
+//			return mystore.New(...)
+//		})
+//	}
+//
+// Run the tests with -race, since some check that the store is safe for concurrent use.
+package storetest
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/store"
+)
+
+// TestStore runs the conformance tests against stores created by newStore, which must
+// return a new, empty store for every call. Stores implementing store.Deleter are also
+// checked against its contract.
+func TestStore(t *testing.T, newStore func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"PutGet", testPutGet},
+		{"PutReplaces", testPutReplaces},
+		{"PutAll", testPutAll},
+		{"GetAll", testGetAll},
+		{"Range", testRange},
+		{"Revision", testRevision},
+		{"ChangedSince", testChangedSince},
+		{"Delete", testDelete},
+		{"Concurrency", testConcurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+// family returns a family of namespace and key, whose default version identifies the
+// put, so that families put one after the other are never equal.
+func family(namespace, key, version string) model.FigFamily {
+	return model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: namespace, Key: key},
+		Figs:           []model.Fig{{Version: version, Payload: []byte("\x06" + version)}},
+		DefaultVersion: &version,
+	}
+}
+
+// version returns the default version of ff, or "" if it has none.
+func version(ff *model.FigFamily) string {
+	if ff == nil || ff.DefaultVersion == nil {
+		return ""
+	}
+	return *ff.DefaultVersion
+}
+
+// keys returns the sorted keys of families.
+func keys(families []model.FigFamily) []string {
+	k := make([]string, len(families))
+	for i := range families {
+		k[i] = families[i].Definition.Key
+	}
+	slices.Sort(k)
+	return k
+}
+
+// rangeKeys returns the sorted keys Range visits in namespace.
+func rangeKeys(s store.Store, namespace string) []string {
+	var k []string
+	s.Range(namespace, func(ff *model.FigFamily) bool {
+		k = append(k, ff.Definition.Key)
+		return true
+	})
+	slices.Sort(k)
+	return k
+}
+
+func testPutGet(t *testing.T, s store.Store) {
+	s.Put(family("ns1", "key1", "v1"))
+	s.Put(family("ns2", "key1", "v2"))
+
+	got, ok := s.Get("ns1", "key1")
+	if !ok || got.Definition.Namespace != "ns1" || got.Definition.Key != "key1" || version(got) != "v1" {
+		t.Errorf("Get(ns1, key1) = %+v, %v, want the family put", got, ok)
+	}
+	if len(got.Figs) != 1 || string(got.Figs[0].Payload) != "\x06v1" {
+		t.Errorf("Get(ns1, key1).Figs = %+v, want the figs put", got.Figs)
+	}
+	if got, ok := s.Get("ns2", "key1"); !ok || version(got) != "v2" {
+		t.Errorf("Get(ns2, key1) = %+v, %v, want the family of ns2", got, ok)
+	}
+	if got, ok := s.Get("ns1", "missing"); ok {
+		t.Errorf("Get(ns1, missing) = %+v, want no family", got)
+	}
+	if got, ok := s.Get("missing", "key1"); ok {
+		t.Errorf("Get(missing, key1) = %+v, want no family", got)
+	}
+}
+
+func testPutReplaces(t *testing.T, s store.Store) {
+	s.Put(family("ns1", "key1", "v1"))
+	s.Put(family("ns1", "key1", "v2"))
+	if got, ok := s.Get("ns1", "key1"); !ok || version(got) != "v2" {
+		t.Errorf("Get() = %+v, %v, want the family put last", got, ok)
+	}
+	if n := s.Len("ns1"); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+}
+
+func testPutAll(t *testing.T, s store.Store) {
+	s.PutAll([]model.FigFamily{
+		family("ns1", "key1", "v1"),
+		family("ns1", "key2", "v2"),
+		family("ns1", "key1", "v3"),
+	})
+	// As if by calling Put for each in order
+	if got, ok := s.Get("ns1", "key1"); !ok || version(got) != "v3" {
+		t.Errorf("Get(key1) = %+v, %v, want the later of the families of key1", got, ok)
+	}
+	if got, ok := s.Get("ns1", "key2"); !ok || version(got) != "v2" {
+		t.Errorf("Get(key2) = %+v, %v", got, ok)
+	}
+	if rev := s.Revision("ns1"); rev != 3 {
+		t.Errorf("Revision() = %d, want one per family put", rev)
+	}
+	s.PutAll(nil)
+	if rev := s.Revision("ns1"); rev != 3 {
+		t.Errorf("Revision() = %d after putting nothing, want 3", rev)
+	}
+}
+
+func testGetAll(t *testing.T, s store.Store) {
+	if all := s.GetAll(); len(all) != 0 {
+		t.Errorf("GetAll() = %d families of an empty store", len(all))
+	}
+	s.Put(family("ns1", "key1", "v1"))
+	s.Put(family("ns1", "key2", "v1"))
+	s.Put(family("ns2", "key3", "v1"))
+	all := s.GetAll()
+	if got := keys(all); !slices.Equal(got, []string{"key1", "key2", "key3"}) {
+		t.Errorf("GetAll() keys = %v, want every family", got)
+	}
+	// GetAll returns a copy
+	all[0].Definition.Key = "changed"
+	if got := keys(s.GetAll()); !slices.Equal(got, []string{"key1", "key2", "key3"}) {
+		t.Errorf("GetAll() keys = %v after modifying its result", got)
+	}
+}
+
+func testRange(t *testing.T, s store.Store) {
+	if n := s.Len("ns1"); n != 0 {
+		t.Errorf("Len() = %d of an empty namespace", n)
+	}
+	s.Range("ns1", func(ff *model.FigFamily) bool {
+		t.Errorf("Range() visited %s of an empty namespace", ff.Definition.Key)
+		return true
+	})
+
+	for i := range 5 {
+		s.Put(family("ns1", fmt.Sprintf("key%d", i), "v1"))
+	}
+	s.Put(family("ns2", "other", "v1"))
+	if got := rangeKeys(s, "ns1"); !slices.Equal(got, []string{"key0", "key1", "key2", "key3", "key4"}) {
+		t.Errorf("Range(ns1) visited %v, want each family of ns1 once", got)
+	}
+	if n := s.Len("ns1"); n != 5 {
+		t.Errorf("Len(ns1) = %d, want 5", n)
+	}
+	if n := s.Len("ns2"); n != 1 {
+		t.Errorf("Len(ns2) = %d, want 1", n)
+	}
+
+	visited := 0
+	s.Range("ns1", func(*model.FigFamily) bool {
+		visited++
+		return visited < 2
+	})
+	if visited != 2 {
+		t.Errorf("Range() visited %d families, want it to stop once fn returns false", visited)
+	}
+	if got := slices.Collect(store.Keys(s, "ns2")); !slices.Equal(got, []string{"other"}) {
+		t.Errorf("Keys(ns2) = %v", got)
+	}
+}
+
+func testRevision(t *testing.T, s store.Store) {
+	if rev := s.Revision("ns1"); rev != 0 {
+		t.Errorf("Revision() = %d of an empty namespace, want 0", rev)
+	}
+	s.Put(family("ns1", "key1", "v1"))
+	s.Put(family("ns1", "key2", "v1"))
+	s.Put(family("ns1", "key1", "v2"))
+	s.Put(family("ns2", "key1", "v1"))
+	if rev := s.Revision("ns1"); rev != 3 {
+		t.Errorf("Revision(ns1) = %d, want one per put into ns1", rev)
+	}
+	if rev := s.Revision("ns2"); rev != 1 {
+		t.Errorf("Revision(ns2) = %d, want 1", rev)
+	}
+}
+
+func testChangedSince(t *testing.T, s store.Store) {
+	if changed, rev := s.ChangedSince("ns1", 0); len(changed) != 0 || rev != 0 {
+		t.Errorf("ChangedSince() = %d families at %d of an empty namespace", len(changed), rev)
+	}
+	s.Put(family("ns1", "key1", "v1"))
+	s.Put(family("ns1", "key2", "v1"))
+	s.Put(family("ns2", "key3", "v1"))
+
+	changed, rev := s.ChangedSince("ns1", 0)
+	if got := keys(changed); !slices.Equal(got, []string{"key1", "key2"}) || rev != 2 {
+		t.Errorf("ChangedSince(ns1, 0) = %v at %d, want [key1 key2] at 2", got, rev)
+	}
+	changed, _ = s.ChangedSince("ns1", 1)
+	if got := keys(changed); !slices.Equal(got, []string{"key2"}) {
+		t.Errorf("ChangedSince(ns1, 1) = %v, want [key2]", got)
+	}
+
+	// Putting key1 again moves it past the previous revision
+	s.Put(family("ns1", "key1", "v2"))
+	changed, rev = s.ChangedSince("ns1", rev)
+	if len(changed) != 1 || changed[0].Definition.Key != "key1" || version(&changed[0]) != "v2" || rev != 3 {
+		t.Errorf("ChangedSince(ns1, 2) = %+v at %d, want key1 v2 at 3", changed, rev)
+	}
+	if changed, _ := s.ChangedSince("ns1", rev); len(changed) != 0 {
+		t.Errorf("ChangedSince(ns1, %d) = %d families, want none", rev, len(changed))
+	}
+}
+
+func testDelete(t *testing.T, s store.Store) {
+	d, ok := s.(store.Deleter)
+	if !ok {
+		t.Skip("store doesn't implement store.Deleter")
+	}
+	s.Put(family("ns1", "key1", "v1"))
+	s.Put(family("ns1", "key2", "v1"))
+	s.Put(family("ns2", "key1", "v1"))
+	rev := s.Revision("ns1")
+
+	d.Delete("ns1", "key1", "missing")
+	if got := rangeKeys(s, "ns1"); !slices.Equal(got, []string{"key2"}) {
+		t.Errorf("Range(ns1) visited %v after Delete, want [key2]", got)
+	}
+	if n := s.Len("ns1"); n != 1 {
+		t.Errorf("Len(ns1) = %d after Delete, want 1", n)
+	}
+	if got := s.Revision("ns1"); got != rev {
+		t.Errorf("Revision(ns1) = %d after Delete, want it unchanged at %d", got, rev)
+	}
+	if _, ok := s.Get("ns2", "key1"); !ok {
+		t.Error("Delete removed the family of the same key in another namespace")
+	}
+}
+
+func testConcurrency(t *testing.T, s store.Store) {
+	const writers, puts = 4, 50
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range puts {
+				s.Put(family("ns1", fmt.Sprintf("key-%d-%d", w, i), "v1"))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := range puts {
+				s.Get("ns1", fmt.Sprintf("key-%d-%d", w, i))
+				s.Range("ns1", func(*model.FigFamily) bool { return true })
+				s.ChangedSince("ns1", uint64(i))
+				s.Len("ns1")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := s.Len("ns1"); n != writers*puts {
+		t.Errorf("Len() = %d after concurrent puts, want %d", n, writers*puts)
+	}
+	if rev := s.Revision("ns1"); rev != writers*puts {
+		t.Errorf("Revision() = %d after concurrent puts, want %d", rev, writers*puts)
+	}
+}
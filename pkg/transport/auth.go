@@ -1,18 +1,45 @@
 package transport
 
 import (
+	"context"
 	"crypto/rsa"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/figchain/go-client/pkg/clock"
 )
 
-// TokenProvider is an interface for providing authentication tokens.
+// TokenProvider is an interface for providing authentication tokens. ctx is the context
+// of the request the token is for; providers that call out, e.g. to a KMS or an OAuth2
+// server, should give up when it's done.
 type TokenProvider interface {
+	GetToken(ctx context.Context) (string, error)
+}
+
+// TokenProviderFunc adapts a function to the TokenProvider interface.
+type TokenProviderFunc func(ctx context.Context) (string, error)
+
+// GetToken implements TokenProvider.
+func (f TokenProviderFunc) GetToken(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// LegacyTokenProvider is the TokenProvider interface from before tokens took a context.
+type LegacyTokenProvider interface {
 	GetToken() (string, error)
 }
 
+// FromLegacyTokenProvider adapts a LegacyTokenProvider to the TokenProvider interface.
+// The request context is ignored, so the provider can't be cancelled.
+func FromLegacyTokenProvider(provider LegacyTokenProvider) TokenProvider {
+	return TokenProviderFunc(func(context.Context) (string, error) {
+		return provider.GetToken()
+	})
+}
+
 // SharedSecretTokenProvider uses a static client secret.
 type SharedSecretTokenProvider struct {
 	clientSecret string
@@ -25,7 +52,7 @@ func NewSharedSecretTokenProvider(clientSecret string) *SharedSecretTokenProvide
 	}
 }
 
-func (p *SharedSecretTokenProvider) GetToken() (string, error) {
+func (p *SharedSecretTokenProvider) GetToken(context.Context) (string, error) {
 	return p.clientSecret, nil
 }
 
@@ -37,31 +64,48 @@ type PrivateKeyTokenProvider struct {
 	namespace        string
 	keyID            string
 	tokenTTL         time.Duration
+	clock            clock.Clock
+}
+
+// PrivateKeyTokenOption configures a PrivateKeyTokenProvider.
+type PrivateKeyTokenOption func(*PrivateKeyTokenProvider)
+
+// WithTokenClock sets the clock tokens are issued and expire by, e.g. a fake clock in
+// tests.
+func WithTokenClock(c clock.Clock) PrivateKeyTokenOption {
+	return func(p *PrivateKeyTokenProvider) {
+		p.clock = clock.OrReal(c)
+	}
 }
 
 // NewPrivateKeyTokenProvider creates a new PrivateKeyTokenProvider.
 // If tokenTTL is 0, it defaults to 10 minutes.
-func NewPrivateKeyTokenProvider(privateKey *rsa.PrivateKey, serviceAccountID, tenantID, namespace, keyID string) *PrivateKeyTokenProvider {
-	return NewPrivateKeyTokenProviderWithTTL(privateKey, serviceAccountID, tenantID, namespace, keyID, 10*time.Minute)
+func NewPrivateKeyTokenProvider(privateKey *rsa.PrivateKey, serviceAccountID, tenantID, namespace, keyID string, opts ...PrivateKeyTokenOption) *PrivateKeyTokenProvider {
+	return NewPrivateKeyTokenProviderWithTTL(privateKey, serviceAccountID, tenantID, namespace, keyID, 10*time.Minute, opts...)
 }
 
 // NewPrivateKeyTokenProviderWithTTL creates a new PrivateKeyTokenProvider with a custom TTL.
-func NewPrivateKeyTokenProviderWithTTL(privateKey *rsa.PrivateKey, serviceAccountID, tenantID, namespace, keyID string, tokenTTL time.Duration) *PrivateKeyTokenProvider {
+func NewPrivateKeyTokenProviderWithTTL(privateKey *rsa.PrivateKey, serviceAccountID, tenantID, namespace, keyID string, tokenTTL time.Duration, opts ...PrivateKeyTokenOption) *PrivateKeyTokenProvider {
 	if tokenTTL == 0 {
 		tokenTTL = 10 * time.Minute
 	}
-	return &PrivateKeyTokenProvider{
+	p := &PrivateKeyTokenProvider{
 		privateKey:       privateKey,
 		serviceAccountID: serviceAccountID,
 		tenantID:         tenantID,
 		namespace:        namespace,
 		keyID:            keyID,
 		tokenTTL:         tokenTTL,
+		clock:            clock.Real,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
 }
 
-func (p *PrivateKeyTokenProvider) GetToken() (string, error) {
-	now := time.Now()
+func (p *PrivateKeyTokenProvider) GetToken(context.Context) (string, error) {
+	now := p.clock.Now()
 	claims := jwt.MapClaims{
 		"iss":       p.serviceAccountID,
 		"sub":       p.serviceAccountID,
@@ -86,3 +130,25 @@ func (p *PrivateKeyTokenProvider) GetToken() (string, error) {
 
 	return signedToken, nil
 }
+
+// SwappableTokenProvider delegates to a TokenProvider that can be replaced at any time,
+// e.g. to rotate credentials on a live client. It is safe for concurrent use.
+type SwappableTokenProvider struct {
+	current atomic.Pointer[TokenProvider]
+}
+
+// NewSwappableTokenProvider creates a new SwappableTokenProvider delegating to provider.
+func NewSwappableTokenProvider(provider TokenProvider) *SwappableTokenProvider {
+	p := &SwappableTokenProvider{}
+	p.Swap(provider)
+	return p
+}
+
+// Swap replaces the delegate. Requests that already obtained a token are unaffected.
+func (p *SwappableTokenProvider) Swap(provider TokenProvider) {
+	p.current.Store(&provider)
+}
+
+func (p *SwappableTokenProvider) GetToken(ctx context.Context) (string, error) {
+	return (*p.current.Load()).GetToken(ctx)
+}
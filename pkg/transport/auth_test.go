@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"testing"
@@ -13,7 +14,7 @@ func TestSharedSecretTokenProvider_GetToken(t *testing.T) {
 	secret := "my-secret-token"
 	provider := NewSharedSecretTokenProvider(secret)
 
-	token, err := provider.GetToken()
+	token, err := provider.GetToken(context.Background())
 	if err != nil {
 		t.Fatalf("GetToken failed: %v", err)
 	}
@@ -35,7 +36,7 @@ func TestPrivateKeyTokenProvider_GetToken(t *testing.T) {
 	keyID := "key-456"
 	provider := NewPrivateKeyTokenProvider(pk, serviceAccountID, tenantID, namespace, keyID)
 
-	tokenString, err := provider.GetToken()
+	tokenString, err := provider.GetToken(context.Background())
 	if err != nil {
 		t.Fatalf("GetToken failed: %v", err)
 	}
@@ -83,3 +84,27 @@ func TestPrivateKeyTokenProvider_GetToken(t *testing.T) {
 		t.Error("Token is already expired")
 	}
 }
+
+func TestSwappableTokenProvider(t *testing.T) {
+	provider := NewSwappableTokenProvider(NewSharedSecretTokenProvider("old"))
+	if token, _ := provider.GetToken(context.Background()); token != "old" {
+		t.Errorf("Expected token old, got %s", token)
+	}
+	provider.Swap(NewSharedSecretTokenProvider("new"))
+	if token, _ := provider.GetToken(context.Background()); token != "new" {
+		t.Errorf("Expected token new, got %s", token)
+	}
+}
+
+func TestFromLegacyTokenProvider(t *testing.T) {
+	provider := FromLegacyTokenProvider(legacyTokenProvider("legacy"))
+	if token, err := provider.GetToken(context.Background()); err != nil || token != "legacy" {
+		t.Errorf("GetToken() = %q, %v, want legacy", token, err)
+	}
+}
+
+type legacyTokenProvider string
+
+func (p legacyTokenProvider) GetToken() (string, error) {
+	return string(p), nil
+}
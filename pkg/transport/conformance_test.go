@@ -0,0 +1,28 @@
+package transport_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/transport"
+	"github.com/figchain/go-client/pkg/transport/transporttest"
+)
+
+func TestHTTPTransport_Conformance(t *testing.T) {
+	transporttest.TestTransport(t, func(t *testing.T, b *transporttest.Backend) transport.Transport {
+		srv := httptest.NewServer(b.Handler())
+		t.Cleanup(srv.Close)
+		return transport.NewHTTPTransport(srv.Client(), srv.URL, transport.NewSharedSecretTokenProvider("secret"), transporttest.EnvironmentID)
+	})
+}
+
+// With single-object encoding, the Backend rejects update fetches with 415 and the
+// transport falls back to OCF.
+func TestHTTPTransport_ConformanceSingleObjectEncoding(t *testing.T) {
+	transporttest.TestTransport(t, func(t *testing.T, b *transporttest.Backend) transport.Transport {
+		srv := httptest.NewServer(b.Handler())
+		t.Cleanup(srv.Close)
+		return transport.NewHTTPTransport(srv.Client(), srv.URL, transport.NewSharedSecretTokenProvider("secret"), transporttest.EnvironmentID,
+			transport.WithSingleObjectEncoding(true))
+	})
+}
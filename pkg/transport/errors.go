@@ -0,0 +1,120 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TransportError is returned when the server answers a request with an error status.
+// Use errors.As to inspect it, e.g. to decide whether and when to retry.
+type TransportError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Code and Message are the server's error code and message, if the response body
+	// is a JSON error.
+	Code    string
+	Message string
+	// Body is the raw response body.
+	Body string
+	// RetryAfter is how long the server asked clients to wait before retrying, from the
+	// Retry-After header, or 0 if it didn't say.
+	RetryAfter time.Duration
+	// RateLimit is the rate limit state the server reported with the response.
+	RateLimit RateLimit
+}
+
+// RateLimit is the rate limit state reported in X-RateLimit-* response headers. Fields
+// the server didn't report are zero.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// newTransportError creates a TransportError from a response and its body, received at
+// now.
+func newTransportError(resp *http.Response, body []byte, now time.Time) *TransportError {
+	e := &TransportError{StatusCode: resp.StatusCode, Body: string(body)}
+	var serverErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &serverErr) == nil {
+		e.Code = serverErr.Code
+		e.Message = serverErr.Message
+	}
+	e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	e.RateLimit.Limit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	e.RateLimit.Remaining, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		e.RateLimit.Reset = time.Unix(reset, 0)
+	}
+	return e
+}
+
+// parseRetryAfter parses a Retry-After header, given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+func (e *TransportError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("server returned error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("server returned error %d: %s", e.StatusCode, e.Body)
+}
+
+// Unwrap returns ErrNotFound for 404 responses and ErrConflict for 409 responses, so
+// errors.Is(err, ErrNotFound) and errors.Is(err, ErrConflict) hold.
+func (e *TransportError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	}
+	return nil
+}
+
+// Retryable reports whether the request may succeed if retried unchanged: on timeouts,
+// rate limiting and server errors other than 501 Not Implemented.
+func (e *TransportError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented:
+		return false
+	}
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// Retryable reports whether a request that failed with err may succeed if retried.
+// Server errors are classified by TransportError.Retryable, and network errors are
+// retryable unless the request's context ended. Other failures, e.g. to decode a
+// response, aren't.
+func Retryable(err error) bool {
+	var te *TransportError
+	if errors.As(err, &te) {
+		return te.Retryable()
+	}
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return false
+}
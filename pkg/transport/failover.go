@@ -0,0 +1,153 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/figchain/go-client/pkg/clock"
+	"github.com/figchain/go-client/pkg/logging"
+)
+
+// DefaultFailoverCooldown is how long a failed endpoint is skipped by default.
+const DefaultFailoverCooldown = 30 * time.Second
+
+// WithFallbackURLs sets base URLs to fail over to, in order, when a request to the
+// primary base URL fails with a network error or a 5xx response. A failed endpoint is
+// skipped for the failover cooldown, after which it is tried again, so requests return
+// to the primary once it recovers. Dual-stack hosts need no fallback: the dialer
+// already falls back between their IPv6 and IPv4 addresses.
+func WithFallbackURLs(urls ...string) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.fallbackURLs = append(t.fallbackURLs, urls...)
+	}
+}
+
+// WithFailoverCooldown sets how long a failed endpoint is skipped.
+func WithFailoverCooldown(d time.Duration) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.failoverCooldown = d
+	}
+}
+
+// WithClock sets the clock failed endpoints are skipped by and Retry-After dates are
+// read against, e.g. a fake clock in tests.
+func WithClock(c clock.Clock) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.clock = clock.OrReal(c)
+	}
+}
+
+// endpointHealth records when failed endpoints may be tried again.
+type endpointHealth struct {
+	mu        sync.Mutex
+	downUntil map[string]time.Time
+}
+
+func (h *endpointHealth) markDown(endpoint string, until time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.downUntil == nil {
+		h.downUntil = make(map[string]time.Time)
+	}
+	h.downUntil[endpoint] = until
+}
+
+func (h *endpointHealth) markUp(endpoint string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.downUntil, endpoint)
+}
+
+// order returns the endpoints to try: the healthy ones in configured order, followed
+// by the ones still cooling down, soonest available first.
+func (h *endpointHealth) order(endpoints []string, now time.Time) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var healthy, down []string
+	for _, e := range endpoints {
+		if now.Before(h.downUntil[e]) {
+			down = append(down, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	slices.SortStableFunc(down, func(a, b string) int {
+		return h.downUntil[a].Compare(h.downUntil[b])
+	})
+	return append(healthy, down...)
+}
+
+// do sends req, failing over to the fallback URLs if it is addressed to the base URL.
+func (t *HTTPTransport) do(req *http.Request) (*http.Response, error) {
+	rawURL := req.URL.String()
+	if len(t.fallbackURLs) == 0 || !strings.HasPrefix(rawURL, t.baseURL) {
+		return t.send(req)
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// The body can't be replayed
+		return t.send(req)
+	}
+	path := strings.TrimPrefix(rawURL, t.baseURL)
+	endpoints := t.health.order(append([]string{t.baseURL}, t.fallbackURLs...), t.clock.Now())
+	cooldown := t.failoverCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultFailoverCooldown
+	}
+
+	var resp *http.Response
+	var err error
+	for i, endpoint := range endpoints {
+		var r *http.Request
+		if r, err = rebase(req, endpoint+path); err != nil {
+			return nil, err
+		}
+		resp, err = t.send(r)
+		if !failed(r, resp, err) {
+			t.health.markUp(endpoint)
+			return resp, err
+		}
+		t.health.markDown(endpoint, t.clock.Now().Add(cooldown))
+		if i < len(endpoints)-1 {
+			reason := err
+			if err == nil {
+				resp.Body.Close()
+				reason = fmt.Errorf("server returned error %d", resp.StatusCode)
+			}
+			logging.Printf("Warning: FigChain endpoint %s failed, failing over to %s: %v", endpoint, endpoints[i+1], reason)
+		}
+	}
+	return resp, err
+}
+
+// rebase returns a copy of req addressed to rawURL, with a fresh body.
+func rebase(req *http.Request, rawURL string) (*http.Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	r := req.Clone(req.Context())
+	r.URL = u
+	r.Host = ""
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// failed reports whether an endpoint failed to serve req, as opposed to the request
+// itself failing or being cancelled.
+func failed(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,99 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPTransport_Failover(t *testing.T) {
+	var primaryDown atomic.Bool
+	primaryDown.Store(true)
+	var primaryHits, fallbackHits atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits.Add(1)
+		if primaryDown.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackHits.Add(1)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Error("fallback request is missing its authorization")
+		}
+		w.Write([]byte("fallback"))
+	}))
+	defer fallback.Close()
+
+	tr := NewHTTPTransport(http.DefaultClient, primary.URL, NewSharedSecretTokenProvider("secret"), "env-1",
+		WithFallbackURLs(fallback.URL),
+		WithFailoverCooldown(50*time.Millisecond),
+	)
+	ctx := context.Background()
+
+	schema, err := tr.FetchSchema(ctx, "schemas/x")
+	if err != nil || schema != "fallback" {
+		t.Fatalf("FetchSchema = %q, %v; want fallback", schema, err)
+	}
+	// The primary is skipped while it cools down
+	if _, err := tr.FetchSchema(ctx, "schemas/x"); err != nil {
+		t.Fatalf("FetchSchema failed: %v", err)
+	}
+	if primaryHits.Load() != 1 || fallbackHits.Load() != 2 {
+		t.Errorf("hits = %d primary, %d fallback; want 1, 2", primaryHits.Load(), fallbackHits.Load())
+	}
+
+	primaryDown.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if schema, err := tr.FetchSchema(ctx, "schemas/x"); err != nil || schema != "primary" {
+		t.Errorf("FetchSchema after recovery = %q, %v; want primary", schema, err)
+	}
+}
+
+func TestHTTPTransport_FailoverReplaysBody(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+	var body string
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer up.Close()
+
+	tr := NewHTTPTransport(http.DefaultClient, down.URL, NewSharedSecretTokenProvider("secret"), "env-1",
+		WithFallbackURLs(up.URL))
+	if _, err := tr.doRequest(context.Background(), down.URL+"/data/updates", []byte("payload")); err != nil {
+		t.Fatalf("doRequest failed: %v", err)
+	}
+	if body != "payload" {
+		t.Errorf("fallback received body %q, want payload", body)
+	}
+}
+
+func TestHTTPTransport_FailoverAllDown(t *testing.T) {
+	var hits atomic.Int32
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	a := httptest.NewServer(failing)
+	defer a.Close()
+	b := httptest.NewServer(failing)
+	defer b.Close()
+
+	tr := NewHTTPTransport(http.DefaultClient, a.URL, NewSharedSecretTokenProvider("secret"), "env-1",
+		WithFallbackURLs(b.URL))
+	if _, err := tr.FetchSchema(context.Background(), "schemas/x"); err == nil {
+		t.Fatal("expected an error when every endpoint fails")
+	}
+	if hits.Load() != 2 {
+		t.Errorf("endpoints hit %d times, want 2", hits.Load())
+	}
+}
@@ -0,0 +1,160 @@
+//go:build js && wasm
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall/js"
+)
+
+// FetchRoundTripper is an http.RoundTripper sending requests with the JavaScript Fetch
+// API, for WASM builds running in browsers, edge runtimes, WASM plugins and Node. Unlike
+// the js transport of net/http, it always uses fetch, including under Node, where
+// net/http falls back to sockets. Response bodies are streamed, so long polls and
+// streamed initial fetches read as they arrive. It is the default transport of clients
+// built for js/wasm.
+type FetchRoundTripper struct{}
+
+// RoundTrip implements http.RoundTripper. The request is aborted once its context ends.
+func (FetchRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	fetch := js.Global().Get("fetch")
+	if fetch.Type() != js.TypeFunction {
+		return nil, errors.New("fetch is not available in this runtime")
+	}
+
+	init := js.Global().Get("Object").New()
+	init.Set("method", req.Method)
+	headers := js.Global().Get("Headers").New()
+	for name, values := range req.Header {
+		for _, value := range values {
+			headers.Call("append", name, value)
+		}
+	}
+	init.Set("headers", headers)
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > 0 {
+			buf := js.Global().Get("Uint8Array").New(len(body))
+			js.CopyBytesToJS(buf, body)
+			init.Set("body", buf)
+		}
+	}
+	controller := js.Global().Get("AbortController").New()
+	init.Set("signal", controller.Get("signal"))
+
+	ctx := req.Context()
+	result, err := await(ctx, controller, fetch.Invoke(req.URL.String(), init))
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	addHeader := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		header.Add(args[1].String(), args[0].String())
+		return nil
+	})
+	result.Get("headers").Call("forEach", addHeader)
+	addHeader.Release()
+
+	contentLength := int64(-1)
+	if cl, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil && cl >= 0 {
+		contentLength = cl
+	}
+	var body io.ReadCloser = http.NoBody
+	if stream := result.Get("body"); stream.Truthy() {
+		body = &fetchBody{ctx: ctx, controller: controller, reader: stream.Call("getReader")}
+	}
+	status := result.Get("status").Int()
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: contentLength,
+		Request:       req,
+	}, nil
+}
+
+// fetchBody reads the body stream of a fetch response.
+type fetchBody struct {
+	ctx        context.Context
+	controller js.Value
+	reader     js.Value
+	chunk      []byte
+	err        error
+}
+
+func (b *fetchBody) Read(p []byte) (int, error) {
+	for len(b.chunk) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		result, err := await(b.ctx, b.controller, b.reader.Call("read"))
+		switch {
+		case err != nil:
+			b.err = err
+		case result.Get("done").Bool():
+			b.err = io.EOF
+		default:
+			value := result.Get("value")
+			b.chunk = make([]byte, value.Get("byteLength").Int())
+			js.CopyBytesToGo(b.chunk, value)
+		}
+	}
+	n := copy(p, b.chunk)
+	b.chunk = b.chunk[n:]
+	return n, nil
+}
+
+// Close cancels the rest of the stream, so that an unread body isn't downloaded.
+func (b *fetchBody) Close() error {
+	if b.err == nil {
+		b.reader.Call("cancel")
+		b.err = errors.New("read on closed response body")
+	}
+	return nil
+}
+
+// await waits for promise to settle and returns its value. If ctx ends first, the fetch
+// is aborted with controller and ctx's error returned.
+func await(ctx context.Context, controller, promise js.Value) (js.Value, error) {
+	type settled struct {
+		value js.Value
+		err   error
+	}
+	ch := make(chan settled, 1)
+	resolve := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		ch <- settled{value: args[0]}
+		return nil
+	})
+	defer resolve.Release()
+	reject := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		ch <- settled{err: fmt.Errorf("fetch failed: %s", js.Global().Get("String").Invoke(args[0]).String())}
+		return nil
+	})
+	defer reject.Release()
+	promise.Call("then", resolve, reject)
+
+	select {
+	case s := <-ch:
+		return s.value, s.err
+	case <-ctx.Done():
+		// The promise rejects once aborted; wait for it so that the callbacks are still
+		// there to be called
+		controller.Call("abort")
+		<-ch
+		return js.Value{}, ctx.Err()
+	}
+}
@@ -0,0 +1,95 @@
+//go:build js && wasm
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall/js"
+	"testing"
+)
+
+// stubFetch replaces the global fetch with the JavaScript function source returns, for
+// the duration of the test.
+func stubFetch(t *testing.T, source string) {
+	t.Helper()
+	previous := js.Global().Get("fetch")
+	js.Global().Set("fetch", js.Global().Get("Function").New(source).Invoke())
+	t.Cleanup(func() { js.Global().Set("fetch", previous) })
+}
+
+func TestFetchRoundTripper(t *testing.T) {
+	stubFetch(t, `return async (url, init) => new Response(
+		JSON.stringify({url, method: init.method, auth: init.headers.get("Authorization"), body: new TextDecoder().decode(init.body)}),
+		{status: 201, headers: {"X-Test": "yes"}})`)
+
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/api/data/env1", strings.NewReader("payload"))
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := FetchRoundTripper{}.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("X-Test") != "yes" {
+		t.Errorf("response = %d %v, want 201 with X-Test", resp.StatusCode, resp.Header)
+	}
+	want := `{"url":"https://example.com/api/data/env1","method":"POST","auth":"Bearer token","body":"payload"}`
+	if string(body) != want {
+		t.Errorf("fetch saw %s, want %s", body, want)
+	}
+}
+
+func TestFetchRoundTripper_StreamedBody(t *testing.T) {
+	stubFetch(t, `return async () => new Response(new ReadableStream({
+		start(controller) {
+			const encoder = new TextEncoder();
+			for (const chunk of ["one ", "two ", "three"]) controller.enqueue(encoder.encode(chunk));
+			controller.close();
+		}
+	}))`)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	resp, err := FetchRoundTripper{}.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "one two three" {
+		t.Errorf("body = %q, %v, want the chunks in order", body, err)
+	}
+}
+
+func TestFetchRoundTripper_Cancellation(t *testing.T) {
+	stubFetch(t, `return (url, init) => new Promise((resolve, reject) => {
+		init.signal.addEventListener("abort", () => reject(init.signal.reason));
+	})`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com/", nil)
+	done := make(chan error, 1)
+	go func() {
+		_, err := FetchRoundTripper{}.RoundTrip(req)
+		done <- err
+	}()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestFetchRoundTripper_Rejected(t *testing.T) {
+	stubFetch(t, `return async () => { throw new TypeError("network down"); }`)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if _, err := (FetchRoundTripper{}).RoundTrip(req); err == nil || !strings.Contains(err.Error(), "network down") {
+		t.Errorf("RoundTrip() error = %v, want the rejection", err)
+	}
+}
@@ -0,0 +1,48 @@
+package transport
+
+import "net/http"
+
+// RequestInterceptor is called with every request before it is sent, after its
+// authorization header is set, e.g. to add correlation IDs or sign it. Returning an
+// error aborts the request.
+type RequestInterceptor func(req *http.Request) error
+
+// ResponseInterceptor is called with the outcome of every request, e.g. for audit
+// logging or chaos injection. It returns the response and error passed on to the next
+// interceptor and eventually the transport, so it may replace either.
+type ResponseInterceptor func(req *http.Request, resp *http.Response, err error) (*http.Response, error)
+
+// HTTPTransportOption is a functional option for configuring an HTTPTransport.
+type HTTPTransportOption func(*HTTPTransport)
+
+// WithRequestInterceptors adds request interceptors, called in the order added.
+func WithRequestInterceptors(interceptors ...RequestInterceptor) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.requestInterceptors = append(t.requestInterceptors, interceptors...)
+	}
+}
+
+// WithResponseInterceptors adds response interceptors, called in the order added.
+func WithResponseInterceptors(interceptors ...ResponseInterceptor) HTTPTransportOption {
+	return func(t *HTTPTransport) {
+		t.responseInterceptors = append(t.responseInterceptors, interceptors...)
+	}
+}
+
+// send sends req through the interceptor chain.
+func (t *HTTPTransport) send(req *http.Request) (*http.Response, error) {
+	t.setProtocolHeaders(req)
+	for _, intercept := range t.requestInterceptors {
+		if err := intercept(req); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := t.client.Do(req)
+	if err == nil {
+		t.recordProtocol(resp)
+	}
+	for _, intercept := range t.responseInterceptors {
+		resp, err = intercept(req, resp, err)
+	}
+	return resp, err
+}
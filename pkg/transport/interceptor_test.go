@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPTransport_Interceptors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Correlation-ID"); got != "abc" {
+			t.Errorf("X-Correlation-ID = %q, want abc", got)
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	var order []string
+	var statuses []int
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1",
+		WithRequestInterceptors(
+			func(req *http.Request) error {
+				if req.Header.Get("Authorization") != "Bearer secret" {
+					t.Error("request interceptor called before authorization was set")
+				}
+				order = append(order, "first")
+				req.Header.Set("X-Correlation-ID", "abc")
+				return nil
+			},
+			func(req *http.Request) error {
+				order = append(order, "second")
+				return nil
+			},
+		),
+		WithResponseInterceptors(func(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+			if err == nil {
+				statuses = append(statuses, resp.StatusCode)
+			}
+			return resp, err
+		}),
+	)
+
+	if _, err := tr.FetchSchema(context.Background(), "schemas/x"); err != nil {
+		t.Fatalf("FetchSchema failed: %v", err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("request interceptors called in order %v", order)
+	}
+	if len(statuses) != 1 || statuses[0] != http.StatusOK {
+		t.Errorf("response interceptor saw statuses %v, want [200]", statuses)
+	}
+}
+
+func TestHTTPTransport_InterceptorErrors(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	errChaos := errors.New("injected failure")
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1",
+		WithRequestInterceptors(func(*http.Request) error { return errChaos }),
+	)
+	if _, err := tr.FetchSchema(context.Background(), "schemas/x"); !errors.Is(err, errChaos) {
+		t.Errorf("FetchSchema error = %v, want the request interceptor's error", err)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests, want 0", requests)
+	}
+
+	// Response interceptors may fail successful requests
+	tr = NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1",
+		WithResponseInterceptors(func(_ *http.Request, resp *http.Response, err error) (*http.Response, error) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, errChaos
+		}),
+	)
+	if _, err := tr.FetchSchema(context.Background(), "schemas/x"); !errors.Is(err, errChaos) {
+		t.Errorf("FetchSchema error = %v, want the response interceptor's error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func (t *HTTPTransport) ListNamespaces(ctx context.Context) ([]*model.NamespaceInfo, error) {
+	query := url.Values{}
+	query.Set("environmentId", t.environmentID)
+	endpoint := fmt.Sprintf("%s/admin/namespaces?%s", t.baseURL, query.Encode())
+	var namespaces []*model.NamespaceInfo
+	if err := t.doJSON(ctx, "GET", endpoint, nil, &namespaces); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+func (t *HTTPTransport) GetNamespace(ctx context.Context, name string) (*model.NamespaceInfo, error) {
+	query := url.Values{}
+	query.Set("environmentId", t.environmentID)
+	endpoint := fmt.Sprintf("%s/admin/namespaces/%s?%s", t.baseURL, url.PathEscape(name), query.Encode())
+	var ns model.NamespaceInfo
+	if err := t.doJSON(ctx, "GET", endpoint, nil, &ns); err != nil {
+		return nil, fmt.Errorf("namespace %s: %w", name, err)
+	}
+	return &ns, nil
+}
+
+func (t *HTTPTransport) CreateNamespace(ctx context.Context, req *model.CreateNamespaceRequest) (*model.NamespaceInfo, error) {
+	var ns model.NamespaceInfo
+	if err := t.doJSON(ctx, "POST", t.baseURL+"/admin/namespaces", req, &ns); err != nil {
+		return nil, fmt.Errorf("namespace %s: %w", req.Name, err)
+	}
+	return &ns, nil
+}
+
+// doJSON sends an authenticated request with in, if not nil, as its JSON body, and
+// decodes the JSON response into out. Responses other than 200 OK and 201 Created fail
+// with a TransportError.
+func (t *HTTPTransport) doJSON(ctx context.Context, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		jsonBytes, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(jsonBytes)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token, err := t.tokenProvider.GetToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get auth token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return newTransportError(resp, bodyBytes, t.clock.Now())
+	}
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
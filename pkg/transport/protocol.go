@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the version of the wire protocol the transport speaks.
+const ProtocolVersion = 1
+
+// Headers negotiating the wire protocol. Requests carry the transport's protocol version
+// and the optional features it supports; servers that negotiate answer with their
+// version and the features they will use. Servers predating negotiation answer without
+// them, and only get requests they understand.
+const (
+	ProtocolVersionHeader = "X-FigChain-Protocol-Version"
+	CapabilitiesHeader    = "X-FigChain-Capabilities"
+)
+
+// Optional protocol features.
+const (
+	// CapabilityStreamedInitialFetch is the streamed initial fetch response, see
+	// StreamedFamiliesContentType.
+	CapabilityStreamedInitialFetch = "streamed-initial-fetch"
+	// CapabilitySingleObjectEncoding is single-object encoding of update fetches, see
+	// SingleObjectContentType.
+	CapabilitySingleObjectEncoding = "single-object-encoding"
+)
+
+// Protocol is the wire protocol negotiated with the server.
+type Protocol struct {
+	// Version is the server's protocol version, or 0 until a server that negotiates has
+	// answered.
+	Version int `json:"version"`
+	// Capabilities are the optional features both the transport and the server support.
+	Capabilities []string `json:"capabilities"`
+}
+
+// Has reports whether capability was negotiated.
+func (p Protocol) Has(capability string) bool {
+	return slices.Contains(p.Capabilities, capability)
+}
+
+// Protocol returns the protocol negotiated by the last response of a server that
+// negotiates.
+func (t *HTTPTransport) Protocol() Protocol {
+	if p := t.protocol.Load(); p != nil {
+		return *p
+	}
+	return Protocol{}
+}
+
+// capabilities returns the optional features the transport supports.
+func (t *HTTPTransport) capabilities() []string {
+	capabilities := []string{CapabilityStreamedInitialFetch}
+	if t.singleObject {
+		capabilities = append(capabilities, CapabilitySingleObjectEncoding)
+	}
+	return capabilities
+}
+
+// serverSupports reports whether the server supports capability, assuming it does until
+// the server has negotiated.
+func (t *HTTPTransport) serverSupports(capability string) bool {
+	p := t.Protocol()
+	return p.Version == 0 || p.Has(capability)
+}
+
+// setProtocolHeaders advertises the transport's protocol on req.
+func (t *HTTPTransport) setProtocolHeaders(req *http.Request) {
+	req.Header.Set(ProtocolVersionHeader, strconv.Itoa(ProtocolVersion))
+	req.Header.Set(CapabilitiesHeader, strings.Join(t.capabilities(), ", "))
+}
+
+// recordProtocol records the protocol negotiated by resp, if its server negotiates.
+func (t *HTTPTransport) recordProtocol(resp *http.Response) {
+	version, err := strconv.Atoi(resp.Header.Get(ProtocolVersionHeader))
+	if err != nil || version <= 0 {
+		return
+	}
+	p := &Protocol{Version: version, Capabilities: []string{}}
+	offered := t.capabilities()
+	for _, c := range strings.Split(resp.Header.Get(CapabilitiesHeader), ",") {
+		if c = strings.TrimSpace(c); slices.Contains(offered, c) && !p.Has(c) {
+			p.Capabilities = append(p.Capabilities, c)
+		}
+	}
+	t.protocol.Store(p)
+}
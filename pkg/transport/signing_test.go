@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/figchain/go-client/pkg/figtest"
+	"github.com/figchain/go-client/pkg/model"
+)
+
+func TestHMACSigner(t *testing.T) {
+	key := []byte("signing-key")
+	var verified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, err := strconv.ParseInt(r.Header.Get(SignatureTimestampHeader), 10, 64)
+		if err != nil {
+			t.Errorf("invalid timestamp header: %v", err)
+		}
+		want, _ := HMACSignature(HMACSHA512, key, ts, body)
+		if got := r.Header.Get(SignatureHeader); got != want {
+			t.Errorf("%s %s signature = %q, want %q", r.Method, r.URL.Path, got, want)
+		} else {
+			verified++
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	signer, err := NewHMACSigner(HMACSHA512, key)
+	if err != nil {
+		t.Fatalf("NewHMACSigner failed: %v", err)
+	}
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1",
+		WithRequestInterceptors(signer))
+
+	// A request with a body and one without
+	if err := tr.UploadPublicKey(context.Background(), &model.UserPublicKey{Email: "a@example.com"}); err != nil {
+		t.Fatalf("UploadPublicKey failed: %v", err)
+	}
+	if err := tr.DeletePublicKey(context.Background(), "key-1"); err != nil {
+		t.Fatalf("DeletePublicKey failed: %v", err)
+	}
+	if verified != 2 {
+		t.Errorf("verified %d signatures, want 2", verified)
+	}
+}
+
+func TestHMACSigner_Clock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	signer, err := NewHMACSigner(HMACSHA256, []byte("key"), WithSignerClock(figtest.NewFakeClock(now)))
+	if err != nil {
+		t.Fatalf("NewHMACSigner failed: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := signer(req); err != nil {
+		t.Fatalf("signer failed: %v", err)
+	}
+	if got, want := req.Header.Get(SignatureTimestampHeader), strconv.FormatInt(now.Unix(), 10); got != want {
+		t.Errorf("timestamp = %s, want %s from the clock", got, want)
+	}
+}
+
+func TestNewHMACSigner_UnknownAlgorithm(t *testing.T) {
+	if _, err := NewHMACSigner("md5", []byte("key")); err == nil {
+		t.Error("NewHMACSigner(md5) succeeded, want error")
+	}
+}
@@ -4,46 +4,152 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/figchain/go-client/pkg/clock"
+	"github.com/figchain/go-client/pkg/logging"
 	"github.com/figchain/go-client/pkg/model"
 	"github.com/hamba/avro/v2"
 	"github.com/hamba/avro/v2/ocf"
 )
 
+// ErrNotFound is returned when the requested resource does not exist. Failures to reach
+// the server wrap the underlying network error; error responses are TransportErrors.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a resource to create already exists.
+var ErrConflict = errors.New("conflict")
+
 // Transport defines the interface for fetching data from the FigChain API.
 type Transport interface {
 	FetchInitial(ctx context.Context, req *model.InitialFetchRequest) (*model.InitialFetchResponse, error)
 	FetchUpdate(ctx context.Context, req *model.UpdateFetchRequest) (*model.UpdateFetchResponse, error)
+	// FetchFamily fetches a single FigFamily. It returns ErrNotFound if the key doesn't exist.
+	FetchFamily(ctx context.Context, namespace, key string) (*model.FigFamily, error)
 	GetNamespaceKey(ctx context.Context, namespace string) ([]*model.NamespaceKey, error)
 	UploadPublicKey(ctx context.Context, key *model.UserPublicKey) error
+	// ListPublicKeys lists the public keys registered for email.
+	ListPublicKeys(ctx context.Context, email string) ([]*model.UserPublicKey, error)
+	// DeletePublicKey deletes a registered public key. It returns ErrNotFound if the key
+	// doesn't exist.
+	DeletePublicKey(ctx context.Context, keyID string) error
+	// FetchSchema fetches the Avro schema at a fig definition's SchemaURI. Relative URIs
+	// are resolved against the base URL. It returns ErrNotFound if the schema doesn't exist.
+	FetchSchema(ctx context.Context, uri string) (string, error)
+	// ReportUsage sends the keys read by the client to the server.
+	ReportUsage(ctx context.Context, report *model.UsageReport) error
+	// PublishFig creates a new fig version and updates the rules and default version of
+	// its family as requested, or only validates the request if it is a dry run. It
+	// requires admin-scoped credentials.
+	PublishFig(ctx context.Context, req *model.PublishRequest) (*model.PublishResponse, error)
+	// ListNamespaces lists the namespaces of the environment.
+	ListNamespaces(ctx context.Context) ([]*model.NamespaceInfo, error)
+	// GetNamespace fetches the metadata of a namespace. It returns ErrNotFound if the
+	// namespace doesn't exist.
+	GetNamespace(ctx context.Context, name string) (*model.NamespaceInfo, error)
+	// CreateNamespace creates a namespace. It returns ErrConflict if the namespace already
+	// exists. It requires admin-scoped credentials.
+	CreateNamespace(ctx context.Context, req *model.CreateNamespaceRequest) (*model.NamespaceInfo, error)
 	Close() error
 }
 
+// InitialStreamer is implemented by transports that can stream initial fetches.
+type InitialStreamer interface {
+	// StreamInitial fetches the fig families of a namespace like FetchInitial, but calls
+	// fn with each family as it is decoded instead of collecting them, so that the
+	// encoded response isn't buffered when the server streams it. It returns the cursor
+	// of the families, or fn's error if it fails.
+	StreamInitial(ctx context.Context, req *model.InitialFetchRequest, fn func(*model.FigFamily) error) (string, error)
+}
+
+// Negotiator is implemented by transports that negotiate the wire protocol with the
+// server.
+type Negotiator interface {
+	// Protocol returns the wire protocol negotiated with the server.
+	Protocol() Protocol
+}
+
 // HTTPTransport is an HTTP implementation of the Transport interface.
 type HTTPTransport struct {
 	client        *http.Client
 	baseURL       string
 	tokenProvider TokenProvider
 	environmentID string
+
+	requestInterceptors  []RequestInterceptor
+	responseInterceptors []ResponseInterceptor
+
+	fallbackURLs     []string
+	failoverCooldown time.Duration
+	health           endpointHealth
+	clock            clock.Clock
+
+	singleObject bool
+	// singleObjectRejected is set once the server rejects single-object encoding.
+	singleObjectRejected atomic.Bool
+	protocol             atomic.Pointer[Protocol]
 }
 
 // NewHTTPTransport creates a new HTTPTransport.
-func NewHTTPTransport(client *http.Client, baseURL string, tokenProvider TokenProvider, environmentID string) *HTTPTransport {
-	return &HTTPTransport{
+func NewHTTPTransport(client *http.Client, baseURL string, tokenProvider TokenProvider, environmentID string, opts ...HTTPTransportOption) *HTTPTransport {
+	t := &HTTPTransport{
 		client:        client,
 		baseURL:       baseURL,
 		tokenProvider: tokenProvider,
 		environmentID: environmentID,
+		clock:         clock.Real,
+	}
+	for _, opt := range opts {
+		opt(t)
 	}
+	return t
 }
 
+// Media type and OCF metadata keys of streamed initial fetch responses. Rather than a
+// single InitialFetchResponse record, holding every family of the namespace, a streamed
+// response has one FigFamily record per family, and the cursor and environment in its
+// file metadata, so that clients can decode it incrementally.
+const (
+	StreamedFamiliesContentType = "application/vnd.figchain.families+avro"
+	CursorMetadataKey           = "figchain.cursor"
+	EnvironmentMetadataKey      = "figchain.environmentId"
+)
+
 func (t *HTTPTransport) FetchInitial(ctx context.Context, req *model.InitialFetchRequest) (*model.InitialFetchResponse, error) {
+	var families []model.FigFamily
+	resp, err := t.streamInitial(ctx, req, func(ff *model.FigFamily) error {
+		families = append(families, *ff)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.FigFamilies = families
+	return resp, nil
+}
+
+// StreamInitial implements InitialStreamer.
+func (t *HTTPTransport) StreamInitial(ctx context.Context, req *model.InitialFetchRequest, fn func(*model.FigFamily) error) (string, error) {
+	resp, err := t.streamInitial(ctx, req, fn)
+	if err != nil {
+		return "", err
+	}
+	return resp.Cursor, nil
+}
+
+// streamInitial fetches the families of req, calling fn with each, and returns the rest
+// of the response. The response body is decoded as it is read, whether it is streamed or
+// a single InitialFetchResponse record.
+func (t *HTTPTransport) streamInitial(ctx context.Context, req *model.InitialFetchRequest, fn func(*model.FigFamily) error) (*model.InitialFetchResponse, error) {
 	endpoint := fmt.Sprintf("%s/data/initial", t.baseURL)
-	scheme, err := avro.Parse(model.Schema)
+	scheme, err := protocolSchema()
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse schema: %w", err)
 	}
@@ -52,7 +158,7 @@ func (t *HTTPTransport) FetchInitial(ctx context.Context, req *model.InitialFetc
 
 	// Use OCF for request
 	var buf bytes.Buffer
-	enc, err := ocf.NewEncoder(reqSchema.String(), &buf)
+	enc, err := ocf.NewEncoderWithSchema(reqSchema, &buf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OCF encoder: %w", err)
 	}
@@ -62,43 +168,85 @@ func (t *HTTPTransport) FetchInitial(ctx context.Context, req *model.InitialFetc
 	if err := enc.Flush(); err != nil {
 		return nil, fmt.Errorf("failed to flush OCF encoder: %w", err)
 	}
-	// OCF encoder writes to buf
 
-	respBytes, err := t.doRequest(ctx, endpoint, buf.Bytes())
+	httpResp, err := t.post(ctx, endpoint, buf.Bytes(), "application/octet-stream", StreamedFamiliesContentType+", application/octet-stream")
 	if err != nil {
 		return nil, err
 	}
+	defer httpResp.Body.Close()
 
 	// Use OCF for response
-	dec, err := ocf.NewDecoder(bytes.NewReader(respBytes))
+	dec, err := newOCFDecoder(httpResp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OCF decoder: %w", err)
 	}
 
 	var resp model.InitialFetchResponse
-	if dec.HasNext() {
-		if err := dec.Decode(&resp); err != nil {
+	if named, ok := dec.Schema().(avro.NamedSchema); ok && named.Name() == "FigFamily" {
+		meta := dec.Metadata()
+		resp.Cursor = string(meta[CursorMetadataKey])
+		resp.EnvironmentID = string(meta[EnvironmentMetadataKey])
+		for dec.HasNext() {
+			var ff model.FigFamily
+			if err := dec.Decode(&ff); err != nil {
+				return nil, fmt.Errorf("failed to decode response: %w", err)
+			}
+			if err := fn(&ff); err != nil {
+				return nil, err
+			}
+		}
+		if err := dec.Error(); err != nil {
 			return nil, fmt.Errorf("failed to decode response: %w", err)
 		}
-	} else {
-		return nil, fmt.Errorf("empty response")
+		return &resp, nil
 	}
 
+	if !dec.HasNext() {
+		if err := dec.Error(); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil, fmt.Errorf("empty response")
+	}
+	if err := dec.Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	for i := range resp.FigFamilies {
+		if err := fn(&resp.FigFamilies[i]); err != nil {
+			return nil, err
+		}
+	}
+	resp.FigFamilies = nil
 	return &resp, nil
 }
 
 func (t *HTTPTransport) FetchUpdate(ctx context.Context, req *model.UpdateFetchRequest) (*model.UpdateFetchResponse, error) {
 	endpoint := fmt.Sprintf("%s/data/updates", t.baseURL)
-	scheme, err := avro.Parse(model.Schema)
+	scheme, err := protocolSchema()
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse schema: %w", err)
 	}
 
+	if t.singleObject && !t.singleObjectRejected.Load() && t.serverSupports(CapabilitySingleObjectEncoding) {
+		resp, err := t.fetchUpdateSingleObject(ctx, endpoint, scheme, req)
+		var te *TransportError
+		switch {
+		case errors.As(err, &te) && te.StatusCode == http.StatusUnsupportedMediaType:
+			logging.Printf("Server doesn't support single-object encoding; falling back to OCF")
+		case errors.Is(err, errFingerprintMismatch):
+			// The server's schema differs from the client's, e.g. in fields added since,
+			// and only OCF carries the writer schema needed to resolve them
+			logging.Printf("Server encodes updates with a different schema; falling back to OCF")
+		default:
+			return resp, err
+		}
+		t.singleObjectRejected.Store(true)
+	}
+
 	reqSchema := findSchemaByName(scheme, "UpdateFetchRequest")
 
 	// Use OCF for request
 	var buf bytes.Buffer
-	enc, err := ocf.NewEncoder(reqSchema.String(), &buf)
+	enc, err := ocf.NewEncoderWithSchema(reqSchema, &buf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OCF encoder: %w", err)
 	}
@@ -114,22 +262,117 @@ func (t *HTTPTransport) FetchUpdate(ctx context.Context, req *model.UpdateFetchR
 		return nil, err
 	}
 
-	// Use OCF for response
-	dec, err := ocf.NewDecoder(bytes.NewReader(respBytes))
+	var resp model.UpdateFetchResponse
+	if err := decodeOCFResponse(respBytes, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// fetchUpdateSingleObject fetches an update with a single-object encoded request. The
+// server may answer with either encoding.
+func (t *HTTPTransport) fetchUpdateSingleObject(ctx context.Context, endpoint string, scheme avro.Schema, req *model.UpdateFetchRequest) (*model.UpdateFetchResponse, error) {
+	reqBytes, err := encodeSingleObject(findSchemaByName(scheme, "UpdateFetchRequest"), req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OCF decoder: %w", err)
+		return nil, fmt.Errorf("failed to encode request: %w", err)
 	}
+	httpResp, err := t.post(ctx, endpoint, reqBytes, SingleObjectContentType, SingleObjectContentType+", application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
 
+	respBytes, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
 	var resp model.UpdateFetchResponse
+	if !isSingleObject(httpResp) {
+		if err := decodeOCFResponse(respBytes, &resp); err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	}
+	if err := decodeSingleObject(findSchemaByName(scheme, "UpdateFetchResponse"), respBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &resp, nil
+}
+
+// protocolSchema returns the parsed protocol schema. It is parsed once, with a cache of
+// its own: avro's global schema cache isn't safe for concurrent use.
+var protocolSchema = sync.OnceValues(func() (avro.Schema, error) {
+	return avro.ParseWithCache(model.Schema, "", &avro.SchemaCache{})
+})
+
+// newOCFDecoder returns an OCF decoder of r, parsing the writer schema of its header with
+// a cache of its own, like protocolSchema. The cache holds the protocol's types, which
+// writer schemas may reference by name.
+func newOCFDecoder(r io.Reader) (*ocf.Decoder, error) {
+	cache := &avro.SchemaCache{}
+	if _, err := avro.ParseWithCache(model.Schema, "", cache); err != nil {
+		return nil, err
+	}
+	return ocf.NewDecoder(r, ocf.WithDecoderSchemaCache(cache))
+}
+
+// decodeOCFResponse decodes the single record of an OCF response body into v.
+func decodeOCFResponse(data []byte, v any) error {
+	dec, err := newOCFDecoder(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create OCF decoder: %w", err)
+	}
+	if !dec.HasNext() {
+		return fmt.Errorf("empty response")
+	}
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (t *HTTPTransport) FetchFamily(ctx context.Context, namespace, key string) (*model.FigFamily, error) {
+	query := url.Values{}
+	query.Set("namespace", namespace)
+	query.Set("key", key)
+	query.Set("environmentId", t.environmentID)
+	endpoint := fmt.Sprintf("%s/data/family?%s", t.baseURL, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	token, err := t.tokenProvider.GetToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fig family %s/%s: %w", namespace, key, newTransportError(resp, bodyBytes, t.clock.Now()))
+	}
+
+	dec, err := newOCFDecoder(bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCF decoder: %w", err)
+	}
+	var figFamily model.FigFamily
 	if dec.HasNext() {
-		if err := dec.Decode(&resp); err != nil {
+		if err := dec.Decode(&figFamily); err != nil {
 			return nil, fmt.Errorf("failed to decode response: %w", err)
 		}
 	} else {
 		return nil, fmt.Errorf("empty response")
 	}
-
-	return &resp, nil
+	return &figFamily, nil
 }
 
 func (t *HTTPTransport) GetNamespaceKey(ctx context.Context, namespace string) ([]*model.NamespaceKey, error) {
@@ -142,12 +385,12 @@ func (t *HTTPTransport) GetNamespaceKey(ctx context.Context, namespace string) (
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	token, err := t.tokenProvider.GetToken()
+	token, err := t.tokenProvider.GetToken(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get auth token: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
-	resp, err := t.client.Do(req)
+	resp, err := t.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -158,7 +401,7 @@ func (t *HTTPTransport) GetNamespaceKey(ctx context.Context, namespace string) (
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned error %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, newTransportError(resp, bodyBytes, t.clock.Now())
 	}
 
 	var nsKeys []*model.NamespaceKey
@@ -183,12 +426,12 @@ func (t *HTTPTransport) UploadPublicKey(ctx context.Context, key *model.UserPubl
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	token, err := t.tokenProvider.GetToken()
+	token, err := t.tokenProvider.GetToken(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get auth token: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
-	resp, err := t.client.Do(req)
+	resp, err := t.do(req)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
@@ -196,34 +439,204 @@ func (t *HTTPTransport) UploadPublicKey(ctx context.Context, key *model.UserPubl
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned error %d: %s", resp.StatusCode, string(bodyBytes))
+		return newTransportError(resp, bodyBytes, t.clock.Now())
 	}
 	return nil
 }
 
-func (t *HTTPTransport) Close() error {
+func (t *HTTPTransport) ListPublicKeys(ctx context.Context, email string) ([]*model.UserPublicKey, error) {
+	query := url.Values{}
+	query.Set("email", email)
+	endpoint := fmt.Sprintf("%s/keys/public?%s", t.baseURL, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	token, err := t.tokenProvider.GetToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newTransportError(resp, bodyBytes, t.clock.Now())
+	}
+
+	var keys []*model.UserPublicKey
+	if err := json.Unmarshal(bodyBytes, &keys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return keys, nil
+}
+
+func (t *HTTPTransport) DeletePublicKey(ctx context.Context, keyID string) error {
+	endpoint := fmt.Sprintf("%s/keys/public/%s", t.baseURL, url.PathEscape(keyID))
+	req, err := http.NewRequestWithContext(ctx, "DELETE", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	token, err := t.tokenProvider.GetToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get auth token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("public key %s: %w", keyID, newTransportError(resp, bodyBytes, t.clock.Now()))
+	}
 	return nil
 }
 
-func (t *HTTPTransport) doRequest(ctx context.Context, urlStr string, reqBytes []byte) ([]byte, error) {
-	u, err := url.Parse(urlStr)
+func (t *HTTPTransport) FetchSchema(ctx context.Context, uri string) (string, error) {
+	base, err := url.Parse(t.baseURL + "/")
 	if err != nil {
-		return nil, fmt.Errorf("invalid url: %w", err)
+		return "", fmt.Errorf("invalid base url: %w", err)
+	}
+	u, err := base.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid schema uri: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	// Only send credentials to the FigChain server
+	if u.Host == base.Host {
+		token, err := t.tokenProvider.GetToken(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get auth token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
+	resp, err := t.do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(reqBytes))
+	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("schema %s: %w", uri, newTransportError(resp, bodyBytes, t.clock.Now()))
 	}
+	return string(bodyBytes), nil
+}
 
-	req.Header.Set("Content-Type", "application/octet-stream")
-	token, err := t.tokenProvider.GetToken()
+func (t *HTTPTransport) ReportUsage(ctx context.Context, report *model.UsageReport) error {
+	jsonBytes, err := json.Marshal(report)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get auth token: %w", err)
+		return fmt.Errorf("failed to marshal usage report: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL+"/usage", bytes.NewReader(jsonBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	token, err := t.tokenProvider.GetToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get auth token: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return newTransportError(resp, bodyBytes, t.clock.Now())
+	}
+	return nil
+}
+
+// publishBody is the JSON body of a publish request.
+type publishBody struct {
+	EnvironmentID  string        `json:"environmentId"`
+	Version        string        `json:"version"`
+	Payload        []byte        `json:"payload"`
+	IsEncrypted    bool          `json:"isEncrypted,omitempty"`
+	WrappedDek     []byte        `json:"wrappedDek,omitempty"`
+	KeyID          string        `json:"keyId,omitempty"`
+	Rules          []publishRule `json:"rules"` // null keeps the family's rules
+	DefaultVersion *string       `json:"defaultVersion"`
+}
+
+type publishRule struct {
+	Description   *string            `json:"description,omitempty"`
+	Conditions    []publishCondition `json:"conditions"`
+	TargetVersion string             `json:"targetVersion"`
+}
+
+type publishCondition struct {
+	Variable string   `json:"variable"`
+	Operator string   `json:"operator"`
+	Values   []string `json:"values"`
+}
 
-	resp, err := t.client.Do(req)
+func newPublishBody(req *model.PublishRequest) *publishBody {
+	body := &publishBody{
+		EnvironmentID:  req.EnvironmentID,
+		Version:        req.Version,
+		Payload:        req.Payload,
+		IsEncrypted:    req.IsEncrypted,
+		WrappedDek:     req.WrappedDek,
+		KeyID:          req.KeyID,
+		DefaultVersion: req.DefaultVersion,
+	}
+	if req.Rules != nil {
+		body.Rules = make([]publishRule, len(req.Rules))
+		for i, r := range req.Rules {
+			conditions := make([]publishCondition, len(r.Conditions))
+			for j, c := range r.Conditions {
+				conditions[j] = publishCondition{Variable: c.Variable, Operator: c.Operator, Values: c.Values}
+			}
+			body.Rules[i] = publishRule{Description: r.Description, Conditions: conditions, TargetVersion: r.TargetVersion}
+		}
+	}
+	return body
+}
+
+func (t *HTTPTransport) PublishFig(ctx context.Context, req *model.PublishRequest) (*model.PublishResponse, error) {
+	jsonBytes, err := json.Marshal(newPublishBody(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal publish request: %w", err)
+	}
+	endpoint := fmt.Sprintf("%s/admin/figs/%s/%s/versions", t.baseURL, url.PathEscape(req.Namespace), url.PathEscape(req.Key))
+	if req.DryRun {
+		endpoint += "?dryRun=true"
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if req.IdempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", req.IdempotencyKey)
+	}
+	token, err := t.tokenProvider.GetToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -233,14 +646,78 @@ func (t *HTTPTransport) doRequest(ctx context.Context, urlStr string, reqBytes [
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	// 202 Accepted means that the version awaits approval
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("publish %s/%s: %w", req.Namespace, req.Key, newTransportError(resp, bodyBytes, t.clock.Now()))
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned error %d: %s", resp.StatusCode, string(bodyBytes))
+	var published model.PublishResponse
+	if err := json.Unmarshal(bodyBytes, &published); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if published.Version == "" {
+		published.Version = req.Version
+	}
+	return &published, nil
+}
+
+func (t *HTTPTransport) Close() error {
+	return nil
+}
+
+func (t *HTTPTransport) doRequest(ctx context.Context, urlStr string, reqBytes []byte) ([]byte, error) {
+	resp, err := t.post(ctx, urlStr, reqBytes, "application/octet-stream", "")
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
 	return bodyBytes, nil
 }
 
+// post posts reqBytes, of contentType, to urlStr and returns the response if it is
+// successful. The caller must close its body. accept, if set, is sent as the Accept
+// header.
+func (t *HTTPTransport) post(ctx context.Context, urlStr string, reqBytes []byte, contentType, accept string) (*http.Response, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	token, err := t.tokenProvider.GetToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := t.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		return nil, newTransportError(resp, bodyBytes, t.clock.Now())
+	}
+	return resp, nil
+}
+
 func findSchemaByName(root avro.Schema, name string) avro.Schema {
 	if union, ok := root.(*avro.UnionSchema); ok {
 		for _, s := range union.Types() {
@@ -3,10 +3,17 @@ package transport
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/figchain/go-client/pkg/figtest"
 	"github.com/figchain/go-client/pkg/model"
 	"github.com/hamba/avro/v2"
 	"github.com/hamba/avro/v2/ocf"
@@ -86,6 +93,65 @@ func TestHTTPTransport_FetchInitial(t *testing.T) {
 	}
 }
 
+func TestHTTPTransport_StreamInitial(t *testing.T) {
+	scheme, _ := avro.Parse(model.Schema)
+	familySchema := findSchemaByName(scheme, "FigFamily")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), StreamedFamiliesContentType) {
+			t.Errorf("Expected Accept to include %s, got %q", StreamedFamiliesContentType, r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", StreamedFamiliesContentType)
+		enc, err := ocf.NewEncoder(familySchema.String(), w, ocf.WithMetadata(map[string][]byte{
+			CursorMetadataKey:      []byte("cursor-123"),
+			EnvironmentMetadataKey: []byte("env-1"),
+		}))
+		if err != nil {
+			t.Errorf("Failed to create OCF encoder: %v", err)
+			return
+		}
+		// One block per family, as a server streaming them would write
+		for _, key := range []string{"fig-1", "fig-2", "fig-3"} {
+			enc.Encode(&model.FigFamily{Definition: model.FigDefinition{Key: key, Namespace: "ns-1"}})
+			enc.Flush()
+		}
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1")
+	req := &model.InitialFetchRequest{Namespace: "ns-1", EnvironmentID: "env-1"}
+
+	var keys []string
+	cursor, err := tr.StreamInitial(context.Background(), req, func(ff *model.FigFamily) error {
+		keys = append(keys, ff.Definition.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamInitial failed: %v", err)
+	}
+	if cursor != "cursor-123" || !reflect.DeepEqual(keys, []string{"fig-1", "fig-2", "fig-3"}) {
+		t.Errorf("StreamInitial() = %q with %v", cursor, keys)
+	}
+
+	resp, err := tr.FetchInitial(context.Background(), req)
+	if err != nil {
+		t.Fatalf("FetchInitial failed: %v", err)
+	}
+	if resp.Cursor != "cursor-123" || resp.EnvironmentID != "env-1" || len(resp.FigFamilies) != 3 {
+		t.Errorf("FetchInitial() = %+v, want the streamed families", resp)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	_, err = tr.StreamInitial(context.Background(), req, func(*model.FigFamily) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("StreamInitial() = %v after %d calls, want the callback's error after one", err, calls)
+	}
+}
+
 func TestHTTPTransport_FetchUpdate(t *testing.T) {
 	mockResp := &model.UpdateFetchResponse{
 		Cursor: "cursor-456",
@@ -169,3 +235,467 @@ func TestHTTPTransport_Error(t *testing.T) {
 		t.Error("Expected error, got nil")
 	}
 }
+
+func TestHTTPTransport_FetchFamily(t *testing.T) {
+	mockResp := &model.FigFamily{
+		Definition: model.FigDefinition{Key: "fig-1", Namespace: "ns-1"},
+	}
+
+	scheme, _ := avro.Parse(model.Schema)
+	respSchema := findSchemaByName(scheme, "FigFamily")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("Expected GET request, got %s", r.Method)
+		}
+		if r.URL.Path != "/data/family" {
+			t.Errorf("Expected path /data/family, got %s", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("namespace") != "ns-1" || q.Get("environmentId") != "env-1" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		if q.Get("key") != "fig-1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var buf bytes.Buffer
+		enc, _ := ocf.NewEncoder(respSchema.String(), &buf)
+		enc.Encode(mockResp)
+		enc.Flush()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1")
+
+	ff, err := tr.FetchFamily(context.Background(), "ns-1", "fig-1")
+	if err != nil {
+		t.Fatalf("FetchFamily failed: %v", err)
+	}
+	if ff.Definition.Key != "fig-1" {
+		t.Errorf("Expected key fig-1, got %s", ff.Definition.Key)
+	}
+
+	if _, err := tr.FetchFamily(context.Background(), "ns-1", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestHTTPTransport_FetchSchema(t *testing.T) {
+	const schema = `{"type":"record","name":"Cfg","fields":[{"name":"enabled","type":"boolean"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected Authorization header Bearer secret, got %s", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path != "/api/schemas/cfg" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(schema))
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL+"/api", NewSharedSecretTokenProvider("secret"), "env-1")
+	for _, uri := range []string{"schemas/cfg", server.URL + "/api/schemas/cfg"} {
+		got, err := tr.FetchSchema(context.Background(), uri)
+		if err != nil {
+			t.Fatalf("FetchSchema(%s) failed: %v", uri, err)
+		}
+		if got != schema {
+			t.Errorf("FetchSchema(%s) = %s, want %s", uri, got, schema)
+		}
+	}
+	if _, err := tr.FetchSchema(context.Background(), "schemas/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FetchSchema(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestHTTPTransport_PublishFig(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/admin/figs/ns-1/my key/versions" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected Authorization header Bearer secret, got %s", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"version":"v2"}`))
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1")
+	resp, err := tr.PublishFig(context.Background(), &model.PublishRequest{
+		Namespace:     "ns-1",
+		Key:           "my key",
+		EnvironmentID: "env-1",
+		Version:       "v2",
+		Payload:       []byte("\x06foo"),
+		Rules: []model.Rule{{
+			Conditions:    []model.Condition{{Variable: "region", Operator: "IN", Values: []string{"eu"}}},
+			TargetVersion: "v2",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("PublishFig failed: %v", err)
+	}
+	if resp.Version != "v2" {
+		t.Errorf("PublishFig() version = %s, want v2", resp.Version)
+	}
+	want := map[string]any{
+		"environmentId": "env-1",
+		"version":       "v2",
+		"payload":       "BmZvbw==",
+		"rules": []any{map[string]any{
+			"conditions":    []any{map[string]any{"variable": "region", "operator": "IN", "values": []any{"eu"}}},
+			"targetVersion": "v2",
+		}},
+		"defaultVersion": nil,
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("PublishFig() sent %v, want %v", body, want)
+	}
+}
+
+func TestHTTPTransport_Namespaces(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected Authorization header Bearer secret, got %s", r.Header.Get("Authorization"))
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/admin/namespaces" && r.URL.Query().Get("environmentId") == "env-1":
+			json.NewEncoder(w).Encode([]model.NamespaceInfo{{Name: "default", KeyCount: 3, LastUpdated: updated}, {Name: "payments", Encrypted: true}})
+		case r.Method == "GET" && r.URL.Path == "/admin/namespaces/default":
+			json.NewEncoder(w).Encode(model.NamespaceInfo{Name: "default", KeyCount: 3, LastUpdated: updated})
+		case r.Method == "POST" && r.URL.Path == "/admin/namespaces":
+			var req model.CreateNamespaceRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Name == "default" {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(model.NamespaceInfo{Name: req.Name, Description: req.Description})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1")
+	ctx := context.Background()
+	namespaces, err := tr.ListNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("ListNamespaces failed: %v", err)
+	}
+	if len(namespaces) != 2 || namespaces[0].KeyCount != 3 || !namespaces[0].LastUpdated.Equal(updated) || !namespaces[1].Encrypted {
+		t.Errorf("ListNamespaces() = %+v", namespaces)
+	}
+
+	ns, err := tr.GetNamespace(ctx, "default")
+	if err != nil || ns.Name != "default" || ns.KeyCount != 3 {
+		t.Errorf("GetNamespace(default) = %+v, %v", ns, err)
+	}
+	if _, err := tr.GetNamespace(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetNamespace(missing) error = %v, want ErrNotFound", err)
+	}
+
+	ns, err = tr.CreateNamespace(ctx, &model.CreateNamespaceRequest{EnvironmentID: "env-1", Name: "billing", Description: "Billing"})
+	if err != nil || ns.Name != "billing" || ns.Description != "Billing" {
+		t.Errorf("CreateNamespace(billing) = %+v, %v", ns, err)
+	}
+	if _, err := tr.CreateNamespace(ctx, &model.CreateNamespaceRequest{EnvironmentID: "env-1", Name: "default"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("CreateNamespace(default) error = %v, want ErrConflict", err)
+	}
+}
+
+func TestHTTPTransport_TokenProviderContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request sent without a token")
+	}))
+	defer server.Close()
+
+	// A slow provider gives up when the request context is done
+	provider := TokenProviderFunc(func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	tr := NewHTTPTransport(server.Client(), server.URL, provider, "env-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := tr.FetchFamily(ctx, "ns-1", "fig-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestHTTPTransport_TransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("key") {
+		case "limited":
+			w.Header().Set("Retry-After", "7")
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "1700000000")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"code":"rate_limited","message":"slow down"}`))
+		case "forbidden":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("no access"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1")
+
+	_, err := tr.FetchFamily(context.Background(), "ns-1", "limited")
+	var te *TransportError
+	if !errors.As(err, &te) {
+		t.Fatalf("Expected a TransportError, got %v", err)
+	}
+	if te.StatusCode != http.StatusTooManyRequests || te.Code != "rate_limited" || te.Message != "slow down" {
+		t.Errorf("Unexpected TransportError %+v", te)
+	}
+	if te.RetryAfter != 7*time.Second || te.RateLimit.Limit != 100 || te.RateLimit.Remaining != 0 || !te.RateLimit.Reset.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected rate limit metadata %+v", te)
+	}
+	if !Retryable(err) {
+		t.Error("Expected a rate-limited request to be retryable")
+	}
+
+	_, err = tr.FetchFamily(context.Background(), "ns-1", "forbidden")
+	if !errors.As(err, &te) || te.StatusCode != http.StatusForbidden || te.Body != "no access" {
+		t.Errorf("Expected a 403 TransportError, got %v", err)
+	}
+	if Retryable(err) {
+		t.Error("Expected a forbidden request not to be retryable")
+	}
+
+	_, err = tr.FetchFamily(context.Background(), "ns-1", "missing")
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &te) {
+		t.Errorf("Expected a TransportError matching ErrNotFound, got %v", err)
+	}
+
+	down := NewHTTPTransport(server.Client(), "http://127.0.0.1:1", NewSharedSecretTokenProvider("secret"), "env-1")
+	if _, err := down.FetchFamily(context.Background(), "ns-1", "fig-1"); !Retryable(err) {
+		t.Errorf("Expected a network error to be retryable, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-1", 0},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPTransport_RetryAfterClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", now.Add(time.Minute).Format(http.TimeFormat))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// The Retry-After date is read against the transport's clock
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1",
+		WithClock(figtest.NewFakeClock(now)))
+	_, err := tr.FetchFamily(context.Background(), "ns-1", "fig-1")
+	var te *TransportError
+	if !errors.As(err, &te) || te.RetryAfter != time.Minute {
+		t.Errorf("FetchFamily() error = %v, want a TransportError retrying after a minute", err)
+	}
+}
+
+func TestHTTPTransport_SingleObjectEncoding(t *testing.T) {
+	scheme, _ := avro.Parse(model.Schema)
+	reqSchema := findSchemaByName(scheme, "UpdateFetchRequest")
+	respSchema := findSchemaByName(scheme, "UpdateFetchResponse")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != SingleObjectContentType {
+			t.Errorf("Expected a single-object encoded request, got %s", r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req model.UpdateFetchRequest
+		if err := decodeSingleObject(reqSchema, body, &req); err != nil || req.Cursor != "cursor-1" {
+			t.Errorf("Request decoded to %+v, %v", req, err)
+		}
+		resp, _ := encodeSingleObject(respSchema, &model.UpdateFetchResponse{
+			Cursor:      "cursor-2",
+			FigFamilies: []model.FigFamily{{Definition: model.FigDefinition{Key: "fig-1", Namespace: "ns-1"}}},
+		})
+		w.Header().Set("Content-Type", SingleObjectContentType)
+		w.Write(resp)
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1", WithSingleObjectEncoding(true))
+	resp, err := tr.FetchUpdate(context.Background(), &model.UpdateFetchRequest{Namespace: "ns-1", Cursor: "cursor-1", EnvironmentID: "env-1"})
+	if err != nil {
+		t.Fatalf("FetchUpdate failed: %v", err)
+	}
+	if resp.Cursor != "cursor-2" || len(resp.FigFamilies) != 1 {
+		t.Errorf("FetchUpdate() = %+v", resp)
+	}
+
+	// The value must have been written with the expected schema
+	encoded, _ := encodeSingleObject(reqSchema, &model.UpdateFetchRequest{})
+	if err := decodeSingleObject(respSchema, encoded, &model.UpdateFetchResponse{}); err == nil {
+		t.Error("Expected a fingerprint mismatch to fail")
+	}
+}
+
+func TestHTTPTransport_SingleObjectEncodingFallback(t *testing.T) {
+	scheme, _ := avro.Parse(model.Schema)
+	respSchema := findSchemaByName(scheme, "UpdateFetchResponse")
+
+	var singleObjectRequests, ocfRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") == SingleObjectContentType {
+			singleObjectRequests++
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		ocfRequests++
+		enc, _ := ocf.NewEncoder(respSchema.String(), w)
+		enc.Encode(&model.UpdateFetchResponse{Cursor: "cursor-2"})
+		enc.Flush()
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1", WithSingleObjectEncoding(true))
+	for range 2 {
+		resp, err := tr.FetchUpdate(context.Background(), &model.UpdateFetchRequest{Namespace: "ns-1", Cursor: "cursor-1"})
+		if err != nil || resp.Cursor != "cursor-2" {
+			t.Fatalf("FetchUpdate() = %+v, %v", resp, err)
+		}
+	}
+	if singleObjectRequests != 1 || ocfRequests != 2 {
+		t.Errorf("Got %d single-object and %d OCF requests, want OCF once the server rejected single-object encoding", singleObjectRequests, ocfRequests)
+	}
+}
+
+func TestHTTPTransport_SingleObjectSchemaMismatch(t *testing.T) {
+	// The server's schema has a field the client's doesn't
+	cache := &avro.SchemaCache{}
+	if _, err := avro.ParseWithCache(model.Schema, "", cache); err != nil {
+		t.Fatal(err)
+	}
+	serverSchema, err := avro.ParseWithCache(`{"type": "record", "name": "UpdateFetchResponse", "namespace": "io.figchain.avro.model", "fields": [
+		{"name": "figFamilies", "type": {"type": "array", "items": "FigFamily"}},
+		{"name": "cursor", "type": "string"},
+		{"name": "expiresAt", "type": ["null", "long"], "default": null}
+	]}`, "", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type serverResponse struct {
+		FigFamilies []model.FigFamily `avro:"figFamilies"`
+		Cursor      string            `avro:"cursor"`
+		ExpiresAt   *int64            `avro:"expiresAt"`
+	}
+	expiresAt := int64(1700000000000)
+	resp := &serverResponse{Cursor: "cursor-2", ExpiresAt: &expiresAt}
+
+	var singleObjectRequests, ocfRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") == SingleObjectContentType {
+			singleObjectRequests++
+			body, _ := encodeSingleObject(serverSchema, resp)
+			w.Header().Set("Content-Type", SingleObjectContentType)
+			w.Write(body)
+			return
+		}
+		ocfRequests++
+		enc, _ := ocf.NewEncoderWithSchema(serverSchema, w)
+		enc.Encode(resp)
+		enc.Flush()
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1", WithSingleObjectEncoding(true))
+	for range 2 {
+		resp, err := tr.FetchUpdate(context.Background(), &model.UpdateFetchRequest{Namespace: "ns-1", Cursor: "cursor-1"})
+		if err != nil || resp.Cursor != "cursor-2" {
+			t.Fatalf("FetchUpdate() = %+v, %v", resp, err)
+		}
+	}
+	if singleObjectRequests != 1 || ocfRequests != 2 {
+		t.Errorf("Got %d single-object and %d OCF requests, want OCF once the schemas were found to differ", singleObjectRequests, ocfRequests)
+	}
+}
+
+func TestHTTPTransport_ProtocolNegotiation(t *testing.T) {
+	scheme, _ := avro.Parse(model.Schema)
+	respSchema := findSchemaByName(scheme, "UpdateFetchResponse")
+
+	negotiate := false
+	var contentTypes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(ProtocolVersionHeader) != "1" || r.Header.Get(CapabilitiesHeader) != "streamed-initial-fetch, single-object-encoding" {
+			t.Errorf("Unexpected protocol headers %q, %q", r.Header.Get(ProtocolVersionHeader), r.Header.Get(CapabilitiesHeader))
+		}
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		if negotiate {
+			w.Header().Set(ProtocolVersionHeader, "3")
+			w.Header().Set(CapabilitiesHeader, "delta-updates, streamed-initial-fetch")
+		}
+		if r.Header.Get("Content-Type") == SingleObjectContentType {
+			resp, _ := encodeSingleObject(respSchema, &model.UpdateFetchResponse{Cursor: "cursor-2"})
+			w.Header().Set("Content-Type", SingleObjectContentType)
+			w.Write(resp)
+			return
+		}
+		enc, _ := ocf.NewEncoder(respSchema.String(), w)
+		enc.Encode(&model.UpdateFetchResponse{Cursor: "cursor-2"})
+		enc.Flush()
+	}))
+	defer server.Close()
+
+	tr := NewHTTPTransport(server.Client(), server.URL, NewSharedSecretTokenProvider("secret"), "env-1", WithSingleObjectEncoding(true))
+	req := &model.UpdateFetchRequest{Namespace: "ns-1", Cursor: "cursor-1"}
+
+	// A server that doesn't negotiate is assumed to support the transport's features
+	if _, err := tr.FetchUpdate(context.Background(), req); err != nil {
+		t.Fatalf("FetchUpdate failed: %v", err)
+	}
+	if p := tr.Protocol(); p.Version != 0 || len(p.Capabilities) != 0 {
+		t.Errorf("Protocol() = %+v before negotiation", p)
+	}
+
+	negotiate = true
+	for range 2 {
+		if _, err := tr.FetchUpdate(context.Background(), req); err != nil {
+			t.Fatalf("FetchUpdate failed: %v", err)
+		}
+	}
+	want := Protocol{Version: 3, Capabilities: []string{CapabilityStreamedInitialFetch}}
+	if p := tr.Protocol(); !reflect.DeepEqual(p, want) {
+		t.Errorf("Protocol() = %+v, want %+v", p, want)
+	}
+	// Single-object encoding stops once the server negotiated without it
+	wantTypes := []string{SingleObjectContentType, SingleObjectContentType, "application/octet-stream"}
+	if !reflect.DeepEqual(contentTypes, wantTypes) {
+		t.Errorf("Request content types = %v, want %v", contentTypes, wantTypes)
+	}
+}
@@ -0,0 +1,519 @@
+package transporttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// errInvalidCursor is returned by Updates for cursors the backend didn't issue.
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Backend is an in-memory FigChain server holding the data TestTransport checks a
+// transport against. HTTP transports can be served by Handler; other transports, e.g.
+// over gRPC, serve the Backend through their own server in their tests, calling its
+// methods for each request. It is safe for concurrent use.
+//
+// The cursor of a namespace is the number of changes made to it, so that Updates
+// returns the families changed since.
+type Backend struct {
+	EnvironmentID string
+
+	mu            sync.Mutex
+	changes       map[string][]model.FigFamily // by namespace, oldest first
+	namespaces    map[string]*model.NamespaceInfo
+	namespaceKeys map[string][]*model.NamespaceKey
+	schemas       map[string]string
+	publicKeys    []*model.UserPublicKey
+	usage         []*model.UsageReport
+	nextKeyID     int
+}
+
+// NewBackend creates an empty Backend of an environment.
+func NewBackend(environmentID string) *Backend {
+	return &Backend{
+		EnvironmentID: environmentID,
+		changes:       make(map[string][]model.FigFamily),
+		namespaces:    make(map[string]*model.NamespaceInfo),
+		namespaceKeys: make(map[string][]*model.NamespaceKey),
+		schemas:       make(map[string]string),
+	}
+}
+
+// Put changes families, creating their namespaces if needed.
+func (b *Backend) Put(families ...model.FigFamily) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ff := range families {
+		b.putLocked(ff)
+	}
+}
+
+func (b *Backend) putLocked(ff model.FigFamily) {
+	ns := ff.Definition.Namespace
+	info, ok := b.namespaces[ns]
+	if !ok {
+		info = &model.NamespaceInfo{Name: ns}
+		b.namespaces[ns] = info
+	}
+	if _, ok := b.familyLocked(ns, ff.Definition.Key); !ok {
+		info.KeyCount++
+	}
+	info.LastUpdated = time.Now().UTC().Truncate(time.Second)
+	b.changes[ns] = append(b.changes[ns], ff)
+}
+
+// SetNamespaceKeys sets the wrapped keys of a namespace.
+func (b *Backend) SetNamespaceKeys(namespace string, keys ...*model.NamespaceKey) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.namespaceKeys[namespace] = keys
+}
+
+// SetSchema serves schema at path, relative to the base URL.
+func (b *Backend) SetSchema(path, schema string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.schemas[strings.TrimPrefix(path, "/")] = schema
+}
+
+// Initial returns the current families of a namespace and its cursor.
+func (b *Backend) Initial(namespace string) ([]model.FigFamily, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	changes := b.changes[namespace]
+	return latest(changes), strconv.Itoa(len(changes))
+}
+
+// Updates returns the families of a namespace changed after cursor and the new cursor.
+func (b *Backend) Updates(namespace, cursor string) ([]model.FigFamily, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	changes := b.changes[namespace]
+	n, err := strconv.Atoi(cursor)
+	if err != nil || n < 0 || n > len(changes) {
+		return nil, "", fmt.Errorf("%w %q", errInvalidCursor, cursor)
+	}
+	return latest(changes[n:]), strconv.Itoa(len(changes)), nil
+}
+
+// latest returns the last change of each family in changes, in the order they changed.
+func latest(changes []model.FigFamily) []model.FigFamily {
+	var families []model.FigFamily
+	for i, ff := range changes {
+		superseded := slices.ContainsFunc(changes[i+1:], func(later model.FigFamily) bool {
+			return later.Definition.Key == ff.Definition.Key
+		})
+		if !superseded {
+			families = append(families, ff)
+		}
+	}
+	return families
+}
+
+// Family returns the current family at key.
+func (b *Backend) Family(namespace, key string) (*model.FigFamily, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.familyLocked(namespace, key)
+}
+
+func (b *Backend) familyLocked(namespace, key string) (*model.FigFamily, bool) {
+	changes := b.changes[namespace]
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Definition.Key == key {
+			ff := changes[i]
+			return &ff, true
+		}
+	}
+	return nil, false
+}
+
+// NamespaceKeys returns the wrapped keys of a namespace.
+func (b *Backend) NamespaceKeys(namespace string) []*model.NamespaceKey {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.namespaceKeys[namespace]
+}
+
+// Schema returns the schema at path.
+func (b *Backend) Schema(path string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	schema, ok := b.schemas[strings.TrimPrefix(path, "/")]
+	return schema, ok
+}
+
+// Publish adds the version of req to its family, creating it if needed, and updates the
+// family's rules and default version as requested. Dry runs change nothing.
+func (b *Backend) Publish(req *model.PublishRequest) *model.PublishResponse {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	resp := &model.PublishResponse{Version: req.Version}
+	if req.DryRun {
+		return resp
+	}
+	ff := model.FigFamily{Definition: model.FigDefinition{Namespace: req.Namespace, Key: req.Key}}
+	if current, ok := b.familyLocked(req.Namespace, req.Key); ok {
+		ff = *current
+	}
+	fig := model.Fig{Version: req.Version, Payload: req.Payload, IsEncrypted: req.IsEncrypted, WrappedDek: req.WrappedDek}
+	if req.KeyID != "" {
+		fig.KeyID = &req.KeyID
+	}
+	ff.Figs = append(slices.DeleteFunc(slices.Clone(ff.Figs), func(f model.Fig) bool {
+		return f.Version == req.Version
+	}), fig)
+	if req.Rules != nil {
+		ff.Rules = req.Rules
+	}
+	if req.DefaultVersion != nil {
+		ff.DefaultVersion = req.DefaultVersion
+	}
+	b.putLocked(ff)
+	return resp
+}
+
+// Namespaces returns the namespaces of the environment, by name.
+func (b *Backend) Namespaces() []*model.NamespaceInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	namespaces := make([]*model.NamespaceInfo, 0, len(b.namespaces))
+	for _, info := range b.namespaces {
+		ns := *info
+		namespaces = append(namespaces, &ns)
+	}
+	slices.SortFunc(namespaces, func(a, b *model.NamespaceInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return namespaces
+}
+
+// Namespace returns the namespace called name.
+func (b *Backend) Namespace(name string) (*model.NamespaceInfo, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	info, ok := b.namespaces[name]
+	if !ok {
+		return nil, false
+	}
+	ns := *info
+	return &ns, true
+}
+
+// CreateNamespace creates the namespace of req. It returns false if it already exists.
+func (b *Backend) CreateNamespace(req *model.CreateNamespaceRequest) (*model.NamespaceInfo, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.namespaces[req.Name]; ok {
+		return nil, false
+	}
+	info := &model.NamespaceInfo{Name: req.Name, Description: req.Description, Encrypted: req.Encrypted}
+	b.namespaces[req.Name] = info
+	ns := *info
+	return &ns, true
+}
+
+// UploadPublicKey registers key, assigning its KeyID.
+func (b *Backend) UploadPublicKey(key *model.UserPublicKey) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextKeyID++
+	uploaded := *key
+	uploaded.KeyID = fmt.Sprintf("key-%d", b.nextKeyID)
+	b.publicKeys = append(b.publicKeys, &uploaded)
+}
+
+// PublicKeys returns the public keys registered for email.
+func (b *Backend) PublicKeys(email string) []*model.UserPublicKey {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keys := []*model.UserPublicKey{}
+	for _, key := range b.publicKeys {
+		if key.Email == email {
+			k := *key
+			keys = append(keys, &k)
+		}
+	}
+	return keys
+}
+
+// DeletePublicKey deletes a registered public key. It returns false if it doesn't exist.
+func (b *Backend) DeletePublicKey(keyID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.publicKeys)
+	b.publicKeys = slices.DeleteFunc(b.publicKeys, func(key *model.UserPublicKey) bool {
+		return key.KeyID == keyID
+	})
+	return len(b.publicKeys) < n
+}
+
+// ReportUsage records a usage report.
+func (b *Backend) ReportUsage(report *model.UsageReport) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.usage = append(b.usage, report)
+}
+
+// UsageReports returns the usage reports received.
+func (b *Backend) UsageReports() []*model.UsageReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.usage)
+}
+
+// Handler returns an http.Handler serving the Backend with the FigChain HTTP API, as
+// transport.HTTPTransport expects it. It streams initial fetches to clients accepting
+// transport.StreamedFamiliesContentType, and rejects single-object encoded requests
+// with 415 Unsupported Media Type. Requests without an Authorization header are
+// rejected with 401 Unauthorized.
+func (b *Backend) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /data/initial", b.serveInitial)
+	mux.HandleFunc("POST /data/updates", b.serveUpdates)
+	mux.HandleFunc("GET /data/family", b.serveFamily)
+	mux.HandleFunc("GET /keys/namespace/{namespace}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, b.NamespaceKeys(r.PathValue("namespace")))
+	})
+	mux.HandleFunc("PUT /keys/public", func(w http.ResponseWriter, r *http.Request) {
+		var key model.UserPublicKey
+		if !readJSON(w, r, &key) {
+			return
+		}
+		b.UploadPublicKey(&key)
+	})
+	mux.HandleFunc("GET /keys/public", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, b.PublicKeys(r.URL.Query().Get("email")))
+	})
+	mux.HandleFunc("DELETE /keys/public/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !b.DeletePublicKey(r.PathValue("id")) {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /usage", func(w http.ResponseWriter, r *http.Request) {
+		var report model.UsageReport
+		if !readJSON(w, r, &report) {
+			return
+		}
+		b.ReportUsage(&report)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /admin/figs/{namespace}/{key}/versions", b.servePublish)
+	mux.HandleFunc("GET /admin/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, b.Namespaces())
+	})
+	mux.HandleFunc("GET /admin/namespaces/{name}", func(w http.ResponseWriter, r *http.Request) {
+		ns, ok := b.Namespace(r.PathValue("name"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, ns)
+	})
+	mux.HandleFunc("POST /admin/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		var req model.CreateNamespaceRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		ns, ok := b.CreateNamespace(&req)
+		if !ok {
+			writeJSON(w, http.StatusConflict, map[string]string{"code": "conflict", "message": "namespace exists"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, ns)
+	})
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		schema, ok := b.Schema(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(schema))
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (b *Backend) serveInitial(w http.ResponseWriter, r *http.Request) {
+	var req model.InitialFetchRequest
+	if !readOCF(w, r, &req) {
+		return
+	}
+	families, cursor := b.Initial(req.Namespace)
+	if !strings.Contains(r.Header.Get("Accept"), transport.StreamedFamiliesContentType) {
+		writeOCF(w, "InitialFetchResponse", &model.InitialFetchResponse{FigFamilies: families, Cursor: cursor, EnvironmentID: b.EnvironmentID})
+		return
+	}
+	w.Header().Set("Content-Type", transport.StreamedFamiliesContentType)
+	enc, err := ocf.NewEncoder(schemaOf("FigFamily").String(), w, ocf.WithMetadata(map[string][]byte{
+		transport.CursorMetadataKey:      []byte(cursor),
+		transport.EnvironmentMetadataKey: []byte(b.EnvironmentID),
+	}))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The status is sent, so failures can only truncate the stream
+	for i := range families {
+		if enc.Encode(&families[i]) != nil || enc.Flush() != nil {
+			return
+		}
+	}
+	enc.Close()
+}
+
+func (b *Backend) serveUpdates(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), transport.SingleObjectContentType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	var req model.UpdateFetchRequest
+	if !readOCF(w, r, &req) {
+		return
+	}
+	families, cursor, err := b.Updates(req.Namespace, req.Cursor)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"code": "invalid_cursor", "message": err.Error()})
+		return
+	}
+	writeOCF(w, "UpdateFetchResponse", &model.UpdateFetchResponse{FigFamilies: families, Cursor: cursor})
+}
+
+func (b *Backend) serveFamily(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ff, ok := b.Family(q.Get("namespace"), q.Get("key"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeOCF(w, "FigFamily", ff)
+}
+
+// publishBody is the JSON body of a publish request.
+type publishBody struct {
+	Version     string `json:"version"`
+	Payload     []byte `json:"payload"`
+	IsEncrypted bool   `json:"isEncrypted"`
+	WrappedDek  []byte `json:"wrappedDek"`
+	KeyID       string `json:"keyId"`
+	Rules       []struct {
+		Description *string `json:"description"`
+		Conditions  []struct {
+			Variable string   `json:"variable"`
+			Operator string   `json:"operator"`
+			Values   []string `json:"values"`
+		} `json:"conditions"`
+		TargetVersion string `json:"targetVersion"`
+	} `json:"rules"`
+	DefaultVersion *string `json:"defaultVersion"`
+}
+
+func (b *Backend) servePublish(w http.ResponseWriter, r *http.Request) {
+	var body publishBody
+	if !readJSON(w, r, &body) {
+		return
+	}
+	req := &model.PublishRequest{
+		Namespace:      r.PathValue("namespace"),
+		Key:            r.PathValue("key"),
+		EnvironmentID:  b.EnvironmentID,
+		Version:        body.Version,
+		Payload:        body.Payload,
+		IsEncrypted:    body.IsEncrypted,
+		WrappedDek:     body.WrappedDek,
+		KeyID:          body.KeyID,
+		DefaultVersion: body.DefaultVersion,
+		DryRun:         r.URL.Query().Get("dryRun") == "true",
+	}
+	if body.Rules != nil {
+		req.Rules = make([]model.Rule, len(body.Rules))
+		for i, rule := range body.Rules {
+			req.Rules[i] = model.Rule{Description: rule.Description, TargetVersion: rule.TargetVersion}
+			for _, c := range rule.Conditions {
+				req.Rules[i].Conditions = append(req.Rules[i].Conditions, model.Condition{Variable: c.Variable, Operator: c.Operator, Values: c.Values})
+			}
+		}
+	}
+	writeJSON(w, http.StatusCreated, b.Publish(req))
+}
+
+// schemaOf returns the named schema of model.Schema.
+func schemaOf(name string) avro.Schema {
+	schema := avro.MustParse(model.Schema)
+	if union, ok := schema.(*avro.UnionSchema); ok {
+		for _, s := range union.Types() {
+			if named, ok := s.(avro.NamedSchema); ok && named.Name() == name {
+				return s
+			}
+		}
+	}
+	return schema
+}
+
+// readOCF decodes the OCF request body into v, or fails the request.
+func readOCF(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec, err := ocf.NewDecoder(r.Body)
+	if err == nil && dec.HasNext() {
+		err = dec.Decode(v)
+	} else if err == nil {
+		err = errors.New("empty request")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeOCF writes v as the single record of an OCF response.
+func writeOCF(w http.ResponseWriter, schemaName string, v any) {
+	var buf bytes.Buffer
+	enc, err := ocf.NewEncoder(schemaOf(schemaName).String(), &buf)
+	if err == nil {
+		err = enc.Encode(v)
+	}
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+// readJSON decodes the JSON request body into v, or fails the request.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
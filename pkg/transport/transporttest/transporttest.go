@@ -0,0 +1,433 @@
+// Package transporttest checks that transport.Transport implementations honour the
+// contract of the interface against an in-memory FigChain server, the Backend, so that
+// alternative transports, e.g. over gRPC, can verify themselves in their own tests:
+//
+//	func TestMyTransport(t *testing.T) {
+//		transporttest.TestTransport(t, func(t *testing.T, b *transporttest.Backend) transport.Transport {
+//			srv := mytransport.NewTestServer(b)
+//			t.Cleanup(srv.Close)
+//			return mytransport.New(srv.Addr(), ...)
+//		})
+//	}
+//
+// Run the tests with -race, since some check that the transport is safe for concurrent
+// use.
+package transporttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+	"github.com/figchain/go-client/pkg/transport"
+)
+
+// EnvironmentID is the environment of the Backends passed to newTransport.
+const EnvironmentID = "env1"
+
+// TestTransport runs the conformance tests against transports created by newTransport,
+// which must return a transport of the environment EnvironmentID serving b. The transport
+// is closed once the test ends.
+func TestTransport(t *testing.T, newTransport func(t *testing.T, b *Backend) transport.Transport) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b *Backend, tr transport.Transport)
+	}{
+		{"FetchInitial", testFetchInitial},
+		{"StreamInitial", testStreamInitial},
+		{"FetchUpdate", testFetchUpdate},
+		{"FetchFamily", testFetchFamily},
+		{"GetNamespaceKey", testGetNamespaceKey},
+		{"PublicKeys", testPublicKeys},
+		{"FetchSchema", testFetchSchema},
+		{"ReportUsage", testReportUsage},
+		{"PublishFig", testPublishFig},
+		{"Namespaces", testNamespaces},
+		{"Cancellation", testCancellation},
+		{"Concurrency", testConcurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := seededBackend()
+			tr := newTransport(t, b)
+			t.Cleanup(func() { tr.Close() })
+			tt.fn(t, b, tr)
+		})
+	}
+}
+
+// testSchema is served at schemas/key1.avsc.
+const testSchema = `{"type":"record","name":"Key1","fields":[{"name":"value","type":"string"}]}`
+
+// seededBackend returns a Backend with families in ns1 and ns2.
+func seededBackend() *Backend {
+	b := NewBackend(EnvironmentID)
+	key1 := family("ns1", "key1", "v1", "v2")
+	desc := "beta users"
+	key1.Rules = []model.Rule{{
+		Description:   &desc,
+		Conditions:    []model.Condition{{Variable: "group", Operator: model.OperatorEquals, Values: []string{"beta"}}},
+		TargetVersion: "v2",
+	}}
+	b.Put(key1, family("ns1", "key2", "v1"), family("ns2", "key1", "v1"))
+	b.SetNamespaceKeys("ns1", &model.NamespaceKey{WrappedKey: "d3JhcHBlZA==", KeyID: "nskey1"})
+	b.SetSchema("schemas/key1.avsc", testSchema)
+	return b
+}
+
+// family returns a family of namespace and key with versions, the first being the
+// default, whose payloads are their version.
+func family(namespace, key string, versions ...string) model.FigFamily {
+	ff := model.FigFamily{
+		Definition:     model.FigDefinition{Namespace: namespace, Key: key},
+		DefaultVersion: &versions[0],
+	}
+	for _, v := range versions {
+		ff.Figs = append(ff.Figs, model.Fig{Version: v, Payload: []byte("\x06" + v)})
+	}
+	return ff
+}
+
+// checkFamily compares the fields of got that transports must carry with want, since
+// empty lists may come back as nil and vice versa.
+func checkFamily(t *testing.T, got, want *model.FigFamily) {
+	t.Helper()
+	if got.Definition.Namespace != want.Definition.Namespace || got.Definition.Key != want.Definition.Key {
+		t.Errorf("family %s/%s, want %s/%s", got.Definition.Namespace, got.Definition.Key, want.Definition.Namespace, want.Definition.Key)
+		return
+	}
+	name := want.Definition.Namespace + "/" + want.Definition.Key
+	if len(got.Figs) != len(want.Figs) {
+		t.Errorf("%s has %d figs, want %d", name, len(got.Figs), len(want.Figs))
+	} else {
+		for i := range want.Figs {
+			if got.Figs[i].Version != want.Figs[i].Version || string(got.Figs[i].Payload) != string(want.Figs[i].Payload) {
+				t.Errorf("%s fig %d = %s %q, want %s %q", name, i, got.Figs[i].Version, got.Figs[i].Payload, want.Figs[i].Version, want.Figs[i].Payload)
+			}
+		}
+	}
+	if (got.DefaultVersion == nil) != (want.DefaultVersion == nil) ||
+		got.DefaultVersion != nil && *got.DefaultVersion != *want.DefaultVersion {
+		t.Errorf("%s default version = %v, want %v", name, got.DefaultVersion, want.DefaultVersion)
+	}
+	if len(got.Rules) != len(want.Rules) {
+		t.Errorf("%s has %d rules, want %d", name, len(got.Rules), len(want.Rules))
+	} else {
+		for i := range want.Rules {
+			if got.Rules[i].TargetVersion != want.Rules[i].TargetVersion || len(got.Rules[i].Conditions) != len(want.Rules[i].Conditions) {
+				t.Errorf("%s rule %d = %+v, want %+v", name, i, got.Rules[i], want.Rules[i])
+			}
+		}
+	}
+}
+
+// checkFamilies compares got with the families of want, in any order.
+func checkFamilies(t *testing.T, got, want []model.FigFamily) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("got %d families, want %d", len(got), len(want))
+		return
+	}
+	for i := range want {
+		j := slices.IndexFunc(got, func(ff model.FigFamily) bool {
+			return ff.Definition.Key == want[i].Definition.Key
+		})
+		if j < 0 {
+			t.Errorf("missing family %s", want[i].Definition.Key)
+			continue
+		}
+		checkFamily(t, &got[j], &want[i])
+	}
+}
+
+func testFetchInitial(t *testing.T, b *Backend, tr transport.Transport) {
+	resp, err := tr.FetchInitial(context.Background(), &model.InitialFetchRequest{Namespace: "ns1", EnvironmentID: EnvironmentID})
+	if err != nil {
+		t.Fatalf("FetchInitial() error = %v", err)
+	}
+	want, cursor := b.Initial("ns1")
+	checkFamilies(t, resp.FigFamilies, want)
+	if resp.Cursor != cursor {
+		t.Errorf("Cursor = %q, want %q", resp.Cursor, cursor)
+	}
+	if resp.EnvironmentID != EnvironmentID {
+		t.Errorf("EnvironmentID = %q, want %q", resp.EnvironmentID, EnvironmentID)
+	}
+
+	resp, err = tr.FetchInitial(context.Background(), &model.InitialFetchRequest{Namespace: "empty", EnvironmentID: EnvironmentID})
+	if err != nil {
+		t.Fatalf("FetchInitial(empty) error = %v", err)
+	}
+	if len(resp.FigFamilies) != 0 {
+		t.Errorf("FetchInitial(empty) = %d families, want none", len(resp.FigFamilies))
+	}
+}
+
+func testStreamInitial(t *testing.T, b *Backend, tr transport.Transport) {
+	streamer, ok := tr.(transport.InitialStreamer)
+	if !ok {
+		t.Skip("the transport doesn't stream initial fetches")
+	}
+	var got []model.FigFamily
+	cursor, err := streamer.StreamInitial(context.Background(), &model.InitialFetchRequest{Namespace: "ns1", EnvironmentID: EnvironmentID}, func(ff *model.FigFamily) error {
+		got = append(got, *ff)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamInitial() error = %v", err)
+	}
+	want, wantCursor := b.Initial("ns1")
+	checkFamilies(t, got, want)
+	if cursor != wantCursor {
+		t.Errorf("StreamInitial() cursor = %q, want %q", cursor, wantCursor)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	_, err = streamer.StreamInitial(context.Background(), &model.InitialFetchRequest{Namespace: "ns1", EnvironmentID: EnvironmentID}, func(*model.FigFamily) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("StreamInitial() error = %v, want the error of fn", err)
+	}
+	if calls != 1 {
+		t.Errorf("StreamInitial() called fn %d times after it failed, want 1", calls)
+	}
+}
+
+func testFetchUpdate(t *testing.T, b *Backend, tr transport.Transport) {
+	ctx := context.Background()
+	_, cursor := b.Initial("ns1")
+	resp, err := tr.FetchUpdate(ctx, &model.UpdateFetchRequest{Namespace: "ns1", Cursor: cursor, EnvironmentID: EnvironmentID})
+	if err != nil {
+		t.Fatalf("FetchUpdate() error = %v", err)
+	}
+	if len(resp.FigFamilies) != 0 || resp.Cursor != cursor {
+		t.Errorf("FetchUpdate() = %d families at %q without changes, want none at %q", len(resp.FigFamilies), resp.Cursor, cursor)
+	}
+
+	changed := family("ns1", "key2", "v3")
+	b.Put(changed, family("ns2", "key1", "v2"))
+	resp, err = tr.FetchUpdate(ctx, &model.UpdateFetchRequest{Namespace: "ns1", Cursor: cursor, EnvironmentID: EnvironmentID})
+	if err != nil {
+		t.Fatalf("FetchUpdate() error = %v", err)
+	}
+	checkFamilies(t, resp.FigFamilies, []model.FigFamily{changed})
+	if resp.Cursor == cursor {
+		t.Errorf("FetchUpdate() cursor = %q after a change, want a new cursor", resp.Cursor)
+	}
+
+	cursor = resp.Cursor
+	resp, err = tr.FetchUpdate(ctx, &model.UpdateFetchRequest{Namespace: "ns1", Cursor: cursor, EnvironmentID: EnvironmentID})
+	if err != nil {
+		t.Fatalf("FetchUpdate() error = %v", err)
+	}
+	if len(resp.FigFamilies) != 0 {
+		t.Errorf("FetchUpdate() = %d families at the latest cursor, want none", len(resp.FigFamilies))
+	}
+}
+
+func testFetchFamily(t *testing.T, b *Backend, tr transport.Transport) {
+	got, err := tr.FetchFamily(context.Background(), "ns1", "key1")
+	if err != nil {
+		t.Fatalf("FetchFamily() error = %v", err)
+	}
+	want, _ := b.Family("ns1", "key1")
+	checkFamily(t, got, want)
+
+	if _, err := tr.FetchFamily(context.Background(), "ns1", "missing"); !errors.Is(err, transport.ErrNotFound) {
+		t.Errorf("FetchFamily(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func testGetNamespaceKey(t *testing.T, b *Backend, tr transport.Transport) {
+	keys, err := tr.GetNamespaceKey(context.Background(), "ns1")
+	if err != nil {
+		t.Fatalf("GetNamespaceKey() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].KeyID != "nskey1" || keys[0].WrappedKey != "d3JhcHBlZA==" {
+		t.Errorf("GetNamespaceKey() = %+v, want the key of ns1", keys)
+	}
+}
+
+func testPublicKeys(t *testing.T, b *Backend, tr transport.Transport) {
+	ctx := context.Background()
+	key := &model.UserPublicKey{Email: "dev@example.com", PublicKey: "public", Algorithm: "RSA"}
+	if err := tr.UploadPublicKey(ctx, key); err != nil {
+		t.Fatalf("UploadPublicKey() error = %v", err)
+	}
+	keys, err := tr.ListPublicKeys(ctx, "dev@example.com")
+	if err != nil {
+		t.Fatalf("ListPublicKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].KeyID == "" || keys[0].PublicKey != "public" || keys[0].Algorithm != "RSA" {
+		t.Fatalf("ListPublicKeys() = %+v, want the uploaded key with its ID", keys)
+	}
+	if others, err := tr.ListPublicKeys(ctx, "other@example.com"); err != nil || len(others) != 0 {
+		t.Errorf("ListPublicKeys(other) = %+v, %v, want none", others, err)
+	}
+
+	if err := tr.DeletePublicKey(ctx, keys[0].KeyID); err != nil {
+		t.Fatalf("DeletePublicKey() error = %v", err)
+	}
+	if keys, err := tr.ListPublicKeys(ctx, "dev@example.com"); err != nil || len(keys) != 0 {
+		t.Errorf("ListPublicKeys() = %+v, %v after deleting the key, want none", keys, err)
+	}
+	if err := tr.DeletePublicKey(ctx, keys[0].KeyID); !errors.Is(err, transport.ErrNotFound) {
+		t.Errorf("DeletePublicKey() error = %v for a deleted key, want ErrNotFound", err)
+	}
+}
+
+func testFetchSchema(t *testing.T, b *Backend, tr transport.Transport) {
+	schema, err := tr.FetchSchema(context.Background(), "schemas/key1.avsc")
+	if err != nil {
+		t.Fatalf("FetchSchema() error = %v", err)
+	}
+	if schema != testSchema {
+		t.Errorf("FetchSchema() = %q, want %q", schema, testSchema)
+	}
+	if _, err := tr.FetchSchema(context.Background(), "schemas/missing.avsc"); !errors.Is(err, transport.ErrNotFound) {
+		t.Errorf("FetchSchema(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func testReportUsage(t *testing.T, b *Backend, tr transport.Transport) {
+	report := &model.UsageReport{
+		EnvironmentID: EnvironmentID,
+		InstanceID:    "instance1",
+		Keys:          []model.KeyUsage{{Namespace: "ns1", Key: "key1", Reads: 3}},
+	}
+	if err := tr.ReportUsage(context.Background(), report); err != nil {
+		t.Fatalf("ReportUsage() error = %v", err)
+	}
+	reports := b.UsageReports()
+	if len(reports) != 1 || reports[0].InstanceID != "instance1" || len(reports[0].Keys) != 1 || reports[0].Keys[0].Reads != 3 {
+		t.Errorf("backend received %+v, want the report", reports)
+	}
+}
+
+func testPublishFig(t *testing.T, b *Backend, tr transport.Transport) {
+	ctx := context.Background()
+	version := "v3"
+	resp, err := tr.PublishFig(ctx, &model.PublishRequest{
+		Namespace:      "ns1",
+		Key:            "key2",
+		EnvironmentID:  EnvironmentID,
+		Version:        version,
+		Payload:        []byte("\x06v3"),
+		Rules:          []model.Rule{},
+		DefaultVersion: &version,
+	})
+	if err != nil {
+		t.Fatalf("PublishFig() error = %v", err)
+	}
+	if resp.Version != version {
+		t.Errorf("PublishFig() version = %q, want %q", resp.Version, version)
+	}
+	got, err := tr.FetchFamily(ctx, "ns1", "key2")
+	if err != nil {
+		t.Fatalf("FetchFamily() error = %v", err)
+	}
+	want := family("ns1", "key2", "v1", "v3")
+	want.DefaultVersion = &version
+	checkFamily(t, got, &want)
+
+	dryRun := "v4"
+	if _, err := tr.PublishFig(ctx, &model.PublishRequest{Namespace: "ns1", Key: "key3", EnvironmentID: EnvironmentID, Version: dryRun, Payload: []byte("\x06v4"), DryRun: true}); err != nil {
+		t.Fatalf("PublishFig(dry run) error = %v", err)
+	}
+	if _, err := tr.FetchFamily(ctx, "ns1", "key3"); !errors.Is(err, transport.ErrNotFound) {
+		t.Errorf("FetchFamily() error = %v after a dry run, want ErrNotFound", err)
+	}
+}
+
+func testNamespaces(t *testing.T, b *Backend, tr transport.Transport) {
+	ctx := context.Background()
+	namespaces, err := tr.ListNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("ListNamespaces() error = %v", err)
+	}
+	var names []string
+	for _, ns := range namespaces {
+		names = append(names, ns.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"ns1", "ns2"}) {
+		t.Errorf("ListNamespaces() = %v, want [ns1 ns2]", names)
+	}
+
+	ns, err := tr.GetNamespace(ctx, "ns1")
+	if err != nil {
+		t.Fatalf("GetNamespace() error = %v", err)
+	}
+	if ns.Name != "ns1" || ns.KeyCount != 2 {
+		t.Errorf("GetNamespace() = %+v, want ns1 with 2 keys", ns)
+	}
+	if _, err := tr.GetNamespace(ctx, "ns3"); !errors.Is(err, transport.ErrNotFound) {
+		t.Errorf("GetNamespace(missing) error = %v, want ErrNotFound", err)
+	}
+
+	req := &model.CreateNamespaceRequest{EnvironmentID: EnvironmentID, Name: "ns3", Description: "new", Encrypted: true}
+	created, err := tr.CreateNamespace(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateNamespace() error = %v", err)
+	}
+	if created.Name != "ns3" || created.Description != "new" || !created.Encrypted {
+		t.Errorf("CreateNamespace() = %+v, want the created namespace", created)
+	}
+	if _, err := tr.CreateNamespace(ctx, req); !errors.Is(err, transport.ErrConflict) {
+		t.Errorf("CreateNamespace() error = %v for an existing namespace, want ErrConflict", err)
+	}
+}
+
+func testCancellation(t *testing.T, b *Backend, tr transport.Transport) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tr.FetchInitial(ctx, &model.InitialFetchRequest{Namespace: "ns1", EnvironmentID: EnvironmentID}); !errors.Is(err, context.Canceled) {
+		t.Errorf("FetchInitial() error = %v with a cancelled context, want context.Canceled", err)
+	}
+	if _, err := tr.FetchUpdate(ctx, &model.UpdateFetchRequest{Namespace: "ns1", Cursor: "0", EnvironmentID: EnvironmentID}); !errors.Is(err, context.Canceled) {
+		t.Errorf("FetchUpdate() error = %v with a cancelled context, want context.Canceled", err)
+	}
+	if _, err := tr.FetchFamily(ctx, "ns1", "key1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("FetchFamily() error = %v with a cancelled context, want context.Canceled", err)
+	}
+}
+
+func testConcurrency(t *testing.T, b *Backend, tr transport.Transport) {
+	const workers, requests = 4, 10
+	ctx := context.Background()
+	_, cursor := b.Initial("ns1")
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*requests)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range requests {
+				var err error
+				switch i % 3 {
+				case 0:
+					_, err = tr.FetchInitial(ctx, &model.InitialFetchRequest{Namespace: "ns1", EnvironmentID: EnvironmentID})
+				case 1:
+					_, err = tr.FetchUpdate(ctx, &model.UpdateFetchRequest{Namespace: "ns1", Cursor: cursor, EnvironmentID: EnvironmentID})
+				case 2:
+					_, err = tr.FetchFamily(ctx, "ns1", "key1")
+				}
+				if err != nil {
+					errs <- fmt.Errorf("worker %d request %d: %w", w, i, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -5,16 +5,16 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
-	"os"
 )
 
 // LoadRSAPrivateKey loads an RSA private key from a PEM-encoded file.
 // It supports both PKCS1 and PKCS8 formats.
 func LoadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
-	keyBytes, err := os.ReadFile(path)
+	keyBytes, err := ReadKeyFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read file: %w", err)
 	}
+	defer clear(keyBytes)
 	return ParseRSAPrivateKey(keyBytes)
 }
 
@@ -24,6 +24,7 @@ func ParseRSAPrivateKey(keyBytes []byte) (*rsa.PrivateKey, error) {
 	if block == nil {
 		return nil, fmt.Errorf("decode pem failed")
 	}
+	defer clear(block.Bytes)
 
 	// Try PKCS8
 	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
@@ -0,0 +1,11 @@
+//go:build !figchain_nokeyfiles && !tinygo
+
+package util
+
+import "os"
+
+// ReadKeyFile reads the private key file at path. Every private key loaded from a file
+// is read with it, so that builds without a filesystem can leave key files out.
+func ReadKeyFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
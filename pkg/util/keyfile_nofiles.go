@@ -0,0 +1,12 @@
+//go:build figchain_nokeyfiles || tinygo
+
+package util
+
+import "errors"
+
+var errKeyFilesUnsupported = errors.New("key files are not supported in this build (figchain_nokeyfiles or TinyGo)")
+
+// ReadKeyFile returns an error: key files aren't supported in this build.
+func ReadKeyFile(path string) ([]byte, error) {
+	return nil, errKeyFilesUnsupported
+}
@@ -0,0 +1,57 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"filippo.io/age"
+)
+
+// ageBackupKey decrypts BackupVersionAge backups with an X25519 age identity.
+type ageBackupKey struct {
+	identity *age.X25519Identity
+}
+
+func parseAgeKey(data []byte) (BackupKey, error) {
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identity: %w", err)
+	}
+	for _, id := range identities {
+		if x, ok := id.(*age.X25519Identity); ok {
+			return ageBackupKey{x}, nil
+		}
+	}
+	return nil, fmt.Errorf("no X25519 age identity found")
+}
+
+// Fingerprint is the SHA-256 of the identity's recipient, e.g. "age1...".
+func (k ageBackupKey) Fingerprint() (string, error) {
+	hash := sha256.Sum256([]byte(k.identity.Recipient().String()))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func (k ageBackupKey) Accepts(version string) bool {
+	return version == BackupVersionAge
+}
+
+func (k ageBackupKey) Decrypt(backup *VaultBackup) ([]byte, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(backup.EncryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 data: %w", err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(encrypted), k.identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		clear(payload)
+		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	return payload, nil
+}
@@ -11,12 +11,13 @@ import (
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
-	"os"
+
+	"github.com/figchain/go-client/pkg/util"
 )
 
 // LoadPrivateKey loads an RSA private key from a PEM file.
 func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
-	keyBytes, err := os.ReadFile(path)
+	keyBytes, err := util.ReadKeyFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read private key file: %w", err)
 	}
@@ -0,0 +1,34 @@
+//go:build figchain_noaws || tinygo
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	fc_config "github.com/figchain/go-client/pkg/config"
+)
+
+var errS3Unsupported = errors.New("the S3 vault fetcher is not included in this build (figchain_noaws or TinyGo); set a VaultFetcher with config.WithVaultFetcher")
+
+// S3VaultFetcher fetches backup files from S3. Builds without the AWS SDK can't create
+// one.
+type S3VaultFetcher struct{}
+
+// NewS3VaultFetcher returns an error: the AWS SDK isn't included in this build.
+func NewS3VaultFetcher(ctx context.Context, cfg *fc_config.Config) (*S3VaultFetcher, error) {
+	return nil, errS3Unsupported
+}
+
+func (f *S3VaultFetcher) FetchBackup(ctx context.Context, keyFingerprint string) (io.ReadCloser, error) {
+	return nil, errS3Unsupported
+}
+
+func (f *S3VaultFetcher) ListBackups(ctx context.Context, keyFingerprint string) ([]BackupInfo, error) {
+	return nil, errS3Unsupported
+}
+
+func (f *S3VaultFetcher) FetchBackupNamed(ctx context.Context, name string) (io.ReadCloser, error) {
+	return nil, errS3Unsupported
+}
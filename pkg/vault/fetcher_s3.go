@@ -0,0 +1,100 @@
+//go:build !figchain_noaws && !tinygo
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	fc_config "github.com/figchain/go-client/pkg/config"
+)
+
+// S3VaultFetcher fetches backup files from S3.
+type S3VaultFetcher struct {
+	client     *s3.Client
+	bucketName string
+	prefix     string
+}
+
+// NewS3VaultFetcher creates a new S3VaultFetcher.
+func NewS3VaultFetcher(ctx context.Context, cfg *fc_config.Config) (*S3VaultFetcher, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config: %w", err)
+	}
+
+	if cfg.VaultRegion != "" {
+		awsCfg.Region = cfg.VaultRegion
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.VaultEndpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.VaultEndpoint)
+		}
+		if cfg.VaultPathStyle {
+			o.UsePathStyle = true
+		}
+	})
+
+	return &S3VaultFetcher{
+		client:     client,
+		bucketName: cfg.VaultBucket,
+		prefix:     cfg.VaultPrefix,
+	}, nil
+}
+
+// objectKey returns the S3 key of name under the fingerprint's prefix.
+func (f *S3VaultFetcher) objectKey(keyFingerprint, name string) string {
+	key := path.Join(keyFingerprint, name)
+	if f.prefix != "" {
+		key = path.Join(f.prefix, key)
+	}
+	return strings.TrimPrefix(key, "/") // Ensure no leading slash for S3 key if prefix was empty/root
+}
+
+// FetchBackup fetches the backup file from S3 for a given key fingerprint.
+func (f *S3VaultFetcher) FetchBackup(ctx context.Context, keyFingerprint string) (io.ReadCloser, error) {
+	return f.FetchBackupNamed(ctx, f.objectKey(keyFingerprint, "backup.json"))
+}
+
+// ListBackups lists the JSON objects under the key fingerprint's prefix, timestamped by
+// when they were last modified.
+func (f *S3VaultFetcher) ListBackups(ctx context.Context, keyFingerprint string) ([]BackupInfo, error) {
+	var backups []BackupInfo
+	paginator := s3.NewListObjectsV2Paginator(f.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(f.bucketName),
+		Prefix: aws.String(f.objectKey(keyFingerprint, "") + "/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			if !strings.HasSuffix(aws.ToString(obj.Key), ".json") {
+				continue
+			}
+			backups = append(backups, BackupInfo{Name: aws.ToString(obj.Key), Time: aws.ToTime(obj.LastModified)})
+		}
+	}
+	return backups, nil
+}
+
+// FetchBackupNamed fetches the backup object with the given key.
+func (f *S3VaultFetcher) FetchBackupNamed(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := f.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.bucketName),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+var testPayload = &VaultPayload{TenantID: "tenant-1", SyncToken: "sync-1"}
+
+func writeKey(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadBackupKey_Age(t *testing.T) {
+	identity, _ := age.GenerateX25519Identity()
+	plaintext, _ := json.Marshal(testPayload)
+	var encrypted bytes.Buffer
+	w, _ := age.Encrypt(&encrypted, identity.Recipient())
+	w.Write(plaintext)
+	w.Close()
+
+	key, err := LoadBackupKey(writeKey(t, []byte("# created: today\n"+identity.String()+"\n")))
+	if err != nil {
+		t.Fatalf("LoadBackupKey failed: %v", err)
+	}
+	backup := &VaultBackup{Version: BackupVersionAge, EncryptedData: base64.StdEncoding.EncodeToString(encrypted.Bytes())}
+	payload, err := DecryptBackup(backup, key)
+	if err != nil || payload.TenantID != "tenant-1" {
+		t.Fatalf("DecryptBackup = %+v, %v", payload, err)
+	}
+
+	backup.Version = "1"
+	if _, err := DecryptBackup(backup, key); err == nil {
+		t.Error("DecryptBackup accepted an RSA backup for an age key")
+	}
+}
+
+func TestLoadBackupKey_PGP(t *testing.T) {
+	entity, err := openpgp.NewEntity("Vault", "", "vault@example.com", nil)
+	if err != nil {
+		t.Fatalf("NewEntity failed: %v", err)
+	}
+	var armored bytes.Buffer
+	aw, _ := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	entity.SerializePrivate(aw, nil)
+	aw.Close()
+
+	plaintext, _ := json.Marshal(testPayload)
+	var encrypted bytes.Buffer
+	w, _ := openpgp.Encrypt(&encrypted, []*openpgp.Entity{entity}, nil, nil, nil)
+	w.Write(plaintext)
+	w.Close()
+
+	key, err := LoadBackupKey(writeKey(t, armored.Bytes()))
+	if err != nil {
+		t.Fatalf("LoadBackupKey failed: %v", err)
+	}
+	if fp, _ := key.Fingerprint(); fp == "" {
+		t.Error("empty fingerprint")
+	}
+	data := encrypted.Bytes()
+	backup := &VaultBackup{Version: BackupVersionPGP, EncryptedData: base64.StdEncoding.EncodeToString(data)}
+	payload, err := DecryptBackup(backup, key)
+	if err != nil || payload.SyncToken != "sync-1" {
+		t.Fatalf("DecryptBackup = %+v, %v", payload, err)
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-5] ^= 1
+	backup.EncryptedData = base64.StdEncoding.EncodeToString(tampered)
+	if _, err := DecryptBackup(backup, key); err == nil {
+		t.Error("DecryptBackup accepted a tampered message")
+	}
+}
+
+func TestLoadBackupKey_RSA(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	key, err := LoadBackupKey(writeKey(t, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+	if err != nil {
+		t.Fatalf("LoadBackupKey failed: %v", err)
+	}
+	want, _ := CalculateKeyFingerprint(rsaKey)
+	if got, _ := key.Fingerprint(); got != want {
+		t.Errorf("Fingerprint = %s, want %s", got, want)
+	}
+	_, err = DecryptBackup(&VaultBackup{Version: BackupVersionAge}, key)
+	if err == nil || !strings.Contains(err.Error(), "not encrypted for") {
+		t.Errorf("DecryptBackup of an age backup with an RSA key error = %v", err)
+	}
+}
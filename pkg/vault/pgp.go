@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// pgpBackupKey decrypts BackupVersionPGP backups with an OpenPGP private key.
+type pgpBackupKey struct {
+	entity *openpgp.Entity
+}
+
+func parsePGPKey(data []byte) (BackupKey, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenPGP key: %w", err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("expected one OpenPGP key, found %d", len(entities))
+	}
+	entity := entities[0]
+	if entity.PrivateKey == nil {
+		return nil, fmt.Errorf("OpenPGP key has no private key")
+	}
+	if entity.PrivateKey.Encrypted {
+		return nil, fmt.Errorf("passphrase-protected OpenPGP keys are not supported")
+	}
+	return pgpBackupKey{entity}, nil
+}
+
+// Fingerprint is the hex fingerprint of the primary key.
+func (k pgpBackupKey) Fingerprint() (string, error) {
+	return hex.EncodeToString(k.entity.PrimaryKey.Fingerprint), nil
+}
+
+func (k pgpBackupKey) Accepts(version string) bool {
+	return version == BackupVersionPGP
+}
+
+func (k pgpBackupKey) Decrypt(backup *VaultBackup) ([]byte, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(backup.EncryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 data: %w", err)
+	}
+	md, err := openpgp.ReadMessage(bytes.NewReader(encrypted), openpgp.EntityList{k.entity}, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	// Reading to the end checks the message's integrity protection
+	payload, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		clear(payload)
+		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	return payload, nil
+}
@@ -3,10 +3,14 @@ package vault
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"time"
 
 	fc_config "github.com/figchain/go-client/pkg/config"
+	"github.com/figchain/go-client/pkg/logging"
 	"github.com/figchain/go-client/pkg/model"
 )
 
@@ -51,24 +55,71 @@ func (s *VaultService) LoadBackup(ctx context.Context) (*VaultPayload, error) {
 	}
 
 	// 1. Load Private Key
-	privateKey, err := LoadPrivateKey(s.cfg.VaultPrivateKeyPath)
+	privateKey, err := LoadBackupKey(s.cfg.VaultPrivateKeyPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load private key: %w", err)
 	}
 
 	// 2. Calculate Fingerprint
-	fingerprint, err := CalculateKeyFingerprint(privateKey)
+	fingerprint, err := privateKey.Fingerprint()
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate key fingerprint: %w", err)
 	}
 
 	// 3. Fetch Encrypted Backup
+	if lister, ok := s.fetcher.(BackupLister); ok {
+		return s.loadNewestBackup(ctx, lister, fingerprint, privateKey)
+	}
 	reader, err := s.fetcher.FetchBackup(ctx, fingerprint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch backup: %w", err)
 	}
-	defer reader.Close()
+	return readBackup(reader, privateKey)
+}
 
+// loadNewestBackup restores the newest listed backup that decrypts, taken at or before
+// the AsOfTimestamp if one is configured.
+func (s *VaultService) loadNewestBackup(ctx context.Context, lister BackupLister, fingerprint string, privateKey BackupKey) (*VaultPayload, error) {
+	backups, err := lister.ListBackups(ctx, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	if s.cfg.AsOfTimestamp != "" {
+		asOf, err := time.Parse(time.RFC3339, s.cfg.AsOfTimestamp)
+		if err != nil {
+			logging.Printf("Invalid AsOfTimestamp format ignored: %s", s.cfg.AsOfTimestamp)
+		} else {
+			backups = slices.DeleteFunc(backups, func(b BackupInfo) bool { return b.Time.After(asOf) })
+		}
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no backups found for key %s", fingerprint)
+	}
+	slices.SortFunc(backups, func(a, b BackupInfo) int { return b.Time.Compare(a.Time) })
+
+	var errs []error
+	for _, b := range backups {
+		payload, err := fetchNamedBackup(ctx, lister, b.Name, privateKey)
+		if err == nil {
+			return payload, nil
+		}
+		logging.Printf("Vault backup %s is unusable, falling back to the previous one: %v", b.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+	}
+	return nil, fmt.Errorf("no usable backup: %w", errors.Join(errs...))
+}
+
+func fetchNamedBackup(ctx context.Context, lister BackupLister, name string, privateKey BackupKey) (*VaultPayload, error) {
+	reader, err := lister.FetchBackupNamed(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch backup: %w", err)
+	}
+	return readBackup(reader, privateKey)
+}
+
+// readBackup reads, closes and decrypts a backup file.
+func readBackup(reader io.ReadCloser, privateKey BackupKey) (*VaultPayload, error) {
+	defer reader.Close()
 	backupBytes, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read backup: %w", err)
@@ -79,21 +130,23 @@ func (s *VaultService) LoadBackup(ctx context.Context) (*VaultPayload, error) {
 		return nil, fmt.Errorf("failed to parse backup file: %w", err)
 	}
 
-	// 4. Decrypt AES Key
-	aesKey, err := DecryptAesKey(backup.EncryptedKey, privateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt AES key: %w", err)
-	}
+	return DecryptBackup(&backup, privateKey)
+}
 
-	// 5. Decrypt Data
-	jsonPayload, err := DecryptData(backup.EncryptedData, aesKey)
+// DecryptBackup decrypts a backup with the private key it was encrypted for. The backup's
+// version selects the encryption scheme, which must be the key's.
+func DecryptBackup(backup *VaultBackup, privateKey BackupKey) (*VaultPayload, error) {
+	if !privateKey.Accepts(backup.Version) {
+		return nil, fmt.Errorf("backup version %q is not encrypted for a %T", backup.Version, privateKey)
+	}
+	jsonPayload, err := privateKey.Decrypt(backup)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+		return nil, err
 	}
+	defer clear(jsonPayload)
 
-	// 6. Parse Payload
 	var payload VaultPayload
-	if err := json.Unmarshal([]byte(jsonPayload), &payload); err != nil {
+	if err := json.Unmarshal(jsonPayload, &payload); err != nil {
 		return nil, fmt.Errorf("failed to parse payload: %w", err)
 	}
 
@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	fc_config "github.com/figchain/go-client/pkg/config"
+)
+
+// fakeLister keeps backups by name.
+type fakeLister struct {
+	backups map[string][]byte
+	times   map[string]time.Time
+}
+
+func (f *fakeLister) FetchBackup(ctx context.Context, keyFingerprint string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("FetchBackup called on a BackupLister")
+}
+
+func (f *fakeLister) ListBackups(_ context.Context, _ string) ([]BackupInfo, error) {
+	var infos []BackupInfo
+	for name, t := range f.times {
+		infos = append(infos, BackupInfo{Name: name, Time: t})
+	}
+	return infos, nil
+}
+
+func (f *fakeLister) FetchBackupNamed(_ context.Context, name string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(f.backups[name])), nil
+}
+
+func TestVaultService_LoadBackup_Rollback(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyPath := writeKey(t, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	lister := &fakeLister{
+		backups: map[string][]byte{
+			"old":     newBundle(t, key, &VaultPayload{SyncToken: "old"}),
+			"middle":  newBundle(t, key, &VaultPayload{SyncToken: "middle"}),
+			"corrupt": []byte(`{"version":"1","encryptedKey":"AAAA","encryptedData":"AAAA"}`),
+		},
+		times: map[string]time.Time{
+			"old":     base,
+			"middle":  base.Add(time.Hour),
+			"corrupt": base.Add(2 * time.Hour),
+		},
+	}
+	load := func(asOf string) (*VaultPayload, error) {
+		cfg := fc_config.DefaultConfig()
+		fc_config.WithVaultFetcher(lister)(cfg)
+		fc_config.WithVaultPrivateKeyPath(keyPath)(cfg)
+		cfg.AsOfTimestamp = asOf
+		return NewVaultService(cfg, lister).LoadBackup(context.Background())
+	}
+
+	// The newest backup doesn't decrypt, so the previous one is restored
+	payload, err := load("")
+	if err != nil || payload.SyncToken != "middle" {
+		t.Errorf("LoadBackup = %+v, %v; want the middle backup", payload, err)
+	}
+	payload, err = load(base.Add(30 * time.Minute).Format(time.RFC3339))
+	if err != nil || payload.SyncToken != "old" {
+		t.Errorf("LoadBackup as of before the middle backup = %+v, %v; want the old backup", payload, err)
+	}
+	if _, err := load(base.Add(-time.Minute).Format(time.RFC3339)); err == nil {
+		t.Error("LoadBackup as of before every backup succeeded")
+	}
+}
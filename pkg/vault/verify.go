@@ -0,0 +1,95 @@
+package vault
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// BundleReport summarizes a verified backup bundle.
+type BundleReport struct {
+	Version        string            `json:"version"`
+	KeyFingerprint string            `json:"keyFingerprint"`
+	TenantID       string            `json:"tenantId"`
+	GeneratedAt    time.Time         `json:"generatedAt"`
+	SyncToken      string            `json:"syncToken"`
+	Families       int               `json:"families"`
+	Figs           int               `json:"figs"`
+	Namespaces     []NamespaceReport `json:"namespaces"`
+}
+
+// NamespaceReport summarizes one namespace of a backup bundle.
+type NamespaceReport struct {
+	Namespace string   `json:"namespace"`
+	Keys      []string `json:"keys"`
+	Figs      int      `json:"figs"`
+}
+
+// VerifyBundle checks a backup bundle offline, without a client or network access: that
+// it was encrypted for privateKey, that its payload decrypts and is authentic (every
+// scheme authenticates it, so a tampered bundle fails to decrypt) and that every family
+// in it is well-formed. It returns a summary of the bundle's contents.
+func VerifyBundle(r io.Reader, privateKey BackupKey) (*BundleReport, error) {
+	var backup VaultBackup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("failed to parse backup file: %w", err)
+	}
+
+	fingerprint, err := privateKey.Fingerprint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate key fingerprint: %w", err)
+	}
+	if backup.KeyFingerprint != "" && backup.KeyFingerprint != fingerprint {
+		return nil, fmt.Errorf("bundle was encrypted for key %s, not %s", backup.KeyFingerprint, fingerprint)
+	}
+
+	payload, err := DecryptBackup(&backup, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BundleReport{
+		Version:        backup.Version,
+		KeyFingerprint: fingerprint,
+		TenantID:       payload.TenantID,
+		SyncToken:      payload.SyncToken,
+		Families:       len(payload.Items),
+	}
+	if payload.GeneratedAt != "" {
+		if report.GeneratedAt, err = time.Parse(time.RFC3339, payload.GeneratedAt); err != nil {
+			return nil, fmt.Errorf("invalid generatedAt: %w", err)
+		}
+	}
+
+	byNamespace := make(map[string]*NamespaceReport)
+	seen := make(map[[2]string]bool)
+	for i, ff := range payload.Items {
+		def := ff.Definition
+		if def.Namespace == "" || def.Key == "" {
+			return nil, fmt.Errorf("item %d has no namespace or key", i)
+		}
+		if seen[[2]string{def.Namespace, def.Key}] {
+			return nil, fmt.Errorf("duplicate family %s/%s", def.Namespace, def.Key)
+		}
+		seen[[2]string{def.Namespace, def.Key}] = true
+		ns := byNamespace[def.Namespace]
+		if ns == nil {
+			ns = &NamespaceReport{Namespace: def.Namespace}
+			byNamespace[def.Namespace] = ns
+		}
+		ns.Keys = append(ns.Keys, def.Key)
+		ns.Figs += len(ff.Figs)
+		report.Figs += len(ff.Figs)
+	}
+	for _, ns := range byNamespace {
+		slices.Sort(ns.Keys)
+		report.Namespaces = append(report.Namespaces, *ns)
+	}
+	slices.SortFunc(report.Namespaces, func(a, b NamespaceReport) int {
+		return cmp.Compare(a.Namespace, b.Namespace)
+	})
+	return report, nil
+}
@@ -0,0 +1,107 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/figchain/go-client/pkg/model"
+)
+
+// newBundle encrypts payload for key the way the FigChain server does.
+func newBundle(t *testing.T, key *rsa.PrivateKey, payload *VaultPayload) []byte {
+	t.Helper()
+	aesKey := make([]byte, 32)
+	rand.Read(aesKey)
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, aesKey, nil)
+	if err != nil {
+		t.Fatalf("failed to encrypt AES key: %v", err)
+	}
+	plaintext, _ := json.Marshal(payload)
+	block, _ := aes.NewCipher(aesKey)
+	gcm, _ := cipher.NewGCM(block)
+	iv := make([]byte, gcm.NonceSize())
+	rand.Read(iv)
+	fingerprint, _ := CalculateKeyFingerprint(key)
+	bundle, _ := json.Marshal(&VaultBackup{
+		Version:        "1",
+		KeyFingerprint: fingerprint,
+		EncryptedKey:   base64.StdEncoding.EncodeToString(encryptedKey),
+		EncryptedData:  base64.StdEncoding.EncodeToString(gcm.Seal(iv, iv, plaintext, nil)),
+	})
+	return bundle
+}
+
+func family(namespace, key string, figs int) model.FigFamily {
+	return model.FigFamily{
+		Definition: model.FigDefinition{Namespace: namespace, Key: key},
+		Figs:       make([]model.Fig, figs),
+	}
+}
+
+func TestVerifyBundle(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	bundle := newBundle(t, key, &VaultPayload{
+		TenantID:    "tenant-1",
+		GeneratedAt: "2025-06-01T12:00:00Z",
+		SyncToken:   "sync-1",
+		Items:       []model.FigFamily{family("web", "b", 2), family("api", "x", 1), family("web", "a", 1)},
+	})
+
+	report, err := VerifyBundle(bytes.NewReader(bundle), NewRSABackupKey(key))
+	if err != nil {
+		t.Fatalf("VerifyBundle failed: %v", err)
+	}
+	if report.TenantID != "tenant-1" || report.GeneratedAt.Year() != 2025 || report.Families != 3 || report.Figs != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	want := []NamespaceReport{
+		{Namespace: "api", Keys: []string{"x"}, Figs: 1},
+		{Namespace: "web", Keys: []string{"a", "b"}, Figs: 3},
+	}
+	if !reflect.DeepEqual(report.Namespaces, want) {
+		t.Errorf("Namespaces = %+v, want %+v", report.Namespaces, want)
+	}
+}
+
+func TestVerifyBundle_Failures(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	valid := newBundle(t, key, &VaultPayload{Items: []model.FigFamily{family("web", "a", 1)}})
+
+	var tampered VaultBackup
+	json.Unmarshal(valid, &tampered)
+	data, _ := base64.StdEncoding.DecodeString(tampered.EncryptedData)
+	data[len(data)-1] ^= 1
+	tampered.EncryptedData = base64.StdEncoding.EncodeToString(data)
+	tamperedBundle, _ := json.Marshal(&tampered)
+
+	tests := []struct {
+		name   string
+		bundle []byte
+		key    *rsa.PrivateKey
+		want   string
+	}{
+		{"wrong key", valid, otherKey, "encrypted for key"},
+		{"tampered", tamperedBundle, key, "failed to decrypt payload"},
+		{"duplicate family", newBundle(t, key, &VaultPayload{Items: []model.FigFamily{family("web", "a", 1), family("web", "a", 1)}}), key, "duplicate family"},
+		{"bad generatedAt", newBundle(t, key, &VaultPayload{GeneratedAt: "yesterday"}), key, "invalid generatedAt"},
+		{"not json", []byte("nope"), key, "failed to parse backup file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyBundle(bytes.NewReader(tt.bundle), NewRSABackupKey(tt.key))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("VerifyBundle error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}